@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/augmentable-dev/askgit/pkg/analyze"
+	"github.com/augmentable-dev/askgit/pkg/gitqlite"
+	"github.com/spf13/cobra"
+)
+
+var (
+	doraDays   int
+	doraTags   string
+	bloatLimit int
+	tsEvery    string
+	tsQuery    string
+	tsParams   []string
+	tzDays     int
+)
+
+func init() {
+	doraCmd.Flags().IntVar(&doraDays, "days", 90, "number of days reported on, up to now")
+	doraCmd.Flags().StringVar(&doraTags, "tags", "*", "GLOB pattern matching the tags of deploys, i.e. 'v*'")
+	bloatCmd.Flags().IntVar(&bloatLimit, "limit", 20, "number of blobs ranked")
+	timeseriesCmd.Flags().StringVar(&tsEvery, "every", "1w", "period between samples, a number of hours, days, weeks, months or years, i.e. 12h, 1d, 1w, 3m or 1y")
+	timeseriesCmd.Flags().StringVar(&tsQuery, "query", "", "path of the file of the query run at every sample")
+	timeseriesCmd.Flags().StringArrayVarP(&tsParams, "param", "p", nil, "value of another parameter of the query, as name=value (may be repeated)")
+	err := timeseriesCmd.MarkFlagRequired("query")
+	handleError(err)
+	analyzeCmd.AddCommand(doraCmd)
+	analyzeCmd.AddCommand(bloatCmd)
+	timezonesCmd.Flags().IntVar(&tzDays, "days", 365, "number of days reported on, up to now (the whole history if 0)")
+	analyzeCmd.AddCommand(timeseriesCmd)
+	analyzeCmd.AddCommand(timezonesCmd)
+	rootCmd.AddCommand(analyzeCmd)
+}
+
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze",
+	Short: "run preset analyses of a repository, combining several queries into a report",
+}
+
+var doraCmd = &cobra.Command{
+	Use:   "dora",
+	Short: "report the DORA metrics of a repository: deployment frequency, lead time for changes and change failure rate",
+	Long: `
+  Reports the DORA metrics of a repository, measured from its history:
+  - deploys are the tags (matching --tags) of commits of the checked out history
+  - the lead time for changes is the time between commits being committed and the first deploy shipping them
+  - failed deploys are the ones which shipped a commit later reverted (see the reverts_hash and reverted_by columns of commits)
+
+  The report is markdown, unless --format is json.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, cleanup := repoDir(cmd)
+		defer cleanup()
+
+		g, err := gitqlite.New(dir, queryOptions(dir))
+		handleError(err)
+
+		report, err := analyze.Dora(g, &analyze.DoraOptions{
+			Since: time.Now().AddDate(0, 0, -doraDays),
+			Tags:  doraTags,
+		})
+		handleError(err)
+
+		if format == "json" {
+			err = report.WriteJSON(os.Stdout)
+		} else {
+			err = report.WriteMarkdown(os.Stdout)
+		}
+		handleError(err)
+	},
+}
+
+var bloatCmd = &cobra.Command{
+	Use:   "bloat",
+	Short: "rank the largest blobs ever committed to a repository, with the commits which introduced them",
+	Long: `
+  Ranks the largest blobs of the object database of a repository, loose or packed, along with the commit (of any ref)
+  which first introduced each of them and its path, as candidates for rewriting history or moving to LFS.
+  Blobs which no commit has anymore (i.e. left over by a rewritten history) are reported as unreachable.
+
+  The report is markdown, unless --format is json.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, cleanup := repoDir(cmd)
+		defer cleanup()
+
+		report, err := analyze.Bloat(dir, bloatLimit)
+		handleError(err)
+
+		if format == "json" {
+			err = report.WriteJSON(os.Stdout)
+		} else {
+			err = report.WriteMarkdown(os.Stdout)
+		}
+		handleError(err)
+	},
+}
+
+var timeseriesCmd = &cobra.Command{
+	Use:   "timeseries",
+	Short: "run a query at commits sampled over time, to chart how a repository grew",
+	Long: `
+  Runs a query at commits of the first parent history of the checked out commit, sampled --every period from the first one:
+  each sample is the most recent commit then, and the last one is the most recent commit. The query is given the id of the
+  commit sampled as the :commit parameter (and its date as :at), to scope the tables it reads to it. For instance, with file_count.sql:
+
+    SELECT count(*) AS files FROM files WHERE commit_id = :commit
+
+  askgit analyze timeseries --every 1m --query file_count.sql --format csv
+
+  The results are in long format, the rows of every sample following its date and commit id, ready to be charted.
+  The report is markdown, unless --format is json or csv.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, cleanup := repoDir(cmd)
+		defer cleanup()
+
+		query, err := ioutil.ReadFile(tsQuery)
+		handleError(err)
+		params, err := parseParams(tsParams)
+		handleError(err)
+
+		g, err := gitqlite.New(dir, queryOptions(dir))
+		handleError(err)
+
+		report, err := analyze.TimeSeries(g, dir, &analyze.TimeSeriesOptions{
+			Every: tsEvery,
+			Query: string(query),
+			Args:  params,
+		})
+		handleError(err)
+
+		switch format {
+		case "json":
+			err = report.WriteJSON(os.Stdout)
+		case "csv":
+			err = report.WriteCSV(os.Stdout)
+		default:
+			err = report.WriteMarkdown(os.Stdout)
+		}
+		handleError(err)
+	},
+}
+
+var timezonesCmd = &cobra.Command{
+	Use:   "timezones",
+	Short: "break the contributors of a repository down by timezone, from the offsets of their commits",
+	Long: `
+  Breaks the contributors of a repository down by timezone, as an approximation of where they work from
+  (i.e. to staff follow-the-sun rotations): every author counts in the timezone offset they authored the most commits from
+  during the last --days days, and the commits of every offset are totaled.
+  An offset isn't a location, daylight saving time moves people between offsets and some tools commit in UTC.
+
+  The report is markdown, unless --format is json.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, cleanup := repoDir(cmd)
+		defer cleanup()
+
+		g, err := gitqlite.New(dir, queryOptions(dir))
+		handleError(err)
+
+		options := &analyze.TimezoneOptions{}
+		if tzDays > 0 {
+			options.Since = time.Now().AddDate(0, 0, -tzDays)
+		}
+		report, err := analyze.Timezones(g, options)
+		handleError(err)
+
+		if format == "json" {
+			err = report.WriteJSON(os.Stdout)
+		} else {
+			err = report.WriteMarkdown(os.Stdout)
+		}
+		handleError(err)
+	},
+}
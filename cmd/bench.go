@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/augmentable-dev/askgit/pkg/gitqlite"
+	"github.com/spf13/cobra"
+)
+
+var benchCount int
+
+func init() {
+	benchCmd.Flags().IntVar(&benchCount, "count", 3, "number of times to scan each table")
+	rootCmd.AddCommand(benchCmd)
+}
+
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "time full scans of every table, with every backend",
+	Long: `
+  Scans every table of a repository, with the libgit2 backend and with the git CLI backend for the tables it implements,
+  and reports the number of rows and the time taken by the fastest and average scan.
+  Use --repo to pick a repository, results are only comparable across runs on the same repository at the same commit.
+  Combine with --cpuprofile and --memprofile to find out where time goes.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if benchCount < 1 {
+			handleError(fmt.Errorf("--count must be at least 1, got %d", benchCount))
+		}
+
+		dir, cleanup := repoDir(cmd)
+		defer cleanup()
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TABLE\tMODULE\tROWS\tMIN\tMEAN")
+
+		baseline, err := gitqlite.New(dir, &gitqlite.Options{MaxMemory: maxMemory * 1024 * 1024})
+		handleError(err)
+		cli, err := gitqlite.New(dir, &gitqlite.Options{UseGitCLI: true, MaxMemory: maxMemory * 1024 * 1024})
+		handleError(err)
+
+		for _, table := range gitqlite.Tables() {
+			// the github tables read the API of GitHub rather than the repository
+			if strings.HasPrefix(table, "github_") {
+				continue
+			}
+			for _, g := range []*gitqlite.GitQLite{baseline, cli} {
+				// only run the tables the git CLI backend implements a second time
+				if g == cli && cli.Module(table) == baseline.Module(table) {
+					continue
+				}
+
+				var rows int
+				var total, min time.Duration
+				for i := 0; i < benchCount; i++ {
+					start := time.Now()
+					rows, err = scan(g.DB, table)
+					handleError(err)
+					elapsed := time.Since(start)
+
+					total += elapsed
+					if i == 0 || elapsed < min {
+						min = elapsed
+					}
+				}
+
+				fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", table, g.Module(table), rows, min, total/time.Duration(benchCount))
+			}
+		}
+
+		err = w.Flush()
+		handleError(err)
+	},
+}
+
+// scan reads every column of every row of table, returning the number of rows
+func scan(db *sql.DB, table string) (int, error) {
+	rows, err := db.Query(fmt.Sprintf("SELECT * FROM %s", table))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+
+	values := make([]interface{}, len(columns))
+	for i := range values {
+		values[i] = new(sql.RawBytes)
+	}
+
+	count := 0
+	for rows.Next() {
+		err := rows.Scan(values...)
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, rows.Err()
+}
@@ -0,0 +1,285 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/augmentable-dev/askgit/pkg/gitqlite"
+	git "github.com/libgit2/git2go/v30"
+	"github.com/spf13/cobra"
+)
+
+var (
+	refA string
+	refB string
+)
+
+func init() {
+	diffQueryCmd.Flags().StringVar(&refA, "ref-a", "", "revision the query is run at first, i.e. v1.0")
+	diffQueryCmd.Flags().StringVar(&refB, "ref-b", "", "revision the query is run at then, i.e. v2.0")
+	err := diffQueryCmd.MarkFlagRequired("ref-a")
+	handleError(err)
+	err = diffQueryCmd.MarkFlagRequired("ref-b")
+	handleError(err)
+	rootCmd.AddCommand(diffQueryCmd)
+}
+
+var diffQueryCmd = &cobra.Command{
+	Use:   `diff-query --ref-a v1.0 --ref-b v2.0 "SELECT ..."`,
+	Short: "run a query at two revisions of a repository and output how its results changed",
+	Long: `
+  Runs a query against the repository as it was at two revisions, as if each of them was checked out
+  (every table reads from the revision rather than from HEAD), and outputs the rows of the results which changed,
+  with a change column:
+  - added rows are only in the results at --ref-b
+  - removed rows are only in the results at --ref-a
+  - changed rows have the same value in their first column in both results, but different values in other columns,
+    and are output as they are at --ref-b
+
+  Results are compared as sets, duplicate rows are only output once. For instance, the files added or resized by a release:
+
+  askgit diff-query --ref-a v1.0 --ref-b v2.0 "SELECT path, length(contents) FROM files"`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, cleanup := repoDir(cmd)
+		defer cleanup()
+
+		results, err := sql.Open("sqlite3", ":memory:")
+		handleError(err)
+		defer results.Close()
+		// every connection to :memory: is a database of its own
+		results.SetMaxOpenConns(1)
+
+		var columns [][]string
+		for _, side := range []struct{ table, ref string }{{"a", refA}, {"b", refB}} {
+			snapshot, err := snapshotRepo(dir, side.ref)
+			handleError(err)
+			defer os.RemoveAll(snapshot)
+
+			// custom views are the ones of the repository, not of the snapshot
+			g, err := gitqlite.New(snapshot, queryOptions(dir))
+			handleError(err)
+			sideColumns, err := loadResults(results, side.table, g, args[0])
+			handleErrorCode(err, exitQuery)
+			columns = append(columns, sideColumns)
+			if g.Truncated() {
+				notice("truncated: history walks stopped at %d commits, use --no-limit to walk them whole\n", gitqlite.DefaultMaxCommits)
+				exitCode = exitTruncated
+			}
+			g.DB.Close()
+		}
+
+		if strings.Join(columns[0], ",") != strings.Join(columns[1], ",") {
+			handleError(fmt.Errorf("the results have different columns at %s (%s) and at %s (%s)", refA, strings.Join(columns[0], ", "), refB, strings.Join(columns[1], ", ")))
+		}
+		rows, err := diffResults(results, columns[1])
+		handleError(err)
+		defer rows.Close()
+
+		color := colored()
+		startPager()
+		truncated, err := gitqlite.DisplayDBWithOptions(rows, os.Stdout, format, &gitqlite.DisplayOptions{
+			MaxRows:    maxRows,
+			JSONShape:  jsonShape,
+			NoHeader:   noHeader,
+			Color:      color,
+			DateFormat: dateFormat,
+			RowNumbers: rowNumbers,
+			Footer:     footer,
+		})
+		stopPager()
+		if pagerQuit(err) {
+			err = nil
+		}
+		handleError(err)
+		if truncated {
+			notice("truncated: output limited to the first %d rows by --max-rows\n", maxRows)
+			exitCode = exitTruncated
+		}
+	},
+}
+
+// snapshotRepo creates a bare repository in a temporary directory sharing the objects and the refs of the repository at dir
+// (and its shallow boundary if it's shallow), with its HEAD detached at ref, so that every table reads from ref as if it was checked out.
+// The caller removes the directory.
+func snapshotRepo(dir, ref string) (string, error) {
+	repo, err := git.OpenRepository(dir)
+	if err != nil {
+		return "", err
+	}
+	defer repo.Free()
+
+	obj, err := repo.RevparseSingle(ref)
+	if err != nil {
+		return "", err
+	}
+	defer obj.Free()
+	commit, err := obj.Peel(git.ObjectCommit)
+	if err != nil {
+		return "", err
+	}
+	defer commit.Free()
+
+	snapshotDir, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		return "", err
+	}
+	snapshot, err := git.InitRepository(snapshotDir, true)
+	if err != nil {
+		os.RemoveAll(snapshotDir)
+		return "", err
+	}
+	snapshot.Free()
+
+	// the objects are read from the repository itself, as an alternate object database,
+	// from the git directory shared by its worktrees as the one of a linked worktree has no objects
+	objects := filepath.Join(gitqlite.CommonDir(repo), "objects")
+	err = ioutil.WriteFile(filepath.Join(snapshotDir, "objects", "info", "alternates"), []byte(objects+"\n"), 0644)
+	if err != nil {
+		os.RemoveAll(snapshotDir)
+		return "", err
+	}
+
+	// walks of the history of a shallow repository stop at its boundary, where the parents of commits are missing
+	shallow, err := ioutil.ReadFile(filepath.Join(gitqlite.CommonDir(repo), "shallow"))
+	if err == nil {
+		err = ioutil.WriteFile(filepath.Join(snapshotDir, "shallow"), shallow, 0644)
+	} else if os.IsNotExist(err) {
+		err = nil
+	}
+	if err != nil {
+		os.RemoveAll(snapshotDir)
+		return "", err
+	}
+
+	// the snapshot is opened again for its object database to include the alternate
+	snapshot, err = git.OpenRepository(snapshotDir)
+	if err != nil {
+		os.RemoveAll(snapshotDir)
+		return "", err
+	}
+	defer snapshot.Free()
+
+	err = copyRefs(repo, snapshot)
+	if err == nil {
+		err = snapshot.SetHeadDetached(commit.Id())
+	}
+	if err != nil {
+		os.RemoveAll(snapshotDir)
+		return "", err
+	}
+	return snapshotDir, nil
+}
+
+// copyRefs creates the direct refs of repo (branches, tags, remote branches...) in snapshot, symbolic refs such as HEAD are left out
+func copyRefs(repo, snapshot *git.Repository) error {
+	iter, err := repo.NewReferenceIterator()
+	if err != nil {
+		return err
+	}
+	defer iter.Free()
+
+	for {
+		ref, err := iter.Next()
+		if err != nil {
+			if git.IsErrorCode(err, git.ErrIterOver) {
+				return nil
+			}
+			return err
+		}
+		if ref.Type() == git.ReferenceOid {
+			created, err := snapshot.References.Create(ref.Name(), ref.Target(), true, "")
+			if err != nil {
+				ref.Free()
+				return err
+			}
+			created.Free()
+		}
+		ref.Free()
+	}
+}
+
+// loadResults runs query with g, and stores its results in a new table of results, with the declared types of the query.
+// Its columns are named by position (c0, c1...), as the ones of the query may have the same names, which are returned.
+func loadResults(results *sql.DB, table string, g *gitqlite.GitQLite, query string) ([]string, error) {
+	rows, err := g.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	definitions := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	for i := range columns {
+		definitions[i] = fmt.Sprintf("c%d %s", i, types[i].DatabaseTypeName())
+		placeholders[i] = "?"
+	}
+	_, err = results.Exec(fmt.Sprintf("CREATE TABLE %s (%s)", table, strings.Join(definitions, ", ")))
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := results.Begin()
+	if err != nil {
+		return nil, err
+	}
+	insert, err := tx.Prepare(fmt.Sprintf("INSERT INTO %s VALUES (%s)", table, strings.Join(placeholders, ", ")))
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		err := rows.Scan(pointers...)
+		if err == nil {
+			_, err = insert.Exec(values...)
+		}
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	return columns, tx.Commit()
+}
+
+// diffResults returns the rows of the results stored in table b which aren't in table a (added, or changed if a row of a
+// has the same value in the first column), and the ones of table a which aren't in table b (removed), with their change first.
+// The stored columns are named after columns.
+func diffResults(results *sql.DB, columns []string) (*sql.Rows, error) {
+	named := make([]string, len(columns))
+	for i, column := range columns {
+		named[i] = fmt.Sprintf("c%d AS %q", i, column)
+	}
+
+	return results.Query(fmt.Sprintf(`
+		WITH
+			added AS (SELECT * FROM b EXCEPT SELECT * FROM a),
+			removed AS (SELECT * FROM a EXCEPT SELECT * FROM b)
+		SELECT * FROM (
+			SELECT CASE WHEN EXISTS (SELECT 1 FROM removed WHERE removed.c0 IS added.c0) THEN 'changed' ELSE 'added' END AS change, %[1]s
+			FROM added
+			UNION ALL
+			SELECT 'removed' AS change, %[1]s FROM removed
+			WHERE NOT EXISTS (SELECT 1 FROM added WHERE added.c0 IS removed.c0)
+		) ORDER BY 2, 1`, strings.Join(named, ", ")))
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/augmentable-dev/askgit/pkg/gitqlite"
+	"github.com/spf13/cobra"
+)
+
+var (
+	hookQuery string
+	hookForce bool
+)
+
+// hookName is the hook installed by hook install. It's not pre-commit, as queries read the checked out commit rather than
+// the staged changes: when pushing, the commits to check are the ones checked out.
+const hookName = "pre-push"
+
+func init() {
+	hookInstallCmd.Flags().StringVar(&hookQuery, "query", "", "path of the file of the query checking the repository, which fails the hook if it returns any row")
+	hookInstallCmd.Flags().BoolVar(&hookForce, "force", false, "whether to replace the hook if there already is one")
+	hookCmd.AddCommand(hookInstallCmd)
+	rootCmd.AddCommand(hookCmd)
+}
+
+var hookCmd = &cobra.Command{
+	Use:   "hook",
+	Short: "manage git hooks running askgit queries",
+}
+
+var hookInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "install a pre-push git hook failing when a query returns rows",
+	Long: `
+  Writes a pre-push hook running the query of the file given with --query,
+  which fails, printing the rows, if it returns any: the query selects the violations of a policy of the repository.
+  A query file in the repository is referred to by its path in the repository, so that the hook runs its latest version.
+  For instance, with checks.sql selecting the files larger than 1MB:
+
+    SELECT path, length(contents) AS size FROM files WHERE length(contents) > 1000000
+
+  askgit hook install --query checks.sql
+
+  Queries run on the checked out commit, which is why there is no pre-commit hook: it would check the repository
+  as it was before the commit rather than the staged changes. Pushing other commits than the checked out ones isn't checked.
+  The hook also fails if the results are truncated, when walks of the history stop at the commit limit.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if hookQuery == "" {
+			handleError(fmt.Errorf("--query is required"))
+		}
+
+		dir, cleanup := repoDir(cmd)
+		defer cleanup()
+
+		// the query is checked now rather than failing every commit
+		query, err := ioutil.ReadFile(hookQuery)
+		handleError(err)
+		g, err := gitqlite.New(dir, queryOptions(dir))
+		handleError(err)
+		err = g.Validate(string(query))
+		handleErrorCode(err, exitQuery)
+
+		queryPath, err := filepath.Abs(hookQuery)
+		handleError(err)
+		if rel, err := filepath.Rel(dir, queryPath); err == nil && !strings.HasPrefix(rel, "..") {
+			queryPath = filepath.ToSlash(rel)
+		}
+
+		hooks, err := gitqlite.HooksDir(dir)
+		handleError(err)
+		path := filepath.Join(hooks, hookName)
+		if _, err := os.Stat(path); err == nil && !hookForce {
+			handleError(fmt.Errorf("there already is a %s hook at %s, use --force to replace it", hookName, path))
+		}
+		err = os.MkdirAll(hooks, 0755)
+		handleError(err)
+		err = ioutil.WriteFile(path, []byte(hookScript(queryPath)), 0755)
+		handleError(err)
+		notice("%s hook installed at %s\n", hookName, path)
+	},
+}
+
+// hookScript returns a hook running the query of the file at queryPath (relative to the top level directory of the working tree,
+// or absolute), which fails if the query returns any row, or fails itself, or its results are truncated
+func hookScript(queryPath string) string {
+	quoted := "'" + strings.ReplaceAll(queryPath, "'", `'\''`) + "'"
+	return fmt.Sprintf(`#!/bin/sh
+# installed by askgit hook install: fails if the query of %s returns any row
+query=%s
+cd "$(git rev-parse --show-toplevel)" || exit 1
+if ! command -v askgit >/dev/null 2>&1; then
+	echo "askgit: not found, it's needed to check $query" >&2
+	exit 1
+fi
+violations=$(askgit --format csv --quiet --no-pager < "$query")
+status=$?
+# --quiet leaves out the notice of truncated results, which only checked part of the repository
+if [ "$status" -eq %d ]; then
+	echo "askgit: the results of $query are truncated, walks of the history stopped at the commit limit" >&2
+	exit 1
+fi
+if [ "$status" -ne 0 ]; then
+	exit "$status"
+fi
+# the header of the results is always output, any other line is a violation
+if [ "$(printf '%%s\n' "$violations" | wc -l)" -gt 1 ]; then
+	echo "askgit: $query found violations:" >&2
+	printf '%%s\n' "$violations" >&2
+	exit 1
+fi
+`, queryPath, quoted, exitTruncated)
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/spf13/cobra"
+)
+
+var writeCommitGraph bool
+
+func init() {
+	indexCmd.Flags().BoolVar(&writeCommitGraph, "write-commit-graph", false, "write a commit-graph file for every commit reachable from a ref, with the locally installed git command")
+	rootCmd.AddCommand(indexCmd)
+}
+
+var indexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "write files speeding up queries on a repository",
+	Long: `
+  Writes files git uses to speed up reading a repository, which askgit uses too.
+  With --write-commit-graph, writes a commit-graph file, which lets queries walking histories (i.e. on commit_branches)
+  read the parents of commits without parsing commit objects. It's only up to date with the commits made before it's written,
+  run it again (or have git maintain it with the fetch.writeCommitGraph setting) as the repository grows.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !writeCommitGraph {
+			err := cmd.Help()
+			handleError(err)
+			return
+		}
+
+		dir, cleanup := repoDir(cmd)
+		defer cleanup()
+
+		gitPath, err := exec.LookPath("git")
+		if err != nil {
+			handleError(fmt.Errorf("git is required to write a commit-graph: %v", err))
+		}
+
+		write := exec.Command(gitPath, "-C", dir, "commit-graph", "write", "--reachable")
+		write.Stdout = os.Stdout
+		write.Stderr = os.Stderr
+		err = write.Run()
+		handleError(err)
+	},
+}
@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/augmentable-dev/askgit/pkg/gitqlite"
+	git "github.com/libgit2/git2go/v30"
+	"github.com/spf13/cobra"
+)
+
+var (
+	openEditor bool
+	openPrint  bool
+	openMax    int
+)
+
+func init() {
+	openCmd.Flags().BoolVar(&openEditor, "editor", false, "whether to open files in $VISUAL or $EDITOR (vi by default) rather than in the web UI of the forge")
+	openCmd.Flags().BoolVar(&openPrint, "print", false, "whether to print the URLs rather than opening them in a browser")
+	openCmd.Flags().IntVar(&openMax, "max", 10, "maximum number of commits and files opened, so that a query forgetting a LIMIT doesn't open hundreds of tabs")
+	rootCmd.AddCommand(openCmd)
+}
+
+var openCmd = &cobra.Command{
+	Use:   "open <query>",
+	Short: "open the commits or files a query returns in the web UI of the forge, or in an editor",
+	Long: `
+  Runs a query returning commit ids or file paths in its first column, and opens them in the web UI of the forge
+  the origin remote is on (GitHub, GitLab or Bitbucket), in a browser. Files are opened at the checked out commit,
+  or in $VISUAL or $EDITOR with --editor. For instance, the most recent commit and the file changed by the most commits:
+
+  askgit open "SELECT id FROM commits LIMIT 1"
+  askgit open --editor "SELECT file FROM file_churn ORDER BY commit_count DESC LIMIT 1"`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, cleanup := repoDir(cmd)
+		defer cleanup()
+
+		g, err := gitqlite.New(dir, queryOptions(dir))
+		handleError(err)
+		values, err := firstColumn(g, args[0])
+		handleErrorCode(err, exitQuery)
+		if len(values) > openMax {
+			notice("opening the first %d of %d results, use --max to open more\n", openMax, len(values))
+			values = values[:openMax]
+		}
+
+		repo, err := git.OpenRepository(dir)
+		handleError(err)
+		defer repo.Free()
+
+		commits, files, err := classifyTargets(repo, values)
+		handleError(err)
+
+		if openEditor {
+			err = openInEditor(repo.Workdir(), files)
+			handleError(err)
+			files = nil
+		}
+		if len(commits) == 0 && len(files) == 0 {
+			return
+		}
+
+		urls, err := forgeURLs(repo, commits, files)
+		handleError(err)
+		for _, url := range urls {
+			if openPrint {
+				fmt.Println(url)
+				continue
+			}
+			err = openInBrowser(url)
+			handleError(err)
+		}
+	},
+}
+
+// firstColumn returns the values of the first column of the results of a query, leaving out NULLs
+func firstColumn(g *gitqlite.GitQLite, query string) ([]string, error) {
+	rows, err := g.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	row := make([]interface{}, len(columns))
+	var first sql.NullString
+	row[0] = &first
+	for i := 1; i < len(row); i++ {
+		row[i] = new(interface{})
+	}
+
+	values := make([]string, 0)
+	for rows.Next() {
+		err = rows.Scan(row...)
+		if err != nil {
+			return nil, err
+		}
+		if first.Valid {
+			values = append(values, first.String)
+		}
+	}
+	return values, rows.Err()
+}
+
+// classifyTargets splits the values a query returned into commits (as full ids) and paths of files of the repository
+func classifyTargets(repo *git.Repository, values []string) ([]string, []string, error) {
+	commits := make([]string, 0)
+	files := make([]string, 0)
+	for _, value := range values {
+		if commitID.MatchString(value) {
+			object, err := repo.RevparseSingle(value)
+			if err == nil {
+				commit, err := object.AsCommit()
+				object.Free()
+				if err == nil {
+					commits = append(commits, commit.Id().String())
+					commit.Free()
+					continue
+				}
+			}
+		}
+		if repo.Workdir() != "" {
+			if _, err := os.Stat(filepath.Join(repo.Workdir(), value)); err == nil {
+				files = append(files, filepath.ToSlash(value))
+				continue
+			}
+		}
+		return nil, nil, fmt.Errorf("%s is neither a commit nor a file of the repository", value)
+	}
+	return commits, files, nil
+}
+
+// commitID matches the (possibly abbreviated) id of a commit
+var commitID = regexp.MustCompile(`^[0-9a-fA-F]{7,40}$`)
+
+// forgeRemote matches the URL of a remote over HTTPS or SSH, capturing its host and path (owner/name, or group/subgroup/name)
+var forgeRemote = regexp.MustCompile(`^(?:[a-z+]+://)?(?:[^@/]+@)?([^:/]+)(?::\d+)?[:/]+(.+?)(?:\.git)?/*$`)
+
+// forgeURLs returns the URLs of commits and files in the web UI of the forge the origin remote is on,
+// files being linked at the checked out commit
+func forgeURLs(repo *git.Repository, commits, files []string) ([]string, error) {
+	remote, err := repo.Remotes.Lookup("origin")
+	if err != nil {
+		return nil, fmt.Errorf("no origin remote to open commits and files in the web UI of: %v", err)
+	}
+	defer remote.Free()
+	match := forgeRemote.FindStringSubmatch(remote.Url())
+	if match == nil {
+		return nil, fmt.Errorf("unsupported origin remote %s", remote.Url())
+	}
+	host, path := match[1], match[2]
+	base := "https://" + host + "/" + path
+
+	// the segments of the URLs of commits and files on GitHub, which most forges share
+	commitPath, filePath := "/commit/", "/blob/"
+	switch {
+	case strings.Contains(host, "gitlab"):
+		commitPath, filePath = "/-/commit/", "/-/blob/"
+	case strings.Contains(host, "bitbucket"):
+		commitPath, filePath = "/commits/", "/src/"
+	}
+
+	urls := make([]string, 0, len(commits)+len(files))
+	for _, commit := range commits {
+		urls = append(urls, base+commitPath+commit)
+	}
+	if len(files) > 0 {
+		head, err := repo.Head()
+		if err != nil {
+			return nil, err
+		}
+		defer head.Free()
+		for _, file := range files {
+			urls = append(urls, base+filePath+head.Target().String()+"/"+file)
+		}
+	}
+	return urls, nil
+}
+
+// openInBrowser opens a URL in the default browser
+func openInBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Run()
+}
+
+// openInEditor opens files of the working directory in $VISUAL or $EDITOR (vi if neither is set), all at once
+func openInEditor(workdir string, files []string) error {
+	if len(files) == 0 {
+		return nil
+	}
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// the editor may have arguments of its own (i.e. code --wait), it's run by the shell like git does
+	cmd := exec.Command("sh", append([]string{"-c", editor + ` "$@"`, editor}, files...)...)
+	cmd.Dir = workdir
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
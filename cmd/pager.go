@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// stopPager waits for the pager started by startPager to exit, once all of the output was written to it.
+// It does nothing if there is no pager running.
+var stopPager = func() {}
+
+// paging is set once stdout is piped to a pager
+var paging bool
+
+// pagerQuit returns whether err is the failure of a write to the pager because it was quit before all of the output was written to it
+// (i.e. with q in less), which is a normal end of the output rather than an error
+func pagerQuit(err error) bool {
+	return paging && errors.Is(err, syscall.EPIPE)
+}
+
+// startPager pipes stdout through a pager like git does, when it's a terminal: $ASKGIT_PAGER, $PAGER or less.
+// less is run with LESS=FRX (unless LESS is set), so that it exits right away if the output fits in the terminal, keeping colors.
+// Output goes to stdout directly if the pager can't be started, or it's cat.
+func startPager() {
+	if noPager || !isTerminal(os.Stdout) {
+		return
+	}
+	pager := os.Getenv("ASKGIT_PAGER")
+	if pager == "" {
+		pager = os.Getenv("PAGER")
+	}
+	if pager == "" {
+		pager = "less"
+	}
+	if pager == "cat" {
+		return
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return
+	}
+	cmd := exec.Command("sh", "-c", pager)
+	cmd.Stdin = r
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = os.Environ()
+	if _, ok := os.LookupEnv("LESS"); !ok {
+		cmd.Env = append(cmd.Env, "LESS=FRX")
+	}
+	err = cmd.Start()
+	r.Close()
+	if err != nil {
+		w.Close()
+		return
+	}
+
+	stdout := os.Stdout
+	os.Stdout = w
+	paging = true
+	stopPager = func() {
+		stopPager = func() {}
+		os.Stdout = stdout
+		w.Close()
+		_ = cmd.Wait()
+	}
+}
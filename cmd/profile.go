@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+var (
+	cpuProfile string
+	memProfile string
+	cpuFile    *os.File
+)
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&cpuProfile, "cpuprofile", "", "write a CPU profile of the command to this file, for use with go tool pprof")
+	rootCmd.PersistentFlags().StringVar(&memProfile, "memprofile", "", "write a memory profile to this file once the command is done, for use with go tool pprof")
+}
+
+// startProfiling starts the CPU profile requested with --cpuprofile, if any
+func startProfiling() {
+	if cpuProfile == "" {
+		return
+	}
+
+	var err error
+	cpuFile, err = os.Create(cpuProfile)
+	handleError(err)
+	err = pprof.StartCPUProfile(cpuFile)
+	handleError(err)
+}
+
+// stopProfiling stops the CPU profile and writes the memory profile requested with --memprofile, if any
+func stopProfiling() {
+	if cpuFile != nil {
+		pprof.StopCPUProfile()
+		err := cpuFile.Close()
+		handleError(err)
+	}
+
+	if memProfile != "" {
+		f, err := os.Create(memProfile)
+		handleError(err)
+		defer f.Close()
+
+		// get up-to-date statistics
+		runtime.GC()
+		err = pprof.WriteHeapProfile(f)
+		handleError(err)
+	}
+}
@@ -2,10 +2,13 @@ package cmd
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"time"
 
 	"github.com/augmentable-dev/askgit/pkg/gitqlite"
 	"github.com/augmentable-dev/askgit/pkg/tui"
@@ -21,21 +24,102 @@ var (
 	useGitCLI   bool
 	cui         bool
 	presetQuery string
+	maxMemory   int64
+	noVendored  bool
+	readOnly    bool
+	maxRows     int
+	jsonShape   string
+	validate    bool
+	noLimit     bool
+	errorFormat string
+	quiet       bool
+	noHeader    bool
+	noColor     bool
+	noPager     bool
+	dateFormat  string
+	rowNumbers  bool
+	footer      bool
 )
 
+// exitCode is the code askgit exits with once the command is done, i.e. when output was truncated
+var exitCode int
+
+// clones are the directories of the remote repositories (and bundles) cloned by repoDir, whose custom views aren't loaded (see viewDirs)
+var clones = make(map[string]bool)
+
+// the exit codes of askgit, so that scripts can tell failures apart
+const (
+	// exitError is the exit code of errors which aren't any of the following ones
+	exitError = 1
+	// exitTruncated is the exit code when output is truncated by --max-rows, or walks of histories by the commit limit
+	exitTruncated = 2
+	// exitQuery is the exit code when a query is invalid, or fails to run
+	exitQuery = 3
+	// exitClone is the exit code when a remote repository can't be cloned, i.e. for lack of access to it
+	exitClone = 4
+)
+
+// errorKinds are the kinds of errors output by --error-format json, by exit code
+var errorKinds = map[int]string{exitError: "error", exitQuery: "query", exitClone: "clone"}
+
 func init() {
-	rootCmd.PersistentFlags().StringVar(&repo, "repo", ".", "path to git repository (defaults to current directory). A remote repo may be specified, it will be cloned to a temporary directory before query execution.")
-	rootCmd.PersistentFlags().StringVar(&format, "format", "table", "specify the output format. Options are 'csv' 'tsv' 'table' 'single' and 'json'")
+	rootCmd.PersistentFlags().StringVar(&repo, "repo", ".", "path to git repository (defaults to current directory). A remote repo or a git bundle file may be specified, it will be cloned to a temporary directory before query execution.")
+	rootCmd.PersistentFlags().StringVar(&format, "format", "table", "specify the output format. Options are 'csv' 'tsv' 'table' 'single' 'json' 'sarif' 'junit' and 'arrow'")
 	rootCmd.PersistentFlags().BoolVar(&useGitCLI, "use-git-cli", false, "whether to use the locally installed git command (if it's available). Defaults to false.")
 	rootCmd.PersistentFlags().BoolVarP(&cui, "interactive", "i", false, "whether to run in interactive mode, which displays a terminal UI")
 	rootCmd.PersistentFlags().StringVar(&presetQuery, "preset", "", "used to pick a preset query")
+	rootCmd.PersistentFlags().Int64Var(&maxMemory, "max-memory", 0, "soft limit on the memory (in MB) used to run a query, past which temporary results are spilled to disk. Defaults to no limit.")
+	rootCmd.PersistentFlags().BoolVar(&noVendored, "exclude-vendored", false, "whether to leave vendored and generated files out of the stats, file_churn, dir_stats and contributors tables. Defaults to false.")
+	rootCmd.PersistentFlags().BoolVar(&readOnly, "read-only", false, "whether to only run statements reading data (SELECT, WITH and reading PRAGMA statements), for running untrusted queries. Defaults to false.")
+	rootCmd.PersistentFlags().StringVar(&jsonShape, "json-shape", "objects", "shape of the json format. Options are 'objects' (an object per row, one per line), 'arrays' (the column names then an array per row, one per line) and 'envelope' (a single object with the rows, column types, row count and duration)")
+	rootCmd.Flags().BoolVar(&validate, "validate", false, "whether to only check that the query is valid (its syntax, and the tables, columns and functions it uses) without running it, exiting with code 3 if it isn't")
+	rootCmd.PersistentFlags().BoolVar(&noLimit, "no-limit", false, fmt.Sprintf("whether to walk histories whole however long they are, rather than stopping at %d commits (in which case askgit exits with code 2). Defaults to false.", gitqlite.DefaultMaxCommits))
+	rootCmd.PersistentFlags().StringVar(&errorFormat, "error-format", "text", "format of errors, printed to stderr. Options are 'text' and 'json' (an object with the error, its kind and the exit code)")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "whether to leave out any output but the results and errors, such as truncation notices. Defaults to false.")
+	rootCmd.PersistentFlags().BoolVar(&noHeader, "no-header", false, "whether to leave out the column names of the csv, tsv and table formats (and of the arrays JSON shape). Defaults to false.")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "whether to leave out the colors of the table format, which is colored when output to a terminal (unless the NO_COLOR environment variable is set). Defaults to false.")
+	rootCmd.PersistentFlags().BoolVar(&noPager, "no-pager", false, "whether to output results to a terminal directly, rather than through $ASKGIT_PAGER or $PAGER (less by default). Defaults to false.")
+	rootCmd.PersistentFlags().StringVar(&dateFormat, "date-format", "iso", "format of the dates of DATETIME columns, in every output format. Options are 'iso' (RFC 3339), 'unix' (seconds since the epoch), 'relative' (i.e. '3 days ago') or a Go time layout (i.e. '2006-01-02 15:04')")
+	rootCmd.PersistentFlags().BoolVar(&rowNumbers, "row-numbers", false, "whether to number the rows of the table format, in a # column. Defaults to false.")
+	rootCmd.PersistentFlags().BoolVar(&footer, "footer", false, "whether to end the table format with the number of rows and the duration of the query. Defaults to false.")
+	rootCmd.PersistentFlags().IntVar(&maxRows, "max-rows", 0, "maximum number of rows to output, past which output is truncated and askgit exits with code 2. Defaults to no limit.")
 }
 
 func handleError(err error) {
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	handleErrorCode(err, exitError)
+}
+
+// isTerminal tells whether f is a terminal, rather than a file or a pipe
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// colored tells whether to color the output, which is only done for terminals which support it, unless --no-color is set
+func colored() bool {
+	_, disabled := os.LookupEnv("NO_COLOR")
+	return !noColor && !disabled && os.Getenv("TERM") != "dumb" && isTerminal(os.Stdout)
+}
+
+// notice prints a message about the output (not part of it) to stderr, unless --quiet is set
+func notice(format string, args ...interface{}) {
+	if !quiet {
+		fmt.Fprintf(os.Stderr, format, args...)
+	}
+}
+
+// handleErrorCode prints err to stderr, in the format set by --error-format, and exits with code (unless err is nil)
+func handleErrorCode(err error, code int) {
+	if err == nil {
+		return
+	}
+	stopPager()
+	if errorFormat == "json" {
+		_ = json.NewEncoder(os.Stderr).Encode(map[string]interface{}{"error": err.Error(), "kind": errorKinds[code], "exit_code": code})
+	} else {
+		fmt.Fprintln(os.Stderr, err)
 	}
+	os.Exit(code)
 }
 
 var rootCmd = &cobra.Command{
@@ -44,6 +128,12 @@ var rootCmd = &cobra.Command{
   askgit is a CLI for querying git repositories with SQL, using SQLite virtual tables.
   Example queries can be found in the GitHub repo: https://github.com/augmentable-dev/askgit`,
 	Short: `query your github repos with SQL`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		startProfiling()
+	},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		stopProfiling()
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		cwd, err := os.Getwd()
 		handleError(err)
@@ -74,51 +164,107 @@ var rootCmd = &cobra.Command{
 				handleError(fmt.Errorf("Unknown Preset Query: %s", presetQuery))
 			}
 		} else {
-			err = cmd.Help()
-			handleError(err)
-			os.Exit(0)
-		}
-		var dir string
-
-		// if the repo can be parsed as a remote git url, clone it to a temporary directory and use that as the repo path
-		if remote, err := vcsurl.Parse(repo); err == nil { // if it can be parsed
-			dir, err = ioutil.TempDir("", "repo")
-			handleError(err)
-			cloneOptions := gitqlite.CreateAuthenticationCallback(remote)
-			_, err = git.Clone(repo, dir, cloneOptions)
-			handleError(err)
-
-			defer func() {
-				err := os.RemoveAll(dir)
+			// outside of a repository, offer to pick one to explore interactively rather than only printing help
+			if _, err := gitqlite.FindRepoPath(cwd, true); err != nil && !cmd.Flags().Changed("repo") {
+				picked, err := pickRepo(os.Stdin, os.Stdout)
 				handleError(err)
-			}()
-		}
-
-		if dir == "" {
-			dir, err = filepath.Abs(repo)
-		} else {
-			dir, err = filepath.Abs(dir)
+				if picked != "" {
+					err = cmd.Flags().Set("repo", picked)
+					handleError(err)
+					cui = true
+				}
+			}
+			if !cui {
+				err = cmd.Help()
+				handleError(err)
+				os.Exit(0)
+			}
 		}
+		dir, cleanup := repoDir(cmd)
+		defer cleanup()
 
-		if err != nil {
-			handleError(err)
-		}
 		if cui {
-			tui.RunGUI(repo, dir, query)
+			// interactive sessions are remembered for the startup wizard (see pickRepo), the history is only a convenience
+			// so failing to record it doesn't fail the command
+			if entry, err := historyEntry(dir); err == nil {
+				_ = rememberRepo(entry)
+			}
+			tui.RunGUI(repo, dir, query, queryOptions(dir))
 			return
 		}
-		g, err := gitqlite.New(dir, &gitqlite.Options{
-			UseGitCLI: useGitCLI,
-		})
+		g, err := gitqlite.New(dir, queryOptions(dir))
 		handleError(err)
 
-		rows, err := g.DB.Query(query)
-		handleError(err)
-		err = gitqlite.DisplayDB(rows, os.Stdout, format)
-		handleError(err)
+		if validate {
+			err = g.Validate(query)
+			handleErrorCode(err, exitQuery)
+			return
+		}
+
+		runQuery(g, query)
 	},
 }
 
+// queryOptions returns the options of the GitQLite instances running queries on the repository in dir, as set by the flags,
+// with the custom views of its .askgit/views directory and of the home directory
+func queryOptions(dir string) *gitqlite.Options {
+	dirs, err := viewDirs(dir)
+	handleError(err)
+	views, err := customViews(dirs)
+	handleError(err)
+	orgs, err := organizations(dir)
+	handleError(err)
+
+	options := &gitqlite.Options{
+		UseGitCLI:       useGitCLI,
+		MaxMemory:       maxMemory * 1024 * 1024,
+		ExcludeVendored: noVendored,
+		ReadOnly:        readOnly,
+		Views:           views,
+		Organizations:   orgs,
+		GitHubToken:     os.Getenv("GITHUB_TOKEN"),
+	}
+	if noLimit {
+		options.MaxCommits = -1
+	}
+	return options
+}
+
+// runQuery runs a query and displays its results on stdout, as set by the flags
+func runQuery(g *gitqlite.GitQLite, query string, args ...interface{}) {
+	start := time.Now()
+	rows, err := g.Query(query, args...)
+	handleErrorCode(err, exitQuery)
+	defer rows.Close()
+
+	// colors depend on stdout being a terminal, which the pager replaces
+	color := colored()
+	startPager()
+	truncated, err := gitqlite.DisplayDBWithOptions(rows, os.Stdout, format, &gitqlite.DisplayOptions{
+		MaxRows:    maxRows,
+		JSONShape:  jsonShape,
+		Start:      start,
+		NoHeader:   noHeader,
+		Color:      color,
+		DateFormat: dateFormat,
+		RowNumbers: rowNumbers,
+		Footer:     footer,
+	})
+	stopPager()
+	if pagerQuit(err) {
+		err = nil
+	}
+	handleErrorCode(err, exitQuery)
+	if truncated {
+		notice("truncated: output limited to the first %d rows by --max-rows\n", maxRows)
+		exitCode = exitTruncated
+	}
+	if g.Truncated() {
+		notice("truncated: history walks stopped at %d commits, use --no-limit to walk them whole\n", gitqlite.DefaultMaxCommits)
+		exitCode = exitTruncated
+	}
+}
+
 // Execute runs the root command
 func Execute() {
 
@@ -126,6 +272,62 @@ func Execute() {
 		handleError(err)
 	}
 
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+
+}
+
+// repoDir returns the path of the repository to query, as given by the --repo flag.
+// Remote repositories and bundles are cloned to a temporary directory, which is removed by the returned cleanup func.
+func repoDir(cmd *cobra.Command) (string, func()) {
+	var dir string
+	var err error
+	cleanup := func() {}
+
+	// if the repo can be parsed as a remote git url, clone it to a temporary directory and use that as the repo path
+	if remote, err := vcsurl.Parse(repo); err == nil { // if it can be parsed
+		dir, err = ioutil.TempDir("", "repo")
+		handleError(err)
+		cloneOptions := gitqlite.CreateAuthenticationCallback(remote)
+		_, err = git.Clone(repo, dir, cloneOptions)
+		handleErrorCode(err, exitClone)
+
+		cleanup = func() {
+			err := os.RemoveAll(dir)
+			handleError(err)
+		}
+	} else if isBundle(repo) { // if the repo is a git bundle file, unbundle it to a temporary directory and use that as the repo path
+		dir, err = ioutil.TempDir("", "bundle")
+		handleError(err)
+		err = unbundle(repo, dir)
+		handleErrorCode(err, exitClone)
+
+		cleanup = func() {
+			err := os.RemoveAll(dir)
+			handleError(err)
+		}
+	}
+
+	cloned := dir != ""
+	if dir == "" {
+		dir, err = filepath.Abs(repo)
+	} else {
+		dir, err = filepath.Abs(dir)
+	}
+
+	if err != nil {
+		handleError(err)
+	}
+
+	// find the repository containing dir like git does, honoring GIT_DIR and friends unless a repo was explicitly provided
+	dir, err = gitqlite.FindRepoPath(dir, !cmd.Flags().Changed("repo"))
+	handleError(err)
+	if cloned {
+		clones[dir] = true
+	}
+
+	return dir, cleanup
 }
 
 func readStdin() (string, error) {
@@ -137,3 +339,33 @@ func readStdin() (string, error) {
 	returnString := string(output)
 	return returnString, nil
 }
+
+// isBundle returns whether path is a git bundle file (as created by `git bundle create`)
+func isBundle(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	header, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	return header == "# v2 git bundle\n" || header == "# v3 git bundle\n"
+}
+
+// unbundle clones the bundle file at path into dir, as a mirror so that all the refs of the bundle are kept as is
+func unbundle(path, dir string) error {
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		return fmt.Errorf("git is required to read bundle files: %v", err)
+	}
+
+	cmd := exec.Command(gitPath, "clone", "--quiet", "--mirror", path, dir)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("could not unbundle %s: %v: %s", path, err, out)
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/augmentable-dev/askgit/pkg/gitqlite"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+var runParams []string
+
+func init() {
+	runCmd.Flags().StringArrayVarP(&runParams, "param", "p", nil, "value of a parameter of the query, as name=value (may be repeated)")
+	rootCmd.AddCommand(runCmd)
+}
+
+var runCmd = &cobra.Command{
+	Use:   "run <name>",
+	Short: "run a saved query",
+	Long: `
+  Runs a query saved as <name>.sql in the .askgit/queries directory of the repository, or in ~/.askgit/queries,
+  so that a team can share a library of queries. Queries of the repository take precedence over the ones of the home directory.
+  Without a name, lists the saved queries.
+
+  Queries may have named parameters (:name, @name or $name), whose values are given with --param name=value.
+  Values which are integers are passed as integers, others as text, and parameters without a value are NULL. For instance,
+  with .askgit/queries/churn.sql:
+
+    SELECT file, commit_count FROM file_churn WHERE commit_count >= :min ORDER BY commit_count DESC
+
+  askgit run churn --param min=10`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, cleanup := repoDir(cmd)
+		defer cleanup()
+
+		dirs, err := queryDirs(dir)
+		handleError(err)
+
+		if len(args) == 0 {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tPATH")
+			for _, query := range savedQueries(dirs) {
+				fmt.Fprintf(w, "%s\t%s\n", query.name, query.path)
+			}
+			err := w.Flush()
+			handleError(err)
+			return
+		}
+
+		path, err := findQuery(dirs, args[0])
+		handleError(err)
+		query, err := ioutil.ReadFile(path)
+		handleError(err)
+		params, err := parseParams(runParams)
+		handleError(err)
+
+		g, err := gitqlite.New(dir, queryOptions(dir))
+		handleError(err)
+		runQuery(g, string(query), params...)
+	},
+}
+
+// queryDirs returns the directories saved queries are looked up in, by order of precedence:
+// the .askgit/queries directory of the repository, then the one of the home directory
+func queryDirs(repoDir string) ([]string, error) {
+	return askgitDirs(repoDir, "queries")
+}
+
+// viewDirs returns the directories custom views are looked up in, by order of precedence like queryDirs:
+// the .askgit/views directory of the repository, then the one of the home directory.
+// The views of a cloned remote repository (or bundle) aren't trusted, only the ones of the home directory are loaded then.
+func viewDirs(repoDir string) ([]string, error) {
+	dirs, err := askgitDirs(repoDir, "views")
+	if err != nil {
+		return nil, err
+	}
+	if clones[repoDir] {
+		return dirs[1:], nil
+	}
+	return dirs, nil
+}
+
+// askgitDirs returns the name subdirectory of the .askgit directories of the repository and of the home directory
+func askgitDirs(repoDir, name string) ([]string, error) {
+	dirs := []string{filepath.Join(repoDir, ".askgit", name)}
+
+	usr, err := user.Current()
+	if err != nil {
+		return nil, err
+	}
+	return append(dirs, filepath.Join(usr.HomeDir, ".askgit", name)), nil
+}
+
+// customViews reads the views defined by the <name>.sql files of dirs, each holding the SELECT statement of view name.
+// A view in several directories is only read from the first one.
+func customViews(dirs []string) (map[string]string, error) {
+	views := make(map[string]string)
+	for _, dir := range dirs {
+		paths, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			name := strings.TrimSuffix(filepath.Base(path), ".sql")
+			if _, ok := views[name]; ok {
+				continue
+			}
+			query, err := ioutil.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			views[name] = string(query)
+		}
+	}
+	return views, nil
+}
+
+// organizations reads the mapping of email domains to organizations of the organizations.yaml files of the .askgit
+// directories (see askgitDirs), i.e. google.com: Google. A domain in both files is mapped as in the one of the repository.
+func organizations(repoDir string) (map[string]string, error) {
+	paths, err := askgitDirs(repoDir, "organizations.yaml")
+	if err != nil {
+		return nil, err
+	}
+
+	orgs := make(map[string]string)
+	for i := len(paths) - 1; i >= 0; i-- {
+		contents, err := ioutil.ReadFile(paths[i])
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		var domains map[string]string
+		err = yaml.Unmarshal(contents, &domains)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", paths[i], err)
+		}
+		for domain, org := range domains {
+			orgs[domain] = org
+		}
+	}
+	return orgs, nil
+}
+
+// findQuery returns the path of the saved query name, from the first directory of dirs it's in
+func findQuery(dirs []string, name string) (string, error) {
+	// names may be in subdirectories (team/churn) but not outside of the directories
+	if filepath.IsAbs(name) || strings.Contains(filepath.ToSlash(name), "..") {
+		return "", fmt.Errorf("invalid query name %s", name)
+	}
+
+	for _, dir := range dirs {
+		path := filepath.Join(dir, filepath.FromSlash(name)+".sql")
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no saved query %s in %s, see askgit run", name, strings.Join(dirs, " or "))
+}
+
+type savedQuery struct {
+	name string
+	path string
+}
+
+// savedQueries lists the queries saved in dirs, a query in several directories is only listed from the first one
+func savedQueries(dirs []string) []*savedQuery {
+	var queries []*savedQuery
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		// a directory which doesn't exist (or can't be read) has no queries
+		_ = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || filepath.Ext(path) != ".sql" {
+				return nil
+			}
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return nil
+			}
+
+			name := strings.TrimSuffix(filepath.ToSlash(rel), ".sql")
+			if !seen[name] {
+				seen[name] = true
+				queries = append(queries, &savedQuery{name: name, path: path})
+			}
+			return nil
+		})
+	}
+	return queries
+}
+
+// parseParams parses the name=value parameters of a query into named arguments
+func parseParams(params []string) ([]interface{}, error) {
+	args := make([]interface{}, 0, len(params))
+	for _, param := range params {
+		i := strings.Index(param, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid parameter %q, expected name=value", param)
+		}
+
+		// the prefix (:, @ or $) of the parameter in the query is optional
+		name, value := strings.TrimLeft(param[:i], ":@$"), param[i+1:]
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			args = append(args, sql.Named(name, n))
+		} else {
+			args = append(args, sql.Named(name, value))
+		}
+	}
+	return args, nil
+}
@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/augmentable-dev/askgit/pkg/gitqlite"
+	"github.com/augmentable-dev/askgit/pkg/schedule"
+	"github.com/gitsight/go-vcsurl"
+	git "github.com/libgit2/git2go/v30"
+	"github.com/spf13/cobra"
+)
+
+var (
+	scheduleConfig string
+	scheduleOnce   bool
+)
+
+func init() {
+	scheduleCmd.Flags().StringVar(&scheduleConfig, "config", "schedules.yaml", "path to the YAML file listing the queries to run")
+	scheduleCmd.Flags().BoolVar(&scheduleOnce, "once", false, "run every query once right away and exit, rather than on their schedules")
+	rootCmd.AddCommand(scheduleCmd)
+}
+
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "run queries on cron schedules, writing their results to files or http endpoints",
+	Long: `
+  Runs the queries listed in a YAML config file on cron schedules, and writes their results to a file
+  or sends them to an http(s) endpoint, until interrupted. For instance:
+
+    schedules:
+      - name: weekly-authors
+        cron: "0 9 * * mon"
+        repo: path/to/repo
+        query: SELECT author_email, count(*) FROM commits GROUP BY author_email
+        format: csv
+        output: reports/authors.csv
+      - name: daily-churn
+        cron: "@daily"
+        query: SELECT * FROM file_churn
+        output: https://example.com/askgit/churn
+        method: PUT
+
+  Queries run on the repository given with --repo unless they set their own, in the local timezone.
+  A remote --repo is cloned once, and fetched before every run so that queries see its new commits.
+  Outputs are file paths or http(s) URLs: there's no S3 output, send results to a presigned URL with the PUT method instead.
+  The --format of results is json unless they set their own, other flags (i.e. --json-shape) apply to every query.
+  A failing run is reported on stderr, and the query runs again on its next schedule.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		config, err := schedule.Load(scheduleConfig)
+		handleError(err)
+
+		// the repository given with --repo is only looked up (or cloned) if a query uses it
+		var defaultDir string
+		var defaultRemote *vcsurl.VCS
+		dirs := make(map[*schedule.Schedule]string)
+		for _, s := range config.Schedules {
+			if s.Repo == "" {
+				if defaultDir == "" {
+					var cleanup func()
+					defaultDir, cleanup = repoDir(cmd)
+					defer cleanup()
+					if remote, err := vcsurl.Parse(repo); err == nil {
+						defaultRemote = remote
+					}
+				}
+				dirs[s] = defaultDir
+				continue
+			}
+
+			dir, err := filepath.Abs(s.Repo)
+			handleError(err)
+			dirs[s], err = gitqlite.FindRepoPath(dir, false)
+			handleError(err)
+		}
+
+		if scheduleOnce {
+			failed := false
+			// the remote was just cloned, there's nothing to fetch
+			for _, s := range config.Schedules {
+				if !runSchedule(s, dirs[s], nil) {
+					failed = true
+				}
+			}
+			if failed {
+				exitCode = exitError
+			}
+			return
+		}
+
+		next := make(map[*schedule.Schedule]time.Time)
+		for _, s := range config.Schedules {
+			next[s] = s.Next(time.Now())
+		}
+		// a schedule which never comes (i.e. on february 30th) is dropped
+		drop := func(s *schedule.Schedule) {
+			if next[s].IsZero() {
+				notice("%s never runs, its schedule %q doesn't match any date\n", s.Name, s.Cron)
+				delete(next, s)
+			}
+		}
+		for _, s := range config.Schedules {
+			drop(s)
+		}
+		for len(next) > 0 {
+			var due time.Time
+			for _, t := range next {
+				if due.IsZero() || t.Before(due) {
+					due = t
+				}
+			}
+			time.Sleep(time.Until(due))
+
+			for _, s := range config.Schedules {
+				if t, ok := next[s]; ok && !t.After(due) {
+					var remote *vcsurl.VCS
+					if s.Repo == "" {
+						remote = defaultRemote
+					}
+					runSchedule(s, dirs[s], remote)
+					next[s] = s.Next(due)
+					drop(s)
+				}
+			}
+		}
+	},
+}
+
+// runSchedule runs the query of a schedule on the repository in dir and writes its results, it returns whether it succeeded.
+// The repository is first fetched from remote, unless it's nil.
+func runSchedule(s *schedule.Schedule, dir string, remote *vcsurl.VCS) bool {
+	start := time.Now()
+	err := func() error {
+		if remote != nil {
+			err := fetchClone(dir, remote)
+			if err != nil {
+				return err
+			}
+		}
+
+		g, err := gitqlite.New(dir, queryOptions(dir))
+		if err != nil {
+			return err
+		}
+		defer g.DB.Close()
+
+		rows, err := g.Query(s.Query)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		var results bytes.Buffer
+		_, err = gitqlite.DisplayDBWithOptions(rows, &results, s.Format, &gitqlite.DisplayOptions{
+			MaxRows:   maxRows,
+			JSONShape: jsonShape,
+			Start:     start,
+		})
+		if err != nil {
+			return err
+		}
+
+		return s.Write(results.Bytes())
+	}()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s failed: %v\n", start.Format(time.RFC3339), s.Name, err)
+		return false
+	}
+	notice("%s: %s written to %s in %s\n", start.Format(time.RFC3339), s.Name, s.Output, time.Since(start).Round(time.Millisecond))
+	return true
+}
+
+// fetchClone fetches the repository in dir, cloned from remote, and moves its branch to the fetched one
+// (the clone is only queried, there are no local commits to keep)
+func fetchClone(dir string, remote *vcsurl.VCS) error {
+	repo, err := git.OpenRepository(dir)
+	if err != nil {
+		return err
+	}
+	defer repo.Free()
+
+	origin, err := repo.Remotes.Lookup("origin")
+	if err != nil {
+		return err
+	}
+	defer origin.Free()
+	err = origin.Fetch(nil, gitqlite.CreateAuthenticationCallback(remote).FetchOptions, "")
+	if err != nil {
+		return fmt.Errorf("fetching the repository failed: %v", err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return err
+	}
+	defer head.Free()
+	upstream, err := head.Branch().Upstream()
+	if err != nil {
+		return err
+	}
+	defer upstream.Free()
+	ref, err := head.SetTarget(upstream.Target(), "fetch")
+	if err != nil {
+		return err
+	}
+	ref.Free()
+	return nil
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/augmentable-dev/askgit/pkg/analyze"
+	"github.com/augmentable-dev/askgit/pkg/gitqlite"
+	"github.com/spf13/cobra"
+)
+
+var (
+	summaryTop    int
+	summaryMonths int
+)
+
+func init() {
+	summaryCmd.Flags().IntVar(&summaryTop, "top", 10, "number of contributors and files ranked")
+	summaryCmd.Flags().IntVar(&summaryMonths, "months", 12, "number of months of activity reported on, up to the most recent commit")
+	rootCmd.AddCommand(summaryCmd)
+}
+
+var summaryCmd = &cobra.Command{
+	Use:   "summary",
+	Short: "print an overview of a repository, without writing any SQL",
+	Long: `
+  Prints an overview of a repository and of the history of its checked out commit: its number of commits,
+  its top contributors, its busiest files (the ones changed by the most commits) and its activity by month.
+
+  The report is ASCII tables, unless --format is markdown or json.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, cleanup := repoDir(cmd)
+		defer cleanup()
+
+		g, err := gitqlite.New(dir, queryOptions(dir))
+		handleError(err)
+
+		report, err := analyze.Summary(g, &analyze.SummaryOptions{
+			Top:    summaryTop,
+			Months: summaryMonths,
+		})
+		handleError(err)
+
+		switch format {
+		case "json":
+			err = report.WriteJSON(os.Stdout)
+		case "markdown":
+			err = report.WriteMarkdown(os.Stdout)
+		default:
+			err = report.WriteTable(os.Stdout)
+		}
+		handleError(err)
+
+		if g.Truncated() {
+			notice("truncated: history walks stopped at %d commits, use --no-limit to walk them whole\n", gitqlite.DefaultMaxCommits)
+			exitCode = exitTruncated
+		}
+	},
+}
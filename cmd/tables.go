@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/augmentable-dev/askgit/pkg/gitqlite"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(tablesCmd)
+	rootCmd.AddCommand(describeCmd)
+}
+
+var tablesCmd = &cobra.Command{
+	Use:   "tables",
+	Short: "list the tables, views and functions available to queries",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TABLE\tDESCRIPTION")
+		for _, table := range gitqlite.Tables() {
+			fmt.Fprintf(w, "%s\t%s\n", table, gitqlite.TableDescription(table))
+		}
+		for _, view := range gitqlite.Views() {
+			fmt.Fprintf(w, "%s\t%s\n", view, gitqlite.TableDescription(view))
+		}
+
+		fmt.Fprintln(w, "\t")
+		fmt.Fprintln(w, "FUNCTION\tDESCRIPTION")
+		for _, function := range gitqlite.Functions() {
+			fmt.Fprintf(w, "%s\t%s\n", function.Signature, function.Description)
+		}
+		err := w.Flush()
+		handleError(err)
+	},
+}
+
+var describeCmd = &cobra.Command{
+	Use:   "describe <table>",
+	Short: "list the columns of a table",
+	Long: `
+  Lists the columns of a table, with their types and descriptions.
+  Hidden columns aren't returned by SELECT *, constraining them changes what the table lists (i.e. WHERE ref = 'main').`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		table := args[0]
+		if gitqlite.TableDescription(table) == "" {
+			handleError(fmt.Errorf("unknown table %s, see askgit tables", table))
+		}
+
+		dir, cleanup := repoDir(cmd)
+		defer cleanup()
+
+		// the columns of a table depend on the backend implementing it, which may differ with --use-git-cli
+		g, err := gitqlite.New(dir, &gitqlite.Options{UseGitCLI: useGitCLI, Tables: []string{table}})
+		handleError(err)
+		columns, err := g.Describe(table)
+		handleError(err)
+
+		fmt.Println(gitqlite.TableDescription(table))
+		fmt.Println()
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "COLUMN\tTYPE\tDESCRIPTION")
+		for _, column := range columns {
+			typ := column.Type
+			if column.Hidden {
+				typ += " HIDDEN"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", column.Name, typ, column.Description)
+		}
+		err = w.Flush()
+		handleError(err)
+	},
+}
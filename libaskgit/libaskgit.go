@@ -0,0 +1,142 @@
+// Command libaskgit builds askgit as a C shared library, so that other languages (i.e. Python with ctypes or cffi, Ruby with ffi,
+// Node with ffi-napi) can query repositories without reimplementing the virtual tables:
+//
+//	go build -buildmode=c-shared -tags="sqlite_vtable,static,system_libgit2" -o libaskgit.so ./libaskgit
+//
+// which also writes the libaskgit.h header. Repositories are referred to by handles, strings returned by the library
+// must be released with askgit_free.
+package main
+
+// #include <stdlib.h>
+import "C"
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"unsafe"
+
+	"github.com/augmentable-dev/askgit/pkg/gitqlite"
+)
+
+// instance is an open repository, along with the error of the last call on its handle
+type instance struct {
+	g         *gitqlite.GitQLite
+	lastError string
+}
+
+var (
+	mu        sync.Mutex
+	instances = make(map[int64]*instance)
+	lastID    int64
+	// openError is the error of the last call to askgit_open, which has no handle to record it on
+	openError string
+)
+
+// askgit_open opens the repository at path, it returns a handle to query it with, or -1 if it can't be opened
+// (see askgit_last_error, with the -1 handle)
+//
+//export askgit_open
+func askgit_open(path *C.char) C.longlong {
+	dir, err := gitqlite.FindRepoPath(C.GoString(path), false)
+	if err == nil {
+		var g *gitqlite.GitQLite
+		g, err = gitqlite.New(dir, &gitqlite.Options{})
+		if err == nil {
+			mu.Lock()
+			defer mu.Unlock()
+			openError = ""
+			lastID++
+			instances[lastID] = &instance{g: g}
+			return C.longlong(lastID)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	openError = err.Error()
+	return -1
+}
+
+// askgit_query runs a query on the repository of handle, it returns the results as a JSON object
+// with the columns, column_types, rows (an object per row), row_count and duration_ms of the query,
+// or NULL if the query fails (see askgit_last_error)
+//
+//export askgit_query
+func askgit_query(handle C.longlong, query *C.char) *C.char {
+	mu.Lock()
+	i, ok := instances[int64(handle)]
+	mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	results, err := i.query(C.GoString(query))
+	mu.Lock()
+	defer mu.Unlock()
+	if err != nil {
+		i.lastError = err.Error()
+		return nil
+	}
+	i.lastError = ""
+	return C.CString(results)
+}
+
+// askgit_close releases the repository of handle, which can't be queried anymore
+//
+//export askgit_close
+func askgit_close(handle C.longlong) {
+	mu.Lock()
+	defer mu.Unlock()
+	if i, ok := instances[int64(handle)]; ok {
+		i.g.DB.Close()
+		delete(instances, int64(handle))
+	}
+}
+
+// askgit_last_error returns the message of the error of the last call on handle, or NULL if it succeeded.
+// The -1 handle returned by a failing askgit_open tells why the last call to askgit_open failed.
+//
+//export askgit_last_error
+func askgit_last_error(handle C.longlong) *C.char {
+	mu.Lock()
+	defer mu.Unlock()
+	message := openError
+	if handle != -1 {
+		i, ok := instances[int64(handle)]
+		if !ok {
+			return C.CString(fmt.Sprintf("unknown handle %d", handle))
+		}
+		message = i.lastError
+	}
+	if message == "" {
+		return nil
+	}
+	return C.CString(message)
+}
+
+// askgit_free releases a string returned by the library
+//
+//export askgit_free
+func askgit_free(s *C.char) {
+	C.free(unsafe.Pointer(s))
+}
+
+// query returns the results of query in the envelope JSON shape
+func (i *instance) query(query string) (string, error) {
+	rows, err := i.g.Query(query)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var results bytes.Buffer
+	_, err = gitqlite.DisplayDBWithOptions(rows, &results, "json", &gitqlite.DisplayOptions{JSONShape: "envelope"})
+	if err != nil {
+		return "", err
+	}
+	return results.String(), nil
+}
+
+// main is required to build a shared library, it isn't run
+func main() {}
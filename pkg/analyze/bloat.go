@@ -0,0 +1,223 @@
+package analyze
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	git "github.com/libgit2/git2go/v30"
+)
+
+// BloatObject is a large blob of a repository, along with the commit which introduced it
+type BloatObject struct {
+	ID   string `json:"id"`
+	Size uint64 `json:"size"`
+	// Path, Commit and When are where the blob was first committed, they're empty if no commit of any ref has it
+	// (i.e. it's only left in the object database by a rewritten history)
+	Path   string    `json:"path,omitempty"`
+	Commit string    `json:"commit,omitempty"`
+	When   time.Time `json:"when"`
+}
+
+// BloatReport ranks the largest blobs ever committed to a repository, the candidates for rewriting history or moving to LFS
+type BloatReport struct {
+	// Blobs is the number of blobs in the object database, and BlobsSize their total (uncompressed) size
+	Blobs     int            `json:"blobs"`
+	BlobsSize uint64         `json:"blobs_size"`
+	Largest   []*BloatObject `json:"largest"`
+}
+
+// Bloat ranks the limit largest blobs of the object database of the repository at repoPath.
+// Every object is looked at, loose or packed, and the commits of every ref are walked from the oldest to find the ones introducing them.
+func Bloat(repoPath string, limit int) (*BloatReport, error) {
+	repo, err := git.OpenRepository(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	defer repo.Free()
+
+	odb, err := repo.Odb()
+	if err != nil {
+		return nil, err
+	}
+	defer odb.Free()
+
+	report := &BloatReport{}
+	blobs := make([]*BloatObject, 0)
+	// an object may be both loose and packed, or in several packs
+	seen := make(map[git.Oid]bool)
+	err = odb.ForEach(func(id *git.Oid) error {
+		if seen[*id] {
+			return nil
+		}
+		seen[*id] = true
+		size, typ, err := odb.ReadHeader(id)
+		if err != nil {
+			return err
+		}
+		if typ != git.ObjectBlob {
+			return nil
+		}
+		report.Blobs++
+		report.BlobsSize += size
+		blobs = append(blobs, &BloatObject{ID: id.String(), Size: size})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(blobs, func(i, j int) bool {
+		return blobs[i].Size > blobs[j].Size
+	})
+	if limit > 0 && len(blobs) > limit {
+		blobs = blobs[:limit]
+	}
+	report.Largest = blobs
+
+	err = introductions(repo, blobs)
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// introductions sets the commit introducing each of blobs, walking the history of every ref from the oldest commit until all are found
+func introductions(repo *git.Repository, blobs []*BloatObject) error {
+	pending := make(map[string]*BloatObject)
+	for _, blob := range blobs {
+		pending[blob.ID] = blob
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	walk, err := repo.Walk()
+	if err != nil {
+		return err
+	}
+	defer walk.Free()
+	walk.Sorting(git.SortTime | git.SortReverse)
+	err = walk.PushGlob("*")
+	if err != nil {
+		return err
+	}
+	// HEAD may be detached, or unborn in which case there is no commit to push
+	if unborn, err := repo.IsHeadUnborn(); err == nil && !unborn {
+		err = walk.PushHead()
+		if err != nil {
+			return err
+		}
+	}
+
+	var walkErr error
+	err = walk.Iterate(func(commit *git.Commit) bool {
+		walkErr = introduced(repo, commit, pending)
+		return walkErr == nil && len(pending) > 0
+	})
+	if err != nil {
+		return err
+	}
+	return walkErr
+}
+
+// introduced records commit as introducing the pending blobs it adds or modifies relative to its first parent
+func introduced(repo *git.Repository, commit *git.Commit, pending map[string]*BloatObject) error {
+	tree, err := commit.Tree()
+	if err != nil {
+		return err
+	}
+	defer tree.Free()
+
+	var parentTree *git.Tree
+	if parent := commit.Parent(0); parent != nil {
+		defer parent.Free()
+		parentTree, err = parent.Tree()
+		if err != nil {
+			return err
+		}
+		defer parentTree.Free()
+	}
+
+	diff, err := repo.DiffTreeToTree(parentTree, tree, nil)
+	if err != nil {
+		return err
+	}
+	defer diff.Free()
+
+	deltas, err := diff.NumDeltas()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < deltas; i++ {
+		delta, err := diff.GetDelta(i)
+		if err != nil {
+			return err
+		}
+		if delta.Status == git.DeltaDeleted {
+			continue
+		}
+		if blob, ok := pending[delta.NewFile.Oid.String()]; ok {
+			blob.Path = delta.NewFile.Path
+			blob.Commit = commit.Id().String()
+			blob.When = commit.Committer().When
+			delete(pending, blob.ID)
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the report as an indented JSON object
+func (r *BloatReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteMarkdown writes the report as a markdown document, with a table of the largest blobs
+func (r *BloatReport) WriteMarkdown(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "# Largest blobs\n\n%d blobs, %s in total (uncompressed).\n", r.Blobs, humanSize(r.BlobsSize))
+	if err != nil {
+		return err
+	}
+	if len(r.Largest) == 0 {
+		return nil
+	}
+
+	_, err = fmt.Fprint(w, "\n| Blob | Size | Path | Commit | Date |\n|------|------|------|--------|------|\n")
+	if err != nil {
+		return err
+	}
+	for _, blob := range r.Largest {
+		date := ""
+		if !blob.When.IsZero() {
+			date = blob.When.Format("2006-01-02")
+		}
+		path := blob.Path
+		if blob.Commit == "" {
+			path = "(unreachable)"
+		}
+		_, err := fmt.Fprintf(w, "| %s | %s | %s | %s | %s |\n", blob.ID, humanSize(blob.Size), path, blob.Commit, date)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// humanSize formats a size in bytes with a binary unit, i.e. 1.5 MiB
+func humanSize(size uint64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := uint64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
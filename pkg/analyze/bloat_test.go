@@ -0,0 +1,94 @@
+package analyze
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	git "github.com/libgit2/git2go/v30"
+)
+
+func TestBloat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bloat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	repo, err := git.InitRepository(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Free()
+
+	// a commit adding a small and a large file, then a blob no commit has
+	small, err := repo.CreateBlobFromBuffer([]byte("small\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	large, err := repo.CreateBlobFromBuffer([]byte(strings.Repeat("large\n", 1000)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dangling, err := repo.CreateBlobFromBuffer([]byte(strings.Repeat("dangling\n", 100)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	builder, err := repo.TreeBuilder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer builder.Free()
+	err = builder.Insert("small.txt", small, git.FilemodeBlob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = builder.Insert("large.txt", large, git.FilemodeBlob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	treeID, err := builder.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := repo.LookupTree(treeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Free()
+	signature := &git.Signature{Name: "askgit", Email: "askgit@example.com", When: time.Now()}
+	commit, err := repo.CreateCommit("HEAD", signature, signature, "add files", tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Bloat(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Blobs != 3 || len(report.Largest) != 2 {
+		t.Fatalf("expected the 2 largest of 3 blobs, got %d of %d", len(report.Largest), report.Blobs)
+	}
+	if report.Largest[0].ID != large.String() || report.Largest[0].Path != "large.txt" || report.Largest[0].Commit != commit.String() {
+		t.Fatalf("expected large.txt to be the largest blob, introduced by %s, got %+v", commit, report.Largest[0])
+	}
+	if report.Largest[1].ID != dangling.String() || report.Largest[1].Commit != "" {
+		t.Fatalf("expected the dangling blob not to be introduced by any commit, got %+v", report.Largest[1])
+	}
+}
+
+func TestHumanSize(t *testing.T) {
+	tests := map[uint64]string{
+		10:              "10 B",
+		1536:            "1.5 KiB",
+		5 * 1024 * 1024: "5.0 MiB",
+	}
+	for size, expected := range tests {
+		if humanSize(size) != expected {
+			t.Fatalf("expected %d bytes to be %s, got %s", size, expected, humanSize(size))
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package analyze
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/augmentable-dev/askgit/pkg/gitqlite"
+)
+
+// DoraOptions are the options of a DORA report
+type DoraOptions struct {
+	// Since is the start of the period reported on, which ends now
+	Since time.Time
+	// Tags is a GLOB pattern matching the tags deploys are tagged with (i.e. v*), every tag if empty
+	Tags string
+}
+
+// Deploy is a release of the repository, found from a tag
+type Deploy struct {
+	Tag    string    `json:"tag"`
+	Commit string    `json:"commit"`
+	When   time.Time `json:"when"`
+	// Commits is the number of commits shipped, the ones in the history of the deploy which weren't in the previous one's
+	Commits int `json:"commits"`
+	// LeadTimeHours is the median time between the commits shipped being committed and the deploy
+	LeadTimeHours float64 `json:"median_lead_time_hours"`
+	// Failed is set when a commit shipped was later reverted
+	Failed bool `json:"failed"`
+
+	leadTimes []time.Duration
+}
+
+// DoraReport are the four key metrics of DORA, lead time for changes, deployment frequency and change failure rate,
+// as measured from the history of a repository.
+// Deploys are tags of commits of the checked out history, and failures are deploys which shipped a commit later reverted
+// (see the reverts_hash and reverted_by columns of commits), time to restore isn't measured.
+type DoraReport struct {
+	Since time.Time `json:"since"`
+	Until time.Time `json:"until"`
+	// Deploys are the deploys of the period, the first deploy ever is left out since what it shipped isn't known
+	Deploys []*Deploy `json:"deploys"`
+	// DeploysPerWeek is the deployment frequency
+	DeploysPerWeek float64 `json:"deploys_per_week"`
+	// LeadTimeHours is the median lead time for changes of every commit shipped during the period
+	LeadTimeHours float64 `json:"median_lead_time_hours"`
+	// ChangeFailureRate is the share of failed deploys, between 0 and 1
+	ChangeFailureRate float64 `json:"change_failure_rate"`
+}
+
+// Dora measures the DORA metrics of the repository a GitQLite instance queries
+func Dora(g *gitqlite.GitQLite, options *DoraOptions) (*DoraReport, error) {
+	pattern := options.Tags
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	// tags of commits outside of the checked out history aren't deploys of it
+	rows, err := g.DB.Query(`
+		SELECT tags.name, commits.id, coalesce(tags.tagger_when, commits.committer_when)
+		FROM tags JOIN commits ON commits.id = tags.target
+		WHERE tags.name GLOB ?`, pattern)
+	if err != nil {
+		return nil, err
+	}
+	deploys := make([]*Deploy, 0)
+	for rows.Next() {
+		var tag, commit, when string
+		err := rows.Scan(&tag, &commit, &when)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		deploy := &Deploy{Tag: tag, Commit: commit}
+		deploy.When, err = time.Parse(time.RFC3339Nano, when)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		deploys = append(deploys, deploy)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(deploys, func(i, j int) bool {
+		return deploys[i].When.Before(deploys[j].When)
+	})
+
+	reverted, err := revertedCommits(g)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, deploy := range deploys {
+		if i == 0 || deploy.When.Before(options.Since) {
+			continue
+		}
+		err := ship(g, deploy, deploys[i-1], reverted)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return doraReport(deploys, options.Since, time.Now()), nil
+}
+
+// revertedCommits returns the ids of the commits of the checked out history which were reverted
+func revertedCommits(g *gitqlite.GitQLite) (map[string]bool, error) {
+	rows, err := g.DB.Query("SELECT id FROM commits WHERE reverted_by IS NOT NULL")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reverted := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		reverted[id] = true
+	}
+	return reverted, rows.Err()
+}
+
+// ship sets the commits a deploy shipped since the previous one, and their lead times
+func ship(g *gitqlite.GitQLite, deploy, previous *Deploy, reverted map[string]bool) error {
+	rows, err := g.DB.Query(`
+		SELECT id, committer_when FROM commits WHERE ref = ?
+		EXCEPT SELECT id, committer_when FROM commits WHERE ref = ?`, deploy.Commit, previous.Commit)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, committed string
+		err := rows.Scan(&id, &committed)
+		if err != nil {
+			return err
+		}
+		when, err := time.Parse(time.RFC3339Nano, committed)
+		if err != nil {
+			return err
+		}
+
+		deploy.Commits++
+		leadTime := deploy.When.Sub(when)
+		if leadTime < 0 {
+			leadTime = 0
+		}
+		deploy.leadTimes = append(deploy.leadTimes, leadTime)
+		if reverted[id] {
+			deploy.Failed = true
+		}
+	}
+	deploy.LeadTimeHours = median(deploy.leadTimes).Hours()
+	return rows.Err()
+}
+
+// doraReport computes the metrics of the deploys between since and until, which are ordered from the oldest
+func doraReport(deploys []*Deploy, since, until time.Time) *DoraReport {
+	report := &DoraReport{Since: since, Until: until, Deploys: make([]*Deploy, 0)}
+
+	var leadTimes []time.Duration
+	failed := 0
+	for i, deploy := range deploys {
+		if i == 0 || deploy.When.Before(since) || deploy.When.After(until) {
+			continue
+		}
+		report.Deploys = append(report.Deploys, deploy)
+		leadTimes = append(leadTimes, deploy.leadTimes...)
+		if deploy.Failed {
+			failed++
+		}
+	}
+
+	if weeks := until.Sub(since).Hours() / (24 * 7); weeks > 0 {
+		report.DeploysPerWeek = float64(len(report.Deploys)) / weeks
+	}
+	report.LeadTimeHours = median(leadTimes).Hours()
+	if len(report.Deploys) > 0 {
+		report.ChangeFailureRate = float64(failed) / float64(len(report.Deploys))
+	}
+	return report
+}
+
+// median returns the median of durations, 0 if there are none
+func median(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration{}, durations...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+// WriteJSON writes the report as an indented JSON object
+func (r *DoraReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteMarkdown writes the report as a markdown document, with a table of the metrics and one of the deploys
+func (r *DoraReport) WriteMarkdown(w io.Writer) error {
+	_, err := fmt.Fprintf(w, `# DORA metrics
+
+From %s to %s, over %d deploys.
+
+| Metric | Value |
+|--------|-------|
+| Deployment frequency | %.2f per week |
+| Lead time for changes | %.1f hours (median) |
+| Change failure rate | %.1f%% |
+`, r.Since.Format("2006-01-02"), r.Until.Format("2006-01-02"), len(r.Deploys), r.DeploysPerWeek, r.LeadTimeHours, r.ChangeFailureRate*100)
+	if err != nil {
+		return err
+	}
+	if len(r.Deploys) == 0 {
+		return nil
+	}
+
+	_, err = fmt.Fprint(w, "\n## Deploys\n\n| Tag | Date | Commits | Median lead time (hours) | Failed |\n|-----|------|---------|--------------------------|--------|\n")
+	if err != nil {
+		return err
+	}
+	for _, deploy := range r.Deploys {
+		_, err := fmt.Fprintf(w, "| %s | %s | %d | %.1f | %t |\n", deploy.Tag, deploy.When.Format("2006-01-02"), deploy.Commits, deploy.LeadTimeHours, deploy.Failed)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
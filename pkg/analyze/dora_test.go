@@ -0,0 +1,56 @@
+package analyze
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDoraReport(t *testing.T) {
+	since := time.Date(2020, time.November, 1, 0, 0, 0, 0, time.UTC)
+	until := since.Add(14 * 24 * time.Hour)
+
+	deploys := []*Deploy{
+		// the first deploy ever, and a deploy before the period, are only baselines
+		{Tag: "v0.1.0", When: since.Add(-30 * 24 * time.Hour)},
+		{Tag: "v0.2.0", When: since.Add(-time.Hour), leadTimes: []time.Duration{time.Hour}},
+		{Tag: "v0.3.0", When: since.Add(24 * time.Hour), Commits: 2, leadTimes: []time.Duration{2 * time.Hour, 4 * time.Hour}},
+		{Tag: "v0.4.0", When: since.Add(48 * time.Hour), Commits: 1, leadTimes: []time.Duration{10 * time.Hour}, Failed: true},
+	}
+	report := doraReport(deploys, since, until)
+
+	if len(report.Deploys) != 2 || report.Deploys[0].Tag != "v0.3.0" {
+		t.Fatalf("expected the deploys of the period to be v0.3.0 and v0.4.0, got %d deploys", len(report.Deploys))
+	}
+	if report.DeploysPerWeek != 1 {
+		t.Fatalf("expected 1 deploy per week, got %f", report.DeploysPerWeek)
+	}
+	if report.LeadTimeHours != 4 {
+		t.Fatalf("expected a median lead time of 4 hours, got %f", report.LeadTimeHours)
+	}
+	if report.ChangeFailureRate != 0.5 {
+		t.Fatalf("expected a change failure rate of 0.5, got %f", report.ChangeFailureRate)
+	}
+
+	var markdown bytes.Buffer
+	err := report.WriteMarkdown(&markdown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(markdown.String(), "| Change failure rate | 50.0% |") || !strings.Contains(markdown.String(), "| v0.4.0 | 2020-11-03 | 1 | 0.0 | true |") {
+		t.Fatalf("unexpected markdown report:\n%s", markdown.String())
+	}
+}
+
+func TestMedian(t *testing.T) {
+	if median(nil) != 0 {
+		t.Fatal("expected the median of nothing to be 0")
+	}
+	if m := median([]time.Duration{3, 1, 2}); m != 2 {
+		t.Fatalf("expected a median of 2, got %d", m)
+	}
+	if m := median([]time.Duration{4, 1, 2, 3}); m != 2 {
+		t.Fatalf("expected a median of 2, got %d", m)
+	}
+}
@@ -0,0 +1,242 @@
+package analyze
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/augmentable-dev/askgit/pkg/gitqlite"
+	"github.com/olekukonko/tablewriter"
+)
+
+// SummaryOptions are the options of a summary of a repository
+type SummaryOptions struct {
+	// Top is the number of contributors and files ranked (10 if 0)
+	Top int
+	// Months is the number of months of activity reported on, up to the most recent commit (12 if 0)
+	Months int
+}
+
+// Contributor is an author of commits, identified after applying the .mailmap of the repository
+type Contributor struct {
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Commits int    `json:"commits"`
+}
+
+// BusyFile is a file changed by many commits
+type BusyFile struct {
+	File    string `json:"file"`
+	Commits int    `json:"commits"`
+	Authors int    `json:"authors"`
+}
+
+// MonthActivity is the number of commits authored during a month (i.e. 2020-11)
+type MonthActivity struct {
+	Month   string `json:"month"`
+	Commits int    `json:"commits"`
+}
+
+// SummaryReport is an overview of a repository and of the history of its checked out commit, for newcomers to it
+type SummaryReport struct {
+	Path    string     `json:"path"`
+	Head    string     `json:"head,omitempty"`
+	Commits int        `json:"commits"`
+	First   *time.Time `json:"first_commit_at,omitempty"`
+	Last    *time.Time `json:"last_commit_at,omitempty"`
+	// Contributors are the authors of the most commits, and Files the files changed by the most commits
+	Contributors []*Contributor   `json:"top_contributors"`
+	Files        []*BusyFile      `json:"busiest_files"`
+	Activity     []*MonthActivity `json:"activity_by_month"`
+}
+
+// Summary summarizes the repository a GitQLite instance queries, from its repo, contributors, file_churn and commits tables
+func Summary(g *gitqlite.GitQLite, options *SummaryOptions) (*SummaryReport, error) {
+	top := options.Top
+	if top <= 0 {
+		top = 10
+	}
+	months := options.Months
+	if months <= 0 {
+		months = 12
+	}
+
+	report := &SummaryReport{
+		Contributors: make([]*Contributor, 0),
+		Files:        make([]*BusyFile, 0),
+		Activity:     make([]*MonthActivity, 0),
+	}
+
+	var head, first, last sql.NullString
+	err := g.DB.QueryRow("SELECT path, head, commit_count, first_commit_at, last_commit_at FROM repo").Scan(&report.Path, &head, &report.Commits, &first, &last)
+	if err != nil {
+		return nil, err
+	}
+	report.Head = head.String
+	report.First, err = parseNullTime(first)
+	if err != nil {
+		return nil, err
+	}
+	report.Last, err = parseNullTime(last)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := g.DB.Query("SELECT name, email, commit_count FROM contributors ORDER BY commit_count DESC LIMIT ?", top)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		contributor := &Contributor{}
+		err := rows.Scan(&contributor.Name, &contributor.Email, &contributor.Commits)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		report.Contributors = append(report.Contributors, contributor)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = g.DB.Query("SELECT file, commit_count, author_count FROM file_churn ORDER BY commit_count DESC, file LIMIT ?", top)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		file := &BusyFile{}
+		err := rows.Scan(&file.File, &file.Commits, &file.Authors)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		report.Files = append(report.Files, file)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// the most recent months, ordered from the oldest
+	rows, err = g.DB.Query(`
+		SELECT * FROM (
+			SELECT strftime('%Y-%m', author_when) AS month, count(*) FROM commits GROUP BY month ORDER BY month DESC LIMIT ?
+		) ORDER BY month`, months)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		month := &MonthActivity{}
+		err := rows.Scan(&month.Month, &month.Commits)
+		if err != nil {
+			return nil, err
+		}
+		report.Activity = append(report.Activity, month)
+	}
+	return report, rows.Err()
+}
+
+// parseNullTime parses a DATETIME value, nil if it's NULL
+func parseNullTime(value sql.NullString) (*time.Time, error) {
+	if !value.Valid {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value.String)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// period returns when the history summarized starts and ends, or "no commits yet"
+func (r *SummaryReport) period() string {
+	if r.First == nil || r.Last == nil {
+		return "no commits yet"
+	}
+	return fmt.Sprintf("%s to %s", r.First.Format("2006-01-02"), r.Last.Format("2006-01-02"))
+}
+
+// WriteJSON writes the report as an indented JSON object
+func (r *SummaryReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteMarkdown writes the report as a markdown document, with a table per section
+func (r *SummaryReport) WriteMarkdown(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "# %s\n\n%d commits on %s, from %s.\n", r.Path, r.Commits, orDetached(r.Head), r.period())
+	if err != nil {
+		return err
+	}
+	for _, section := range r.sections() {
+		_, err := fmt.Fprintf(w, "\n## %s\n\n", section.title)
+		if err != nil {
+			return err
+		}
+		table := tablewriter.NewWriter(w)
+		table.SetHeader(section.header)
+		table.SetAutoFormatHeaders(false)
+		table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+		table.SetCenterSeparator("|")
+		table.AppendBulk(section.rows)
+		table.Render()
+	}
+	return nil
+}
+
+// WriteTable writes the report as ASCII tables, like the table format of queries
+func (r *SummaryReport) WriteTable(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "%s: %d commits on %s, from %s\n", r.Path, r.Commits, orDetached(r.Head), r.period())
+	if err != nil {
+		return err
+	}
+	for _, section := range r.sections() {
+		_, err := fmt.Fprintf(w, "\n%s\n", section.title)
+		if err != nil {
+			return err
+		}
+		table := tablewriter.NewWriter(w)
+		table.SetHeader(section.header)
+		table.AppendBulk(section.rows)
+		table.Render()
+	}
+	return nil
+}
+
+// summarySection is a section of a report, as a table
+type summarySection struct {
+	title  string
+	header []string
+	rows   [][]string
+}
+
+func (r *SummaryReport) sections() []*summarySection {
+	contributors := &summarySection{title: "Top contributors", header: []string{"Name", "Email", "Commits"}}
+	for _, c := range r.Contributors {
+		contributors.rows = append(contributors.rows, []string{c.Name, c.Email, strconv.Itoa(c.Commits)})
+	}
+	files := &summarySection{title: "Busiest files", header: []string{"File", "Commits", "Authors"}}
+	for _, f := range r.Files {
+		files.rows = append(files.rows, []string{f.File, strconv.Itoa(f.Commits), strconv.Itoa(f.Authors)})
+	}
+	activity := &summarySection{title: "Activity by month", header: []string{"Month", "Commits"}}
+	for _, m := range r.Activity {
+		activity.rows = append(activity.rows, []string{m.Month, strconv.Itoa(m.Commits)})
+	}
+	return []*summarySection{contributors, files, activity}
+}
+
+// orDetached returns the branch HEAD points to, without its refs/heads/ prefix, or "a detached HEAD"
+func orDetached(head string) string {
+	if head == "" {
+		return "a detached HEAD"
+	}
+	return strings.TrimPrefix(head, "refs/heads/")
+}
@@ -0,0 +1,44 @@
+package analyze
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummaryReport(t *testing.T) {
+	first := time.Date(2020, time.November, 1, 0, 0, 0, 0, time.UTC)
+	last := first.Add(48 * time.Hour)
+	report := &SummaryReport{
+		Path:         "askgit",
+		Head:         "refs/heads/main",
+		Commits:      3,
+		First:        &first,
+		Last:         &last,
+		Contributors: []*Contributor{{Name: "Alice", Email: "alice@example.com", Commits: 2}, {Name: "Bob", Email: "bob@example.com", Commits: 1}},
+		Files:        []*BusyFile{{File: "main.go", Commits: 3, Authors: 2}},
+		Activity:     []*MonthActivity{{Month: "2020-11", Commits: 3}},
+	}
+
+	var markdown bytes.Buffer
+	err := report.WriteMarkdown(&markdown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"# askgit\n\n3 commits on main, from 2020-11-01 to 2020-11-03.\n", "## Busiest files", "alice@example.com", "main.go", "2020-11"} {
+		if !strings.Contains(markdown.String(), expected) {
+			t.Fatalf("expected %q in the markdown report:\n%s", expected, markdown.String())
+		}
+	}
+
+	empty := &SummaryReport{Path: "empty"}
+	var table bytes.Buffer
+	err = empty.WriteTable(&table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(table.String(), "empty: 0 commits on a detached HEAD, from no commits yet\n") {
+		t.Fatalf("unexpected table report:\n%s", table.String())
+	}
+}
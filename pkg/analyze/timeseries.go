@@ -0,0 +1,264 @@
+package analyze
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/augmentable-dev/askgit/pkg/gitqlite"
+	git "github.com/libgit2/git2go/v30"
+)
+
+// TimeSeriesOptions are the options of a time series
+type TimeSeriesOptions struct {
+	// Every is the period between samples: a number of hours, days, weeks, months or years, i.e. 12h, 1d, 1w, 3m or 1y
+	Every string
+	// Query is run at every sample, with the id of the commit sampled as the :commit parameter (and its date as :at),
+	// i.e. SELECT count(*) AS files FROM files WHERE commit_id = :commit
+	Query string
+	// Args are the other arguments of the query
+	Args []interface{}
+}
+
+// TimeSeriesReport are the results of a query at commits sampled over time, in long format:
+// the rows of the results of every sample, following the date of the sample and the id of the commit sampled
+type TimeSeriesReport struct {
+	Columns []string        `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+}
+
+// sample is a commit of the first parent history, the most recent one at a date
+type sample struct {
+	at     time.Time
+	commit string
+}
+
+// committed is a commit, along with when it was committed
+type committed struct {
+	id   string
+	when time.Time
+}
+
+var everyPattern = regexp.MustCompile(`^(\d+)([hdwmy])$`)
+
+// parseEvery parses a period (see TimeSeriesOptions.Every), into the function stepping a date to the next sample
+func parseEvery(every string) (func(time.Time) time.Time, error) {
+	match := everyPattern.FindStringSubmatch(every)
+	if match == nil {
+		return nil, fmt.Errorf("invalid period %q, expected a number of hours, days, weeks, months or years, i.e. 1w", every)
+	}
+	n, err := strconv.Atoi(match[1])
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("invalid period %q, expected a period longer than 0", every)
+	}
+
+	switch match[2] {
+	case "h":
+		return func(t time.Time) time.Time { return t.Add(time.Duration(n) * time.Hour) }, nil
+	case "d":
+		return func(t time.Time) time.Time { return t.AddDate(0, 0, n) }, nil
+	case "w":
+		return func(t time.Time) time.Time { return t.AddDate(0, 0, 7*n) }, nil
+	case "m":
+		return func(t time.Time) time.Time { return t.AddDate(0, n, 0) }, nil
+	default:
+		return func(t time.Time) time.Time { return t.AddDate(n, 0, 0) }, nil
+	}
+}
+
+// samples samples commits, ordered from the oldest, every step from the first one: each sample is the most recent commit
+// at its date. The last sample is the most recent commit, at its date.
+func samples(commits []*committed, step func(time.Time) time.Time) []*sample {
+	sampled := make([]*sample, 0)
+	if len(commits) == 0 {
+		return sampled
+	}
+
+	last := commits[len(commits)-1]
+	i := 0
+	for at := commits[0].when; at.Before(last.when); at = step(at) {
+		for i+1 < len(commits) && !commits[i+1].when.After(at) {
+			i++
+		}
+		sampled = append(sampled, &sample{at: at, commit: commits[i].id})
+	}
+	return append(sampled, &sample{at: last.when, commit: last.id})
+}
+
+// firstParentHistory returns the commits of the first parent history of HEAD of the repository at repoPath, ordered from the oldest.
+// Following first parents only, the commits sampled are the ones of the mainline rather than of merged branches,
+// and their dates only go forward (unless clocks were wrong).
+func firstParentHistory(repoPath string) ([]*committed, error) {
+	repo, err := git.OpenRepository(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	defer repo.Free()
+
+	commits := make([]*committed, 0)
+	unborn, err := repo.IsHeadUnborn()
+	if err != nil || unborn {
+		return commits, err
+	}
+
+	walk, err := repo.Walk()
+	if err != nil {
+		return nil, err
+	}
+	defer walk.Free()
+	walk.SimplifyFirstParent()
+	err = walk.PushHead()
+	if err != nil {
+		return nil, err
+	}
+
+	err = walk.Iterate(func(commit *git.Commit) bool {
+		commits = append(commits, &committed{id: commit.Id().String(), when: commit.Committer().When})
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the walk goes from HEAD, the most recent commit
+	for i, j := 0, len(commits)-1; i < j; i, j = i+1, j-1 {
+		commits[i], commits[j] = commits[j], commits[i]
+	}
+	return commits, nil
+}
+
+// TimeSeries runs a query with a GitQLite instance on the repository at repoPath, at commits of the first parent history of HEAD
+// sampled over time (see TimeSeriesOptions). A commit sampled several times (when nothing was committed for a while)
+// is only queried once.
+func TimeSeries(g *gitqlite.GitQLite, repoPath string, options *TimeSeriesOptions) (*TimeSeriesReport, error) {
+	step, err := parseEvery(options.Every)
+	if err != nil {
+		return nil, err
+	}
+	commits, err := firstParentHistory(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	series := &TimeSeriesReport{Columns: []string{"at", "commit_id"}, Rows: make([][]interface{}, 0)}
+	results := make(map[string][][]interface{})
+	for _, s := range samples(commits, step) {
+		rows, ok := results[s.commit]
+		if !ok {
+			args := append(append([]interface{}{}, options.Args...), sql.Named("commit", s.commit), sql.Named("at", s.at.Format(time.RFC3339)))
+			var columns []string
+			columns, rows, err = queryRows(g, options.Query, args...)
+			if err != nil {
+				return nil, fmt.Errorf("at %s: %v", s.commit, err)
+			}
+			if len(series.Columns) == 2 {
+				series.Columns = append(series.Columns, columns...)
+			}
+			results[s.commit] = rows
+		}
+		for _, row := range rows {
+			series.Rows = append(series.Rows, append([]interface{}{s.at.Format(time.RFC3339), s.commit}, row...))
+		}
+	}
+	return series, nil
+}
+
+// queryRows runs query, and returns its columns and the values of its rows (text and dates read as strings)
+func queryRows(g *gitqlite.GitQLite, query string, args ...interface{}) ([]string, [][]interface{}, error) {
+	rows, err := g.DB.Query(query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+	values := make([][]interface{}, 0)
+	for rows.Next() {
+		row := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range row {
+			pointers[i] = &row[i]
+		}
+		err := rows.Scan(pointers...)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i, value := range row {
+			switch v := value.(type) {
+			case []byte:
+				row[i] = string(v)
+			case time.Time:
+				row[i] = v.Format(time.RFC3339Nano)
+			}
+		}
+		values = append(values, row)
+	}
+	return columns, values, rows.Err()
+}
+
+// WriteJSON writes the time series as an indented JSON object, with its columns and an array of values per row
+func (s *TimeSeriesReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
+
+// WriteCSV writes the time series as CSV, with a header, for charting tools
+func (s *TimeSeriesReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write(s.Columns)
+	if err != nil {
+		return err
+	}
+	for _, row := range s.Rows {
+		err := writer.Write(s.formatRow(row, "", false))
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteMarkdown writes the time series as a markdown table
+func (s *TimeSeriesReport) WriteMarkdown(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "| %s |\n|%s\n", strings.Join(s.Columns, " | "), strings.Repeat("---|", len(s.Columns)))
+	if err != nil {
+		return err
+	}
+	for _, row := range s.Rows {
+		_, err := fmt.Fprintf(w, "| %s |\n", strings.Join(s.formatRow(row, "NULL", true), " | "))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatRow formats the values of a row, NULL values as null, escaping pipes for markdown if escape is set
+func (s *TimeSeriesReport) formatRow(row []interface{}, null string, escape bool) []string {
+	values := make([]string, len(row))
+	for i, value := range row {
+		switch {
+		case value == nil:
+			values[i] = null
+		case escape:
+			values[i] = strings.ReplaceAll(fmt.Sprint(value), "|", "\\|")
+		default:
+			values[i] = fmt.Sprint(value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,92 @@
+package analyze
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestParseEvery(t *testing.T) {
+	at := time.Date(2020, time.January, 31, 0, 0, 0, 0, time.UTC)
+	for every, expected := range map[string]time.Time{
+		"12h": at.Add(12 * time.Hour),
+		"1d":  at.AddDate(0, 0, 1),
+		"2w":  at.AddDate(0, 0, 14),
+		"1m":  at.AddDate(0, 1, 0),
+		"1y":  at.AddDate(1, 0, 0),
+	} {
+		step, err := parseEvery(every)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next := step(at); !next.Equal(expected) {
+			t.Fatalf("expected %s to step to %s, got %s", every, expected, next)
+		}
+	}
+
+	for _, every := range []string{"", "1", "w", "0d", "1 week", "-1d"} {
+		if _, err := parseEvery(every); err == nil {
+			t.Fatalf("expected %q to be an invalid period", every)
+		}
+	}
+}
+
+func TestSamples(t *testing.T) {
+	start := time.Date(2020, time.November, 1, 0, 0, 0, 0, time.UTC)
+	commits := []*committed{
+		{id: "a", when: start},
+		{id: "b", when: start.Add(12 * time.Hour)},
+		{id: "c", when: start.Add(36 * time.Hour)},
+		{id: "d", when: start.Add(90 * time.Hour)},
+	}
+	step, err := parseEvery("1d")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a commit is sampled every day, the most recent one then, and the last commit ends the series
+	expected := []string{"a", "b", "c", "c", "d"}
+	sampled := samples(commits, step)
+	if len(sampled) != len(expected) {
+		t.Fatalf("expected %d samples, got %d", len(expected), len(sampled))
+	}
+	for i, s := range sampled {
+		if s.commit != expected[i] {
+			t.Fatalf("expected sample %d to be %s, got %s", i, expected[i], s.commit)
+		}
+	}
+	if !sampled[4].at.Equal(commits[3].when) {
+		t.Fatalf("expected the last sample to be at the last commit, got %s", sampled[4].at)
+	}
+
+	if len(samples(nil, step)) != 0 {
+		t.Fatal("expected no samples without commits")
+	}
+}
+
+func TestTimeSeriesReport(t *testing.T) {
+	report := &TimeSeriesReport{
+		Columns: []string{"at", "commit_id", "extension", "files"},
+		Rows:    [][]interface{}{{"2020-11-01T00:00:00Z", "a", "go", int64(2)}, {"2020-11-01T00:00:00Z", "a", nil, int64(1)}},
+	}
+
+	var csv bytes.Buffer
+	err := report.WriteCSV(&csv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "at,commit_id,extension,files\n2020-11-01T00:00:00Z,a,go,2\n2020-11-01T00:00:00Z,a,,1\n"
+	if csv.String() != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, csv.String())
+	}
+
+	var markdown bytes.Buffer
+	err = report.WriteMarkdown(&markdown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "| at | commit_id | extension | files |\n|---|---|---|---|\n| 2020-11-01T00:00:00Z | a | go | 2 |\n| 2020-11-01T00:00:00Z | a | NULL | 1 |\n"
+	if markdown.String() != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, markdown.String())
+	}
+}
@@ -0,0 +1,158 @@
+package analyze
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/augmentable-dev/askgit/pkg/gitqlite"
+)
+
+// TimezoneOptions are the options of a timezone breakdown
+type TimezoneOptions struct {
+	// Since is the start of the period reported on, which ends now (the whole history if zero)
+	Since time.Time
+}
+
+// Timezone is a timezone offset contributors commit from
+type Timezone struct {
+	// Offset is the offset from UTC, i.e. +02:00, and Minutes the same offset in minutes east of UTC
+	Offset  string `json:"offset"`
+	Minutes int    `json:"minutes"`
+	// Authors is the number of authors whose main timezone it is (the one they authored the most commits from),
+	// and Commits the number of commits authored from it by anyone
+	Authors int `json:"authors"`
+	Commits int `json:"commits"`
+}
+
+// TimezoneReport breaks contributors down by timezone, from the offsets of the author dates of their commits,
+// as an approximation of where they work from (i.e. to staff follow-the-sun rotations).
+// An offset isn't a location: daylight saving time moves people between offsets, and tools may commit in UTC.
+type TimezoneReport struct {
+	Since     *time.Time  `json:"since,omitempty"`
+	Authors   int         `json:"authors"`
+	Timezones []*Timezone `json:"timezones"`
+}
+
+// authorOffset is the number of commits an author authored from an offset
+type authorOffset struct {
+	email   string
+	minutes int
+	commits int
+}
+
+// Timezones breaks the contributors of the repository a GitQLite instance queries down by timezone
+func Timezones(g *gitqlite.GitQLite, options *TimezoneOptions) (*TimezoneReport, error) {
+	since := ""
+	if !options.Since.IsZero() {
+		since = options.Since.UTC().Format(time.RFC3339)
+	}
+	rows, err := g.DB.Query(`
+		SELECT author_email, author_tz_offset, count(*) FROM commits
+		WHERE ? = '' OR author_when_utc >= ?
+		GROUP BY author_email, author_tz_offset`, since, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	offsets := make([]*authorOffset, 0)
+	for rows.Next() {
+		offset := &authorOffset{}
+		err := rows.Scan(&offset.email, &offset.minutes, &offset.commits)
+		if err != nil {
+			return nil, err
+		}
+		offsets = append(offsets, offset)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	report := timezoneReport(offsets)
+	if !options.Since.IsZero() {
+		report.Since = &options.Since
+	}
+	return report, nil
+}
+
+// timezoneReport totals the commits of every offset, and attributes every author to the offset they authored
+// the most commits from (the most eastern one in case of a tie). Timezones are ordered from the most authors.
+func timezoneReport(offsets []*authorOffset) *TimezoneReport {
+	zones := make(map[int]*Timezone)
+	primary := make(map[string]*authorOffset)
+	for _, o := range offsets {
+		zone, ok := zones[o.minutes]
+		if !ok {
+			zone = &Timezone{Offset: formatOffset(o.minutes), Minutes: o.minutes}
+			zones[o.minutes] = zone
+		}
+		zone.Commits += o.commits
+
+		m, ok := primary[o.email]
+		if !ok || o.commits > m.commits || (o.commits == m.commits && o.minutes > m.minutes) {
+			primary[o.email] = o
+		}
+	}
+	for _, o := range primary {
+		zones[o.minutes].Authors++
+	}
+
+	report := &TimezoneReport{Authors: len(primary), Timezones: make([]*Timezone, 0, len(zones))}
+	for _, zone := range zones {
+		report.Timezones = append(report.Timezones, zone)
+	}
+	sort.Slice(report.Timezones, func(i, j int) bool {
+		a, b := report.Timezones[i], report.Timezones[j]
+		if a.Authors != b.Authors {
+			return a.Authors > b.Authors
+		}
+		if a.Commits != b.Commits {
+			return a.Commits > b.Commits
+		}
+		return a.Minutes < b.Minutes
+	})
+	return report
+}
+
+// formatOffset formats an offset in minutes east of UTC like in ISO 8601, i.e. +05:30
+func formatOffset(minutes int) string {
+	sign := '+'
+	if minutes < 0 {
+		sign = '-'
+		minutes = -minutes
+	}
+	return fmt.Sprintf("%c%02d:%02d", sign, minutes/60, minutes%60)
+}
+
+// WriteJSON writes the report as an indented JSON object
+func (r *TimezoneReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteMarkdown writes the report as a markdown document, with a table of the timezones
+func (r *TimezoneReport) WriteMarkdown(w io.Writer) error {
+	period := "over the whole history"
+	if r.Since != nil {
+		period = "since " + r.Since.Format("2006-01-02")
+	}
+	_, err := fmt.Fprintf(w, "# Contributors by timezone\n\n%d authors, %s.\n\n| Offset | Authors | Share of authors | Commits |\n|--------|---------|------------------|---------|\n", r.Authors, period)
+	if err != nil {
+		return err
+	}
+	for _, zone := range r.Timezones {
+		share := 0.0
+		if r.Authors > 0 {
+			share = float64(zone.Authors) / float64(r.Authors) * 100
+		}
+		_, err := fmt.Fprintf(w, "| UTC%s | %d | %.1f%% | %d |\n", zone.Offset, zone.Authors, share, zone.Commits)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
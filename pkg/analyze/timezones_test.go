@@ -0,0 +1,43 @@
+package analyze
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTimezoneReport(t *testing.T) {
+	report := timezoneReport([]*authorOffset{
+		// alice mostly commits from Paris, but travelled to New York
+		{email: "alice@example.com", minutes: 60, commits: 10},
+		{email: "alice@example.com", minutes: -300, commits: 2},
+		{email: "bob@example.com", minutes: -300, commits: 5},
+		{email: "carol@example.com", minutes: 60, commits: 1},
+		// dan committed as much from both, the most eastern offset wins
+		{email: "dan@example.com", minutes: 330, commits: 3},
+		{email: "dan@example.com", minutes: 60, commits: 3},
+	})
+
+	if report.Authors != 4 || len(report.Timezones) != 3 {
+		t.Fatalf("expected 4 authors in 3 timezones, got %d in %d", report.Authors, len(report.Timezones))
+	}
+	expected := []Timezone{
+		{Offset: "+01:00", Minutes: 60, Authors: 2, Commits: 14},
+		{Offset: "-05:00", Minutes: -300, Authors: 1, Commits: 7},
+		{Offset: "+05:30", Minutes: 330, Authors: 1, Commits: 3},
+	}
+	for i, zone := range report.Timezones {
+		if *zone != expected[i] {
+			t.Fatalf("expected timezone %d to be %+v, got %+v", i, expected[i], *zone)
+		}
+	}
+
+	var markdown bytes.Buffer
+	err := report.WriteMarkdown(&markdown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(markdown.String(), "| UTC+01:00 | 2 | 50.0% | 14 |") {
+		t.Fatalf("unexpected markdown report:\n%s", markdown.String())
+	}
+}
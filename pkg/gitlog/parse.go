@@ -29,6 +29,8 @@ type CommitIter struct {
 	reader  io.ReadCloser
 	scanner *bufio.Scanner
 	current *Commit
+	// message accumulates the lines of a commit message until its terminating NUL byte, nil outside of a message
+	message *strings.Builder
 }
 
 func newCommitIter(reader io.ReadCloser) *CommitIter {
@@ -54,6 +56,10 @@ const (
 func (iter *CommitIter) Next() (*Commit, error) {
 	for iter.scanner.Scan() {
 		line := iter.scanner.Text()
+		if iter.message != nil {
+			iter.readMessage(line)
+			continue
+		}
 		switch {
 		case strings.HasPrefix(line, commit):
 			current := iter.current // save the previous current
@@ -96,7 +102,8 @@ func (iter *CommitIter) Next() (*Commit, error) {
 			}
 			iter.current.CommitterWhen = cD
 		case strings.HasPrefix(line, message):
-			iter.current.Message = strings.TrimPrefix(line, message)
+			iter.message = &strings.Builder{}
+			iter.readMessage(strings.TrimPrefix(line, message))
 		case strings.TrimSpace(line) == "": // ignore empty lines
 		default:
 			s := strings.Split(line, "\t")
@@ -130,14 +137,32 @@ func (iter *CommitIter) Next() (*Commit, error) {
 	return nil, io.EOF
 }
 
-func Execute(repoPath string) (*CommitIter, error) {
+// readMessage adds a line to the message of the current commit, which is raw and may span many lines.
+// The message is terminated by a NUL byte, so that its lines aren't mistaken for other fields.
+func (iter *CommitIter) readMessage(line string) {
+	if i := strings.IndexByte(line, 0); i >= 0 {
+		iter.message.WriteString(line[:i])
+		iter.current.Message = iter.message.String()
+		iter.message = nil
+		return
+	}
+	iter.message.WriteString(line)
+	iter.message.WriteString("\n")
+}
+
+// Execute runs git log in the repository at repoPath, over the history of ref (HEAD if it's empty)
+func Execute(repoPath, ref string) (*CommitIter, error) {
 	gitPath, err := exec.LookPath("git")
 	if err != nil {
 		return nil, err
 	}
 
 	args := []string{"log"}
-	args = append(args, "--format=commit %H%ntree %T%nparent %P%nAuthor: %an %ae%nAuthorDate: %aI%nCommit: %cn %ce%nCommitDate: %cI%nMessage: %s", "--numstat", "-m", "-c", "--date-order")
+	args = append(args, "--format=commit %H%ntree %T%nparent %P%nAuthor: %an %ae%nAuthorDate: %aI%nCommit: %cn %ce%nCommitDate: %cI%nMessage: %B%x00", "--numstat", "-m", "-c", "--date-order")
+	if ref != "" {
+		// the trailing -- prevents ref from being mistaken for a path
+		args = append(args, ref, "--")
+	}
 
 	cmd := exec.Command(gitPath, args...)
 	cmd.Dir = repoPath
@@ -57,7 +57,7 @@ func initFixtureRepo() (func() error, error) {
 }
 
 func TestParse(t *testing.T) {
-	iter, err := Execute(fixtureRepoDir)
+	iter, err := Execute(fixtureRepoDir, "")
 	if err != nil {
 		t.Fatal(err)
 	}
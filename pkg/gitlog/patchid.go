@@ -0,0 +1,131 @@
+package gitlog
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var hunkHeader = regexp.MustCompile(`^@@ -\d+(?:,(\d+))? \+\d+(?:,(\d+))? @@`)
+
+// PatchID computes the patch ID of the patch text read from r, following `git patch-id --stable`:
+// whitespace and line numbers are ignored and the per-file hashes are summed, so the result doesn't depend on file order.
+// Commits introducing the same change (cherry-picks, backports) share a patch ID. An empty string is returned for an empty patch.
+func PatchID(r io.Reader) (string, error) {
+	var result [sha1.Size]byte
+	h := sha1.New()
+
+	// flush adds the hash of the current file to the result (a 20 byte sum, with carry)
+	flush := func() {
+		sum := h.Sum(nil)
+		h.Reset()
+		carry := 0
+		for i := range result {
+			carry += int(result[i]) + int(sum[i])
+			result[i] = byte(carry)
+			carry >>= 8
+		}
+	}
+
+	patchLen := 0
+	before, after := -1, -1
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		if line == "" && err == io.EOF {
+			break
+		}
+
+		switch {
+		case strings.HasPrefix(line, `\ `): // "\ No newline at end of file"
+			continue
+		case patchLen == 0 && !strings.HasPrefix(line, "diff "): // ignore anything before the first diff
+			continue
+		}
+
+		// parsing a diff header
+		if before == -1 {
+			if strings.HasPrefix(line, "index ") {
+				continue
+			} else if strings.HasPrefix(line, "--- ") {
+				before, after = 1, 1
+			} else if !unicode.IsLetter(rune(line[0])) {
+				break
+			}
+		}
+
+		// looking for a hunk header, or the header of the next file
+		if before == 0 && after == 0 {
+			if m := hunkHeader.FindStringSubmatch(line); m != nil {
+				before, after = hunkLength(m[1]), hunkLength(m[2])
+				continue
+			}
+			if !strings.HasPrefix(line, "diff ") {
+				break
+			}
+			flush()
+			before, after = -1, -1
+		}
+
+		if line[0] == '-' || line[0] == ' ' {
+			before--
+		}
+		if line[0] == '+' || line[0] == ' ' {
+			after--
+		}
+
+		stripped := strings.Map(func(r rune) rune {
+			if unicode.IsSpace(r) {
+				return -1
+			}
+			return r
+		}, line)
+		patchLen += len(stripped)
+		h.Write([]byte(stripped))
+	}
+
+	if patchLen == 0 {
+		return "", nil
+	}
+	flush()
+	return fmt.Sprintf("%x", result), nil
+}
+
+// hunkLength parses the line count of a hunk range, which defaults to 1 when omitted
+func hunkLength(s string) int {
+	if s == "" {
+		return 1
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// Patch returns the patch introduced by a commit relative to its parent, as produced by `git diff-tree -p`.
+// If parentSHA is empty, the commit is treated as a root commit.
+func Patch(repoPath, sha, parentSHA string) ([]byte, error) {
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"diff-tree", "-p", "-M", "--no-color"}
+	if parentSHA == "" {
+		args = append(args, "--root", sha)
+	} else {
+		args = append(args, parentSHA, sha)
+	}
+
+	cmd := exec.Command(gitPath, args...)
+	cmd.Dir = repoPath
+
+	return cmd.Output()
+}
@@ -0,0 +1,76 @@
+package gitlog
+
+import (
+	"strings"
+	"testing"
+)
+
+const patchA = `diff --git a/a.txt b/a.txt
+index 3b18e51..a042389 100644
+--- a/a.txt
++++ b/a.txt
+@@ -1,2 +1,2 @@
+ hello
+-world
++there
+`
+
+const patchB = `diff --git a/b.txt b/b.txt
+new file mode 100644
+index 0000000..ce01362
+--- /dev/null
++++ b/b.txt
+@@ -0,0 +1 @@
++hello
+\ No newline at end of file
+`
+
+func TestPatchID(t *testing.T) {
+	id, err := PatchID(strings.NewReader(patchA + patchB))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(id) != 40 {
+		t.Fatalf("expected a 40 character patch id, got %q", id)
+	}
+
+	// file order doesn't matter
+	reordered, err := PatchID(strings.NewReader(patchB + patchA))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reordered != id {
+		t.Fatalf("expected %s for reordered patch, got %s", id, reordered)
+	}
+
+	// neither do line numbers, whitespace or blob ids
+	shifted := strings.Replace(patchA, "@@ -1,2 +1,2 @@", "@@ -10,2 +10,2 @@", 1)
+	shifted = strings.Replace(shifted, "index 3b18e51..a042389", "index 1111111..2222222", 1)
+	shifted = strings.Replace(shifted, "+there", "+  there", 1)
+	moved, err := PatchID(strings.NewReader(shifted + patchB))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved != id {
+		t.Fatalf("expected %s for shifted patch, got %s", id, moved)
+	}
+
+	// but the content does
+	changed, err := PatchID(strings.NewReader(strings.Replace(patchA, "+there", "+where", 1) + patchB))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed == id {
+		t.Fatalf("expected a different patch id for a different change, got %s", changed)
+	}
+}
+
+func TestPatchIDEmpty(t *testing.T) {
+	id, err := PatchID(strings.NewReader(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "" {
+		t.Fatalf("expected empty patch id for empty patch, got %s", id)
+	}
+}
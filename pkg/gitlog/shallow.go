@@ -0,0 +1,45 @@
+package gitlog
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ShallowCommits returns the SHAs of the commits at the boundary of a shallow repository (whose parents weren't fetched).
+// It's empty if the repository isn't shallow.
+func ShallowCommits(repoPath string) (map[string]bool, error) {
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(gitPath, "rev-parse", "--git-path", "shallow")
+	cmd.Dir = repoPath
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	shallowPath := strings.TrimSpace(string(out))
+	if !filepath.IsAbs(shallowPath) {
+		shallowPath = filepath.Join(repoPath, shallowPath)
+	}
+
+	commits := make(map[string]bool)
+	contents, err := ioutil.ReadFile(shallowPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return commits, nil
+		}
+		return nil, err
+	}
+
+	for _, sha := range strings.Fields(string(contents)) {
+		commits[sha] = true
+	}
+	return commits, nil
+}
@@ -0,0 +1,344 @@
+package gitqlite
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// arrowBatchRows is the number of rows of each record batch of the arrow format,
+// the rows of a batch are held in memory until it's written
+const arrowBatchRows = 64 * 1024
+
+// arrowType is the Arrow type of a column, as numbered in the Type union of Schema.fbs
+type arrowType byte
+
+const (
+	arrowInt64   arrowType = 2
+	arrowFloat64 arrowType = 3
+	arrowBinary  arrowType = 4
+	arrowUtf8    arrowType = 5
+	arrowBool    arrowType = 6
+)
+
+// the headers of Arrow IPC messages, as numbered in the MessageHeader union of Message.fbs
+const (
+	arrowSchemaMessage      = 1
+	arrowRecordBatchMessage = 3
+)
+
+// arrowMetadataV5 is the version of the Arrow format the messages are written in
+const arrowMetadataV5 = 4
+
+// arrowDisplay outputs the rows in the Arrow IPC streaming format (https://arrow.apache.org/docs/format/Columnar.html#ipc-streaming-format),
+// which dataframe libraries read without parsing text: a schema message, then a record batch per arrowBatchRows rows.
+// Columns are typed by their declared types: integers (INT), doubles (REAL), booleans (BOOL), binary (BLOB), and strings for anything else,
+// dates being RFC 3339 strings like in the json format. The type of a column computed by an expression is the one of its values in the first batch.
+func arrowDisplay(rows ResultRows, write io.Writer) error {
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	types, err := declaredTypes(rows)
+	if err != nil {
+		return err
+	}
+
+	values := make([]interface{}, len(columns))
+	for i := range values {
+		values[i] = new(interface{})
+	}
+	readBatch := func() ([][]interface{}, error) {
+		batch := make([][]interface{}, 0)
+		for len(batch) < arrowBatchRows && rows.Next() {
+			err := rows.Scan(values...)
+			if err != nil {
+				return nil, err
+			}
+			row := make([]interface{}, len(columns))
+			for i := range row {
+				row[i] = *(values[i].(*interface{}))
+			}
+			batch = append(batch, row)
+		}
+		return batch, nil
+	}
+
+	batch, err := readBatch()
+	if err != nil {
+		return err
+	}
+	arrowTypes := make([]arrowType, len(columns))
+	for i := range columns {
+		arrowTypes[i] = arrowColumnType(types[i], batch, i)
+	}
+
+	err = writeArrowMessage(write, arrowSchema(columns, arrowTypes), nil)
+	if err != nil {
+		return err
+	}
+	for len(batch) > 0 {
+		metadata, body, err := arrowRecordBatch(columns, arrowTypes, batch)
+		if err != nil {
+			return err
+		}
+		err = writeArrowMessage(write, metadata, body)
+		if err != nil {
+			return err
+		}
+		batch, err = readBatch()
+		if err != nil {
+			return err
+		}
+	}
+
+	// the end of the stream is a message of no metadata
+	_, err = write.Write([]byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0})
+	return err
+}
+
+// arrowColumnType returns the Arrow type of the column at index i, from its declared type (like SQLite's affinity rules),
+// or if it has none, from its values in the first batch: integers, doubles if some of them aren't integers, or else strings
+func arrowColumnType(declaredType string, batch [][]interface{}, i int) arrowType {
+	switch {
+	case strings.Contains(declaredType, "BOOL"):
+		return arrowBool
+	case strings.Contains(declaredType, "INT"):
+		return arrowInt64
+	case strings.Contains(declaredType, "REAL"), strings.Contains(declaredType, "FLOA"), strings.Contains(declaredType, "DOUB"):
+		return arrowFloat64
+	case declaredType == "BLOB":
+		return arrowBinary
+	case declaredType != "":
+		return arrowUtf8
+	}
+
+	inferred := arrowType(0)
+	for _, row := range batch {
+		switch row[i].(type) {
+		case nil:
+		case int64:
+			if inferred == 0 {
+				inferred = arrowInt64
+			}
+		case float64:
+			inferred = arrowFloat64
+		default:
+			return arrowUtf8
+		}
+	}
+	if inferred == 0 {
+		return arrowUtf8
+	}
+	return inferred
+}
+
+// arrowSchema returns the metadata of the schema message of columns
+func arrowSchema(columns []string, types []arrowType) []byte {
+	fields := make([]*flatTable, len(columns))
+	for i, column := range columns {
+		var typ *flatTable
+		switch types[i] {
+		case arrowInt64:
+			// Int { bitWidth: int, is_signed: bool }
+			typ = &flatTable{fields: []flatField{flatScalar(int32(64)), flatScalar(true)}}
+		case arrowFloat64:
+			// FloatingPoint { precision: DOUBLE }
+			typ = &flatTable{fields: []flatField{flatScalar(int16(2))}}
+		default:
+			// Utf8, Binary and Bool have no fields
+			typ = &flatTable{}
+		}
+		// Field { name, nullable, type_type, type, dictionary, children }
+		fields[i] = &flatTable{fields: []flatField{
+			{child: flatString(column)},
+			flatScalar(true),
+			flatScalar(byte(types[i])),
+			{child: typ},
+			{},
+			{child: &flatTables{}},
+		}}
+	}
+	// Schema { endianness: Little, fields }
+	schema := &flatTable{fields: []flatField{flatScalar(int16(0)), {child: &flatTables{tables: fields}}}}
+	return arrowMessage(arrowSchemaMessage, schema, 0)
+}
+
+// arrowRecordBatch returns the metadata and body of the record batch message of rows
+func arrowRecordBatch(columns []string, types []arrowType, rows [][]interface{}) ([]byte, []byte, error) {
+	var body []byte
+	var nodes, buffers []byte
+	addBuffer := func(buffer []byte) {
+		buffers = appendInt64s(buffers, int64(len(body)), int64(len(buffer)))
+		body = append(body, buffer...)
+		// buffers are aligned on 8 bytes
+		for len(body)%8 != 0 {
+			body = append(body, 0)
+		}
+	}
+
+	for i, column := range columns {
+		validity := make([]byte, (len(rows)+7)/8)
+		nulls := 0
+		var values, data []byte
+		offsets := appendInt32s(nil, 0)
+		for row, value := range rows {
+			if value[i] == nil {
+				nulls++
+				switch types[i] {
+				case arrowInt64, arrowFloat64:
+					values = append(values, make([]byte, 8)...)
+				case arrowUtf8, arrowBinary:
+					offsets = appendInt32s(offsets, int32(len(data)))
+				}
+				continue
+			}
+			validity[row/8] |= 1 << (row % 8)
+
+			switch types[i] {
+			case arrowInt64:
+				n, ok := arrowInt(value[i])
+				if !ok {
+					return nil, nil, fmt.Errorf("value %v of column %s isn't an integer", value[i], column)
+				}
+				values = appendInt64s(values, n)
+			case arrowFloat64:
+				f, ok := arrowFloat(value[i])
+				if !ok {
+					return nil, nil, fmt.Errorf("value %v of column %s isn't a number", value[i], column)
+				}
+				values = appendInt64s(values, int64(math.Float64bits(f)))
+			case arrowBool:
+				n, ok := arrowInt(value[i])
+				if !ok {
+					return nil, nil, fmt.Errorf("value %v of column %s isn't a boolean", value[i], column)
+				}
+				if values == nil {
+					values = make([]byte, len(validity))
+				}
+				if n != 0 {
+					values[row/8] |= 1 << (row % 8)
+				}
+			default:
+				data = append(data, arrowText(value[i])...)
+				offsets = appendInt32s(offsets, int32(len(data)))
+			}
+		}
+
+		// FieldNode { length, null_count }
+		nodes = appendInt64s(nodes, int64(len(rows)), int64(nulls))
+		if nulls == 0 {
+			addBuffer(nil)
+		} else {
+			addBuffer(validity)
+		}
+		switch types[i] {
+		case arrowUtf8, arrowBinary:
+			addBuffer(offsets)
+			addBuffer(data)
+		case arrowBool:
+			if values == nil {
+				values = make([]byte, len(validity))
+			}
+			addBuffer(values)
+		default:
+			addBuffer(values)
+		}
+	}
+
+	// RecordBatch { length, nodes, buffers }
+	batch := &flatTable{fields: []flatField{
+		flatScalar(int64(len(rows))),
+		{child: &flatStructs{data: nodes, count: len(nodes) / 16}},
+		{child: &flatStructs{data: buffers, count: len(buffers) / 16}},
+	}}
+	return arrowMessage(arrowRecordBatchMessage, batch, len(body)), body, nil
+}
+
+// arrowMessage returns the metadata of a message: Message { version, header_type, header, bodyLength }
+func arrowMessage(headerType byte, header *flatTable, bodyLength int) []byte {
+	return (&flatTable{fields: []flatField{
+		flatScalar(int16(arrowMetadataV5)),
+		flatScalar(headerType),
+		{child: header},
+		flatScalar(int64(bodyLength)),
+	}}).bytes()
+}
+
+// writeArrowMessage writes an encapsulated message: a continuation marker, the length of its metadata,
+// its metadata padded so that the body is aligned on 8 bytes, and its body
+func writeArrowMessage(write io.Writer, metadata, body []byte) error {
+	for len(metadata)%8 != 0 {
+		metadata = append(metadata, 0)
+	}
+	prefix := appendInt32s([]byte{0xff, 0xff, 0xff, 0xff}, int32(len(metadata)))
+	for _, b := range [][]byte{prefix, metadata, body} {
+		_, err := write.Write(b)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// arrowInt converts a value of an integer or boolean column
+func arrowInt(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// arrowFloat converts a value of a column of doubles
+func arrowFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// arrowText converts a value of a string or binary column
+func arrowText(value interface{}) string {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+func appendInt32s(b []byte, values ...int32) []byte {
+	for _, v := range values {
+		b = append(b, 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(b[len(b)-4:], uint32(v))
+	}
+	return b
+}
+
+func appendInt64s(b []byte, values ...int64) []byte {
+	for _, v := range values {
+		b = append(b, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.LittleEndian.PutUint64(b[len(b)-8:], uint64(v))
+	}
+	return b
+}
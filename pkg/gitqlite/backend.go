@@ -0,0 +1,112 @@
+package gitqlite
+
+import (
+	"os/exec"
+)
+
+// backend is a way of reading a git repository, which implements some tables as virtual table modules.
+// Every backend implementing a table declares the same schema for it, and returns the same rows.
+type backend interface {
+	// available returns whether the backend can be used on this system
+	available() bool
+	// module returns the name of the virtual table module implementing table, or "" if the backend doesn't implement it
+	module(table string) string
+}
+
+// libgit2Backend reads repositories with libgit2, it implements every table
+type libgit2Backend struct{}
+
+func (libgit2Backend) available() bool { return true }
+
+func (libgit2Backend) module(table string) string {
+	switch table {
+	case "commits":
+		return "git_log"
+	case "stats":
+		return "git_stats"
+	case "commit_files":
+		return "git_commit_files"
+	case "files":
+		return "git_tree"
+	case "tags":
+		return "git_tag"
+	case "branches":
+		return "git_branch"
+	case "authors":
+		return "git_author"
+	case "file_churn":
+		return "git_file_churn"
+	case "contributors":
+		return "git_contributor"
+	case "dir_stats":
+		return "git_dir_stats"
+	case "file_last_modified":
+		return "git_file_last_modified"
+	case "commit_branches":
+		return "git_commit_branch"
+	case "branch_lifecycle":
+		return "git_branch_lifecycle"
+	case "merge_preview":
+		return "git_merge_preview"
+	case "cherries":
+		return "git_cherry"
+	case "commit_lint":
+		return "git_commit_lint"
+	case "commit_issue_refs":
+		return "git_commit_issue_ref"
+	case "hooks":
+		return "git_hook"
+	case "ci_workflows":
+		return "git_ci_workflow"
+	case "dependency_updates":
+		return "git_dependency_update"
+	case "gitattributes":
+		return "git_attribute"
+	case "repo":
+		return "git_repo"
+	default:
+		return ""
+	}
+}
+
+// gitCLIBackend reads repositories by running the locally installed git command, it only implements the commits table
+type gitCLIBackend struct{}
+
+func (gitCLIBackend) available() bool {
+	_, err := exec.LookPath("git")
+	return err == nil
+}
+
+func (gitCLIBackend) module(table string) string {
+	switch table {
+	case "commits":
+		return "git_log_cli"
+	default:
+		return ""
+	}
+}
+
+// tables lists the tables created by New, in the order they're created
+var tables = []string{"commits", "stats", "commit_files", "files", "tags", "branches", "authors", "file_churn", "contributors", "dir_stats", "file_last_modified", "commit_branches", "branch_lifecycle", "merge_preview", "cherries", "commit_lint", "commit_issue_refs", "hooks", "ci_workflows", "dependency_updates", "gitattributes", "repo"}
+
+// backends returns the backends to use for the given options, by order of preference
+func backends(options *Options) []backend {
+	if options.UseGitCLI {
+		return []backend{gitCLIBackend{}, libgit2Backend{}}
+	}
+	return []backend{libgit2Backend{}}
+}
+
+// selectModule returns the name of the virtual table module implementing table,
+// from the first available backend implementing it. If none does, it returns "".
+func selectModule(table string, backends []backend) string {
+	for _, b := range backends {
+		if !b.available() {
+			continue
+		}
+		if module := b.module(table); module != "" {
+			return module
+		}
+	}
+	return ""
+}
@@ -0,0 +1,80 @@
+package gitqlite
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestSelectModule(t *testing.T) {
+	libgit2 := backends(&Options{})
+	cli := backends(&Options{UseGitCLI: true})
+	gitCLI := gitCLIBackend{}
+
+	for _, table := range tables {
+		if selectModule(table, libgit2) == "" {
+			t.Fatalf("expected the libgit2 backend to implement %s", table)
+		}
+
+		// the git CLI backend falls back to libgit2 for the tables it doesn't implement
+		expected := libgit2Backend{}.module(table)
+		if gitCLI.available() && gitCLI.module(table) != "" {
+			expected = gitCLI.module(table)
+		}
+		if module := selectModule(table, cli); module != expected {
+			t.Fatalf("expected module %s for %s with the git CLI backend, got %s", expected, table, module)
+		}
+	}
+}
+
+func TestBackendsMatch(t *testing.T) {
+	query := func(options *Options, table string) ([]string, [][]string) {
+		instance, err := New(fixtureRepoDir, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// patch ids are compared in TestPatchIDMatchesCLI, they're too slow to compute over the whole history
+		q := fmt.Sprintf("SELECT * FROM %s ORDER BY 1", table)
+		if table == "commits" {
+			q = "SELECT id, message, summary, author_name, author_email, author_when, committer_name, committer_email, committer_when, parent_id, parent_count, tree_id, is_shallow_boundary, author_when_utc, author_tz_offset, committer_when_utc, committer_tz_offset, is_merge, parent_1, parent_2, body FROM commits ORDER BY id"
+		}
+
+		rows, err := instance.DB.Query(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+
+		columns, err := rows.Columns()
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, contents, err := GetContents(rows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return columns, contents
+	}
+
+	gitCLI := gitCLIBackend{}
+	for _, table := range tables {
+		if gitCLI.module(table) == "" {
+			continue
+		}
+
+		columns, libgit2 := query(&Options{}, table)
+		cliColumns, cli := query(&Options{UseGitCLI: true}, table)
+		if !reflect.DeepEqual(columns, cliColumns) {
+			t.Fatalf("expected the same columns for %s with both backends, got %v and %v", table, columns, cliColumns)
+		}
+		if len(libgit2) != len(cli) {
+			t.Fatalf("expected the same number of rows in %s with both backends, got %d and %d", table, len(libgit2), len(cli))
+		}
+		for i := range libgit2 {
+			if !reflect.DeepEqual(libgit2[i], cli[i]) {
+				t.Fatalf("expected the same row %d in %s with both backends, got %v and %v", i, table, libgit2[i], cli[i])
+			}
+		}
+	}
+}
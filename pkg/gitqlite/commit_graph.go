@@ -0,0 +1,290 @@
+package gitqlite
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	git "github.com/libgit2/git2go/v30"
+)
+
+// commitGraph is a commit-graph file, which git writes (i.e. with `git commit-graph write`) to speed up history traversals:
+// it lists the parents and generation number of every commit, so walking a history doesn't require parsing commit objects.
+// See https://git-scm.com/docs/commit-graph-format
+type commitGraph struct {
+	fanout  []byte
+	ids     []byte
+	data    []byte
+	edges   []byte
+	commits uint32
+}
+
+const (
+	commitGraphSignature = "CGPH"
+	oidSize              = len(git.Oid{})
+	// commitDataSize is the size of the data of a commit in the CDAT chunk:
+	// its tree id, the positions of its first two parents and its generation number and commit time
+	commitDataSize = oidSize + 16
+	// graphParentNone marks a missing parent, graphParentEdges marks a second parent pointing to the list of parents in the EDGE chunk
+	graphParentNone  = 0x70000000
+	graphParentEdges = 0x80000000
+)
+
+// openCommitGraph reads the commit-graph file of a repository, it returns nil (and no error) if there is none.
+// Split commit-graphs (a chain of files in objects/info/commit-graphs) aren't read, nor are commit-graphs of shallow repositories,
+// which git doesn't write anyway.
+func openCommitGraph(repo *git.Repository) (*commitGraph, error) {
+	shallow, err := repo.IsShallow()
+	if err != nil {
+		return nil, err
+	}
+	if shallow {
+		return nil, nil
+	}
+
+	path := filepath.Join(CommonDir(repo), "objects", "info", "commit-graph")
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	graph, err := parseCommitGraph(contents)
+	if err != nil {
+		return nil, fmt.Errorf("invalid commit-graph file %s: %v", path, err)
+	}
+	return graph, nil
+}
+
+// parseCommitGraph parses the contents of a commit-graph file.
+// It returns nil (and no error) for files in a format it doesn't support, i.e. of a later version or using another hash than SHA-1.
+func parseCommitGraph(contents []byte) (*commitGraph, error) {
+	if len(contents) < 8 || string(contents[:4]) != commitGraphSignature {
+		return nil, fmt.Errorf("missing signature")
+	}
+	version, hashVersion, chunkCount, baseGraphs := contents[4], contents[5], int(contents[6]), contents[7]
+	if version != 1 || hashVersion != 1 || baseGraphs != 0 {
+		return nil, nil
+	}
+
+	chunks := make(map[string][]byte)
+	table := contents[8:]
+	if len(table) < (chunkCount+1)*12 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	for i := 0; i < chunkCount; i++ {
+		entry, next := table[i*12:], table[(i+1)*12:]
+		start, end := binary.BigEndian.Uint64(entry[4:12]), binary.BigEndian.Uint64(next[4:12])
+		if start > end || end > uint64(len(contents)) {
+			return nil, fmt.Errorf("chunk %s out of bounds", entry[:4])
+		}
+		chunks[string(entry[:4])] = contents[start:end]
+	}
+
+	graph := &commitGraph{fanout: chunks["OIDF"], ids: chunks["OIDL"], data: chunks["CDAT"], edges: chunks["EDGE"]}
+	if len(graph.fanout) != 256*4 {
+		return nil, fmt.Errorf("invalid OIDF chunk")
+	}
+	graph.commits = binary.BigEndian.Uint32(graph.fanout[255*4:])
+	if len(graph.ids) != int(graph.commits)*oidSize || len(graph.data) != int(graph.commits)*commitDataSize {
+		return nil, fmt.Errorf("invalid OIDL or CDAT chunk")
+	}
+
+	return graph, nil
+}
+
+// position returns the position of a commit in the graph, and whether it's in the graph at all
+// (commits made since the graph was written aren't)
+func (g *commitGraph) position(id *git.Oid) (uint32, bool) {
+	var low uint32
+	if id[0] > 0 {
+		low = binary.BigEndian.Uint32(g.fanout[(int(id[0])-1)*4:])
+	}
+	high := binary.BigEndian.Uint32(g.fanout[int(id[0])*4:])
+
+	for low < high {
+		mid := low + (high-low)/2
+		switch cmp := bytes.Compare(g.ids[int(mid)*oidSize:(int(mid)+1)*oidSize], id[:]); {
+		case cmp == 0:
+			return mid, true
+		case cmp < 0:
+			low = mid + 1
+		default:
+			high = mid
+		}
+	}
+	return 0, false
+}
+
+// id returns the id of the commit at position pos
+func (g *commitGraph) id(pos uint32) *git.Oid {
+	return git.NewOidFromBytes(g.ids[int(pos)*oidSize : (int(pos)+1)*oidSize])
+}
+
+// generation returns the generation number of the commit at position pos, which is greater than the generation numbers of its ancestors
+func (g *commitGraph) generation(pos uint32) uint32 {
+	data := g.data[int(pos)*commitDataSize:]
+	return binary.BigEndian.Uint32(data[oidSize+8:]) >> 2
+}
+
+// parents returns the positions of the parents of the commit at position pos
+func (g *commitGraph) parents(pos uint32) ([]uint32, error) {
+	data := g.data[int(pos)*commitDataSize:]
+	first, second := binary.BigEndian.Uint32(data[oidSize:]), binary.BigEndian.Uint32(data[oidSize+4:])
+
+	parents := make([]uint32, 0, 2)
+	if first == graphParentNone {
+		return parents, nil
+	}
+	parents = append(parents, first)
+
+	switch {
+	case second == graphParentNone:
+	case second&graphParentEdges == 0:
+		parents = append(parents, second)
+	default:
+		// octopus merges list their parents past the first one in the EDGE chunk, the last one is flagged
+		for i := int(second &^ graphParentEdges); ; i++ {
+			if (i+1)*4 > len(g.edges) {
+				return nil, fmt.Errorf("invalid EDGE chunk")
+			}
+			edge := binary.BigEndian.Uint32(g.edges[i*4:])
+			parents = append(parents, edge&^graphParentEdges)
+			if edge&graphParentEdges != 0 {
+				break
+			}
+		}
+	}
+
+	for _, parent := range parents {
+		if parent >= g.commits {
+			return nil, fmt.Errorf("invalid parent position %d", parent)
+		}
+	}
+	return parents, nil
+}
+
+// parentIDs returns the ids of the parents of a commit, from the commit-graph if the commit is in it, otherwise from the commit object
+func parentIDs(repo *git.Repository, graph *commitGraph, id *git.Oid) ([]*git.Oid, error) {
+	if graph != nil {
+		if pos, ok := graph.position(id); ok {
+			positions, err := graph.parents(pos)
+			if err != nil {
+				return nil, err
+			}
+			ids := make([]*git.Oid, len(positions))
+			for i, p := range positions {
+				ids[i] = graph.id(p)
+			}
+			return ids, nil
+		}
+	}
+
+	commit, err := repo.LookupCommit(id)
+	if err != nil {
+		return nil, err
+	}
+	defer commit.Free()
+
+	ids := make([]*git.Oid, commit.ParentCount())
+	for i := range ids {
+		ids[i] = commit.ParentId(uint(i))
+	}
+	return ids, nil
+}
+
+// graphWalk is a commitWalker reading parents from a commit-graph, in depth first order.
+// Commits which aren't in the graph are read from the repository.
+type graphWalk struct {
+	repo  *git.Repository
+	graph *commitGraph
+	stack []*git.Oid
+	seen  map[git.Oid]bool
+}
+
+func (w *graphWalk) Next(id *git.Oid) error {
+	for len(w.stack) > 0 {
+		next := w.stack[len(w.stack)-1]
+		w.stack = w.stack[:len(w.stack)-1]
+		if w.seen[*next] {
+			continue
+		}
+		w.seen[*next] = true
+
+		parents, err := parentIDs(w.repo, w.graph, next)
+		if err != nil {
+			return err
+		}
+		for i := len(parents) - 1; i >= 0; i-- {
+			if !w.seen[*parents[i]] {
+				w.stack = append(w.stack, parents[i])
+			}
+		}
+
+		*id = *next
+		return nil
+	}
+
+	*id = git.Oid{}
+	return io.EOF
+}
+
+func (w *graphWalk) Free() {}
+
+// walkGraph returns a commitWalker over the history of a commit, which reads parents from graph.
+// It falls back to walkFrom if graph is nil, and stops at limit if it isn't nil.
+func walkGraph(repo *git.Repository, graph *commitGraph, id *git.Oid, limit *commitLimit) (commitWalker, error) {
+	if graph == nil {
+		return walkFrom(repo, id, limit)
+	}
+	start := *id
+	return limitWalk(&graphWalk{repo: repo, graph: graph, stack: []*git.Oid{&start}, seen: make(map[git.Oid]bool)}, limit), nil
+}
+
+// descendantOf returns whether commit is a descendant of ancestor, like repo.DescendantOf.
+// With a commit-graph, histories are walked without parsing commit objects, and commits whose generation number is lower
+// than ancestor's aren't walked at all since ancestor can't be in their history.
+func descendantOf(repo *git.Repository, graph *commitGraph, commit, ancestor *git.Oid) (bool, error) {
+	if graph == nil {
+		return repo.DescendantOf(commit, ancestor)
+	}
+
+	ancestorPos, ok := graph.position(ancestor)
+	if !ok {
+		// commits outside of the graph are more recent than it, the ancestry has to be checked the usual way
+		return repo.DescendantOf(commit, ancestor)
+	}
+	ancestorGeneration := graph.generation(ancestorPos)
+
+	stack := []*git.Oid{commit}
+	seen := make(map[git.Oid]bool)
+	for len(stack) > 0 {
+		next := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[*next] {
+			continue
+		}
+		seen[*next] = true
+
+		if next != commit && next.Equal(ancestor) {
+			return true, nil
+		}
+		// a generation number of 0 means it wasn't computed (by older versions of git)
+		if pos, ok := graph.position(next); ok && graph.generation(pos) != 0 && graph.generation(pos) <= ancestorGeneration {
+			continue
+		}
+
+		parents, err := parentIDs(repo, graph, next)
+		if err != nil {
+			return false, err
+		}
+		stack = append(stack, parents...)
+	}
+	return false, nil
+}
@@ -0,0 +1,94 @@
+package gitqlite
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	git "github.com/libgit2/git2go/v30"
+)
+
+func TestCommitGraph(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is required to write a commit-graph")
+	}
+
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var walked, contained int
+	err = instance.DB.QueryRow("SELECT count(*) FROM commit_branches").Scan(&walked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = instance.DB.QueryRow("SELECT count(*) FROM commit_branches WHERE commit_id = (SELECT id FROM commits WHERE parent_count = 0 LIMIT 1)").Scan(&contained)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := exec.Command("git", "-C", fixtureRepoDir, "commit-graph", "write", "--reachable").CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	defer os.Remove(filepath.Join(fixtureRepoDir, ".git", "objects", "info", "commit-graph"))
+
+	graph, err := openCommitGraph(fixtureRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if graph == nil {
+		t.Skip("the commit-graph written by git isn't in a supported format")
+	}
+
+	// the parents read from the graph are those of the commit objects
+	rows, err := instance.DB.Query("SELECT id FROM commits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		err = rows.Scan(&id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		oid, err := git.NewOid(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fromGraph, err := parentIDs(fixtureRepo, graph, oid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fromObject, err := parentIDs(fixtureRepo, nil, oid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(fromGraph) != len(fromObject) {
+			t.Fatalf("expected %d parents for commit %s, got %d", len(fromObject), id, len(fromGraph))
+		}
+		for i := range fromGraph {
+			if !fromGraph[i].Equal(fromObject[i]) {
+				t.Fatalf("expected parent %d of commit %s to be %s, got %s", i, id, fromObject[i], fromGraph[i])
+			}
+		}
+	}
+
+	// and the tables relying on it return the same rows
+	var walkedWithGraph, containedWithGraph int
+	err = instance.DB.QueryRow("SELECT count(*) FROM commit_branches").Scan(&walkedWithGraph)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = instance.DB.QueryRow("SELECT count(*) FROM commit_branches WHERE commit_id = (SELECT id FROM commits WHERE parent_count = 0 LIMIT 1)").Scan(&containedWithGraph)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if walkedWithGraph != walked || containedWithGraph != contained {
+		t.Fatalf("expected %d and %d rows with a commit-graph, got %d and %d", walked, contained, walkedWithGraph, containedWithGraph)
+	}
+}
@@ -0,0 +1,353 @@
+package gitqlite
+
+import (
+	"fmt"
+)
+
+// Column is a column of a table, as described by Describe
+type Column struct {
+	Name string
+	Type string
+	// Hidden columns aren't returned by SELECT *, they're constrained to change what a table lists (i.e. the ref of commits)
+	Hidden      bool
+	Description string
+}
+
+// Function is an SQL function askgit adds, as listed by Functions
+type Function struct {
+	Signature   string
+	Description string
+}
+
+// tableDescriptions are short descriptions of every table, for TableDescription
+var tableDescriptions = map[string]string{
+	"commits":            "the commits in the history of the checked out commit, like git log",
+	"stats":              "the files changed by every commit in the history, and their added and deleted lines",
+	"commit_files":       "the files changed by every commit in the history relative to its first parent, with renames and copies",
+	"files":              "the files in the tree of every commit in the history",
+	"tags":               "the tags of the repository, lightweight or annotated",
+	"branches":           "the local and remote branches of the repository",
+	"authors":            "every distinct author identity in the history, clustered under canonical identities",
+	"file_churn":         "the changes made to every file in the history, totaled per file",
+	"contributors":       "every author in the history with their commit and line counts, like git shortlog -sne",
+	"dir_stats":          "the changes made to the files of every directory in the history, totaled per directory",
+	"file_last_modified": "the last commit changing every file checked out, and how many days ago it was committed",
+	"commit_branches":    "which branches contain each commit, like git branch -a --contains",
+	"branch_lifecycle":   "when every branch, deleted local ones included, was created and last committed to, and where it was merged",
+	"merge_preview":      "the paths which conflict when merging two refs, like git merge-tree, i.e. merge_preview('main', 'feature')",
+	"cherries":           "the commits of a ref missing from its upstream and whether they have an equivalent there, like git cherry",
+	"commit_lint":        "the rules every commit message of the history passes or fails, one row per commit and rule",
+	"commit_issue_refs":  "the issues (#123, JIRA-456...) the commit messages of the history reference, one row per commit and issue",
+	"hooks":              "the hooks git runs and the hook scripts and configurations committed to the repository",
+	"ci_workflows":       "the steps of the GitHub Actions workflows committed to the repository, and the actions they use",
+	"dependency_updates": "whether Dependabot and Renovate are configured in the repository, and their main settings",
+	"gitattributes":      "the attributes assigned by the .gitattributes files of the repository, one row per attribute",
+	"repo":               "a summary of the repository, in a single row",
+	"commit_sizes":       "the files and lines changed by every commit in the history, and its size from XS to XXL",
+	"weekly_activity":    "the commits, authors and lines changed of every week of the history, with their trends over 4 weeks",
+	"monthly_activity":   "the commits, authors and lines changed of every month of the history, with their trends over 3 months",
+
+	// the github tables read the API of GitHub, rather than the repository
+	"github_branch_protection": "the branch protection rules of the GitHub repository of the origin remote, or of the repositories of an organization",
+	"github_stargazers":        "the users who starred the GitHub repository of the origin remote, and when",
+	"github_forks":             "the forks of the GitHub repository of the origin remote, and when they were created and last pushed to",
+}
+
+// columnDescriptions describe the columns of every table, columns which are obvious from their name aren't described
+var columnDescriptions = map[string]map[string]string{
+	"commits": {
+		"id":                  "the commit id",
+		"summary":             "the first paragraph of the message",
+		"body":                "the message past its summary, NULL if there is none",
+		"author_when":         "when the commit was authored, with the timezone offset it was recorded with",
+		"committer_when":      "when the commit was committed, with the timezone offset it was recorded with",
+		"parent_id":           "the id of the first parent",
+		"patch_id":            "the id of the changes of the commit, like git patch-id --stable, NULL for an empty diff",
+		"is_shallow_boundary": "whether the commit is one of the oldest of a shallow clone, whose parents weren't fetched",
+		"author_when_utc":     "author_when in UTC",
+		"author_tz_offset":    "the timezone offset of author_when, in minutes east of UTC",
+		"committer_when_utc":  "committer_when in UTC",
+		"committer_tz_offset": "the timezone offset of committer_when, in minutes east of UTC",
+		"parent_1":            "the id of the first parent",
+		"parent_2":            "the id of the second parent of a merge, NULL otherwise",
+		"reverts_hash":        "the id of the commit the commit reverts, named by its message or whose changes it undoes, NULL otherwise",
+		"reverted_by":         "the id of the first commit reverting the commit, NULL if none does",
+		"author_domain":       "the domain of the author's email, lowercased, NULL if the email has none",
+		"pr_number":           "the number of the pull request the commit landed with, parsed from its message, NULL if it names none",
+		"ref":                 "the revision whose history is listed, the checked out commit if unconstrained",
+	},
+	"stats": {
+		"file":              "the path of the file",
+		"is_binary":         "whether the file is binary, in which case no lines are counted",
+		"old_mode":          "the octal mode of the file before the change, NULL if it didn't exist",
+		"new_mode":          "the octal mode of the file after the change, NULL if it was deleted",
+		"status":            "the kind of change: A, M, D, R, C or T like git diff --name-status",
+		"ignore_whitespace": "whether whitespace-only changes are ignored",
+		"ref":               "the revision whose history is listed, the checked out commit if unconstrained",
+		"path":              "a directory or file to limit the diffs to, the whole tree if unconstrained",
+	},
+	"commit_files": {
+		"old_path":   "the path before the change, NULL for an added file",
+		"new_path":   "the path after the change, NULL for a deleted file",
+		"status":     "the kind of change: A, M, D, R, C or T like git diff --name-status",
+		"similarity": "the percentage of similarity of a rename or copy, NULL for other changes",
+		"is_binary":  "whether the file is binary, in which case no lines are counted",
+		"old_mode":   "the octal mode of the file before the change, NULL if it didn't exist",
+		"new_mode":   "the octal mode of the file after the change, NULL if it was deleted",
+	},
+	"files": {
+		"file_id":        "the id of the blob of the file, the id of the commit checked out for a gitlink",
+		"name":           "the path of the file",
+		"contents":       "the contents of the file, the target of a symlink, NULL for a gitlink",
+		"is_binary":      "whether the file is binary, sniffing its contents for a NUL byte like git does",
+		"type":           "blob for a regular file, symlink, or gitlink for a submodule",
+		"symlink_target": "the path a symlink points to, NULL for other files",
+		"path":           "a directory or file to only list the files of, the whole tree if unconstrained",
+		"ref":            "a revision to only list the tree of, every tree of the checked out history if unconstrained",
+	},
+	"tags": {
+		"full_name":   "the name of the ref, i.e. refs/tags/v1.0.0",
+		"lightweight": "whether the tag is lightweight rather than annotated",
+		"target":      "the id of the object the tag points to",
+		"tagger_name": "the name of the tagger, NULL for a lightweight tag",
+		"message":     "the message of the tag, NULL for a lightweight tag",
+		"target_type": "the type of the object the tag points to, usually commit",
+		"tagger_when": "when the tag was created, NULL for a lightweight tag",
+	},
+	"branches": {
+		"remote": "whether the branch is a remote-tracking branch",
+		"target": "the id of the commit the branch points to",
+		"head":   "whether the branch is checked out",
+	},
+	"authors": {
+		"canonical_id":    "the identity the author is clustered under, by email, name or .mailmap",
+		"canonical_name":  "the name of the canonical identity, the one with the most commits",
+		"canonical_email": "the email of the canonical identity, the one with the most commits",
+	},
+	"file_churn": {
+		"file":           "the path of the file, renamed files are totaled under their new path",
+		"first_modified": "the commit date of the first change",
+		"last_modified":  "the commit date of the last change",
+		"author_count":   "the number of distinct author emails",
+	},
+	"contributors": {
+		"name":         "the name of the author, after applying .mailmap",
+		"email":        "the email of the author, after applying .mailmap",
+		"first_commit": "the author date of the first commit",
+		"last_commit":  "the author date of the last commit",
+	},
+	"dir_stats": {
+		"dir":            "the path of the directory, . for the root of the repository",
+		"depth":          "the number of directories from the root to the directory, 0 for the root",
+		"commit_count":   "the number of commits changing a file of the directory or of its subdirectories",
+		"file_count":     "the number of distinct paths changed in the directory or its subdirectories",
+		"first_modified": "the commit date of the first change",
+		"last_modified":  "the commit date of the last change",
+		"author_count":   "the number of distinct author emails",
+		"max_depth":      "the depth of the deepest directories totaled, all of them if unconstrained",
+	},
+	"file_last_modified": {
+		"file":           "the path of the file in the tree of HEAD",
+		"commit_id":      "the id of the last commit changing the file, relative to its first parent",
+		"author_when":    "when the last commit was authored",
+		"committer_when": "when the last commit was committed",
+		"age_days":       "the number of days since the last commit was committed",
+	},
+	"commit_branches": {
+		"branch": "the name of a branch containing the commit, i.e. main or origin/main",
+	},
+	"branch_lifecycle": {
+		"name":           "the name of the branch, i.e. main or origin/main",
+		"remote":         "whether the branch is a remote-tracking branch",
+		"deleted":        "whether the branch was deleted, as found in the HEAD reflog",
+		"tip":            "the id of the commit the branch points to, the one last checked out for a deleted branch",
+		"created_at":     "the time of the oldest reflog entry of the branch, when it was first checked out for a deleted branch",
+		"last_commit_at": "the commit date of the tip, NULL if it's unknown",
+		"idle_days":      "the number of days since the tip was committed",
+		"merged_into":    "the checked out branch or default branch of a remote containing the tip, NULL if none does",
+	},
+	"merge_preview": {
+		"path":        "the path which conflicts, the one of ref1 unless ref1 deleted it",
+		"conflict":    "content, add/add, modify/delete or delete/modify, the side deleting the path being ref2 for modify/delete",
+		"ancestor_id": "the id of the blob in the merge base, NULL if the path was added",
+		"our_id":      "the id of the blob in ref1, NULL if ref1 deleted it",
+		"their_id":    "the id of the blob in ref2, NULL if ref2 deleted it",
+		"ref1":        "the revision merged into, like the checked out branch of git merge",
+		"ref2":        "the revision merged",
+	},
+	"cherries": {
+		"commit_id":   "the id of a commit of head which isn't in the history of upstream, merges excepted",
+		"summary":     "the first paragraph of the message",
+		"patch_id":    "the id of the changes of the commit, like git patch-id --stable, NULL for an empty diff",
+		"equivalent":  "whether a commit of upstream makes the same changes, - in the output of git cherry",
+		"upstream_id": "the id of the commit of upstream making the same changes, NULL if there is none",
+		"upstream":    "the revision compared with, the upstream branch of the checked out branch if unconstrained",
+		"head":        "the revision whose commits are listed, the checked out commit if unconstrained",
+	},
+	"commit_lint": {
+		"commit_id":          "the id of the commit, merges aren't linted",
+		"summary":            "the first paragraph of the message",
+		"rule":               "subject_length, imperative_mood, conventional or trailer",
+		"passed":             "whether the message passes the rule",
+		"detail":             "why the message fails the rule, NULL if it passes",
+		"ref":                "the revision whose history is linted, the checked out commit if unconstrained",
+		"max_subject_length": "the number of characters subjects are limited to, 72 if unconstrained",
+		"trailer":            "the trailer every message must have (i.e. Signed-off-by), checked by the trailer rule only if constrained",
+	},
+	"commit_issue_refs": {
+		"commit_id": "the id of the commit",
+		"issue":     "the issue as referenced, #123 for GitHub references (GH-123 included), i.e. JIRA-456 for Jira ones",
+		"tracker":   "github, jira, or custom for the matches of pattern",
+		"number":    "the number ending the reference, NULL if it has none",
+		"closes":    "whether a closing keyword precedes the reference (i.e. Fixes #123)",
+		"ref":       "the revision whose history is read, the checked out commit if unconstrained",
+		"pattern":   "a regular expression matching the references (or its first group), instead of the GitHub and Jira ones",
+	},
+	"ci_workflows": {
+		"path":     "the path of the workflow file, in .github/workflows",
+		"workflow": "the name of the workflow, its path if it has none",
+		"triggers": "the events triggering the workflow, comma separated",
+		"job":      "the id of the job",
+		"step":     "the position of the step in the job from 0, NULL for a job calling a reusable workflow",
+		"uses":     "the action (or reusable workflow) the step uses, NULL if it runs commands",
+		"run":      "the commands the step runs, NULL if it uses an action",
+		"action":   "the action used, without its version, i.e. actions/checkout",
+		"version":  "the version of the action used, i.e. v2",
+		"pinned":   "whether the action is pinned to a commit id or an image digest, or is in the repository, NULL if no action is used",
+		"ref":      "the revision whose workflows are read, the checked out commit if unconstrained",
+	},
+	"dependency_updates": {
+		"tool":       "dependabot or renovate, one row each",
+		"present":    "whether the tool is configured",
+		"path":       "the path of the configuration file, NULL if there is none",
+		"ecosystems": "the package ecosystems updated, comma separated, NULL for Renovate updating all of the ones it supports",
+		"schedule":   "when updates are proposed, comma separated: the intervals of Dependabot or the schedule of Renovate",
+		"extends":    "the presets the Renovate configuration extends, comma separated",
+		"automerge":  "whether Renovate merges updates itself, NULL if unset",
+		"ref":        "the revision whose configuration is read, the checked out commit if unconstrained",
+	},
+	"hooks": {
+		"name":       "the name of the hook, NULL for configuration files",
+		"source":     "git for the hooks git runs, otherwise the tool installing a committed hook or configuration",
+		"path":       "the path of the hook, relative to the repository for committed files",
+		"present":    "whether the hook exists",
+		"executable": "whether the hook is executable",
+		"size":       "the size of the hook, in bytes",
+		"shebang":    "the interpreter of the hook script",
+	},
+	"gitattributes": {
+		"file":      "the file the attribute is assigned in",
+		"line":      "the line of the file",
+		"pattern":   "the pattern of the files the attribute is assigned to",
+		"attribute": "the name of the attribute",
+		"value":     "set, unset, unspecified or the value of the attribute, like git check-attr prints it",
+	},
+	"repo": {
+		"path":            "the path of the repository",
+		"is_bare":         "whether the repository has no working tree",
+		"head":            "the branch checked out (i.e. refs/heads/main), NULL if HEAD is detached",
+		"default_branch":  "the default branch of the origin remote, NULL if there is none",
+		"remote_url":      "the URL of the origin remote, NULL if there is none",
+		"commit_count":    "the number of commits in the history of HEAD",
+		"first_commit_at": "when the oldest commit in the history of HEAD was committed",
+		"last_commit_at":  "when the most recent commit in the history of HEAD was committed",
+	},
+	"github_branch_protection": {
+		"repo":                       "the GitHub repository, as owner/name, the one of the origin remote if unconstrained",
+		"pattern":                    "the pattern of the names of the branches the rule protects, i.e. release/*",
+		"required_reviews":           "the number of approving reviews required to merge, 0 if none are",
+		"dismiss_stale_reviews":      "whether approvals are dismissed by new commits",
+		"require_code_owner_reviews": "whether code owners must approve changes to the files they own",
+		"required_checks":            "the status checks which must pass to merge, comma separated, NULL if none are required",
+		"strict_checks":              "whether branches must be up to date with the protected branch to merge",
+		"enforce_admins":             "whether the rule applies to administrators too",
+		"org":                        "the GitHub organization whose repositories are listed, if constrained",
+	},
+	"github_stargazers": {
+		"repo":       "the GitHub repository, as owner/name, the one of the origin remote if unconstrained",
+		"login":      "the login of the user who starred the repository",
+		"starred_at": "when the user starred the repository",
+		"org":        "the GitHub organization whose repositories are listed, if constrained",
+	},
+	"github_forks": {
+		"repo":      "the GitHub repository, as owner/name, the one of the origin remote if unconstrained",
+		"fork":      "the fork, as owner/name",
+		"owner":     "the login of the user or organization owning the fork",
+		"pushed_at": "when the fork was last pushed to, NULL if it never was",
+		"stars":     "the number of users who starred the fork",
+		"org":       "the GitHub organization whose repositories are listed, if constrained",
+	},
+	"commit_sizes": {
+		"commit_id":     "the commit id",
+		"files_changed": "the number of files changed relative to the first parent",
+		"lines_changed": "the number of lines added and deleted",
+		"size":          "XS under 10 lines changed, S under 30, M under 100, L under 500, XL under 1000, XXL past that",
+	},
+	"weekly_activity": {
+		"period":          "the first day of the week, when its commits were committed",
+		"authors":         "the number of distinct author emails of the commits of the week",
+		"churn":           "the number of lines added and deleted",
+		"rolling_commits": "the number of commits of the week and of the previous 3 weeks",
+		"rolling_churn":   "the churn of the week and of the previous 3 weeks",
+		"commits_change":  "the difference with the number of commits of the previous week, NULL for the first one",
+		"churn_change":    "the difference with the churn of the previous week, NULL for the first one",
+	},
+	"monthly_activity": {
+		"period":          "the first day of the month, when its commits were committed",
+		"authors":         "the number of distinct author emails of the commits of the month",
+		"churn":           "the number of lines added and deleted",
+		"rolling_commits": "the number of commits of the month and of the previous 2 months",
+		"rolling_churn":   "the churn of the month and of the previous 2 months",
+		"commits_change":  "the difference with the number of commits of the previous month, NULL for the first one",
+		"churn_change":    "the difference with the churn of the previous month, NULL for the first one",
+	},
+}
+
+// functions are the SQL functions askgit adds
+var functions = []*Function{
+	{"attr(path, name)", "the state of attribute name for the file at path: set, unset or its value, NULL if unspecified"},
+	{"is_vendored(path)", "whether the file at path is vendored code, like GitHub linguist classifies it"},
+	{"is_generated(path)", "whether the file at path is generated, like GitHub linguist classifies it"},
+	{"str_split(string, separator, index)", "the part of string at index once split on separator, empty if there is none"},
+	{"is_weekend(when)", "whether a date is on a Saturday or a Sunday, in the local time of its offset"},
+	{"is_after_hours(when[, start, end])", "whether a date is outside of working hours, from 9 to 18 o'clock unless given, in the local time of its offset"},
+	{"organization(email)", "the organization the domain of an email belongs to, from the organizations.yaml files, NULL if none"},
+}
+
+// TableDescription returns a short description of a table or view, it's empty if there is no such table
+func TableDescription(table string) string {
+	return tableDescriptions[table]
+}
+
+// Functions returns the SQL functions askgit adds
+func Functions() []*Function {
+	return append([]*Function{}, functions...)
+}
+
+// Describe returns the columns of a table or view, hidden ones included
+func (g *GitQLite) Describe(table string) ([]*Column, error) {
+	if _, ok := g.modules[table]; !ok && findView(table) == nil {
+		return nil, fmt.Errorf("unknown table %s", table)
+	}
+
+	rows, err := g.DB.Query(fmt.Sprintf("PRAGMA table_xinfo(%q)", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []*Column
+	for rows.Next() {
+		var (
+			cid, notNull, pk, hidden int
+			name, typ                string
+			defaultValue             interface{}
+		)
+		err := rows.Scan(&cid, &name, &typ, &notNull, &defaultValue, &pk, &hidden)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, &Column{Name: name, Type: typ, Hidden: hidden != 0, Description: columnDescriptions[table][name]})
+	}
+	return columns, rows.Err()
+}
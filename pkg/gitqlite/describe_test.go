@@ -0,0 +1,46 @@
+package gitqlite
+
+import "testing"
+
+func TestDescribe(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, table := range append(Tables(), Views()...) {
+		if TableDescription(table) == "" {
+			t.Fatalf("expected table %s to have a description", table)
+		}
+
+		columns, err := instance.Describe(table)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := make(map[string]*Column)
+		for _, column := range columns {
+			names[column.Name] = column
+		}
+
+		// descriptions of columns which don't exist (anymore) are mistakes
+		for name := range columnDescriptions[table] {
+			if names[name] == nil {
+				t.Fatalf("column %s of table %s is described but doesn't exist", name, table)
+			}
+		}
+
+		if table == "commits" {
+			ref := names["ref"]
+			if ref == nil || !ref.Hidden || ref.Type != "TEXT" {
+				t.Fatalf("expected commits to have a hidden TEXT ref column, got %+v", ref)
+			}
+			if names["id"] == nil || names["id"].Hidden || names["id"].Description == "" {
+				t.Fatalf("expected commits to have a described id column, got %+v", names["id"])
+			}
+		}
+	}
+
+	if _, err := instance.Describe("not_a_table"); err == nil {
+		t.Fatal("expected describing an unknown table to fail")
+	}
+}
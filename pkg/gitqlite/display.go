@@ -6,12 +6,182 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/olekukonko/tablewriter"
 )
 
-func DisplayDB(rows *sql.Rows, w io.Writer, format string) error {
+// DisplayOptions tweak the output of DisplayDBWithOptions
+type DisplayOptions struct {
+	// MaxRows is the number of rows past which the output is truncated (no limit if 0)
+	MaxRows int
+	// JSONShape is the shape of the json format, one of JSONShapes ("objects" if empty)
+	JSONShape string
+	// Start is when the query started, to report its duration in the "envelope" JSON shape (when displaying started if zero)
+	Start time.Time
+	// NoHeader leaves out the column names of the csv, tsv and table formats, and of the "arrays" JSON shape
+	NoHeader bool
+	// Color colors the table format with ANSI escape codes: bold headers, alternating rows, dimmed NULLs and negative numbers in red
+	Color bool
+	// DateFormat is how dates are output in every format: one of DateFormats, or a Go time layout (i.e. "2006-01-02"), "iso" if empty
+	DateFormat string
+	// RowNumbers adds a # column numbering the rows to the table format
+	RowNumbers bool
+	// Footer ends the table format with the number of rows and the duration of the query (since Start)
+	Footer bool
+}
+
+// DateFormats are the named formats of dates:
+// "iso" outputs ISO 8601 (RFC 3339) timestamps.
+// "unix" outputs the number of seconds since the Unix epoch.
+// "relative" outputs how long ago dates are, i.e. "3 days ago".
+var DateFormats = []string{"iso", "unix", "relative"}
+
+// JSONShapes are the shapes of the json format:
+// "objects" outputs an object per row mapping columns to values, one per line.
+// "arrays" outputs the array of column names, followed by an array of values per row, one per line.
+// "envelope" outputs a single object, with the columns, their types, the rows (as objects), the row count and the duration of the query.
+var JSONShapes = []string{"objects", "arrays", "envelope"}
+
+// ResultRows are the rows of a query result, as displayed: it's implemented by *sql.Rows and *QueryRows
+type ResultRows interface {
+	Columns() ([]string, error)
+	ColumnTypes() ([]*sql.ColumnType, error)
+	Next() bool
+	Scan(dest ...interface{}) error
+}
+
+// limitedRows stops iterating over rows once max rows were read (no limit if max is 0),
+// and records whether there were any rows left then
+type limitedRows struct {
+	ResultRows
+	max       int
+	count     int
+	truncated bool
+}
+
+func (r *limitedRows) Next() bool {
+	if r.max > 0 && r.count >= r.max {
+		r.truncated = r.ResultRows.Next()
+		return false
+	}
+	if !r.ResultRows.Next() {
+		return false
+	}
+	r.count++
+	return true
+}
+
+// datedRows formats the dates of rows (read as time.Time) with a DateFormat other than "iso"
+type datedRows struct {
+	ResultRows
+	format string
+	// now is when dates are relative to
+	now time.Time
+}
+
+// Scan scans the row into values, formats their dates, then assigns them to dest,
+// which are *interface{} or sql.Scanner (i.e. *sql.NullString) like the display formats scan into
+func (r *datedRows) Scan(dest ...interface{}) error {
+	values := make([]interface{}, len(dest))
+	pointers := make([]interface{}, len(dest))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	err := r.ResultRows.Scan(pointers...)
+	if err != nil {
+		return err
+	}
+
+	for i, value := range values {
+		if t, ok := value.(time.Time); ok {
+			value = formatDate(t, r.format, r.now)
+		}
+		switch d := dest[i].(type) {
+		case *interface{}:
+			*d = value
+		case sql.Scanner:
+			err = d.Scan(value)
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported destination %T for formatted dates", d)
+		}
+	}
+	return nil
+}
+
+// formatDate formats t with a DateFormat other than "iso", or a Go time layout
+func formatDate(t time.Time, format string, now time.Time) interface{} {
+	switch format {
+	case "unix":
+		return t.Unix()
+	case "relative":
+		return relativeDate(t, now)
+	default:
+		return t.Format(format)
+	}
+}
+
+// relativeDate returns how long before (or after) now t is, in the largest whole unit, i.e. "3 days ago" or "in 2 hours"
+func relativeDate(t, now time.Time) string {
+	d := now.Sub(t)
+	suffix := " ago"
+	if d < 0 {
+		d = -d
+		suffix = ""
+	}
+	units := []struct {
+		name     string
+		duration time.Duration
+	}{
+		{"year", 365 * 24 * time.Hour},
+		{"month", 30 * 24 * time.Hour},
+		{"week", 7 * 24 * time.Hour},
+		{"day", 24 * time.Hour},
+		{"hour", time.Hour},
+		{"minute", time.Minute},
+		{"second", time.Second},
+	}
+	for _, unit := range units {
+		n := int64(d / unit.duration)
+		if n == 0 {
+			continue
+		}
+		name := unit.name
+		if n > 1 {
+			name += "s"
+		}
+		if suffix == "" {
+			return fmt.Sprintf("in %d %s", n, name)
+		}
+		return fmt.Sprintf("%d %s%s", n, name, suffix)
+	}
+	return "now"
+}
+
+func DisplayDB(rows ResultRows, w io.Writer, format string) error {
+	_, err := DisplayDBWithOptions(rows, w, format, &DisplayOptions{})
+	return err
+}
+
+// DisplayDBWithOptions displays rows like DisplayDB, it returns whether the output was truncated to options.MaxRows rows
+func DisplayDBWithOptions(rows ResultRows, w io.Writer, format string, options *DisplayOptions) (bool, error) {
+	if options.DateFormat != "" && options.DateFormat != "iso" {
+		rows = &datedRows{ResultRows: rows, format: options.DateFormat, now: time.Now()}
+	}
+	limited := &limitedRows{ResultRows: rows, max: options.MaxRows}
+	err := display(limited, w, format, options)
+	if err != nil {
+		return false, err
+	}
+	return limited.truncated, nil
+}
 
+func display(rows *limitedRows, w io.Writer, format string, options *DisplayOptions) error {
 	switch format {
 	case "single":
 		err := single(rows, w)
@@ -19,23 +189,52 @@ func DisplayDB(rows *sql.Rows, w io.Writer, format string) error {
 			return err
 		}
 	case "csv":
-		err := csvDisplay(rows, ',', w)
+		err := csvDisplay(rows, ',', w, !options.NoHeader)
 		if err != nil {
 			return err
 		}
 	case "tsv":
-		err := csvDisplay(rows, '\t', w)
+		err := csvDisplay(rows, '\t', w, !options.NoHeader)
 		if err != nil {
 			return err
 		}
 	case "json":
-		err := jsonDisplay(rows, w)
+		var err error
+		switch options.JSONShape {
+		case "", "objects":
+			err = jsonDisplay(rows, w)
+		case "arrays":
+			err = jsonArraysDisplay(rows, w, !options.NoHeader)
+		case "envelope":
+			start := options.Start
+			if start.IsZero() {
+				start = time.Now()
+			}
+			err = jsonEnvelopeDisplay(rows, w, start)
+		default:
+			err = fmt.Errorf("unknown JSON shape %q, expected one of %s", options.JSONShape, strings.Join(JSONShapes, ", "))
+		}
+		if err != nil {
+			return err
+		}
+	case "sarif":
+		err := sarifDisplay(rows, w)
+		if err != nil {
+			return err
+		}
+	case "junit":
+		err := junitDisplay(rows, w)
+		if err != nil {
+			return err
+		}
+	case "arrow":
+		err := arrowDisplay(rows, w)
 		if err != nil {
 			return err
 		}
 	//TODO: switch between table and csv dependent on num columns(suggested num for table 5<=
 	default:
-		err := tableDisplay(rows, w)
+		err := tableDisplay(rows, w, options)
 		if err != nil {
 			return err
 		}
@@ -43,7 +242,7 @@ func DisplayDB(rows *sql.Rows, w io.Writer, format string) error {
 	}
 	return nil
 }
-func single(rows *sql.Rows, write io.Writer) error {
+func single(rows ResultRows, write io.Writer) error {
 
 	columns, err := rows.Columns()
 	if err != nil {
@@ -73,7 +272,7 @@ func single(rows *sql.Rows, write io.Writer) error {
 	return nil
 }
 
-func csvDisplay(rows *sql.Rows, commaChar rune, write io.Writer) error {
+func csvDisplay(rows ResultRows, commaChar rune, write io.Writer, header bool) error {
 
 	columns, err := rows.Columns()
 	if err != nil {
@@ -82,9 +281,11 @@ func csvDisplay(rows *sql.Rows, commaChar rune, write io.Writer) error {
 	w := csv.NewWriter(write)
 	w.Comma = commaChar
 
-	err = w.Write(columns)
-	if err != nil {
-		return err
+	if header {
+		err = w.Write(columns)
+		if err != nil {
+			return err
+		}
 	}
 	pointers := make([]interface{}, len(columns))
 	container := make([]sql.NullString, len(columns))
@@ -114,11 +315,15 @@ func csvDisplay(rows *sql.Rows, commaChar rune, write io.Writer) error {
 	return nil
 }
 
-func jsonDisplay(rows *sql.Rows, write io.Writer) error {
+func jsonDisplay(rows ResultRows, write io.Writer) error {
 	columns, err := rows.Columns()
 	if err != nil {
 		return err
 	}
+	types, err := declaredTypes(rows)
+	if err != nil {
+		return err
+	}
 
 	values := make([]interface{}, len(columns))
 	for i := range values {
@@ -136,7 +341,7 @@ func jsonDisplay(rows *sql.Rows, write io.Writer) error {
 		dest := make(map[string]interface{})
 
 		for i, column := range columns {
-			dest[column] = *(values[i].(*interface{}))
+			dest[column] = jsonValue(types[i], *(values[i].(*interface{})))
 		}
 
 		err := enc.Encode(dest)
@@ -148,7 +353,163 @@ func jsonDisplay(rows *sql.Rows, write io.Writer) error {
 
 	return nil
 }
-func tableDisplay(rows *sql.Rows, write io.Writer) error {
+
+// declaredTypes returns the types columns are declared with (i.e. TEXT, INT, BOOL or DATETIME for the columns of tables),
+// a column computed by an expression has no declared type
+func declaredTypes(rows ResultRows) ([]string, error) {
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	types := make([]string, len(columnTypes))
+	for i, columnType := range columnTypes {
+		types[i] = strings.ToUpper(columnType.DatabaseTypeName())
+	}
+	return types, nil
+}
+
+// jsonValue converts a value to the JSON type matching its declared type: SQLite stores booleans as integers,
+// and text is read as bytes. Integers and reals are already numbers, and dates (read as time.Time) are output as RFC 3339 strings.
+func jsonValue(declaredType string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case int64:
+		if declaredType == "BOOL" || declaredType == "BOOLEAN" {
+			return v != 0
+		}
+	case []byte:
+		if declaredType != "BLOB" {
+			return string(v)
+		}
+	}
+	return value
+}
+
+// jsonArraysDisplay outputs the array of column names (if header is set), then an array of values per row
+func jsonArraysDisplay(rows ResultRows, write io.Writer, header bool) error {
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	types, err := declaredTypes(rows)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(write)
+	if header {
+		err = enc.Encode(columns)
+		if err != nil {
+			return err
+		}
+	}
+
+	values := make([]interface{}, len(columns))
+	for i := range values {
+		values[i] = new(interface{})
+	}
+	for rows.Next() {
+		err = rows.Scan(values...)
+		if err != nil {
+			return err
+		}
+
+		dest := make([]interface{}, len(columns))
+		for i := range columns {
+			dest[i] = jsonValue(types[i], *(values[i].(*interface{})))
+		}
+
+		err := enc.Encode(dest)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// jsonEnvelope is the single object output by the "envelope" JSON shape, fields are output in this order
+type jsonEnvelope struct {
+	Columns     []string                 `json:"columns"`
+	ColumnTypes []string                 `json:"column_types"`
+	Rows        []map[string]interface{} `json:"rows"`
+	RowCount    int                      `json:"row_count"`
+	Truncated   bool                     `json:"truncated"`
+	DurationMS  float64                  `json:"duration_ms"`
+}
+
+// jsonEnvelopeDisplay outputs the rows and their metadata as a single object, which requires holding every row in memory
+func jsonEnvelopeDisplay(rows *limitedRows, write io.Writer, start time.Time) error {
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	types, err := declaredTypes(rows)
+	if err != nil {
+		return err
+	}
+
+	envelope := &jsonEnvelope{Columns: columns, ColumnTypes: types, Rows: make([]map[string]interface{}, 0)}
+
+	values := make([]interface{}, len(columns))
+	for i := range values {
+		values[i] = new(interface{})
+	}
+	for rows.Next() {
+		err = rows.Scan(values...)
+		if err != nil {
+			return err
+		}
+
+		dest := make(map[string]interface{})
+		for i, column := range columns {
+			dest[column] = jsonValue(types[i], *(values[i].(*interface{})))
+		}
+		envelope.Rows = append(envelope.Rows, dest)
+	}
+
+	envelope.RowCount = len(envelope.Rows)
+	envelope.Truncated = rows.truncated
+	envelope.DurationMS = float64(time.Since(start).Microseconds()) / 1000
+
+	return json.NewEncoder(write).Encode(envelope)
+}
+
+// the ANSI codes (SGR parameters) the table format is colored with
+const (
+	ansiBold    = 1
+	ansiFaint   = 2
+	ansiRed     = 31
+	ansiCyan    = 36
+	ansiBgBlack = 100
+)
+
+// cellColors returns the colors of a cell of the table format: NULLs are dimmed, negative numbers are red,
+// and every other row has a background so that wide rows can be followed
+func cellColors(value sql.NullString, row int) tablewriter.Colors {
+	colors := tablewriter.Colors{}
+	if row%2 == 1 {
+		colors = append(colors, ansiBgBlack)
+	}
+	switch {
+	case !value.Valid:
+		colors = append(colors, ansiFaint)
+	case strings.HasPrefix(value.String, "-"):
+		if _, err := strconv.ParseFloat(value.String, 64); err == nil {
+			colors = append(colors, ansiRed)
+		}
+	}
+	return colors
+}
+
+func tableDisplay(rows ResultRows, write io.Writer, options *DisplayOptions) error {
+	start := options.Start
+	if start.IsZero() {
+		start = time.Now()
+	}
+	color := options.Color
+
 	columns, err := rows.Columns()
 	if err != nil {
 		return err
@@ -159,29 +520,65 @@ func tableDisplay(rows *sql.Rows, write io.Writer) error {
 	for i := range pointers {
 		pointers[i] = &container[i]
 	}
+	if options.RowNumbers {
+		columns = append([]string{"#"}, columns...)
+	}
 	table := tablewriter.NewWriter(write)
-	table.SetHeader(columns)
+	if !options.NoHeader {
+		table.SetHeader(columns)
+		if color {
+			headerColors := make([]tablewriter.Colors, len(columns))
+			for i := range headerColors {
+				headerColors[i] = tablewriter.Colors{ansiBold, ansiCyan}
+			}
+			table.SetHeaderColor(headerColors...)
+		}
+	}
+	count := 0
 	for rows.Next() {
 		err := rows.Scan(pointers...)
 		if err != nil {
 			return err
 		}
 
-		r := make([]string, len(columns))
-		for i, c := range container {
+		r := make([]string, 0, len(columns))
+		if options.RowNumbers {
+			r = append(r, strconv.Itoa(count+1))
+		}
+		for _, c := range container {
 			if c.Valid {
-				r[i] = c.String
+				r = append(r, c.String)
 			} else {
-				r[i] = "NULL"
+				r = append(r, "NULL")
 			}
 		}
 
-		table.Append(r)
-		if err != nil {
-			return err
+		if color {
+			colors := make([]tablewriter.Colors, 0, len(columns))
+			if options.RowNumbers {
+				// row numbers aren't data, they're dimmed like NULLs
+				colors = append(colors, cellColors(sql.NullString{}, count))
+			}
+			for _, c := range container {
+				colors = append(colors, cellColors(c, count))
+			}
+			table.Rich(r, colors)
+		} else {
+			table.Append(r)
 		}
+		count++
 	}
 
 	table.Render()
+	if options.Footer {
+		unit := "rows"
+		if count == 1 {
+			unit = "row"
+		}
+		_, err = fmt.Fprintf(write, "%d %s in %s\n", count, unit, time.Since(start).Round(time.Millisecond))
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
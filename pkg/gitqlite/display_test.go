@@ -2,9 +2,14 @@ package gitqlite
 
 import (
 	"bytes"
+	"database/sql"
+	"encoding/binary"
 	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestDisplayCSV(t *testing.T) {
@@ -39,3 +44,434 @@ func TestDisplayCSV(t *testing.T) {
 
 	// TODO perhaps test the actual content of the lines?
 }
+
+func TestDisplayNoHeader(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := instance.DB.Query("select id from commits limit 10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var b bytes.Buffer
+	_, err = DisplayDBWithOptions(rows, &b, "csv", &DisplayOptions{NoHeader: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 10 {
+		t.Fatalf("expected 10 lines of output, got: %d", len(lines))
+	}
+	if lines[0] == "id" {
+		t.Fatal("expected no header")
+	}
+}
+
+func TestDisplayMaxRows(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, maxRows := range []int{3, 10, 20} {
+		rows, err := instance.DB.Query("select * from commits limit 10")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var b bytes.Buffer
+		truncated, err := DisplayDBWithOptions(rows, &b, "csv", &DisplayOptions{MaxRows: maxRows})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows.Close()
+
+		records, err := csv.NewReader(strings.NewReader(b.String())).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := maxRows
+		if expected > 10 {
+			expected = 10
+		}
+		if len(records) != expected+1 {
+			t.Fatalf("expected %d lines of output with %d max rows, got: %d", expected+1, maxRows, len(records))
+		}
+		if truncated != (maxRows < 10) {
+			t.Fatalf("expected output to be truncated with %d max rows: %v, got %v", maxRows, maxRows < 10, truncated)
+		}
+	}
+}
+
+func TestDisplayJSONShapes(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	display := func(shape string) string {
+		rows, err := instance.DB.Query("select id, parent_count from commits limit 10")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+
+		var b bytes.Buffer
+		_, err = DisplayDBWithOptions(rows, &b, "json", &DisplayOptions{JSONShape: shape})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b.String()
+	}
+
+	lines := strings.Split(strings.TrimSpace(display("objects")), "\n")
+	if len(lines) != 10 {
+		t.Fatalf("expected 10 objects, got: %d", len(lines))
+	}
+
+	lines = strings.Split(strings.TrimSpace(display("arrays")), "\n")
+	if len(lines) != 11 {
+		t.Fatalf("expected the columns and 10 arrays, got: %d lines", len(lines))
+	}
+	var columns []string
+	err = json.Unmarshal([]byte(lines[0]), &columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(columns, ",") != "id,parent_count" {
+		t.Fatalf("expected the columns first, got: %s", lines[0])
+	}
+
+	var envelope struct {
+		Columns     []string                 `json:"columns"`
+		ColumnTypes []string                 `json:"column_types"`
+		Rows        []map[string]interface{} `json:"rows"`
+		RowCount    int                      `json:"row_count"`
+	}
+	err = json.Unmarshal([]byte(display("envelope")), &envelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if envelope.RowCount != 10 || len(envelope.Rows) != 10 || len(envelope.ColumnTypes) != 2 {
+		t.Fatalf("expected 10 rows and 2 column types, got: %+v", envelope)
+	}
+
+	rows, err := instance.DB.Query("select id from commits limit 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	_, err = DisplayDBWithOptions(rows, &bytes.Buffer{}, "json", &DisplayOptions{JSONShape: "xml"})
+	if err == nil {
+		t.Fatal("expected an unknown JSON shape to fail")
+	}
+}
+
+func TestDisplayJSONTypes(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := instance.DB.Query("select summary, parent_count, is_merge, author_when from commits limit 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var b bytes.Buffer
+	err = DisplayDB(rows, &b, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var commit map[string]interface{}
+	err = json.Unmarshal(b.Bytes(), &commit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := commit["summary"].(string); !ok {
+		t.Fatalf("expected summary to be a string, got: %T", commit["summary"])
+	}
+	if _, ok := commit["parent_count"].(float64); !ok {
+		t.Fatalf("expected parent_count to be a number, got: %T", commit["parent_count"])
+	}
+	if _, ok := commit["is_merge"].(bool); !ok {
+		t.Fatalf("expected is_merge to be a boolean, got: %T", commit["is_merge"])
+	}
+	when, ok := commit["author_when"].(string)
+	if !ok {
+		t.Fatalf("expected author_when to be a string, got: %T", commit["author_when"])
+	}
+	_, err = time.Parse(time.RFC3339, when)
+	if err != nil {
+		t.Fatalf("expected author_when to be an RFC 3339 timestamp: %v", err)
+	}
+}
+
+func TestDisplaySARIF(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := instance.DB.Query("select 'large_file' as rule, 'error' as level, name as path, 3 as line, 'too large' as message from files limit 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var b bytes.Buffer
+	err = DisplayDB(rows, &b, "sarif")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var log sarifLog
+	err = json.Unmarshal(b.Bytes(), &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("expected a single SARIF 2.1.0 run, got: %s", b.String())
+	}
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != 1 || run.Tool.Driver.Rules[0].ID != "large_file" {
+		t.Fatalf("expected the large_file rule, got: %v", run.Tool.Driver.Rules)
+	}
+	if len(run.Results) != 2 {
+		t.Fatalf("expected 2 results, got: %d", len(run.Results))
+	}
+	result := run.Results[0]
+	if result.RuleID != "large_file" || result.Level != "error" || result.Message.Text != "too large" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if len(result.Locations) != 1 || result.Locations[0].PhysicalLocation.ArtifactLocation.URI == "" {
+		t.Fatalf("expected the result to be located at a path, got: %+v", result.Locations)
+	}
+	if region := result.Locations[0].PhysicalLocation.Region; region == nil || region.StartLine != 3 {
+		t.Fatalf("expected the result to be located at line 3, got: %+v", region)
+	}
+}
+
+func TestDisplayJUnit(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := instance.DB.Query(`select 'subject_length' as rule, 1 as passed, 'ok' as detail
+		union all select 'trailer', 0, 'no Signed-off-by'
+		union all select 'trailer', 0, 'no Signed-off-by'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var b bytes.Buffer
+	err = DisplayDB(rows, &b, "junit")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var suites junitTestSuites
+	err = xml.Unmarshal(b.Bytes(), &suites)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suites.Suites) != 1 {
+		t.Fatalf("expected a single test suite, got: %s", b.String())
+	}
+	suite := suites.Suites[0]
+	if suite.Tests != 2 || suite.Failures != 1 || len(suite.TestCases) != 2 {
+		t.Fatalf("expected 2 tests and 1 failure, got: %s", b.String())
+	}
+	if suite.TestCases[0].Name != "subject_length" || suite.TestCases[0].Failure != nil {
+		t.Fatalf("expected subject_length to pass, got: %+v", suite.TestCases[0])
+	}
+	failure := suite.TestCases[1].Failure
+	if suite.TestCases[1].Name != "trailer" || failure == nil || strings.Count(failure.Text, "no Signed-off-by") != 2 {
+		t.Fatalf("expected trailer to fail twice, got: %+v", suite.TestCases[1])
+	}
+}
+
+func TestDisplayArrow(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := instance.DB.Query("select id, author_name, author_when, parent_count from commits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var b bytes.Buffer
+	err = DisplayDB(rows, &b, "arrow")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the stream is a schema message and a record batch, each a continuation marker, the length of its metadata, its metadata and its body,
+	// then the end of stream marker
+	stream := b.Bytes()
+	messages := 0
+	for {
+		if len(stream) < 8 || binary.LittleEndian.Uint32(stream) != 0xffffffff {
+			t.Fatalf("expected a continuation marker after %d messages, got: %v", messages, stream)
+		}
+		length := int(binary.LittleEndian.Uint32(stream[4:]))
+		if length == 0 {
+			break
+		}
+		if length%8 != 0 || len(stream) < 8+length {
+			t.Fatalf("expected metadata of a length multiple of 8, got %d", length)
+		}
+		metadata := stream[8 : 8+length]
+		// Message { version, header_type, header, bodyLength }, whose bodyLength is the last field of the table
+		table := int(binary.LittleEndian.Uint32(metadata))
+		vtable := table - int(int32(binary.LittleEndian.Uint32(metadata[table:])))
+		bodyLength := 0
+		if offset := int(binary.LittleEndian.Uint16(metadata[vtable+10:])); offset != 0 {
+			bodyLength = int(binary.LittleEndian.Uint64(metadata[table+offset:]))
+		}
+		stream = stream[8+length+bodyLength:]
+		messages++
+	}
+	if messages != 2 || len(stream) != 8 {
+		t.Fatalf("expected a schema and a record batch then the end of the stream, got %d messages and %d bytes left", messages, len(stream))
+	}
+}
+
+func TestDisplayColor(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := instance.DB.Query("select -1 as delta, null as missing union all select 2, 'x'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var b bytes.Buffer
+	_, err = DisplayDBWithOptions(rows, &b, "table", &DisplayOptions{Color: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "\x1b[") {
+		t.Fatalf("expected colored output, got: %q", b.String())
+	}
+
+	colors := cellColors(sql.NullString{String: "-1", Valid: true}, 0)
+	if len(colors) != 1 || colors[0] != ansiRed {
+		t.Fatalf("expected negative numbers to be red, got: %v", colors)
+	}
+	colors = cellColors(sql.NullString{}, 1)
+	if len(colors) != 2 || colors[0] != ansiBgBlack || colors[1] != ansiFaint {
+		t.Fatalf("expected NULLs of odd rows to be dimmed and shaded, got: %v", colors)
+	}
+	colors = cellColors(sql.NullString{String: "-x", Valid: true}, 0)
+	if len(colors) != 0 {
+		t.Fatalf("expected text not to be colored, got: %v", colors)
+	}
+}
+
+func TestDisplayDateFormat(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for format, check := range map[string]func(interface{}) bool{
+		"unix": func(v interface{}) bool {
+			_, ok := v.(float64)
+			return ok
+		},
+		"relative": func(v interface{}) bool {
+			s, ok := v.(string)
+			return ok && strings.HasSuffix(s, " ago")
+		},
+		"2006-01-02": func(v interface{}) bool {
+			s, ok := v.(string)
+			if !ok {
+				return false
+			}
+			_, err := time.Parse("2006-01-02", s)
+			return err == nil
+		},
+	} {
+		rows, err := instance.DB.Query("select author_when from commits limit 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var b bytes.Buffer
+		_, err = DisplayDBWithOptions(rows, &b, "json", &DisplayOptions{DateFormat: format})
+		rows.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var commit map[string]interface{}
+		err = json.Unmarshal(b.Bytes(), &commit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !check(commit["author_when"]) {
+			t.Fatalf("unexpected author_when in the %s format: %v", format, commit["author_when"])
+		}
+	}
+}
+
+func TestRelativeDate(t *testing.T) {
+	now := time.Date(2020, 6, 15, 12, 0, 0, 0, time.UTC)
+	tests := map[time.Duration]string{
+		0:                     "now",
+		-90 * time.Second:     "1 minute ago",
+		-3 * 24 * time.Hour:   "3 days ago",
+		-400 * 24 * time.Hour: "1 year ago",
+		2 * time.Hour:         "in 2 hours",
+	}
+	for d, expected := range tests {
+		if relative := relativeDate(now.Add(d), now); relative != expected {
+			t.Fatalf("expected %q for %s, got %q", expected, d, relative)
+		}
+	}
+}
+
+func TestDisplayRowNumbersAndFooter(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := instance.DB.Query("select id from commits limit 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var b bytes.Buffer
+	_, err = DisplayDBWithOptions(rows, &b, "table", &DisplayOptions{RowNumbers: true, Footer: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if !strings.Contains(lines[1], "#") {
+		t.Fatalf("expected a # column, got: %s", b.String())
+	}
+	if !strings.HasPrefix(lines[len(lines)-1], "3 rows in ") {
+		t.Fatalf("expected a footer with 3 rows, got: %s", lines[len(lines)-1])
+	}
+}
@@ -0,0 +1,171 @@
+package gitqlite
+
+import (
+	"encoding/binary"
+)
+
+// flatTable is a FlatBuffers table (https://google.github.io/flatbuffers/flatbuffers_internals.html),
+// as written for the metadata of the arrow format. Its fields are in the order of their ids, an empty flatField being absent.
+// Unlike the builders of the FlatBuffers libraries, tables are written front to back: a table, then the objects it refers to.
+type flatTable struct {
+	fields []flatField
+}
+
+// flatField is a field of a flatTable: either a scalar (its little endian bytes), or an offset to another object
+type flatField struct {
+	scalar []byte
+	child  flatObject
+}
+
+// flatObject is written to a flatBuilder, returning its position
+type flatObject interface {
+	write(b *flatBuilder) int
+}
+
+// flatString is a string
+type flatString string
+
+// flatTables is a vector of tables
+type flatTables struct {
+	tables []*flatTable
+}
+
+// flatStructs is a vector of count structs of 8 byte fields, data holding their bytes
+type flatStructs struct {
+	data  []byte
+	count int
+}
+
+type flatBuilder struct {
+	buf []byte
+}
+
+// flatScalar returns the field of a scalar value, an integer or a bool
+func flatScalar(value interface{}) flatField {
+	var b []byte
+	switch v := value.(type) {
+	case bool:
+		b = []byte{0}
+		if v {
+			b[0] = 1
+		}
+	case byte:
+		b = []byte{v}
+	case int16:
+		b = make([]byte, 2)
+		binary.LittleEndian.PutUint16(b, uint16(v))
+	case int32:
+		b = make([]byte, 4)
+		binary.LittleEndian.PutUint32(b, uint32(v))
+	case int64:
+		b = make([]byte, 8)
+		binary.LittleEndian.PutUint64(b, uint64(v))
+	}
+	return flatField{scalar: b}
+}
+
+// bytes returns the buffer of which t is the root table
+func (t *flatTable) bytes() []byte {
+	b := &flatBuilder{buf: make([]byte, 4)}
+	root := t.write(b)
+	binary.LittleEndian.PutUint32(b.buf, uint32(root))
+	return b.buf
+}
+
+func (t *flatTable) write(b *flatBuilder) int {
+	// fields are aligned on their size, relative to the start of the table which is aligned on the largest of them
+	offsets := make([]int, len(t.fields))
+	size, align := 4, 4
+	for i, field := range t.fields {
+		fieldSize := len(field.scalar)
+		if field.child != nil {
+			fieldSize = 4
+		}
+		if fieldSize == 0 {
+			continue
+		}
+		for size%fieldSize != 0 {
+			size++
+		}
+		offsets[i] = size
+		size += fieldSize
+		if fieldSize > align {
+			align = fieldSize
+		}
+	}
+
+	// the vtable tells where the fields are in the table, 0 for absent ones
+	b.align(2)
+	vtable := len(b.buf)
+	b.uint16(4 + 2*len(t.fields))
+	b.uint16(size)
+	for _, offset := range offsets {
+		b.uint16(offset)
+	}
+
+	b.align(align)
+	table := len(b.buf)
+	b.buf = append(b.buf, make([]byte, size)...)
+	binary.LittleEndian.PutUint32(b.buf[table:], uint32(table-vtable))
+	for i, field := range t.fields {
+		copy(b.buf[table+offsets[i]:], field.scalar)
+	}
+	for i, field := range t.fields {
+		if field.child != nil {
+			b.patch(table+offsets[i], field.child.write(b))
+		}
+	}
+	return table
+}
+
+func (s flatString) write(b *flatBuilder) int {
+	b.align(4)
+	pos := len(b.buf)
+	b.uint32(len(s))
+	b.buf = append(b.buf, s...)
+	b.buf = append(b.buf, 0)
+	return pos
+}
+
+func (v *flatTables) write(b *flatBuilder) int {
+	b.align(4)
+	pos := len(b.buf)
+	b.uint32(len(v.tables))
+	b.buf = append(b.buf, make([]byte, 4*len(v.tables))...)
+	for i, table := range v.tables {
+		b.patch(pos+4+4*i, table.write(b))
+	}
+	return pos
+}
+
+func (v *flatStructs) write(b *flatBuilder) int {
+	// the structs follow the length of the vector, aligned on 8 bytes
+	for len(b.buf)%8 != 4 {
+		b.buf = append(b.buf, 0)
+	}
+	pos := len(b.buf)
+	b.uint32(v.count)
+	b.buf = append(b.buf, v.data...)
+	return pos
+}
+
+func (b *flatBuilder) align(n int) {
+	for len(b.buf)%n != 0 {
+		b.buf = append(b.buf, 0)
+	}
+}
+
+func (b *flatBuilder) uint16(v int) {
+	b.buf = append(b.buf, 0, 0)
+	binary.LittleEndian.PutUint16(b.buf[len(b.buf)-2:], uint16(v))
+}
+
+func (b *flatBuilder) uint32(v int) {
+	b.buf = append(b.buf, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(b.buf[len(b.buf)-4:], uint32(v))
+}
+
+// patch sets the offset at pos to refer to the object at target, which follows it
+func (b *flatBuilder) patch(pos, target int) {
+	binary.LittleEndian.PutUint32(b.buf[pos:], uint32(target-pos))
+}
@@ -0,0 +1,417 @@
+package gitqlite
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	git "github.com/libgit2/git2go/v30"
+	"github.com/mattn/go-sqlite3"
+)
+
+type gitAttributeModule struct {
+	repos *repoPool
+	attrs *attributes
+}
+
+type gitAttributeTable struct {
+	repoPath string
+	repos    *repoPool
+	repo     *git.Repository
+}
+
+func (m *gitAttributeModule) Create(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	err := c.DeclareVTab(fmt.Sprintf(`
+		CREATE TABLE %q (
+			file TEXT,
+			line INT,
+			pattern TEXT,
+			attribute TEXT,
+			value TEXT
+		)`, args[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	// the repoPath will be enclosed in double quotes "..." since createTables uses %q when setting up the table
+	// we need to pop those off when referring to the actual directory in the fs
+	repoPath := args[3][1 : len(args[3])-1]
+
+	// the attr function of this connection looks attributes up in this same repository
+	m.attrs.setRepoPath(repoPath)
+
+	return &gitAttributeTable{repoPath: repoPath, repos: m.repos}, nil
+}
+
+func (m *gitAttributeModule) Connect(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	return m.Create(c, args)
+}
+
+func (m *gitAttributeModule) DestroyModule() {}
+
+func (v *gitAttributeTable) Open() (sqlite3.VTabCursor, error) {
+	repo, err := v.repos.open(v.repoPath)
+	if err != nil {
+		return nil, err
+	}
+	v.repo = repo
+
+	return &attributeCursor{repo: v.repo}, nil
+}
+
+func (v *gitAttributeTable) BestIndex(cst []sqlite3.InfoConstraint, ob []sqlite3.InfoOrderBy) (*sqlite3.IndexResult, error) {
+	// every .gitattributes file needs to be read whatever the constraints
+	dummy := make([]bool, len(cst))
+	return &sqlite3.IndexResult{Used: dummy}, nil
+}
+
+func (v *gitAttributeTable) Disconnect() error {
+	v.repo = nil
+	return nil
+}
+func (v *gitAttributeTable) Destroy() error { return nil }
+
+// attributeRule is a line of a gitattributes file, assigning attributes to the files matching its pattern
+type attributeRule struct {
+	// file is the path of the gitattributes file the rule is read from
+	file string
+	line int
+	// dir is the directory patterns are relative to, "" for the top level directory
+	dir     string
+	pattern string
+	// re matches the paths (or base names if basename is set) of the files the rule applies to, it's nil if the rule never applies
+	re          *regexp.Regexp
+	basename    bool
+	assignments []attributeAssignment
+}
+
+// attributeAssignment is the state an attribute is set to: "set", "unset", "unspecified" or its value, like `git check-attr` prints them
+type attributeAssignment struct {
+	name  string
+	value string
+}
+
+// attributeMacros are the macros predefined by git, i.e. "binary" stands for "binary -diff -merge -text"
+var attributeMacros = map[string][]attributeAssignment{
+	"binary": {{"diff", "unset"}, {"merge", "unset"}, {"text", "unset"}},
+}
+
+type attributeRow struct {
+	rule       *attributeRule
+	assignment attributeAssignment
+}
+
+type attributeCursor struct {
+	repo  *git.Repository
+	index int
+	rows  []*attributeRow
+}
+
+func (vc *attributeCursor) Column(c *sqlite3.SQLiteContext, col int) error {
+	row := vc.rows[vc.index]
+
+	switch col {
+	case 0:
+		c.ResultText(row.rule.file)
+	case 1:
+		c.ResultInt(row.rule.line)
+	case 2:
+		c.ResultText(row.rule.pattern)
+	case 3:
+		c.ResultText(row.assignment.name)
+	case 4:
+		c.ResultText(row.assignment.value)
+	}
+	return nil
+}
+
+func (vc *attributeCursor) Filter(idxNum int, idxStr string, vals []interface{}) error {
+	rules, err := attributeRules(vc.repo)
+	if err != nil {
+		return err
+	}
+
+	rows := make([]*attributeRow, 0)
+	for _, rule := range rules {
+		for _, assignment := range rule.assignments {
+			rows = append(rows, &attributeRow{rule: rule, assignment: assignment})
+		}
+	}
+
+	vc.rows = rows
+	vc.index = 0
+
+	return nil
+}
+
+func (vc *attributeCursor) Next() error {
+	vc.index++
+	return nil
+}
+
+func (vc *attributeCursor) EOF() bool {
+	return vc.index >= len(vc.rows)
+}
+
+func (vc *attributeCursor) Rowid() (int64, error) {
+	return int64(vc.index), nil
+}
+
+func (vc *attributeCursor) Close() error {
+	return nil
+}
+
+// attributeRules reads the .gitattributes files committed to the repository (as of HEAD) and its info/attributes file.
+// Rules are ordered by increasing precedence: files of deeper directories come after those of their parents, and info/attributes comes last.
+func attributeRules(repo *git.Repository) ([]*attributeRule, error) {
+	rules := make([]*attributeRule, 0)
+
+	// if HEAD is unborn (no commit yet) only info/attributes applies
+	unborn, err := repo.IsHeadUnborn()
+	if err != nil {
+		return nil, err
+	}
+	if !unborn {
+		head, err := repo.Head()
+		if err != nil {
+			return nil, err
+		}
+		defer head.Free()
+
+		commit, err := repo.LookupCommit(head.Target())
+		if err != nil {
+			return nil, err
+		}
+		defer commit.Free()
+
+		tree, err := commit.Tree()
+		if err != nil {
+			return nil, err
+		}
+		defer tree.Free()
+
+		files := make(map[string]*git.Oid)
+		dirs := make([]string, 0)
+		err = tree.Walk(func(dir string, entry *git.TreeEntry) int {
+			if entry.Type == git.ObjectBlob && entry.Name == ".gitattributes" {
+				files[dir] = entry.Id
+				dirs = append(dirs, dir)
+			}
+			return 0
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		sort.SliceStable(dirs, func(i, j int) bool {
+			return strings.Count(dirs[i], "/") < strings.Count(dirs[j], "/")
+		})
+		for _, dir := range dirs {
+			blob, err := repo.LookupBlob(files[dir])
+			if err != nil {
+				return nil, err
+			}
+			contents := string(blob.Contents())
+			blob.Free()
+
+			rules = append(rules, parseAttributes(dir+".gitattributes", strings.TrimSuffix(dir, "/"), contents)...)
+		}
+	}
+
+	info := filepath.Join(CommonDir(repo), "info", "attributes")
+	contents, err := ioutil.ReadFile(info)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	rules = append(rules, parseAttributes(info, "", string(contents))...)
+
+	return rules, nil
+}
+
+// parseAttributes parses the contents of a gitattributes file, see https://git-scm.com/docs/gitattributes
+func parseAttributes(file, dir, contents string) []*attributeRule {
+	rules := make([]*attributeRule, 0)
+	for i, line := range strings.Split(contents, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		rule := &attributeRule{file: file, line: i + 1, dir: dir, pattern: fields[0]}
+		for _, field := range fields[1:] {
+			switch {
+			case strings.HasPrefix(field, "-"):
+				rule.assignments = append(rule.assignments, attributeAssignment{field[1:], "unset"})
+			case strings.HasPrefix(field, "!"):
+				rule.assignments = append(rule.assignments, attributeAssignment{field[1:], "unspecified"})
+			case strings.Contains(field, "="):
+				parts := strings.SplitN(field, "=", 2)
+				rule.assignments = append(rule.assignments, attributeAssignment{parts[0], parts[1]})
+			default:
+				rule.assignments = append(rule.assignments, attributeAssignment{field, "set"})
+				rule.assignments = append(rule.assignments, attributeMacros[field]...)
+			}
+		}
+
+		// macro definitions ([attr]name) and negative patterns (which git rejects) don't apply to any file,
+		// neither do directory patterns (with a trailing slash) since attributes are only looked up for files
+		pattern := rule.pattern
+		if !strings.HasPrefix(pattern, "[attr]") && !strings.HasPrefix(pattern, "!") && !strings.HasSuffix(pattern, "/") {
+			rule.basename = !strings.Contains(pattern, "/")
+			rule.re = globRegexp(strings.TrimPrefix(pattern, "/"))
+		}
+
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// globRegexp compiles a gitignore style glob: * and ? don't match slashes, while ** matches across directories
+func globRegexp(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/") && (i == 0 || pattern[i-1] == '/'):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case pattern[i:] == "**" && i > 0 && pattern[i-1] == '/':
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		// i.e. an invalid character class, such a pattern matches nothing
+		return nil
+	}
+	return re
+}
+
+// matches returns whether the rule applies to file, a path relative to the top level directory
+func (r *attributeRule) matches(file string) bool {
+	if r.re == nil {
+		return false
+	}
+	if r.dir != "" {
+		if !strings.HasPrefix(file, r.dir+"/") {
+			return false
+		}
+		file = file[len(r.dir)+1:]
+	}
+	if r.basename {
+		file = path.Base(file)
+	}
+	return r.re.MatchString(file)
+}
+
+// attribute returns the state of attribute name for file, as the rule with the highest precedence matching file sets it.
+// It's "unspecified" if no rule sets the attribute.
+func attribute(rules []*attributeRule, file, name string) string {
+	value := "unspecified"
+	for _, rule := range rules {
+		if !rule.matches(file) {
+			continue
+		}
+		for _, assignment := range rule.assignments {
+			if assignment.name == name {
+				value = assignment.value
+			}
+		}
+	}
+	return value
+}
+
+// attributes looks up the attributes of files in the repository of a connection, for the attr SQL function.
+// The rules are read again whenever HEAD moves.
+type attributes struct {
+	repos    *repoPool
+	mu       sync.Mutex
+	repoPath string
+	head     git.Oid
+	rules    []*attributeRule
+}
+
+func (a *attributes) setRepoPath(repoPath string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.repoPath != repoPath {
+		a.repoPath = repoPath
+		a.rules = nil
+	}
+}
+
+// load returns the attribute rules of the repository, as of its current HEAD
+func (a *attributes) load() ([]*attributeRule, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.repoPath == "" {
+		return nil, fmt.Errorf("no repository to look attributes up in")
+	}
+	repo, err := a.repos.open(a.repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var head git.Oid
+	if ref, err := repo.Head(); err == nil {
+		head = *ref.Target()
+		ref.Free()
+	}
+	if a.rules != nil && head == a.head {
+		return a.rules, nil
+	}
+
+	rules, err := attributeRules(repo)
+	if err != nil {
+		return nil, err
+	}
+	a.head = head
+	a.rules = rules
+	return rules, nil
+}
+
+// attr implements attr(path, name), which returns the state of attribute name for the file at path, like `git check-attr`:
+// "set", "unset" or the value of the attribute. It's NULL if the attribute is unspecified.
+func (a *attributes) attr(file, name string) (interface{}, error) {
+	rules, err := a.load()
+	if err != nil {
+		return nil, err
+	}
+
+	value := attribute(rules, strings.TrimPrefix(file, "/"), name)
+	if value == "unspecified" {
+		return nil, nil
+	}
+	return value, nil
+}
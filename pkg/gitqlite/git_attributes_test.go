@@ -0,0 +1,84 @@
+package gitqlite
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAttributeMatching(t *testing.T) {
+	rules := parseAttributes(".gitattributes", "", `
+# comment
+*.go text diff=golang
+vendor/** linguist-vendored
+/docs/*.md -diff
+**/testdata/** linguist-generated
+*.png binary
+`)
+	rules = append(rules, parseAttributes("pkg/.gitattributes", "pkg", `
+*.go !diff
+gen_*.go linguist-generated=true
+`)...)
+
+	tests := []struct {
+		file, name, want string
+	}{
+		{"main.go", "text", "set"},
+		{"main.go", "diff", "golang"},
+		{"pkg/main.go", "diff", "unspecified"},
+		{"pkg/gen_types.go", "linguist-generated", "true"},
+		{"gen_types.go", "linguist-generated", "unspecified"},
+		{"vendor/github.com/foo/foo.go", "linguist-vendored", "set"},
+		{"pkg/vendor/foo.go", "linguist-vendored", "unspecified"},
+		{"docs/README.md", "diff", "unset"},
+		{"docs/api/README.md", "diff", "unspecified"},
+		{"a/b/testdata/c/d.txt", "linguist-generated", "set"},
+		{"logo.png", "binary", "set"},
+		{"assets/logo.png", "text", "unset"},
+		{"main.go", "missing", "unspecified"},
+	}
+	for _, test := range tests {
+		if got := attribute(rules, test.file, test.name); got != test.want {
+			t.Errorf("expected %s of %s to be %q, got %q", test.name, test.file, test.want, got)
+		}
+	}
+}
+
+func TestAttr(t *testing.T) {
+	info := filepath.Join(fixtureRepoDir, ".git", "info", "attributes")
+	err := os.MkdirAll(filepath.Dir(info), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(info, []byte("*.md linguist-documentation\n*.go -linguist-documentation diff=golang\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(info)
+
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+	err = instance.DB.QueryRow("SELECT count(*) FROM gitattributes WHERE file = ?", info).Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Fatalf("expected 3 attributes set in %s, got %d", info, count)
+	}
+
+	var doc, code, diff string
+	var missing sql.NullString
+	err = instance.DB.QueryRow("SELECT attr('README.md', 'linguist-documentation'), attr('cmd/main.go', 'linguist-documentation'), attr('main.go', 'diff'), attr('main.go', 'missing')").Scan(&doc, &code, &diff, &missing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc != "set" || code != "unset" || diff != "golang" || missing.Valid {
+		t.Fatalf("unexpected attributes: %q, %q, %q, %v", doc, code, diff, missing)
+	}
+}
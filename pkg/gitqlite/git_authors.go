@@ -0,0 +1,255 @@
+package gitqlite
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	git "github.com/libgit2/git2go/v30"
+	"github.com/mattn/go-sqlite3"
+)
+
+type gitAuthorModule struct {
+	repos *repoPool
+}
+
+type gitAuthorTable struct {
+	repoPath string
+	repos    *repoPool
+	repo     *git.Repository
+}
+
+func (m *gitAuthorModule) Create(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	err := c.DeclareVTab(fmt.Sprintf(`
+		CREATE TABLE %q (
+			name TEXT,
+			email TEXT,
+			canonical_id TEXT,
+			canonical_name TEXT,
+			canonical_email TEXT,
+			commit_count INT
+		)`, args[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	// the repoPath will be enclosed in double quotes "..." since createTables uses %q when setting up the table
+	// we need to pop those off when referring to the actual directory in the fs
+	repoPath := args[3][1 : len(args[3])-1]
+	return &gitAuthorTable{repoPath: repoPath, repos: m.repos}, nil
+}
+
+func (m *gitAuthorModule) Connect(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	return m.Create(c, args)
+}
+
+func (m *gitAuthorModule) DestroyModule() {}
+
+func (v *gitAuthorTable) Open() (sqlite3.VTabCursor, error) {
+	repo, err := v.repos.open(v.repoPath)
+	if err != nil {
+		return nil, err
+	}
+	v.repo = repo
+
+	return &authorCursor{repo: v.repo, limit: v.repos.limit}, nil
+}
+
+func (v *gitAuthorTable) BestIndex(cst []sqlite3.InfoConstraint, ob []sqlite3.InfoOrderBy) (*sqlite3.IndexResult, error) {
+	// TODO this should actually be implemented!
+	dummy := make([]bool, len(cst))
+	return &sqlite3.IndexResult{Used: dummy}, nil
+}
+
+func (v *gitAuthorTable) Disconnect() error {
+	v.repo = nil
+	return nil
+}
+func (v *gitAuthorTable) Destroy() error { return nil }
+
+// authorIdentity is a single distinct (name, email) pair seen as a commit author
+type authorIdentity struct {
+	name    string
+	email   string
+	commits int
+	// parent is used to cluster identities together (union-find), the root of a cluster is its canonical identity
+	parent *authorIdentity
+}
+
+func (a *authorIdentity) root() *authorIdentity {
+	for a.parent != a {
+		a.parent = a.parent.parent
+		a = a.parent
+	}
+	return a
+}
+
+func (a *authorIdentity) canonicalID() string {
+	return strings.ToLower(a.email)
+}
+
+// union merges the clusters of a and b, keeping the identity with the most commits as the canonical one
+func union(a, b *authorIdentity) {
+	ra, rb := a.root(), b.root()
+	if ra == rb {
+		return
+	}
+	if rb.commits > ra.commits {
+		ra, rb = rb, ra
+	}
+	rb.parent = ra
+}
+
+// clusterIndex maps a normalized key (email, name...) to the first identity seen with it
+type clusterIndex map[string]*authorIdentity
+
+func (idx clusterIndex) add(key string, identity *authorIdentity) {
+	if key == "" {
+		return
+	}
+	if other, ok := idx[key]; ok {
+		union(identity, other)
+	} else {
+		idx[key] = identity
+	}
+}
+
+type authorCursor struct {
+	repo       *git.Repository
+	limit      *commitLimit
+	index      int
+	identities []*authorIdentity
+}
+
+func (vc *authorCursor) Column(c *sqlite3.SQLiteContext, col int) error {
+	identity := vc.identities[vc.index]
+	canonical := identity.root()
+
+	switch col {
+	case 0:
+		c.ResultText(identity.name)
+	case 1:
+		c.ResultText(identity.email)
+	case 2:
+		c.ResultText(canonical.canonicalID())
+	case 3:
+		c.ResultText(canonical.name)
+	case 4:
+		c.ResultText(canonical.email)
+	case 5:
+		c.ResultInt(identity.commits)
+	}
+	return nil
+}
+
+func (vc *authorCursor) Filter(idxNum int, idxStr string, vals []interface{}) error {
+	identities, err := clusterAuthors(vc.repo, vc.limit)
+	if err != nil {
+		return err
+	}
+
+	vc.identities = identities
+	vc.index = 0
+
+	return nil
+}
+
+// clusterAuthors walks the history of HEAD and groups author identities that share an email,
+// share a name, or are mapped to the same identity by the repository's .mailmap
+func clusterAuthors(repo *git.Repository, limit *commitLimit) ([]*authorIdentity, error) {
+	mailmap := readMailmap(repo)
+
+	identities := make([]*authorIdentity, 0)
+
+	// if HEAD is unborn (no commit yet) there are no authors
+	unborn, err := repo.IsHeadUnborn()
+	if err != nil {
+		return nil, err
+	}
+	if unborn {
+		return identities, nil
+	}
+
+	walk, err := walkHead(repo, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer walk.Free()
+
+	authors := make([]*git.Signature, 0)
+	committed := make([]time.Time, 0)
+	id := new(git.Oid)
+	for {
+		err := walk.Next(id)
+		if err != nil {
+			if id.IsZero() {
+				break
+			}
+			return nil, err
+		}
+
+		commit, err := repo.LookupCommit(id)
+		if err != nil {
+			return nil, err
+		}
+		authors = append(authors, commit.Author())
+		committed = append(committed, commit.Committer().When)
+		commit.Free()
+		*id = git.Oid{}
+	}
+
+	// identities are listed from the oldest commit, the walk itself is unordered
+	order := make([]int, len(authors))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return committed[order[i]].Before(committed[order[j]])
+	})
+
+	byIdentity := make(map[string]*authorIdentity)
+	for _, i := range order {
+		author := authors[i]
+		key := author.Name + "\x00" + author.Email
+		identity, ok := byIdentity[key]
+		if !ok {
+			identity = &authorIdentity{name: author.Name, email: author.Email}
+			identity.parent = identity
+			byIdentity[key] = identity
+			identities = append(identities, identity)
+		}
+		identity.commits++
+	}
+
+	// commit counts are only known once the walk is done, so clustering happens afterwards
+	// which lets union pick the most active identity as the canonical one
+	byEmail := make(clusterIndex)
+	byName := make(clusterIndex)
+	byMailmap := make(clusterIndex)
+	for _, identity := range identities {
+		byEmail.add(strings.ToLower(strings.TrimSpace(identity.email)), identity)
+		byName.add(strings.ToLower(strings.Join(strings.Fields(identity.name), " ")), identity)
+		name, email := mailmap.resolve(identity.name, identity.email)
+		byMailmap.add(strings.ToLower(name+"\x00"+email), identity)
+	}
+
+	return identities, nil
+}
+
+func (vc *authorCursor) Next() error {
+	vc.index++
+	return nil
+}
+
+func (vc *authorCursor) EOF() bool {
+	return vc.index >= len(vc.identities)
+}
+
+func (vc *authorCursor) Rowid() (int64, error) {
+	return int64(vc.index), nil
+}
+
+func (vc *authorCursor) Close() error {
+	return nil
+}
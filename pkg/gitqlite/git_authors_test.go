@@ -0,0 +1,106 @@
+package gitqlite
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	git "github.com/libgit2/git2go/v30"
+)
+
+func TestAuthors(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	revWalk, err := fixtureRepo.Walk()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer revWalk.Free()
+
+	err = revWalk.PushHead()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	identities := make(map[string]bool)
+	commitCount := 0
+	err = revWalk.Iterate(func(c *git.Commit) bool {
+		identities[c.Author().Name+" "+c.Author().Email] = true
+		commitCount++
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := instance.DB.Query("SELECT name, email, canonical_id, commit_count FROM authors")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rowNum, contents, err := GetContents(rows)
+	if err != nil {
+		t.Fatalf("err %d at row Number %d", err, rowNum)
+	}
+
+	if len(contents) != len(identities) {
+		t.Fatalf("expected %d distinct author identities, got %d", len(identities), len(contents))
+	}
+
+	rows, err = instance.DB.Query("SELECT sum(commit_count) FROM authors")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, contents, err = GetContents(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if contents[0][0] != strconv.Itoa(commitCount) {
+		t.Fatalf("expected %d total commits, got %s", commitCount, contents[0][0])
+	}
+}
+
+func TestAuthorsSameEmailClustered(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := instance.DB.Query("SELECT count(DISTINCT canonical_id) FROM authors GROUP BY lower(email)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, contents, err := GetContents(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range contents {
+		if strings.TrimSpace(c[0]) != "1" {
+			t.Fatalf("expected identities sharing an email to share a canonical_id, got %s canonical ids", c[0])
+		}
+	}
+}
+
+func BenchmarkAuthors(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		instance, err := New(fixtureRepoDir, &Options{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		rows, err := instance.DB.Query("SELECT * FROM authors")
+		if err != nil {
+			b.Fatal(err)
+		}
+		rowNum, _, err := GetContents(rows)
+		if err != nil {
+			b.Fatalf("err %d at row Number %d", err, rowNum)
+		}
+	}
+}
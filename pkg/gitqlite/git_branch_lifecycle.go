@@ -0,0 +1,436 @@
+package gitqlite
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	git "github.com/libgit2/git2go/v30"
+	"github.com/mattn/go-sqlite3"
+)
+
+type gitBranchLifecycleModule struct {
+	repos *repoPool
+}
+
+type gitBranchLifecycleTable struct {
+	repoPath string
+	repos    *repoPool
+	repo     *git.Repository
+}
+
+func (m *gitBranchLifecycleModule) Create(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	err := c.DeclareVTab(fmt.Sprintf(`
+		CREATE TABLE %q (
+			name TEXT,
+			remote BOOL,
+			deleted BOOL,
+			tip TEXT,
+			created_at DATETIME,
+			last_commit_at DATETIME,
+			idle_days INT,
+			merged_into TEXT
+		)`, args[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	// the repoPath will be enclosed in double quotes "..." since createTables uses %q when setting up the table
+	// we need to pop those off when referring to the actual directory in the fs
+	repoPath := args[3][1 : len(args[3])-1]
+	return &gitBranchLifecycleTable{repoPath: repoPath, repos: m.repos}, nil
+}
+
+func (m *gitBranchLifecycleModule) Connect(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	return m.Create(c, args)
+}
+
+func (m *gitBranchLifecycleModule) DestroyModule() {}
+
+func (v *gitBranchLifecycleTable) Open() (sqlite3.VTabCursor, error) {
+	repo, err := v.repos.open(v.repoPath)
+	if err != nil {
+		return nil, err
+	}
+	v.repo = repo
+
+	return &branchLifecycleCursor{repo: v.repo}, nil
+}
+
+func (v *gitBranchLifecycleTable) BestIndex(cst []sqlite3.InfoConstraint, ob []sqlite3.InfoOrderBy) (*sqlite3.IndexResult, error) {
+	// every branch is looked at, there are few enough of them
+	dummy := make([]bool, len(cst))
+	return &sqlite3.IndexResult{Used: dummy}, nil
+}
+
+func (v *gitBranchLifecycleTable) Disconnect() error {
+	v.repo = nil
+	return nil
+}
+func (v *gitBranchLifecycleTable) Destroy() error { return nil }
+
+// branchLifecycle is when a branch was created and last committed to, and which branch it was merged into.
+// Times which aren't known are zero, and so is tip for a deleted branch which was never seen checked out.
+type branchLifecycle struct {
+	name         string
+	remote       bool
+	deleted      bool
+	tip          *git.Oid
+	createdAt    time.Time
+	lastCommitAt time.Time
+	mergedInto   string
+}
+
+type branchLifecycleCursor struct {
+	repo     *git.Repository
+	index    int
+	branches []*branchLifecycle
+	now      time.Time
+}
+
+func (vc *branchLifecycleCursor) Column(c *sqlite3.SQLiteContext, col int) error {
+	branch := vc.branches[vc.index]
+
+	switch col {
+	case 0:
+		c.ResultText(branch.name)
+	case 1:
+		c.ResultBool(branch.remote)
+	case 2:
+		c.ResultBool(branch.deleted)
+	case 3:
+		if branch.tip == nil {
+			c.ResultNull()
+		} else {
+			c.ResultText(branch.tip.String())
+		}
+	case 4:
+		resultTime(c, branch.createdAt)
+	case 5:
+		resultTime(c, branch.lastCommitAt)
+	case 6:
+		if branch.lastCommitAt.IsZero() {
+			c.ResultNull()
+		} else {
+			c.ResultInt(int(vc.now.Sub(branch.lastCommitAt) / (24 * time.Hour)))
+		}
+	case 7:
+		if branch.mergedInto == "" {
+			c.ResultNull()
+		} else {
+			c.ResultText(branch.mergedInto)
+		}
+	}
+	return nil
+}
+
+// resultTime sets the result of a DATETIME column, NULL for the zero time
+func resultTime(c *sqlite3.SQLiteContext, t time.Time) {
+	if t.IsZero() {
+		c.ResultNull()
+	} else {
+		c.ResultText(t.Format(time.RFC3339Nano))
+	}
+}
+
+func (vc *branchLifecycleCursor) Filter(idxNum int, idxStr string, vals []interface{}) error {
+	branches, err := branchLifecycles(vc.repo)
+	if err != nil {
+		return err
+	}
+
+	vc.branches = branches
+	vc.index = 0
+	vc.now = time.Now()
+
+	return nil
+}
+
+// branchLifecycles lists the branches of a repository, along with the local branches which were deleted.
+//
+// A branch is created at its oldest reflog entry, which git drops along with the branch, so deleted branches are the ones
+// the HEAD reflog records being checked out ("checkout: moving from a to b") which no longer exist (nor resolve to anything else).
+// A deleted branch is created when it was first checked out, and its tip is the commit HEAD was at when it was last checked out of.
+// Branches are merged into the checked out branch or the default branch of a remote (its HEAD) when their tip is in its history.
+func branchLifecycles(repo *git.Repository) ([]*branchLifecycle, error) {
+	tips, err := branchTips(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	branches := make([]*branchLifecycle, 0, len(tips))
+	existing := make(map[string]bool)
+	for _, tip := range tips {
+		prefix := "refs/heads/"
+		if tip.remote {
+			prefix = "refs/remotes/"
+		}
+		createdAt, err := reflogStart(repo, prefix+tip.name)
+		if err != nil {
+			return nil, err
+		}
+		existing[tip.name] = true
+		branches = append(branches, &branchLifecycle{name: tip.name, remote: tip.remote, tip: tip.tip, createdAt: createdAt})
+	}
+
+	deleted, err := deletedBranches(repo, existing)
+	if err != nil {
+		return nil, err
+	}
+	branches = append(branches, deleted...)
+
+	mainlines, err := mainlineBranches(repo, tips)
+	if err != nil {
+		return nil, err
+	}
+	graph, err := openCommitGraph(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, branch := range branches {
+		if branch.tip == nil {
+			continue
+		}
+		commit, err := repo.LookupCommit(branch.tip)
+		if err != nil {
+			// the tip of a deleted branch may have been garbage collected
+			if branch.deleted {
+				continue
+			}
+			return nil, err
+		}
+		branch.lastCommitAt = commit.Committer().When
+		commit.Free()
+
+		for _, mainline := range mainlines {
+			if mainline.name == branch.name {
+				continue
+			}
+			merged := mainline.tip.Equal(branch.tip)
+			if !merged {
+				merged, err = descendantOf(repo, graph, mainline.tip, branch.tip)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if merged {
+				branch.mergedInto = mainline.name
+				break
+			}
+		}
+	}
+
+	sort.SliceStable(branches, func(i, j int) bool {
+		return branches[i].name < branches[j].name
+	})
+
+	return branches, nil
+}
+
+// reflogEntry is an update of a reference its reflog records
+type reflogEntry struct {
+	old, new *git.Oid
+	when     time.Time
+	message  string
+}
+
+// readReflog reads the reflog of a reference, from its oldest entry, like shallowBoundary reads the shallow file:
+// libgit2's reflog isn't exposed by git2go. A reference without a reflog has no entries.
+// HEAD is the only reference each worktree has its own reflog of, the others are shared by all of them.
+func readReflog(repo *git.Repository, name string) ([]*reflogEntry, error) {
+	dir := CommonDir(repo)
+	if name == "HEAD" {
+		dir = repo.Path()
+	}
+	contents, err := ioutil.ReadFile(filepath.Join(dir, "logs", filepath.FromSlash(name)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	entries := make([]*reflogEntry, 0)
+	for _, line := range strings.Split(string(contents), "\n") {
+		if line == "" {
+			continue
+		}
+		entry, err := parseReflogEntry(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reflog of %s: %v", name, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// parseReflogEntry parses a line of a reflog: "<old id> <new id> Name <email> <unix time> <timezone>\t<message>"
+func parseReflogEntry(line string) (*reflogEntry, error) {
+	header, message := line, ""
+	if i := strings.Index(line, "\t"); i >= 0 {
+		header, message = line[:i], line[i+1:]
+	}
+	fields := strings.Fields(header)
+	end := strings.LastIndex(header, ">")
+	if len(fields) < 2 || end < 0 {
+		return nil, fmt.Errorf("malformed entry %q", line)
+	}
+
+	previous, err := git.NewOid(fields[0])
+	if err != nil {
+		return nil, err
+	}
+	next, err := git.NewOid(fields[1])
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := strings.Fields(header[end+1:])
+	if len(timestamp) != 2 || len(timestamp[1]) != 5 {
+		return nil, fmt.Errorf("malformed time in entry %q", line)
+	}
+	seconds, err := strconv.ParseInt(timestamp[0], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	hours, err := strconv.Atoi(timestamp[1][1:3])
+	if err != nil {
+		return nil, err
+	}
+	minutes, err := strconv.Atoi(timestamp[1][3:])
+	if err != nil {
+		return nil, err
+	}
+	offset := hours*3600 + minutes*60
+	if timestamp[1][0] == '-' {
+		offset = -offset
+	}
+
+	return &reflogEntry{
+		old:     previous,
+		new:     next,
+		when:    time.Unix(seconds, 0).In(time.FixedZone("", offset)),
+		message: message,
+	}, nil
+}
+
+// reflogStart returns the time of the oldest entry of the reflog of a reference, zero if it has none
+func reflogStart(repo *git.Repository, name string) (time.Time, error) {
+	reflog, err := readReflog(repo, name)
+	if err != nil || len(reflog) == 0 {
+		return time.Time{}, err
+	}
+	return reflog[0].when, nil
+}
+
+// deletedBranches returns the branches the HEAD reflog records being checked out which aren't among existing,
+// and which don't resolve to another object either (i.e. a tag or a commit checked out detached)
+func deletedBranches(repo *git.Repository, existing map[string]bool) ([]*branchLifecycle, error) {
+	reflog, err := readReflog(repo, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*branchLifecycle)
+	branches := make([]*branchLifecycle, 0)
+	seen := func(name string, when time.Time) *branchLifecycle {
+		if existing[name] {
+			return nil
+		}
+		branch, ok := byName[name]
+		if !ok {
+			if object, err := repo.RevparseSingle(name); err == nil {
+				object.Free()
+				existing[name] = true
+				return nil
+			}
+			branch = &branchLifecycle{name: name, deleted: true, createdAt: when}
+			byName[name] = branch
+			branches = append(branches, branch)
+		}
+		return branch
+	}
+
+	// entries are read from the oldest so that the last checkout wins
+	for _, entry := range reflog {
+		if !strings.HasPrefix(entry.message, "checkout: moving from ") {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimPrefix(entry.message, "checkout: moving from "), " to ", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		from, to := parts[0], parts[1]
+		if branch := seen(from, entry.when); branch != nil {
+			branch.tip = entry.old
+		}
+		if branch := seen(to, entry.when); branch != nil {
+			branch.tip = entry.new
+		}
+	}
+
+	return branches, nil
+}
+
+// mainlineBranches returns the branches others are merged into: the checked out branch, then the default branches of the remotes
+func mainlineBranches(repo *git.Repository, tips []*branchTip) ([]*branchTip, error) {
+	names := make([]string, 0)
+
+	head, err := repo.Head()
+	if err == nil {
+		if head.IsBranch() {
+			names = append(names, head.Shorthand())
+		}
+		head.Free()
+	}
+
+	remotes, err := repo.Remotes.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, remote := range remotes {
+		ref, err := repo.References.Lookup("refs/remotes/" + remote + "/HEAD")
+		if err != nil {
+			// not every remote has a default branch, i.e. if it wasn't cloned from
+			continue
+		}
+		if ref.Type() == git.ReferenceSymbolic {
+			names = append(names, strings.TrimPrefix(ref.SymbolicTarget(), "refs/remotes/"))
+		}
+		ref.Free()
+	}
+
+	mainlines := make([]*branchTip, 0, len(names))
+	for _, name := range names {
+		for _, tip := range tips {
+			if tip.name == name {
+				mainlines = append(mainlines, tip)
+				break
+			}
+		}
+	}
+	return mainlines, nil
+}
+
+func (vc *branchLifecycleCursor) Next() error {
+	vc.index++
+	return nil
+}
+
+func (vc *branchLifecycleCursor) EOF() bool {
+	return vc.index >= len(vc.branches)
+}
+
+func (vc *branchLifecycleCursor) Rowid() (int64, error) {
+	return int64(vc.index), nil
+}
+
+func (vc *branchLifecycleCursor) Close() error {
+	return nil
+}
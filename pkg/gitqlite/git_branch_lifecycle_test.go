@@ -0,0 +1,79 @@
+package gitqlite
+
+import (
+	"testing"
+)
+
+func TestBranchLifecycle(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every branch which isn't symbolic is listed, and none of them is deleted
+	var branches, listed int
+	err = instance.DB.QueryRow("SELECT count(*) FROM branches WHERE target NOT LIKE 'refs/%'").Scan(&branches)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = instance.DB.QueryRow("SELECT count(*) FROM branch_lifecycle WHERE NOT deleted AND tip IN (SELECT target FROM branches)").Scan(&listed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if listed != branches {
+		t.Fatalf("expected %d branches, got %d", branches, listed)
+	}
+
+	// the checked out branch was created by the clone, and last committed to by its tip
+	var head string
+	err = instance.DB.QueryRow("SELECT name FROM branches WHERE head").Scan(&head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var createdAt, lastCommitAt string
+	var idleDays int
+	err = instance.DB.QueryRow("SELECT created_at, last_commit_at, idle_days FROM branch_lifecycle WHERE name = ?", head).Scan(&createdAt, &lastCommitAt, &idleDays)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var latest string
+	err = instance.DB.QueryRow("SELECT committer_when FROM commits WHERE id = (SELECT target FROM branches WHERE head)").Scan(&latest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if createdAt == "" || lastCommitAt != latest || idleDays < 0 {
+		t.Fatalf("unexpected lifecycle of %s: created at %q, last commit at %q (expected %q), idle for %d days", head, createdAt, lastCommitAt, latest, idleDays)
+	}
+
+	// the remote-tracking branch of the checked out branch is merged into it
+	var merged int
+	err = instance.DB.QueryRow("SELECT count(*) FROM branch_lifecycle WHERE name = ? AND merged_into = ?", "origin/"+head, head).Scan(&merged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged != 1 {
+		t.Fatalf("expected origin/%s to be merged into %s", head, head)
+	}
+}
+
+func TestParseReflogEntry(t *testing.T) {
+	line := "0000000000000000000000000000000000000000 5f2b1b5a2ab4c0d2b4fa6c0b69cbc3f6e8a3a2d1 Jane Doe <jane@example.com> 1600000000 -0230\tcheckout: moving from main to feature"
+	entry, err := parseReflogEntry(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !entry.old.IsZero() || entry.new.String() != "5f2b1b5a2ab4c0d2b4fa6c0b69cbc3f6e8a3a2d1" {
+		t.Fatalf("unexpected ids %s and %s", entry.old, entry.new)
+	}
+	if _, offset := entry.when.Zone(); entry.when.Unix() != 1600000000 || offset != -(2*3600+30*60) {
+		t.Fatalf("unexpected time %s", entry.when)
+	}
+	if entry.message != "checkout: moving from main to feature" {
+		t.Fatalf("unexpected message %q", entry.message)
+	}
+
+	_, err = parseReflogEntry("not a reflog entry")
+	if err == nil {
+		t.Fatal("expected a malformed entry to fail")
+	}
+}
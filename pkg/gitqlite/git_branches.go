@@ -7,10 +7,13 @@ import (
 	"github.com/mattn/go-sqlite3"
 )
 
-type gitBranchModule struct{}
+type gitBranchModule struct {
+	repos *repoPool
+}
 
 type gitBranchTable struct {
 	repoPath string
+	repos    *repoPool
 }
 
 func (m *gitBranchModule) Create(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
@@ -25,10 +28,10 @@ func (m *gitBranchModule) Create(c *sqlite3.SQLiteConn, args []string) (sqlite3.
 		return nil, err
 	}
 
-	// the repoPath will be enclosed in double quotes "..." since ensureTables uses %q when setting up the table
+	// the repoPath will be enclosed in double quotes "..." since createTables uses %q when setting up the table
 	// we need to pop those off when referring to the actual directory in the fs
 	repoPath := args[3][1 : len(args[3])-1]
-	return &gitBranchTable{repoPath: repoPath}, nil
+	return &gitBranchTable{repoPath: repoPath, repos: m.repos}, nil
 }
 
 func (m *gitBranchModule) Connect(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
@@ -38,7 +41,7 @@ func (m *gitBranchModule) Connect(c *sqlite3.SQLiteConn, args []string) (sqlite3
 func (m *gitBranchModule) DestroyModule() {}
 
 func (v *gitBranchTable) Open() (sqlite3.VTabCursor, error) {
-	repo, err := git.OpenRepository(v.repoPath)
+	repo, err := v.repos.open(v.repoPath)
 	if err != nil {
 		return nil, err
 	}
@@ -68,6 +71,7 @@ type branchCursor struct {
 	repo    *git.Repository
 	current *currentBranch
 	iter    *git.BranchIterator
+	rowid   int64
 }
 
 func (vc *branchCursor) Column(c *sqlite3.SQLiteContext, col int) error {
@@ -100,6 +104,7 @@ func (vc *branchCursor) Column(c *sqlite3.SQLiteContext, col int) error {
 }
 
 func (vc *branchCursor) Filter(idxNum int, idxStr string, vals []interface{}) error {
+	vc.rowid = 0
 	branchIter, err := vc.repo.NewBranchIterator(git.BranchAll)
 	if err != nil {
 		return err
@@ -109,6 +114,11 @@ func (vc *branchCursor) Filter(idxNum int, idxStr string, vals []interface{}) er
 
 	branch, branchType, err := vc.iter.Next()
 	if err != nil {
+		// no branches at all, i.e. in a freshly initialized repository
+		if branch == nil {
+			vc.current = nil
+			return nil
+		}
 		return err
 	}
 
@@ -118,6 +128,7 @@ func (vc *branchCursor) Filter(idxNum int, idxStr string, vals []interface{}) er
 }
 
 func (vc *branchCursor) Next() error {
+	vc.rowid++
 	branch, branchType, err := vc.iter.Next()
 	if err != nil {
 		if branch == nil {
@@ -137,7 +148,7 @@ func (vc *branchCursor) EOF() bool {
 }
 
 func (vc *branchCursor) Rowid() (int64, error) {
-	return int64(0), nil
+	return vc.rowid, nil
 }
 
 func (vc *branchCursor) Close() error {
@@ -0,0 +1,311 @@
+package gitqlite
+
+import (
+	"fmt"
+	"strings"
+
+	git "github.com/libgit2/git2go/v30"
+	"github.com/mattn/go-sqlite3"
+)
+
+type gitCherryModule struct {
+	repos *repoPool
+}
+
+type gitCherryTable struct {
+	repoPath string
+	repos    *repoPool
+	repo     *git.Repository
+}
+
+func (m *gitCherryModule) Create(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	err := c.DeclareVTab(fmt.Sprintf(`
+		CREATE TABLE %q (
+			commit_id TEXT,
+			summary TEXT,
+			patch_id TEXT,
+			equivalent BOOL,
+			upstream_id TEXT,
+			upstream TEXT HIDDEN,
+			head TEXT HIDDEN
+		)`, args[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	// the repoPath will be enclosed in double quotes "..." since createTables uses %q when setting up the table
+	// we need to pop those off when referring to the actual directory in the fs
+	repoPath := args[3][1 : len(args[3])-1]
+	return &gitCherryTable{repoPath: repoPath, repos: m.repos}, nil
+}
+
+func (m *gitCherryModule) Connect(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	return m.Create(c, args)
+}
+
+func (m *gitCherryModule) DestroyModule() {}
+
+func (v *gitCherryTable) Open() (sqlite3.VTabCursor, error) {
+	repo, err := v.repos.open(v.repoPath)
+	if err != nil {
+		return nil, err
+	}
+	v.repo = repo
+
+	return &cherryCursor{repo: v.repo}, nil
+}
+
+func (v *gitCherryTable) BestIndex(cst []sqlite3.InfoConstraint, ob []sqlite3.InfoOrderBy) (*sqlite3.IndexResult, error) {
+	used := make([]bool, len(cst))
+	// the values of used constraints are passed to Filter in the order they appear in cst,
+	// so the names of the constrained columns are passed along in that same order in the IdxStr
+	columns := make([]string, 0)
+	cost := 1000.0
+	for c, constraint := range cst {
+		if !constraint.Usable || constraint.Op != sqlite3.OpEQ {
+			continue
+		}
+		switch {
+		case constraint.Column == 5 && !contains(columns, "upstream"):
+			used[c] = true
+			columns = append(columns, "upstream")
+			cost /= 10
+		case constraint.Column == 6 && !contains(columns, "head"):
+			used[c] = true
+			columns = append(columns, "head")
+			cost /= 10
+		}
+	}
+
+	return &sqlite3.IndexResult{Used: used, IdxNum: len(columns), IdxStr: strings.Join(columns, ","), EstimatedCost: cost}, nil
+}
+
+func (v *gitCherryTable) Disconnect() error {
+	v.repo = nil
+	return nil
+}
+func (v *gitCherryTable) Destroy() error { return nil }
+
+// cherry is a commit of head missing from upstream, along with the commit of upstream making the same changes if any
+type cherry struct {
+	commitID   string
+	summary    string
+	patchID    string
+	upstreamID string
+}
+
+type cherryCursor struct {
+	repo     *git.Repository
+	index    int
+	cherries []*cherry
+	// upstream and head are the values of the constraints, nil if unconstrained
+	upstream interface{}
+	head     interface{}
+}
+
+func (vc *cherryCursor) Column(c *sqlite3.SQLiteContext, col int) error {
+	cherry := vc.cherries[vc.index]
+
+	switch col {
+	case 0:
+		c.ResultText(cherry.commitID)
+	case 1:
+		c.ResultText(cherry.summary)
+	case 2:
+		if cherry.patchID == "" {
+			c.ResultNull()
+		} else {
+			c.ResultText(cherry.patchID)
+		}
+	case 3:
+		c.ResultBool(cherry.upstreamID != "")
+	case 4:
+		if cherry.upstreamID == "" {
+			c.ResultNull()
+		} else {
+			c.ResultText(cherry.upstreamID)
+		}
+	case 5:
+		// the constrained refs are returned as is, so that SQLite's own check of the constraints passes
+		if vc.upstream == nil {
+			c.ResultNull()
+		} else {
+			c.ResultText(vc.upstream.(string))
+		}
+	case 6:
+		if vc.head == nil {
+			c.ResultNull()
+		} else {
+			c.ResultText(vc.head.(string))
+		}
+	}
+	return nil
+}
+
+func (vc *cherryCursor) Filter(idxNum int, idxStr string, vals []interface{}) error {
+	vc.index = 0
+	vc.cherries = nil
+	vc.upstream = nil
+	vc.head = nil
+	if idxNum > 0 {
+		for i, column := range strings.Split(idxStr, ",") {
+			if _, ok := vals[i].(string); !ok {
+				return fmt.Errorf("%s must be a ref, got %v", column, vals[i])
+			}
+			switch column {
+			case "upstream":
+				vc.upstream = vals[i]
+			case "head":
+				vc.head = vals[i]
+			}
+		}
+	}
+
+	head := "HEAD"
+	if vc.head != nil {
+		head = vc.head.(string)
+	}
+	upstream := ""
+	if vc.upstream != nil {
+		upstream = vc.upstream.(string)
+	} else {
+		var err error
+		upstream, err = headUpstream(vc.repo)
+		if err != nil {
+			return err
+		}
+		// there is nothing to compare with without an upstream
+		if upstream == "" {
+			return nil
+		}
+	}
+
+	cherries, err := cherries(vc.repo, upstream, head)
+	if err != nil {
+		return err
+	}
+	vc.cherries = cherries
+
+	return nil
+}
+
+// headUpstream returns the name of the upstream branch of the checked out branch, empty if there is none
+func headUpstream(repo *git.Repository) (string, error) {
+	head, err := repo.Head()
+	if err != nil {
+		// i.e. HEAD is unborn
+		return "", nil
+	}
+	defer head.Free()
+	if !head.IsBranch() {
+		return "", nil
+	}
+
+	upstream, err := head.Branch().Upstream()
+	if err != nil {
+		// the branch doesn't track any
+		return "", nil
+	}
+	defer upstream.Free()
+	return upstream.Name(), nil
+}
+
+// cherries lists the commits of head which aren't in the history of upstream, oldest first, like git cherry.
+// A commit has an equivalent in upstream when a commit of upstream (which isn't in the history of head) has the same patch id,
+// i.e. when it was cherry-picked or rebased onto upstream. Merges are skipped, like git cherry does.
+func cherries(repo *git.Repository, upstream, head string) ([]*cherry, error) {
+	upstreamCommit, err := lookupRef(repo, upstream)
+	if err != nil {
+		return nil, err
+	}
+	defer upstreamCommit.Free()
+	headCommit, err := lookupRef(repo, head)
+	if err != nil {
+		return nil, err
+	}
+	defer headCommit.Free()
+
+	// the patch ids of the commits of upstream missing from head
+	upstreamPatches := make(map[string]string)
+	err = walkRange(repo, headCommit.Id(), upstreamCommit.Id(), func(commit *git.Commit) error {
+		id, err := patchID(repo, commit)
+		if err != nil {
+			return err
+		}
+		if _, ok := upstreamPatches[id]; id != "" && !ok {
+			upstreamPatches[id] = commit.Id().String()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cherries := make([]*cherry, 0)
+	err = walkRange(repo, upstreamCommit.Id(), headCommit.Id(), func(commit *git.Commit) error {
+		id, err := patchID(repo, commit)
+		if err != nil {
+			return err
+		}
+		cherry := &cherry{commitID: commit.Id().String(), summary: commit.Summary(), patchID: id}
+		if id != "" {
+			cherry.upstreamID = upstreamPatches[id]
+		}
+		cherries = append(cherries, cherry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return cherries, nil
+}
+
+// walkRange calls fn with the commits in the history of to which aren't in the history of from, oldest first, merges excepted
+func walkRange(repo *git.Repository, from, to *git.Oid, fn func(commit *git.Commit) error) error {
+	walk, err := repo.Walk()
+	if err != nil {
+		return err
+	}
+	defer walk.Free()
+
+	walk.Sorting(git.SortTopological | git.SortReverse)
+	err = walk.Push(to)
+	if err != nil {
+		return err
+	}
+	err = walk.Hide(from)
+	if err != nil {
+		return err
+	}
+
+	var fnErr error
+	err = walk.Iterate(func(commit *git.Commit) bool {
+		if commit.ParentCount() > 1 {
+			return true
+		}
+		fnErr = fn(commit)
+		return fnErr == nil
+	})
+	if err != nil {
+		return err
+	}
+	return fnErr
+}
+
+func (vc *cherryCursor) Next() error {
+	vc.index++
+	return nil
+}
+
+func (vc *cherryCursor) EOF() bool {
+	return vc.index >= len(vc.cherries)
+}
+
+func (vc *cherryCursor) Rowid() (int64, error) {
+	return int64(vc.index), nil
+}
+
+func (vc *cherryCursor) Close() error {
+	return nil
+}
@@ -0,0 +1,64 @@
+package gitqlite
+
+import (
+	"testing"
+)
+
+func TestCherries(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	head, err := fixtureRepo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer head.Free()
+	commit, err := fixtureRepo.LookupCommit(head.Target())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer commit.Free()
+
+	// upstream picks a change, which head makes on top of another one
+	upstream := commitContents(t, fixtureRepo, commit, "CHERRY.md", "picked\n")
+	other := commitContents(t, fixtureRepo, commit, "OTHER.md", "not picked\n")
+	otherCommit, err := fixtureRepo.LookupCommit(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer otherCommit.Free()
+	picked := commitContents(t, fixtureRepo, otherCommit, "CHERRY.md", "picked\n")
+
+	rows, err := instance.DB.Query("SELECT commit_id, equivalent, upstream_id FROM cherries(?, ?)", upstream.String(), picked.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	_, contents, err := GetContents(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// commits are listed oldest first
+	if len(contents) != 2 {
+		t.Fatalf("expected 2 commits missing from upstream, got %d", len(contents))
+	}
+	if contents[0][0] != other.String() || contents[0][1] != "0" || contents[0][2] != "NULL" {
+		t.Fatalf("expected %s to have no equivalent upstream, got %v", other, contents[0])
+	}
+	if contents[1][0] != picked.String() || contents[1][1] != "1" || contents[1][2] != upstream.String() {
+		t.Fatalf("expected %s to be equivalent to %s, got %v", picked, upstream, contents[1])
+	}
+
+	// nothing of HEAD is missing from HEAD
+	var count int
+	err = instance.DB.QueryRow("SELECT count(*) FROM cherries('HEAD')").Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Fatalf("expected no commit, got %d", count)
+	}
+}
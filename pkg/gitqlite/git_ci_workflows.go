@@ -0,0 +1,370 @@
+package gitqlite
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	git "github.com/libgit2/git2go/v30"
+	"github.com/mattn/go-sqlite3"
+	"gopkg.in/yaml.v2"
+)
+
+type gitCIWorkflowModule struct {
+	repos *repoPool
+}
+
+type gitCIWorkflowTable struct {
+	repoPath string
+	repos    *repoPool
+	repo     *git.Repository
+}
+
+func (m *gitCIWorkflowModule) Create(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	err := c.DeclareVTab(fmt.Sprintf(`
+		CREATE TABLE %q (
+			path TEXT,
+			workflow TEXT,
+			triggers TEXT,
+			job TEXT,
+			step INT,
+			step_name TEXT,
+			uses TEXT,
+			run TEXT,
+			action TEXT,
+			version TEXT,
+			pinned BOOL,
+			ref TEXT HIDDEN
+		)`, args[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	// the repoPath will be enclosed in double quotes "..." since createTables uses %q when setting up the table
+	// we need to pop those off when referring to the actual directory in the fs
+	repoPath := args[3][1 : len(args[3])-1]
+	return &gitCIWorkflowTable{repoPath: repoPath, repos: m.repos}, nil
+}
+
+func (m *gitCIWorkflowModule) Connect(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	return m.Create(c, args)
+}
+
+func (m *gitCIWorkflowModule) DestroyModule() {}
+
+func (v *gitCIWorkflowTable) Open() (sqlite3.VTabCursor, error) {
+	repo, err := v.repos.open(v.repoPath)
+	if err != nil {
+		return nil, err
+	}
+	v.repo = repo
+
+	return &ciWorkflowCursor{repo: v.repo}, nil
+}
+
+func (v *gitCIWorkflowTable) BestIndex(cst []sqlite3.InfoConstraint, ob []sqlite3.InfoOrderBy) (*sqlite3.IndexResult, error) {
+	used := make([]bool, len(cst))
+	// the values of used constraints are passed to Filter in the order they appear in cst,
+	// so the names of the constrained columns are passed along in that same order in the IdxStr
+	columns := make([]string, 0)
+	for c, constraint := range cst {
+		if !constraint.Usable || constraint.Op != sqlite3.OpEQ {
+			continue
+		}
+		if constraint.Column == 11 && !contains(columns, "ref") {
+			used[c] = true
+			columns = append(columns, "ref")
+		}
+	}
+
+	return &sqlite3.IndexResult{Used: used, IdxNum: len(columns), IdxStr: strings.Join(columns, ",")}, nil
+}
+
+func (v *gitCIWorkflowTable) Disconnect() error {
+	v.repo = nil
+	return nil
+}
+func (v *gitCIWorkflowTable) Destroy() error { return nil }
+
+// workflowsDir is the directory GitHub Actions workflows are defined in
+const workflowsDir = ".github/workflows"
+
+// workflowStep is a step of a job of a workflow, or a job calling a reusable workflow (which has no steps) if index is -1
+type workflowStep struct {
+	path, workflow, triggers, job string
+	index                         int
+	name, uses, run               string
+}
+
+// commitSHA matches the full id of a commit, the only version of an action which can't be moved to other code
+var commitSHA = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// action splits what a step uses into the action and its version (i.e. actions/checkout and v2), and tells whether it's pinned:
+// to a commit id, to the digest of a Docker image, or in the repository itself (./path)
+func action(uses string) (string, string, bool) {
+	switch {
+	case strings.HasPrefix(uses, "./"):
+		return uses, "", true
+	case strings.HasPrefix(uses, "docker://"):
+		if i := strings.Index(uses, "@"); i >= 0 {
+			return uses[:i], uses[i+1:], strings.HasPrefix(uses[i+1:], "sha256:")
+		}
+		if i := strings.LastIndex(uses, ":"); i > len("docker:") {
+			return uses[:i], uses[i+1:], false
+		}
+		return uses, "", false
+	}
+	i := strings.LastIndex(uses, "@")
+	if i < 0 {
+		return uses, "", false
+	}
+	return uses[:i], uses[i+1:], commitSHA.MatchString(uses[i+1:])
+}
+
+// mapValue returns the value of key in a YAML mapping, nil if it isn't one or it has no such key
+func mapValue(m interface{}, key string) interface{} {
+	ms, ok := m.(yaml.MapSlice)
+	if !ok {
+		return nil
+	}
+	for _, item := range ms {
+		// YAML 1.1 reads an unquoted on as true, and workflows are written with on: as their triggers
+		k := fmt.Sprint(item.Key)
+		if k == key || (key == "on" && item.Key == true) {
+			return item.Value
+		}
+	}
+	return nil
+}
+
+// stringValue returns a YAML scalar as a string, "" if it isn't one
+func stringValue(v interface{}) string {
+	switch v.(type) {
+	case nil, yaml.MapSlice, []interface{}:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// triggers returns the events triggering a workflow, comma separated and sorted: on is an event, a list of events, or a mapping
+// of events to their settings
+func triggers(on interface{}) string {
+	events := make([]string, 0)
+	switch v := on.(type) {
+	case yaml.MapSlice:
+		for _, item := range v {
+			events = append(events, fmt.Sprint(item.Key))
+		}
+	case []interface{}:
+		for _, event := range v {
+			events = append(events, fmt.Sprint(event))
+		}
+	default:
+		if s := stringValue(v); s != "" {
+			events = append(events, s)
+		}
+	}
+	sort.Strings(events)
+	return strings.Join(events, ",")
+}
+
+// parseWorkflow returns the steps of the workflow defined in the file at filePath, in the order of its jobs and of their steps
+func parseWorkflow(filePath string, contents []byte) ([]*workflowStep, error) {
+	var doc yaml.MapSlice
+	err := yaml.Unmarshal(contents, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filePath, err)
+	}
+
+	name := stringValue(mapValue(doc, "name"))
+	if name == "" {
+		name = filePath
+	}
+	on := triggers(mapValue(doc, "on"))
+
+	steps := make([]*workflowStep, 0)
+	jobs, _ := mapValue(doc, "jobs").(yaml.MapSlice)
+	for _, job := range jobs {
+		id := fmt.Sprint(job.Key)
+
+		// a job calling a reusable workflow has no steps of its own
+		if uses := stringValue(mapValue(job.Value, "uses")); uses != "" {
+			steps = append(steps, &workflowStep{path: filePath, workflow: name, triggers: on, job: id, index: -1, uses: uses})
+			continue
+		}
+
+		list, _ := mapValue(job.Value, "steps").([]interface{})
+		for i, step := range list {
+			steps = append(steps, &workflowStep{
+				path:     filePath,
+				workflow: name,
+				triggers: on,
+				job:      id,
+				index:    i,
+				name:     stringValue(mapValue(step, "name")),
+				uses:     stringValue(mapValue(step, "uses")),
+				run:      stringValue(mapValue(step, "run")),
+			})
+		}
+	}
+	return steps, nil
+}
+
+// workflowSteps returns the steps of the GitHub Actions workflows committed to a tree, the *.yml and *.yaml files of .github/workflows
+func workflowSteps(repo *git.Repository, tree *git.Tree) ([]*workflowStep, error) {
+	steps := make([]*workflowStep, 0)
+
+	entry, err := tree.EntryByPath(workflowsDir)
+	if err != nil {
+		if git.IsErrorCode(err, git.ErrNotFound) {
+			return steps, nil
+		}
+		return nil, err
+	}
+	if entry.Type != git.ObjectTree {
+		return steps, nil
+	}
+	dir, err := repo.LookupTree(entry.Id)
+	if err != nil {
+		return nil, err
+	}
+	defer dir.Free()
+
+	for i := uint64(0); i < dir.EntryCount(); i++ {
+		file := dir.EntryByIndex(i)
+		ext := path.Ext(file.Name)
+		if file.Type != git.ObjectBlob || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+
+		blob, err := repo.LookupBlob(file.Id)
+		if err != nil {
+			return nil, err
+		}
+		workflow, err := parseWorkflow(path.Join(workflowsDir, file.Name), blob.Contents())
+		blob.Free()
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, workflow...)
+	}
+	return steps, nil
+}
+
+type ciWorkflowCursor struct {
+	repo *git.Repository
+	// the value of the constraint on ref, returned as is so that SQLite's own check of the constraint passes
+	ref   interface{}
+	steps []*workflowStep
+	index int
+}
+
+func (vc *ciWorkflowCursor) Column(c *sqlite3.SQLiteContext, col int) error {
+	step := vc.steps[vc.index]
+	resultText := func(s string) {
+		if s == "" {
+			c.ResultNull()
+		} else {
+			c.ResultText(s)
+		}
+	}
+
+	switch col {
+	case 0:
+		c.ResultText(step.path)
+	case 1:
+		c.ResultText(step.workflow)
+	case 2:
+		resultText(step.triggers)
+	case 3:
+		c.ResultText(step.job)
+	case 4:
+		if step.index < 0 {
+			c.ResultNull()
+		} else {
+			c.ResultInt(step.index)
+		}
+	case 5:
+		resultText(step.name)
+	case 6:
+		resultText(step.uses)
+	case 7:
+		resultText(step.run)
+	case 8, 9, 10:
+		// only steps using an action have one
+		name, version, pinned := action(step.uses)
+		switch {
+		case step.uses == "":
+			c.ResultNull()
+		case col == 8:
+			c.ResultText(name)
+		case col == 9:
+			resultText(version)
+		default:
+			c.ResultBool(pinned)
+		}
+	case 11:
+		resultConstraint(c, vc.ref)
+	}
+	return nil
+}
+
+func (vc *ciWorkflowCursor) Filter(idxNum int, idxStr string, vals []interface{}) error {
+	vc.ref = nil
+	vc.steps = nil
+	vc.index = 0
+	if idxNum > 0 {
+		for i, column := range strings.Split(idxStr, ",") {
+			if column == "ref" {
+				vc.ref = vals[i]
+			}
+		}
+	}
+
+	ref, ok := vc.ref.(string)
+	if !ok {
+		// there are no workflows if HEAD is unborn
+		unborn, err := vc.repo.IsHeadUnborn()
+		if err != nil {
+			return err
+		}
+		if unborn {
+			return nil
+		}
+		ref = "HEAD"
+	}
+
+	commit, err := lookupRef(vc.repo, ref)
+	if err != nil {
+		return err
+	}
+	defer commit.Free()
+	tree, err := commit.Tree()
+	if err != nil {
+		return err
+	}
+	defer tree.Free()
+
+	vc.steps, err = workflowSteps(vc.repo, tree)
+	return err
+}
+
+func (vc *ciWorkflowCursor) Next() error {
+	vc.index++
+	return nil
+}
+
+func (vc *ciWorkflowCursor) EOF() bool {
+	return vc.index >= len(vc.steps)
+}
+
+func (vc *ciWorkflowCursor) Rowid() (int64, error) {
+	return int64(vc.index), nil
+}
+
+func (vc *ciWorkflowCursor) Close() error {
+	return nil
+}
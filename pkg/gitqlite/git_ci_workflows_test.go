@@ -0,0 +1,88 @@
+package gitqlite
+
+import (
+	"testing"
+)
+
+func TestParseWorkflow(t *testing.T) {
+	workflow := []byte(`
+name: CI
+on:
+  push:
+    branches: [main]
+  pull_request:
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v2
+      - name: Setup
+        uses: actions/setup-go@37335c7bb261b353407cff977110895fa0b4f7d8
+      - run: make test
+  release:
+    uses: octo/workflows/.github/workflows/release.yml@main
+`)
+
+	steps, err := parseWorkflow(".github/workflows/ci.yml", workflow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 4 {
+		t.Fatalf("expected 4 steps, got %d", len(steps))
+	}
+	for _, step := range steps {
+		if step.workflow != "CI" || step.triggers != "pull_request,push" {
+			t.Fatalf("expected the CI workflow triggered by pull_request,push, got %s triggered by %s", step.workflow, step.triggers)
+		}
+	}
+	if steps[0].job != "test" || steps[0].index != 0 || steps[0].uses != "actions/checkout@v2" {
+		t.Fatalf("expected the checkout step first, got %+v", steps[0])
+	}
+	if steps[1].name != "Setup" || steps[2].run != "make test" {
+		t.Fatalf("expected the setup and test steps, got %+v and %+v", steps[1], steps[2])
+	}
+	if steps[3].job != "release" || steps[3].index != -1 {
+		t.Fatalf("expected the release job calling a reusable workflow, got %+v", steps[3])
+	}
+
+	if _, err := parseWorkflow(".github/workflows/broken.yml", []byte("jobs: [")); err == nil {
+		t.Fatal("expected an invalid workflow to fail")
+	}
+}
+
+func TestAction(t *testing.T) {
+	tests := []struct {
+		uses, action, version string
+		pinned                bool
+	}{
+		{"actions/checkout@v2", "actions/checkout", "v2", false},
+		{"actions/setup-go@37335c7bb261b353407cff977110895fa0b4f7d8", "actions/setup-go", "37335c7bb261b353407cff977110895fa0b4f7d8", true},
+		{"./.github/actions/build", "./.github/actions/build", "", true},
+		{"docker://alpine:3.12", "docker://alpine", "3.12", false},
+		{"docker://alpine@sha256:abc", "docker://alpine", "sha256:abc", true},
+	}
+
+	for _, test := range tests {
+		action, version, pinned := action(test.uses)
+		if action != test.action || version != test.version || pinned != test.pinned {
+			t.Fatalf("expected %s, %s and %v for %s, got %s, %s and %v", test.action, test.version, test.pinned, test.uses, action, version, pinned)
+		}
+	}
+}
+
+func TestCIWorkflows(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// steps either use an action or run commands
+	var steps int
+	err = instance.DB.QueryRow("SELECT count(*) FROM ci_workflows WHERE (uses IS NULL) = (pinned IS NOT NULL) OR path NOT LIKE '.github/workflows/%'").Scan(&steps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if steps != 0 {
+		t.Fatalf("expected consistent steps, got %d inconsistent ones", steps)
+	}
+}
@@ -0,0 +1,265 @@
+package gitqlite
+
+import (
+	"fmt"
+	"strings"
+
+	git "github.com/libgit2/git2go/v30"
+	"github.com/mattn/go-sqlite3"
+)
+
+type gitCommitBranchModule struct {
+	repos *repoPool
+}
+
+type gitCommitBranchTable struct {
+	repoPath string
+	repos    *repoPool
+	repo     *git.Repository
+}
+
+func (m *gitCommitBranchModule) Create(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	err := c.DeclareVTab(fmt.Sprintf(`
+		CREATE TABLE %q (
+			commit_id TEXT,
+			branch TEXT
+		)`, args[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	// the repoPath will be enclosed in double quotes "..." since createTables uses %q when setting up the table
+	// we need to pop those off when referring to the actual directory in the fs
+	repoPath := args[3][1 : len(args[3])-1]
+	return &gitCommitBranchTable{repoPath: repoPath, repos: m.repos}, nil
+}
+
+func (m *gitCommitBranchModule) Connect(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	return m.Create(c, args)
+}
+
+func (m *gitCommitBranchModule) DestroyModule() {}
+
+func (v *gitCommitBranchTable) Open() (sqlite3.VTabCursor, error) {
+	repo, err := v.repos.open(v.repoPath)
+	if err != nil {
+		return nil, err
+	}
+	v.repo = repo
+
+	return &commitBranchCursor{repo: v.repo, limit: v.repos.limit}, nil
+}
+
+func (v *gitCommitBranchTable) BestIndex(cst []sqlite3.InfoConstraint, ob []sqlite3.InfoOrderBy) (*sqlite3.IndexResult, error) {
+	used := make([]bool, len(cst))
+	// the values of used constraints are passed to Filter in the order they appear in cst,
+	// so the names of the constrained columns are passed along in that same order in the IdxStr
+	columns := make([]string, 0)
+	cost := 1000.0
+	for c, constraint := range cst {
+		if !constraint.Usable || constraint.Op != sqlite3.OpEQ {
+			continue
+		}
+		switch {
+		case constraint.Column == 0 && !contains(columns, "commit_id"):
+			used[c] = true
+			columns = append(columns, "commit_id")
+			cost /= 100
+		case constraint.Column == 1 && !contains(columns, "branch"):
+			used[c] = true
+			columns = append(columns, "branch")
+			cost /= 10
+		}
+	}
+
+	return &sqlite3.IndexResult{Used: used, IdxNum: len(columns), IdxStr: strings.Join(columns, ","), EstimatedCost: cost}, nil
+}
+
+func (v *gitCommitBranchTable) Disconnect() error {
+	v.repo = nil
+	return nil
+}
+func (v *gitCommitBranchTable) Destroy() error { return nil }
+
+// branchTip is a branch, along with the commit it points to
+type branchTip struct {
+	name   string
+	remote bool
+	tip    *git.Oid
+}
+
+// branchTips lists the local and remote branches of a repository, skipping symbolic ones (i.e. origin/HEAD)
+func branchTips(repo *git.Repository) ([]*branchTip, error) {
+	branches := make([]*branchTip, 0)
+
+	iter, err := repo.NewBranchIterator(git.BranchAll)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Free()
+
+	err = iter.ForEach(func(branch *git.Branch, branchType git.BranchType) error {
+		defer branch.Free()
+		if branch.Type() != git.ReferenceOid {
+			return nil
+		}
+		name, err := branch.Name()
+		if err != nil {
+			return err
+		}
+		branches = append(branches, &branchTip{name: name, remote: branchType == git.BranchRemote, tip: branch.Target()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return branches, nil
+}
+
+type commitBranchCursor struct {
+	repo     *git.Repository
+	limit    *commitLimit
+	branches []*branchTip
+	// branchIndex is the index of the branch currently looked at in branches
+	branchIndex int
+	walk        commitWalker
+	// commitID is set when only the branches containing that commit are listed
+	commitID *git.Oid
+	// graph is the commit-graph of the repository, if it has one
+	graph   *commitGraph
+	current [2]string
+	done    bool
+	rowid   int64
+}
+
+func (vc *commitBranchCursor) Column(c *sqlite3.SQLiteContext, col int) error {
+	switch col {
+	case 0:
+		c.ResultText(vc.current[0])
+	case 1:
+		c.ResultText(vc.current[1])
+	}
+	return nil
+}
+
+func (vc *commitBranchCursor) Filter(idxNum int, idxStr string, vals []interface{}) error {
+	vc.rowid = 0
+	vc.branchIndex = 0
+	vc.commitID = nil
+	vc.done = false
+	if vc.walk != nil {
+		vc.walk.Free()
+		vc.walk = nil
+	}
+
+	branches, err := branchTips(vc.repo)
+	if err != nil {
+		return err
+	}
+
+	vc.graph, err = openCommitGraph(vc.repo)
+	if err != nil {
+		return err
+	}
+
+	if idxNum > 0 {
+		for i, column := range strings.Split(idxStr, ",") {
+			value, ok := vals[i].(string)
+			if !ok {
+				return fmt.Errorf("%s must be a string, got %v", column, vals[i])
+			}
+			switch column {
+			case "commit_id":
+				vc.commitID, err = git.NewOid(value)
+				if err != nil {
+					return err
+				}
+			case "branch":
+				filtered := make([]*branchTip, 0)
+				for _, branch := range branches {
+					if branch.name == value {
+						filtered = append(filtered, branch)
+					}
+				}
+				branches = filtered
+			}
+		}
+	}
+	vc.branches = branches
+
+	return vc.advance()
+}
+
+// advance moves the cursor to the next (commit, branch) pair.
+// Looking for the branches of a single commit only takes a reachability check per branch,
+// otherwise the history of every branch is walked in turn. Both are faster with a commit-graph file.
+func (vc *commitBranchCursor) advance() error {
+	for vc.branchIndex < len(vc.branches) {
+		branch := vc.branches[vc.branchIndex]
+
+		if vc.commitID != nil {
+			vc.branchIndex++
+			contained := branch.tip.Equal(vc.commitID)
+			if !contained {
+				var err error
+				contained, err = descendantOf(vc.repo, vc.graph, branch.tip, vc.commitID)
+				if err != nil {
+					return err
+				}
+			}
+			if contained {
+				vc.current = [2]string{vc.commitID.String(), branch.name}
+				return nil
+			}
+			continue
+		}
+
+		if vc.walk == nil {
+			walk, err := walkGraph(vc.repo, vc.graph, branch.tip, vc.limit)
+			if err != nil {
+				return err
+			}
+			vc.walk = walk
+		}
+
+		id := new(git.Oid)
+		err := vc.walk.Next(id)
+		if err != nil {
+			if id.IsZero() {
+				// done with this branch, move on to the next one
+				vc.walk.Free()
+				vc.walk = nil
+				vc.branchIndex++
+				continue
+			}
+			return err
+		}
+
+		vc.current = [2]string{id.String(), branch.name}
+		return nil
+	}
+
+	vc.done = true
+	return nil
+}
+
+func (vc *commitBranchCursor) Next() error {
+	vc.rowid++
+	return vc.advance()
+}
+
+func (vc *commitBranchCursor) EOF() bool {
+	return vc.done
+}
+
+func (vc *commitBranchCursor) Rowid() (int64, error) {
+	return vc.rowid, nil
+}
+
+func (vc *commitBranchCursor) Close() error {
+	if vc.walk != nil {
+		vc.walk.Free()
+	}
+	return nil
+}
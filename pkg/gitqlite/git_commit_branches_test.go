@@ -0,0 +1,61 @@
+package gitqlite
+
+import (
+	"testing"
+)
+
+func TestCommitBranches(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var head string
+	err = instance.DB.QueryRow("SELECT name FROM branches WHERE head").Scan(&head)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the checked out branch contains every commit of the commits table
+	var commits, onHead int
+	err = instance.DB.QueryRow("SELECT count(*) FROM commits").Scan(&commits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = instance.DB.QueryRow("SELECT count(*) FROM commit_branches WHERE branch = ?", head).Scan(&onHead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if onHead != commits {
+		t.Fatalf("expected %d commits on %s, got %d", commits, head, onHead)
+	}
+
+	// and the root commit is on every branch
+	var root string
+	err = instance.DB.QueryRow("SELECT id FROM commits WHERE parent_count = 0 LIMIT 1").Scan(&root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var containing, found int
+	err = instance.DB.QueryRow("SELECT count(*) FROM commit_branches WHERE commit_id = ?", root).Scan(&containing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = instance.DB.QueryRow("SELECT count(*) FROM commit_branches WHERE commit_id = ? AND branch = ?", root, head).Scan(&found)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if containing < 1 || found != 1 {
+		t.Fatalf("expected root commit %s to be on %s, found on %d branches", root, head, containing)
+	}
+
+	// the reachability check and the walk of every branch agree
+	var walked int
+	err = instance.DB.QueryRow("SELECT count(*) FROM commit_branches WHERE commit_id || '' = ?", root).Scan(&walked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if walked != containing {
+		t.Fatalf("expected root commit %s to be found on %d branches when walking them, got %d", root, containing, walked)
+	}
+}
@@ -0,0 +1,188 @@
+package gitqlite
+
+import (
+	"fmt"
+	"io"
+
+	git "github.com/libgit2/git2go/v30"
+	"github.com/mattn/go-sqlite3"
+)
+
+type gitCommitFilesModule struct {
+	repos *repoPool
+}
+
+type gitCommitFilesTable struct {
+	repoPath string
+	repos    *repoPool
+	repo     *git.Repository
+}
+
+func (m *gitCommitFilesModule) Create(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	err := c.DeclareVTab(fmt.Sprintf(`
+			CREATE TABLE %q (
+			commit_id TEXT,
+			old_path TEXT,
+			new_path TEXT,
+			status TEXT,
+			similarity INT,
+			additions INT,
+			deletions INT,
+			is_binary BOOL,
+			old_mode TEXT,
+			new_mode TEXT
+			)`, args[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	// the repoPath will be enclosed in double quotes "..." since createTables uses %q when setting up the table
+	// we need to pop those off when referring to the actual directory in the fs
+	repoPath := args[3][1 : len(args[3])-1]
+	return &gitCommitFilesTable{repoPath: repoPath, repos: m.repos}, nil
+}
+
+func (m *gitCommitFilesModule) Connect(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	return m.Create(c, args)
+}
+
+func (m *gitCommitFilesModule) DestroyModule() {}
+
+func (v *gitCommitFilesTable) Open() (sqlite3.VTabCursor, error) {
+	repo, err := v.repos.open(v.repoPath)
+	if err != nil {
+		return nil, err
+	}
+	v.repo = repo
+
+	return &commitFilesCursor{repo: v.repo, limit: v.repos.limit}, nil
+}
+
+func (v *gitCommitFilesTable) BestIndex(cst []sqlite3.InfoConstraint, ob []sqlite3.InfoOrderBy) (*sqlite3.IndexResult, error) {
+	used := make([]bool, len(cst))
+	for c, constraint := range cst {
+		switch {
+		case constraint.Usable && constraint.Column == 0 && constraint.Op == sqlite3.OpEQ:
+			used[c] = true
+			return &sqlite3.IndexResult{Used: used, IdxNum: 1, IdxStr: "commit-files-by-commit-id", EstimatedCost: 1.0, EstimatedRows: 1}, nil
+		}
+	}
+
+	return &sqlite3.IndexResult{Used: used, EstimatedCost: 100}, nil
+}
+
+func (v *gitCommitFilesTable) Disconnect() error {
+	v.repo = nil
+	return nil
+}
+func (v *gitCommitFilesTable) Destroy() error { return nil }
+
+type commitFilesCursor struct {
+	repo     *git.Repository
+	limit    *commitLimit
+	iterator *commitStatsIter
+	current  *commitStat
+	rowid    int64
+}
+
+func (vc *commitFilesCursor) Column(c *sqlite3.SQLiteContext, col int) error {
+	file := vc.current
+	switch col {
+	case 0:
+		//commit id
+		c.ResultText(file.commitID)
+	case 1:
+		//old path, there is none for an added file
+		if file.status == git.DeltaAdded {
+			c.ResultNull()
+		} else {
+			c.ResultText(file.oldFile)
+		}
+	case 2:
+		//new path, there is none for a deleted file
+		if file.status == git.DeltaDeleted {
+			c.ResultNull()
+		} else {
+			c.ResultText(file.file)
+		}
+	case 3:
+		c.ResultText(deltaStatus(file.status))
+	case 4:
+		//similarity is only meaningful for renames and copies
+		if file.status == git.DeltaRenamed || file.status == git.DeltaCopied {
+			c.ResultInt(int(file.similarity))
+		} else {
+			c.ResultNull()
+		}
+	case 5:
+		c.ResultInt(file.additions)
+	case 6:
+		c.ResultInt(file.deletions)
+	case 7:
+		c.ResultBool(file.isBinary)
+	case 8:
+		resultFileMode(c, file.oldMode)
+	case 9:
+		resultFileMode(c, file.newMode)
+	}
+
+	return nil
+}
+
+func (vc *commitFilesCursor) Filter(idxNum int, idxStr string, vals []interface{}) error {
+	vc.rowid = 0
+	var opt *commitStatsIterOptions
+
+	switch idxNum {
+	case 0:
+		opt = &commitStatsIterOptions{limit: vc.limit}
+	case 1:
+		opt = &commitStatsIterOptions{commitID: vals[0].(string), limit: vc.limit}
+	}
+
+	iter, err := NewCommitStatsIter(vc.repo, opt)
+	if err != nil {
+		return err
+	}
+
+	vc.iterator = iter
+
+	file, err := vc.iterator.Next()
+	if err != nil {
+		if err == io.EOF {
+			vc.current = nil
+			return nil
+		}
+		return err
+	}
+
+	vc.current = file
+	return nil
+}
+
+func (vc *commitFilesCursor) Next() error {
+	vc.rowid++
+	file, err := vc.iterator.Next()
+	if err != nil {
+		if err == io.EOF {
+			vc.current = nil
+			return nil
+		}
+		return err
+	}
+	vc.current = file
+	return nil
+}
+
+func (vc *commitFilesCursor) EOF() bool {
+	return vc.current == nil
+}
+
+func (vc *commitFilesCursor) Rowid() (int64, error) {
+	return vc.rowid, nil
+}
+
+func (vc *commitFilesCursor) Close() error {
+	vc.iterator.Close()
+	return nil
+}
@@ -0,0 +1,94 @@
+package gitqlite
+
+import (
+	"testing"
+)
+
+func TestCommitFiles(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := instance.DB.Query("SELECT * FROM commit_files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(columns) != 10 {
+		t.Fatalf("expected 10 columns, got %d", len(columns))
+	}
+
+	_, contents, err := GetContents(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statRows, err := instance.DB.Query("SELECT * FROM stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer statRows.Close()
+
+	statCount := GetRowsCount(statRows)
+	if len(contents) != statCount {
+		t.Fatalf("expected %d commit files (one per stats row), got %d", statCount, len(contents))
+	}
+
+	for i, c := range contents {
+		switch c[3] {
+		case "A":
+			if c[1] != "NULL" {
+				t.Fatalf("expected no old path for added file at row %d, got %s", i, c[1])
+			}
+		case "D":
+			if c[2] != "NULL" {
+				t.Fatalf("expected no new path for deleted file at row %d, got %s", i, c[2])
+			}
+		case "R":
+			if c[4] == "NULL" {
+				t.Fatalf("expected a similarity for renamed file at row %d", i)
+			}
+		}
+	}
+}
+
+func TestCommitFilesCommitIDIndex(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := instance.DB.Query("SELECT DISTINCT commit_id FROM commit_files WHERE commit_id = (SELECT id FROM commits LIMIT 1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	if count := GetRowsCount(rows); count > 1 {
+		t.Fatalf("expected files of a single commit, got %d commits", count)
+	}
+}
+
+func BenchmarkCommitFiles(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		instance, err := New(fixtureRepoDir, &Options{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		rows, err := instance.DB.Query("SELECT * FROM commit_files")
+		if err != nil {
+			b.Fatal(err)
+		}
+		rowNum, _, err := GetContents(rows)
+		if err != nil {
+			b.Fatalf("err %d at row Number %d", err, rowNum)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package gitqlite
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	git "github.com/libgit2/git2go/v30"
+	"github.com/mattn/go-sqlite3"
+)
+
+type gitCommitIssueRefModule struct {
+	repos *repoPool
+}
+
+type gitCommitIssueRefTable struct {
+	repoPath string
+	repos    *repoPool
+	repo     *git.Repository
+}
+
+func (m *gitCommitIssueRefModule) Create(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	err := c.DeclareVTab(fmt.Sprintf(`
+		CREATE TABLE %q (
+			commit_id TEXT,
+			issue TEXT,
+			tracker TEXT,
+			number INT,
+			closes BOOL,
+			ref TEXT HIDDEN,
+			pattern TEXT HIDDEN
+		)`, args[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	// the repoPath will be enclosed in double quotes "..." since createTables uses %q when setting up the table
+	// we need to pop those off when referring to the actual directory in the fs
+	repoPath := args[3][1 : len(args[3])-1]
+	return &gitCommitIssueRefTable{repoPath: repoPath, repos: m.repos}, nil
+}
+
+func (m *gitCommitIssueRefModule) Connect(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	return m.Create(c, args)
+}
+
+func (m *gitCommitIssueRefModule) DestroyModule() {}
+
+func (v *gitCommitIssueRefTable) Open() (sqlite3.VTabCursor, error) {
+	repo, err := v.repos.open(v.repoPath)
+	if err != nil {
+		return nil, err
+	}
+	v.repo = repo
+
+	return &commitIssueRefCursor{repo: v.repo, limit: v.repos.limit}, nil
+}
+
+func (v *gitCommitIssueRefTable) BestIndex(cst []sqlite3.InfoConstraint, ob []sqlite3.InfoOrderBy) (*sqlite3.IndexResult, error) {
+	used := make([]bool, len(cst))
+	// the values of used constraints are passed to Filter in the order they appear in cst,
+	// so the names of the constrained columns are passed along in that same order in the IdxStr
+	columns := make([]string, 0)
+	for c, constraint := range cst {
+		if !constraint.Usable || constraint.Op != sqlite3.OpEQ {
+			continue
+		}
+		switch {
+		case constraint.Column == 5 && !contains(columns, "ref"):
+			used[c] = true
+			columns = append(columns, "ref")
+		case constraint.Column == 6 && !contains(columns, "pattern"):
+			used[c] = true
+			columns = append(columns, "pattern")
+		}
+	}
+
+	return &sqlite3.IndexResult{Used: used, IdxNum: len(columns), IdxStr: strings.Join(columns, ",")}, nil
+}
+
+func (v *gitCommitIssueRefTable) Disconnect() error {
+	v.repo = nil
+	return nil
+}
+func (v *gitCommitIssueRefTable) Destroy() error { return nil }
+
+// issueRef is a reference to an issue in a commit message
+type issueRef struct {
+	// issue is the issue as referenced (i.e. #123 or JIRA-456), tracker what it's tracked by (github, jira or custom),
+	// and number its number, 0 if it has none
+	issue   string
+	tracker string
+	number  int
+	// closes is whether a closing keyword precedes the reference (i.e. Fixes #123), which closes GitHub issues
+	closes bool
+}
+
+// issuePatterns match the issues referenced by default, their first group being the reference
+var issuePatterns = []struct {
+	tracker string
+	pattern *regexp.Regexp
+}{
+	// #123 and GH-123, but not HTML entities (&#39;), anchors of URLs or headings (##)
+	{"github", regexp.MustCompile(`(?:^|[^\w&/#])((?:#|GH-)\d+)\b`)},
+	// JIRA-456, the key of the project in capitals
+	{"jira", regexp.MustCompile(`\b([A-Z][A-Z0-9]+-\d+)\b`)},
+}
+
+// notJiraKeys are the prefixes of names looking like issue keys which aren't (UTF-8, SHA-256...)
+var notJiraKeys = map[string]bool{
+	"UTF": true, "SHA": true, "ISO": true, "RFC": true, "CVE": true, "PEP": true, "GH": true,
+	"GPL": true, "LGPL": true, "AGPL": true, "MPL": true, "APACHE": true, "BSD": true, "CC": true,
+}
+
+// closingKeyword matches the keywords closing the issue referenced right after them, like GitHub does
+var closingKeyword = regexp.MustCompile(`(?i)\b(?:close[sd]?|fix(?:e[sd])?|resolve[sd]?):?\s*$`)
+
+// trailingNumber matches the number ending a reference
+var trailingNumber = regexp.MustCompile(`(\d+)$`)
+
+// issueRefs finds the issues a commit message references, each one once. If pattern isn't nil, the references are its matches
+// (its first group if it has one) rather than the default ones. The pull request a commit landed with (see pullRequestNumber)
+// isn't an issue it references.
+func issueRefs(message string, pattern *regexp.Regexp) []*issueRef {
+	refs := make([]*issueRef, 0)
+	seen := make(map[string]bool)
+	add := func(message string, start, end int, tracker string) {
+		issue := message[start:end]
+		if tracker == "github" {
+			issue = "#" + strings.TrimPrefix(issue, "GH-")
+		}
+		if seen[issue] {
+			return
+		}
+		seen[issue] = true
+
+		ref := &issueRef{issue: issue, tracker: tracker, closes: closingKeyword.MatchString(message[:start])}
+		if match := trailingNumber.FindString(issue); match != "" {
+			ref.number, _ = strconv.Atoi(match)
+		}
+		refs = append(refs, ref)
+	}
+
+	if pattern != nil {
+		for _, match := range pattern.FindAllStringSubmatchIndex(message, -1) {
+			if len(match) > 2 && match[2] >= 0 {
+				add(message, match[2], match[3], "custom")
+			} else {
+				add(message, match[0], match[1], "custom")
+			}
+		}
+		return refs
+	}
+
+	pr := pullRequestNumber(message)
+	for _, p := range issuePatterns {
+		for _, match := range p.pattern.FindAllStringSubmatchIndex(message, -1) {
+			issue := message[match[2]:match[3]]
+			if p.tracker == "jira" && notJiraKeys[issue[:strings.Index(issue, "-")]] {
+				continue
+			}
+			if p.tracker == "github" && pr != 0 && strings.TrimLeft(issue, "#GH-") == strconv.Itoa(pr) {
+				continue
+			}
+			add(message, match[2], match[3], p.tracker)
+		}
+	}
+	return refs
+}
+
+type commitIssueRefCursor struct {
+	repo    *git.Repository
+	limit   *commitLimit
+	walk    commitWalker
+	pattern *regexp.Regexp
+	// the values of the constraints on the hidden columns, returned as is so that SQLite's own check of the constraints passes
+	ref, patternValue interface{}
+	commitID          string
+	refs              []*issueRef
+	index             int
+	rowid             int64
+}
+
+func (vc *commitIssueRefCursor) Column(c *sqlite3.SQLiteContext, col int) error {
+	ref := vc.refs[vc.index]
+
+	switch col {
+	case 0:
+		c.ResultText(vc.commitID)
+	case 1:
+		c.ResultText(ref.issue)
+	case 2:
+		c.ResultText(ref.tracker)
+	case 3:
+		if ref.number == 0 {
+			c.ResultNull()
+		} else {
+			c.ResultInt(ref.number)
+		}
+	case 4:
+		c.ResultBool(ref.closes)
+	case 5:
+		resultConstraint(c, vc.ref)
+	case 6:
+		resultConstraint(c, vc.patternValue)
+	}
+	return nil
+}
+
+func (vc *commitIssueRefCursor) Filter(idxNum int, idxStr string, vals []interface{}) error {
+	vc.rowid = 0
+	vc.ref, vc.patternValue, vc.pattern = nil, nil, nil
+	if vc.walk != nil {
+		vc.walk.Free()
+		vc.walk = nil
+	}
+
+	if idxNum > 0 {
+		for i, column := range strings.Split(idxStr, ",") {
+			switch column {
+			case "ref":
+				vc.ref = vals[i]
+			case "pattern":
+				pattern, ok := vals[i].(string)
+				if !ok {
+					return fmt.Errorf("pattern must be a regular expression, got %v", vals[i])
+				}
+				compiled, err := regexp.Compile(pattern)
+				if err != nil {
+					return fmt.Errorf("invalid pattern %s: %v", pattern, err)
+				}
+				vc.patternValue = pattern
+				vc.pattern = compiled
+			}
+		}
+	}
+
+	vc.refs = nil
+	vc.index = 0
+
+	var walk commitWalker
+	if ref, ok := vc.ref.(string); ok {
+		var err error
+		walk, err = walkRef(vc.repo, ref, vc.limit)
+		if err != nil {
+			return err
+		}
+	} else {
+		// there is no commit to read if HEAD is unborn
+		unborn, err := vc.repo.IsHeadUnborn()
+		if err != nil {
+			return err
+		}
+		if unborn {
+			return nil
+		}
+		walk, err = walkHead(vc.repo, vc.limit)
+		if err != nil {
+			return err
+		}
+	}
+	vc.walk = walk
+
+	return vc.nextCommit()
+}
+
+// nextCommit moves the cursor to the issue references of the next commit referencing any
+func (vc *commitIssueRefCursor) nextCommit() error {
+	id := new(git.Oid)
+	for {
+		err := vc.walk.Next(id)
+		if err != nil {
+			if id.IsZero() {
+				vc.refs = nil
+				vc.index = 0
+				return nil
+			}
+			return err
+		}
+
+		commit, err := vc.repo.LookupCommit(id)
+		if err != nil {
+			return err
+		}
+		refs := issueRefs(commit.Message(), vc.pattern)
+		commit.Free()
+		if len(refs) == 0 {
+			*id = git.Oid{}
+			continue
+		}
+
+		vc.commitID = id.String()
+		vc.refs = refs
+		vc.index = 0
+		return nil
+	}
+}
+
+func (vc *commitIssueRefCursor) Next() error {
+	vc.rowid++
+	vc.index++
+	if vc.index < len(vc.refs) {
+		return nil
+	}
+	return vc.nextCommit()
+}
+
+func (vc *commitIssueRefCursor) EOF() bool {
+	return vc.index >= len(vc.refs)
+}
+
+func (vc *commitIssueRefCursor) Rowid() (int64, error) {
+	return vc.rowid, nil
+}
+
+func (vc *commitIssueRefCursor) Close() error {
+	if vc.walk != nil {
+		vc.walk.Free()
+		vc.walk = nil
+	}
+	return nil
+}
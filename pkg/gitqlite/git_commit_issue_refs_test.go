@@ -0,0 +1,87 @@
+package gitqlite
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestIssueRefs(t *testing.T) {
+	tests := []struct {
+		message string
+		pattern string
+		issues  []string
+		closes  []string
+	}{
+		{"Fix a crash\n\nFixes #12, see also GH-34 and #12", "", []string{"#12", "#34"}, []string{"#12"}},
+		{"PROJ-7: handle UTF-8 and SHA-256\n\nResolves: PROJ-8", "", []string{"PROJ-7", "PROJ-8"}, []string{"PROJ-8"}},
+		{"Merge pull request #56 from user/branch\n\nCloses #55", "", []string{"#55"}, []string{"#55"}},
+		{"Add a feature (#78)", "", nil, nil},
+		{"Escape &#39; in https://example.com/page#12\n\n## 3 notes", "", nil, nil},
+		{"Fix bug 1234 and Bug 99", `(?i)bug (\d+)`, []string{"1234", "99"}, nil},
+	}
+
+	for _, test := range tests {
+		var pattern *regexp.Regexp
+		if test.pattern != "" {
+			pattern = regexp.MustCompile(test.pattern)
+		}
+		issues := make([]string, 0)
+		closes := make([]string, 0)
+		for _, ref := range issueRefs(test.message, pattern) {
+			issues = append(issues, ref.issue)
+			if ref.closes {
+				closes = append(closes, ref.issue)
+			}
+			if ref.number == 0 {
+				t.Fatalf("expected %s referenced by %q to have a number", ref.issue, test.message)
+			}
+		}
+		if len(issues) != len(test.issues) || len(closes) != len(test.closes) {
+			t.Fatalf("expected %q to reference %v and close %v, got %v and %v", test.message, test.issues, test.closes, issues, closes)
+		}
+		for i, issue := range test.issues {
+			if issues[i] != issue {
+				t.Fatalf("expected %q to reference %v, got %v", test.message, test.issues, issues)
+			}
+		}
+	}
+}
+
+func TestCommitIssueRefs(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the references are the ones of the messages of the commits
+	var rows int
+	err = instance.DB.QueryRow(`
+		SELECT count(*) FROM commit_issue_refs
+		LEFT JOIN commits ON commits.id = commit_issue_refs.commit_id
+		WHERE commits.id IS NULL OR tracker NOT IN ('github', 'jira') OR number IS NULL`).Scan(&rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows != 0 {
+		t.Fatalf("expected references of the commits of the history, got %d unexpected ones", rows)
+	}
+
+	// a pattern matching the first word of every message references every commit once
+	var commits, matched int
+	err = instance.DB.QueryRow("SELECT count(*) FROM commits").Scan(&commits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = instance.DB.QueryRow(`SELECT count(DISTINCT commit_id) FROM commit_issue_refs WHERE pattern = '\A\S+' AND tracker = 'custom'`).Scan(&matched)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if matched != commits {
+		t.Fatalf("expected %d commits to match, got %d", commits, matched)
+	}
+
+	err = instance.DB.QueryRow("SELECT count(*) FROM commit_issue_refs WHERE pattern = '('").Scan(&matched)
+	if err == nil {
+		t.Fatal("expected an invalid pattern to fail")
+	}
+}
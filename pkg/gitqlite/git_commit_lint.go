@@ -0,0 +1,358 @@
+package gitqlite
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	git "github.com/libgit2/git2go/v30"
+	"github.com/mattn/go-sqlite3"
+)
+
+type gitCommitLintModule struct {
+	repos *repoPool
+}
+
+type gitCommitLintTable struct {
+	repoPath string
+	repos    *repoPool
+	repo     *git.Repository
+}
+
+func (m *gitCommitLintModule) Create(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	err := c.DeclareVTab(fmt.Sprintf(`
+		CREATE TABLE %q (
+			commit_id TEXT,
+			summary TEXT,
+			rule TEXT,
+			passed BOOL,
+			detail TEXT,
+			ref TEXT HIDDEN,
+			max_subject_length INT HIDDEN,
+			trailer TEXT HIDDEN
+		)`, args[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	// the repoPath will be enclosed in double quotes "..." since createTables uses %q when setting up the table
+	// we need to pop those off when referring to the actual directory in the fs
+	repoPath := args[3][1 : len(args[3])-1]
+	return &gitCommitLintTable{repoPath: repoPath, repos: m.repos}, nil
+}
+
+func (m *gitCommitLintModule) Connect(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	return m.Create(c, args)
+}
+
+func (m *gitCommitLintModule) DestroyModule() {}
+
+func (v *gitCommitLintTable) Open() (sqlite3.VTabCursor, error) {
+	repo, err := v.repos.open(v.repoPath)
+	if err != nil {
+		return nil, err
+	}
+	v.repo = repo
+
+	return &commitLintCursor{repo: v.repo, limit: v.repos.limit}, nil
+}
+
+func (v *gitCommitLintTable) BestIndex(cst []sqlite3.InfoConstraint, ob []sqlite3.InfoOrderBy) (*sqlite3.IndexResult, error) {
+	used := make([]bool, len(cst))
+	// the values of used constraints are passed to Filter in the order they appear in cst,
+	// so the names of the constrained columns are passed along in that same order in the IdxStr
+	columns := make([]string, 0)
+	for c, constraint := range cst {
+		if !constraint.Usable || constraint.Op != sqlite3.OpEQ {
+			continue
+		}
+		switch {
+		case constraint.Column == 5 && !contains(columns, "ref"):
+			used[c] = true
+			columns = append(columns, "ref")
+		case constraint.Column == 6 && !contains(columns, "max_subject_length"):
+			used[c] = true
+			columns = append(columns, "max_subject_length")
+		case constraint.Column == 7 && !contains(columns, "trailer"):
+			used[c] = true
+			columns = append(columns, "trailer")
+		}
+	}
+
+	return &sqlite3.IndexResult{Used: used, IdxNum: len(columns), IdxStr: strings.Join(columns, ",")}, nil
+}
+
+func (v *gitCommitLintTable) Disconnect() error {
+	v.repo = nil
+	return nil
+}
+func (v *gitCommitLintTable) Destroy() error { return nil }
+
+// defaultMaxSubjectLength is the length subjects are limited to unless max_subject_length is constrained,
+// the width git tools and forges display them in
+const defaultMaxSubjectLength = 72
+
+// lintRules configure the rules commit messages are linted with
+type lintRules struct {
+	maxSubjectLength int
+	// trailer is the trailer every message must have (i.e. Signed-off-by), its rule is skipped if empty
+	trailer string
+}
+
+// lintResult is the result of a rule for a commit message, detail explains why it failed
+type lintResult struct {
+	rule   string
+	passed bool
+	detail string
+}
+
+// conventionalSubject matches the subjects of conventional commits, i.e. "feat(parser)!: add arrays"
+var conventionalSubject = regexp.MustCompile(`^[a-z]+(\([^()]+\))?!?: \S`)
+
+// conventionalType matches the type prefix of a conventional subject, which isn't part of the sentence checked for mood
+var conventionalType = regexp.MustCompile(`^[a-z]+(\([^()]+\))?!?: `)
+
+// imperativeExceptions are verbs in the imperative mood which look like they aren't
+var imperativeExceptions = map[string]bool{
+	"embed": true, "shred": true, "bring": true, "string": true, "ping": true, "sing": true,
+	"process": true, "focus": true, "bias": true, "alias": true, "canvas": true, "bypass": true,
+}
+
+// imperative tells whether the first word of a subject looks like an imperative verb (fix rather than fixed, fixes or fixing)
+func imperative(subject string) (bool, string) {
+	fields := strings.Fields(conventionalType.ReplaceAllString(subject, ""))
+	if len(fields) == 0 {
+		return true, ""
+	}
+	word := strings.ToLower(strings.Trim(fields[0], ".,:;!?\"'`"))
+	if imperativeExceptions[word] || strings.HasSuffix(word, "eed") || strings.HasSuffix(word, "ss") || strings.HasSuffix(word, "us") {
+		return true, word
+	}
+	imperative := !strings.HasSuffix(word, "ed") && !strings.HasSuffix(word, "ing") && !(len(word) > 3 && strings.HasSuffix(word, "s"))
+	return imperative, word
+}
+
+// hasTrailer tells whether the last paragraph of a message has a trailer line with the given key, like git interpret-trailers
+func hasTrailer(message, key string) bool {
+	paragraphs := strings.Split(strings.TrimSpace(message), "\n\n")
+	if len(paragraphs) < 2 {
+		return false
+	}
+	prefix := strings.ToLower(key) + ":"
+	for _, line := range strings.Split(paragraphs[len(paragraphs)-1], "\n") {
+		if strings.HasPrefix(strings.ToLower(line), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// lint checks a commit message against rules
+func lint(message string, rules *lintRules) []*lintResult {
+	subject := strings.SplitN(message, "\n", 2)[0]
+	results := make([]*lintResult, 0, 4)
+
+	length := len([]rune(subject))
+	result := &lintResult{rule: "subject_length", passed: length <= rules.maxSubjectLength}
+	if !result.passed {
+		result.detail = fmt.Sprintf("the subject is %d characters long, more than %d", length, rules.maxSubjectLength)
+	}
+	results = append(results, result)
+
+	passed, word := imperative(subject)
+	result = &lintResult{rule: "imperative_mood", passed: passed}
+	if !passed {
+		result.detail = fmt.Sprintf("the subject starts with %q, which doesn't look imperative", word)
+	}
+	results = append(results, result)
+
+	result = &lintResult{rule: "conventional", passed: conventionalSubject.MatchString(subject)}
+	if !result.passed {
+		result.detail = "the subject isn't formatted like type(scope): description"
+	}
+	results = append(results, result)
+
+	if rules.trailer != "" {
+		result = &lintResult{rule: "trailer", passed: hasTrailer(message, rules.trailer)}
+		if !result.passed {
+			result.detail = fmt.Sprintf("the message has no %s trailer", rules.trailer)
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
+
+type commitLintCursor struct {
+	repo  *git.Repository
+	limit *commitLimit
+	walk  commitWalker
+	rules *lintRules
+	// the values of the constraints on the hidden columns, returned as is so that SQLite's own check of the constraints passes
+	ref, maxSubjectLength, trailer interface{}
+	commitID                       string
+	summary                        string
+	results                        []*lintResult
+	index                          int
+	rowid                          int64
+}
+
+func (vc *commitLintCursor) Column(c *sqlite3.SQLiteContext, col int) error {
+	result := vc.results[vc.index]
+
+	switch col {
+	case 0:
+		c.ResultText(vc.commitID)
+	case 1:
+		c.ResultText(vc.summary)
+	case 2:
+		c.ResultText(result.rule)
+	case 3:
+		c.ResultBool(result.passed)
+	case 4:
+		if result.detail == "" {
+			c.ResultNull()
+		} else {
+			c.ResultText(result.detail)
+		}
+	case 5:
+		resultConstraint(c, vc.ref)
+	case 6:
+		resultConstraint(c, vc.maxSubjectLength)
+	case 7:
+		resultConstraint(c, vc.trailer)
+	}
+	return nil
+}
+
+// resultConstraint sets the result of a hidden column to the value it's constrained to, NULL if it isn't
+func resultConstraint(c *sqlite3.SQLiteContext, val interface{}) {
+	switch v := val.(type) {
+	case string:
+		c.ResultText(v)
+	case int64:
+		c.ResultInt64(v)
+	default:
+		c.ResultNull()
+	}
+}
+
+func (vc *commitLintCursor) Filter(idxNum int, idxStr string, vals []interface{}) error {
+	vc.rowid = 0
+	vc.ref, vc.maxSubjectLength, vc.trailer = nil, nil, nil
+	vc.rules = &lintRules{maxSubjectLength: defaultMaxSubjectLength}
+	if vc.walk != nil {
+		vc.walk.Free()
+		vc.walk = nil
+	}
+
+	if idxNum > 0 {
+		for i, column := range strings.Split(idxStr, ",") {
+			switch column {
+			case "ref":
+				vc.ref = vals[i]
+			case "max_subject_length":
+				length, ok := vals[i].(int64)
+				if !ok {
+					return fmt.Errorf("max_subject_length must be an integer, got %v", vals[i])
+				}
+				vc.maxSubjectLength = length
+				vc.rules.maxSubjectLength = int(length)
+			case "trailer":
+				trailer, ok := vals[i].(string)
+				if !ok {
+					return fmt.Errorf("trailer must be a trailer key, got %v", vals[i])
+				}
+				vc.trailer = trailer
+				vc.rules.trailer = trailer
+			}
+		}
+	}
+
+	vc.results = nil
+	vc.index = 0
+
+	var walk commitWalker
+	if ref, ok := vc.ref.(string); ok {
+		var err error
+		walk, err = walkRef(vc.repo, ref, vc.limit)
+		if err != nil {
+			return err
+		}
+	} else {
+		// there is no commit to lint if HEAD is unborn
+		unborn, err := vc.repo.IsHeadUnborn()
+		if err != nil {
+			return err
+		}
+		if unborn {
+			return nil
+		}
+		walk, err = walkHead(vc.repo, vc.limit)
+		if err != nil {
+			return err
+		}
+	}
+	vc.walk = walk
+
+	return vc.nextCommit()
+}
+
+// nextCommit moves the cursor to the results of the next commit which isn't a merge, merges being written by tools
+func (vc *commitLintCursor) nextCommit() error {
+	id := new(git.Oid)
+	for {
+		err := vc.walk.Next(id)
+		if err != nil {
+			if id.IsZero() {
+				vc.results = nil
+				vc.index = 0
+				return nil
+			}
+			return err
+		}
+
+		commit, err := vc.repo.LookupCommit(id)
+		if err != nil {
+			return err
+		}
+		merge := commit.ParentCount() > 1
+		message := commit.Message()
+		commit.Free()
+		if merge {
+			*id = git.Oid{}
+			continue
+		}
+
+		vc.commitID = id.String()
+		vc.summary = summary(message)
+		vc.results = lint(message, vc.rules)
+		vc.index = 0
+		return nil
+	}
+}
+
+func (vc *commitLintCursor) Next() error {
+	vc.rowid++
+	vc.index++
+	if vc.index < len(vc.results) {
+		return nil
+	}
+	return vc.nextCommit()
+}
+
+func (vc *commitLintCursor) EOF() bool {
+	return vc.index >= len(vc.results)
+}
+
+func (vc *commitLintCursor) Rowid() (int64, error) {
+	return vc.rowid, nil
+}
+
+func (vc *commitLintCursor) Close() error {
+	if vc.walk != nil {
+		vc.walk.Free()
+		vc.walk = nil
+	}
+	return nil
+}
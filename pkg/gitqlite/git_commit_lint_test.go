@@ -0,0 +1,82 @@
+package gitqlite
+
+import (
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	rules := &lintRules{maxSubjectLength: 20, trailer: "Signed-off-by"}
+
+	tests := []struct {
+		message string
+		failed  []string
+	}{
+		{"feat(parser): add arrays\n\nSigned-off-by: someone <someone@example.com>\n", []string{"subject_length"}},
+		{"fix: embed the assets\n\nSigned-off-by: someone <someone@example.com>\n", []string{"subject_length"}},
+		{"Added a table\n", []string{"conventional", "imperative_mood", "trailer"}},
+		{"fix: fixes a crash\n", []string{"imperative_mood", "trailer"}},
+		{"chore: process\n\nsigned-off-by: someone\n", nil},
+	}
+
+	for _, test := range tests {
+		failed := make([]string, 0)
+		for _, result := range lint(test.message, rules) {
+			if !result.passed {
+				if result.detail == "" {
+					t.Fatalf("expected rule %s failed by %q to be explained", result.rule, test.message)
+				}
+				failed = append(failed, result.rule)
+			}
+		}
+		if len(failed) != len(test.failed) {
+			t.Fatalf("expected %q to fail %v, got %v", test.message, test.failed, failed)
+		}
+		for _, rule := range test.failed {
+			if !contains(failed, rule) {
+				t.Fatalf("expected %q to fail %v, got %v", test.message, test.failed, failed)
+			}
+		}
+	}
+}
+
+func TestCommitLint(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every commit which isn't a merge is linted once per rule
+	var commits, rows int
+	err = instance.DB.QueryRow("SELECT count(*) FROM commits WHERE NOT is_merge").Scan(&commits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = instance.DB.QueryRow("SELECT count(*) FROM commit_lint").Scan(&rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows != 3*commits {
+		t.Fatalf("expected %d rows for %d commits, got %d", 3*commits, commits, rows)
+	}
+
+	// constraining trailer adds its rule, and a longer max_subject_length fails fewer commits
+	err = instance.DB.QueryRow("SELECT count(*) FROM commit_lint WHERE trailer = 'Signed-off-by' AND rule = 'trailer'").Scan(&rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows != commits {
+		t.Fatalf("expected %d trailer rows, got %d", commits, rows)
+	}
+	var short, long int
+	err = instance.DB.QueryRow("SELECT count(*) FROM commit_lint WHERE max_subject_length = 10 AND rule = 'subject_length' AND NOT passed").Scan(&short)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = instance.DB.QueryRow("SELECT count(*) FROM commit_lint WHERE max_subject_length = 1000 AND rule = 'subject_length' AND NOT passed").Scan(&long)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if short == 0 || long != 0 {
+		t.Fatalf("expected subjects to be longer than 10 characters and shorter than 1000, got %d and %d failures", short, long)
+	}
+}
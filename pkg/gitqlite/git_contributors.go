@@ -0,0 +1,229 @@
+package gitqlite
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	git "github.com/libgit2/git2go/v30"
+	"github.com/mattn/go-sqlite3"
+)
+
+type gitContributorModule struct {
+	repos *repoPool
+}
+
+type gitContributorTable struct {
+	repoPath        string
+	repos           *repoPool
+	repo            *git.Repository
+	excludeVendored bool
+}
+
+func (m *gitContributorModule) Create(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	err := c.DeclareVTab(fmt.Sprintf(`
+		CREATE TABLE %q (
+			name TEXT,
+			email TEXT,
+			commit_count INT,
+			additions INT,
+			deletions INT,
+			first_commit DATETIME,
+			last_commit DATETIME
+		)`, args[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	// the repoPath will be enclosed in double quotes "..." since createTables uses %q when setting up the table
+	// we need to pop those off when referring to the actual directory in the fs
+	repoPath := args[3][1 : len(args[3])-1]
+	return &gitContributorTable{repoPath: repoPath, repos: m.repos, excludeVendored: moduleOption(args, "exclude_vendored")}, nil
+}
+
+func (m *gitContributorModule) Connect(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	return m.Create(c, args)
+}
+
+func (m *gitContributorModule) DestroyModule() {}
+
+func (v *gitContributorTable) Open() (sqlite3.VTabCursor, error) {
+	repo, err := v.repos.open(v.repoPath)
+	if err != nil {
+		return nil, err
+	}
+	v.repo = repo
+
+	return &contributorCursor{repo: v.repo, limit: v.repos.limit, excludeVendored: v.excludeVendored}, nil
+}
+
+func (v *gitContributorTable) BestIndex(cst []sqlite3.InfoConstraint, ob []sqlite3.InfoOrderBy) (*sqlite3.IndexResult, error) {
+	// the whole history needs to be walked whatever the constraints
+	dummy := make([]bool, len(cst))
+	return &sqlite3.IndexResult{Used: dummy}, nil
+}
+
+func (v *gitContributorTable) Disconnect() error {
+	v.repo = nil
+	return nil
+}
+func (v *gitContributorTable) Destroy() error { return nil }
+
+// contributor is an author of commits, as identified by `git shortlog -se` (after applying the .mailmap)
+type contributor struct {
+	name        string
+	email       string
+	commits     int
+	additions   int
+	deletions   int
+	firstCommit time.Time
+	lastCommit  time.Time
+}
+
+type contributorCursor struct {
+	repo            *git.Repository
+	limit           *commitLimit
+	index           int
+	contributors    []*contributor
+	excludeVendored bool
+}
+
+func (vc *contributorCursor) Column(c *sqlite3.SQLiteContext, col int) error {
+	contributor := vc.contributors[vc.index]
+
+	switch col {
+	case 0:
+		c.ResultText(contributor.name)
+	case 1:
+		c.ResultText(contributor.email)
+	case 2:
+		c.ResultInt(contributor.commits)
+	case 3:
+		c.ResultInt(contributor.additions)
+	case 4:
+		c.ResultInt(contributor.deletions)
+	case 5:
+		c.ResultText(contributor.firstCommit.Format(time.RFC3339Nano))
+	case 6:
+		c.ResultText(contributor.lastCommit.Format(time.RFC3339Nano))
+	}
+	return nil
+}
+
+func (vc *contributorCursor) Filter(idxNum int, idxStr string, vals []interface{}) error {
+	var exclude func(file string) bool
+	if vc.excludeVendored {
+		var err error
+		exclude, err = excludedFiles(vc.repo)
+		if err != nil {
+			return err
+		}
+	}
+
+	contributors, err := shortlog(vc.repo, exclude, vc.limit)
+	if err != nil {
+		return err
+	}
+
+	vc.contributors = contributors
+	vc.index = 0
+
+	return nil
+}
+
+// shortlog walks the history of HEAD once, totaling the commits and changed lines of every author.
+// Authors are identified by name and email after applying the repository's .mailmap, like `git shortlog -se` does,
+// and listed by descending number of commits. The changes to files for which exclude returns true aren't counted, if it's set.
+func shortlog(repo *git.Repository, exclude func(file string) bool, limit *commitLimit) ([]*contributor, error) {
+	mailmap := readMailmap(repo)
+
+	contributors := make([]*contributor, 0)
+
+	// if HEAD is unborn (no commit yet) there are no contributors
+	unborn, err := repo.IsHeadUnborn()
+	if err != nil {
+		return nil, err
+	}
+	if unborn {
+		return contributors, nil
+	}
+
+	walk, err := walkHead(repo, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer walk.Free()
+
+	byIdentity := make(map[string]*contributor)
+	id := new(git.Oid)
+	for {
+		err := walk.Next(id)
+		if err != nil {
+			if id.IsZero() {
+				break
+			}
+			return nil, err
+		}
+
+		commit, err := repo.LookupCommit(id)
+		if err != nil {
+			return nil, err
+		}
+		commitStats, err := stats(commit, false, "")
+		if err != nil {
+			commit.Free()
+			return nil, err
+		}
+		commitStats = filterStats(commitStats, exclude)
+		author := commit.Author()
+		commit.Free()
+		*id = git.Oid{}
+
+		name, email := mailmap.resolve(author.Name, author.Email)
+
+		key := name + "\x00" + email
+		c, ok := byIdentity[key]
+		if !ok {
+			c = &contributor{name: name, email: email, firstCommit: author.When, lastCommit: author.When}
+			byIdentity[key] = c
+			contributors = append(contributors, c)
+		}
+		c.commits++
+		for _, stat := range commitStats {
+			c.additions += stat.additions
+			c.deletions += stat.deletions
+		}
+		if author.When.Before(c.firstCommit) {
+			c.firstCommit = author.When
+		}
+		if author.When.After(c.lastCommit) {
+			c.lastCommit = author.When
+		}
+	}
+
+	sort.Slice(contributors, func(i, j int) bool {
+		if contributors[i].commits != contributors[j].commits {
+			return contributors[i].commits > contributors[j].commits
+		}
+		return contributors[i].name < contributors[j].name
+	})
+
+	return contributors, nil
+}
+
+func (vc *contributorCursor) Next() error {
+	vc.index++
+	return nil
+}
+
+func (vc *contributorCursor) EOF() bool {
+	return vc.index >= len(vc.contributors)
+}
+
+func (vc *contributorCursor) Rowid() (int64, error) {
+	return int64(vc.index), nil
+}
+
+func (vc *contributorCursor) Close() error {
+	return nil
+}
@@ -0,0 +1,78 @@
+package gitqlite
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestContributors(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := instance.DB.Query("SELECT name, email, commit_count FROM contributors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, contents, err := GetContents(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+
+	// git shortlog lists "<count>\t<name> <<email>>" by descending count
+	cmd := exec.Command("git", "shortlog", "-sne", "HEAD")
+	cmd.Dir = fixtureRepoDir
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := make(map[string]int)
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), "\t", 2)
+		count, err := strconv.Atoi(parts[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected[parts[1]] = count
+	}
+
+	if len(contents) != len(expected) {
+		t.Fatalf("expected %d contributors, got %d", len(expected), len(contents))
+	}
+	previous := -1
+	for _, c := range contents {
+		identity := c[0] + " <" + c[1] + ">"
+		count, err := strconv.Atoi(c[2])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != expected[identity] {
+			t.Fatalf("expected %d commits for %s, got %d", expected[identity], identity, count)
+		}
+		if previous >= 0 && count > previous {
+			t.Fatalf("expected contributors by descending number of commits, got %d after %d", count, previous)
+		}
+		previous = count
+	}
+}
+
+func BenchmarkContributors(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		instance, err := New(fixtureRepoDir, &Options{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		rows, err := instance.DB.Query("SELECT * FROM contributors")
+		if err != nil {
+			b.Fatal(err)
+		}
+		rowNum, _, err := GetContents(rows)
+		if err != nil {
+			b.Fatalf("err %d at row Number %d", err, rowNum)
+		}
+	}
+}
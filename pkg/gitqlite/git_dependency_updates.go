@@ -0,0 +1,288 @@
+package gitqlite
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	git "github.com/libgit2/git2go/v30"
+	"github.com/mattn/go-sqlite3"
+	"gopkg.in/yaml.v2"
+)
+
+type gitDependencyUpdateModule struct {
+	repos *repoPool
+}
+
+type gitDependencyUpdateTable struct {
+	repoPath string
+	repos    *repoPool
+	repo     *git.Repository
+}
+
+func (m *gitDependencyUpdateModule) Create(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	err := c.DeclareVTab(fmt.Sprintf(`
+		CREATE TABLE %q (
+			tool TEXT,
+			present BOOL,
+			path TEXT,
+			ecosystems TEXT,
+			schedule TEXT,
+			extends TEXT,
+			automerge BOOL,
+			ref TEXT HIDDEN
+		)`, args[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	// the repoPath will be enclosed in double quotes "..." since createTables uses %q when setting up the table
+	// we need to pop those off when referring to the actual directory in the fs
+	repoPath := args[3][1 : len(args[3])-1]
+	return &gitDependencyUpdateTable{repoPath: repoPath, repos: m.repos}, nil
+}
+
+func (m *gitDependencyUpdateModule) Connect(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	return m.Create(c, args)
+}
+
+func (m *gitDependencyUpdateModule) DestroyModule() {}
+
+func (v *gitDependencyUpdateTable) Open() (sqlite3.VTabCursor, error) {
+	repo, err := v.repos.open(v.repoPath)
+	if err != nil {
+		return nil, err
+	}
+	v.repo = repo
+
+	return &dependencyUpdateCursor{repo: v.repo}, nil
+}
+
+func (v *gitDependencyUpdateTable) BestIndex(cst []sqlite3.InfoConstraint, ob []sqlite3.InfoOrderBy) (*sqlite3.IndexResult, error) {
+	used := make([]bool, len(cst))
+	// the values of used constraints are passed to Filter in the order they appear in cst,
+	// so the names of the constrained columns are passed along in that same order in the IdxStr
+	columns := make([]string, 0)
+	for c, constraint := range cst {
+		if !constraint.Usable || constraint.Op != sqlite3.OpEQ {
+			continue
+		}
+		if constraint.Column == 7 && !contains(columns, "ref") {
+			used[c] = true
+			columns = append(columns, "ref")
+		}
+	}
+
+	return &sqlite3.IndexResult{Used: used, IdxNum: len(columns), IdxStr: strings.Join(columns, ",")}, nil
+}
+
+func (v *gitDependencyUpdateTable) Disconnect() error {
+	v.repo = nil
+	return nil
+}
+func (v *gitDependencyUpdateTable) Destroy() error { return nil }
+
+// dependencyUpdateTools are the dependency update tools, and the paths of their configuration files by order of precedence
+var dependencyUpdateTools = []struct {
+	tool  string
+	paths []string
+}{
+	{"dependabot", []string{".github/dependabot.yml", ".github/dependabot.yaml"}},
+	{"renovate", []string{
+		"renovate.json", "renovate.json5", ".github/renovate.json", ".github/renovate.json5",
+		".gitlab/renovate.json", ".gitlab/renovate.json5", ".renovaterc", ".renovaterc.json",
+	}},
+}
+
+// dependencyUpdateConfig is the configuration of a dependency update tool, its settings are "" (or nil) if it has none,
+// or if its file can't be parsed
+type dependencyUpdateConfig struct {
+	tool, path                    string
+	ecosystems, schedule, extends string
+	automerge                     interface{}
+}
+
+// distinctList returns a YAML scalar or sequence of scalars as a sorted, comma separated list of the distinct values
+func distinctList(values ...interface{}) string {
+	seen := make(map[string]bool)
+	for _, value := range values {
+		items, ok := value.([]interface{})
+		if !ok {
+			items = []interface{}{value}
+		}
+		for _, item := range items {
+			if s := stringValue(item); s != "" {
+				seen[s] = true
+			}
+		}
+	}
+	distinct := make([]string, 0, len(seen))
+	for s := range seen {
+		distinct = append(distinct, s)
+	}
+	sort.Strings(distinct)
+	return strings.Join(distinct, ",")
+}
+
+// parseDependencyUpdateConfig reads the settings of the configuration of tool, in a file at path.
+// Renovate configurations are JSON, which YAML parsers read too, unless they're JSON5 with comments.
+func parseDependencyUpdateConfig(tool, path string, contents []byte) *dependencyUpdateConfig {
+	config := &dependencyUpdateConfig{tool: tool, path: path}
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(contents, &doc); err != nil {
+		return config
+	}
+
+	switch tool {
+	case "dependabot":
+		// every update has its ecosystem (i.e. npm or gomod) and its schedule
+		var ecosystems, intervals []interface{}
+		updates, _ := mapValue(doc, "updates").([]interface{})
+		for _, update := range updates {
+			ecosystems = append(ecosystems, mapValue(update, "package-ecosystem"))
+			intervals = append(intervals, mapValue(mapValue(update, "schedule"), "interval"))
+		}
+		config.ecosystems = distinctList(ecosystems...)
+		config.schedule = distinctList(intervals...)
+	case "renovate":
+		// Renovate updates every ecosystem it supports, unless only some of its managers are enabled
+		config.ecosystems = distinctList(mapValue(doc, "enabledManagers"))
+		config.schedule = distinctList(mapValue(doc, "schedule"))
+		config.extends = distinctList(mapValue(doc, "extends"))
+		if automerge, ok := mapValue(doc, "automerge").(bool); ok {
+			config.automerge = automerge
+		}
+	}
+	return config
+}
+
+// dependencyUpdateConfigs returns the configuration of every dependency update tool committed to a tree,
+// a configuration without path if there is none
+func dependencyUpdateConfigs(repo *git.Repository, tree *git.Tree) ([]*dependencyUpdateConfig, error) {
+	configs := make([]*dependencyUpdateConfig, 0, len(dependencyUpdateTools))
+tools:
+	for _, t := range dependencyUpdateTools {
+		for _, path := range t.paths {
+			entry, err := tree.EntryByPath(path)
+			if err != nil {
+				if git.IsErrorCode(err, git.ErrNotFound) {
+					continue
+				}
+				return nil, err
+			}
+			if entry.Type != git.ObjectBlob {
+				continue
+			}
+
+			blob, err := repo.LookupBlob(entry.Id)
+			if err != nil {
+				return nil, err
+			}
+			configs = append(configs, parseDependencyUpdateConfig(t.tool, path, blob.Contents()))
+			blob.Free()
+			continue tools
+		}
+		configs = append(configs, &dependencyUpdateConfig{tool: t.tool})
+	}
+	return configs, nil
+}
+
+type dependencyUpdateCursor struct {
+	repo *git.Repository
+	// the value of the constraint on ref, returned as is so that SQLite's own check of the constraint passes
+	ref     interface{}
+	configs []*dependencyUpdateConfig
+	index   int
+}
+
+func (vc *dependencyUpdateCursor) Column(c *sqlite3.SQLiteContext, col int) error {
+	config := vc.configs[vc.index]
+	resultText := func(s string) {
+		if s == "" {
+			c.ResultNull()
+		} else {
+			c.ResultText(s)
+		}
+	}
+
+	switch col {
+	case 0:
+		c.ResultText(config.tool)
+	case 1:
+		c.ResultBool(config.path != "")
+	case 2:
+		resultText(config.path)
+	case 3:
+		resultText(config.ecosystems)
+	case 4:
+		resultText(config.schedule)
+	case 5:
+		resultText(config.extends)
+	case 6:
+		if automerge, ok := config.automerge.(bool); ok {
+			c.ResultBool(automerge)
+		} else {
+			c.ResultNull()
+		}
+	case 7:
+		resultConstraint(c, vc.ref)
+	}
+	return nil
+}
+
+func (vc *dependencyUpdateCursor) Filter(idxNum int, idxStr string, vals []interface{}) error {
+	vc.ref = nil
+	vc.configs = nil
+	vc.index = 0
+	if idxNum > 0 {
+		for i, column := range strings.Split(idxStr, ",") {
+			if column == "ref" {
+				vc.ref = vals[i]
+			}
+		}
+	}
+
+	ref, ok := vc.ref.(string)
+	if !ok {
+		// nothing is configured if HEAD is unborn
+		unborn, err := vc.repo.IsHeadUnborn()
+		if err != nil {
+			return err
+		}
+		if unborn {
+			return nil
+		}
+		ref = "HEAD"
+	}
+
+	commit, err := lookupRef(vc.repo, ref)
+	if err != nil {
+		return err
+	}
+	defer commit.Free()
+	tree, err := commit.Tree()
+	if err != nil {
+		return err
+	}
+	defer tree.Free()
+
+	vc.configs, err = dependencyUpdateConfigs(vc.repo, tree)
+	return err
+}
+
+func (vc *dependencyUpdateCursor) Next() error {
+	vc.index++
+	return nil
+}
+
+func (vc *dependencyUpdateCursor) EOF() bool {
+	return vc.index >= len(vc.configs)
+}
+
+func (vc *dependencyUpdateCursor) Rowid() (int64, error) {
+	return int64(vc.index), nil
+}
+
+func (vc *dependencyUpdateCursor) Close() error {
+	return nil
+}
@@ -0,0 +1,59 @@
+package gitqlite
+
+import (
+	"testing"
+)
+
+func TestParseDependencyUpdateConfig(t *testing.T) {
+	dependabot := parseDependencyUpdateConfig("dependabot", ".github/dependabot.yml", []byte(`
+version: 2
+updates:
+  - package-ecosystem: gomod
+    directory: /
+    schedule:
+      interval: weekly
+  - package-ecosystem: github-actions
+    directory: /
+    schedule:
+      interval: daily
+  - package-ecosystem: gomod
+    directory: /tools
+    schedule:
+      interval: weekly
+`))
+	if dependabot.ecosystems != "github-actions,gomod" || dependabot.schedule != "daily,weekly" || dependabot.automerge != nil {
+		t.Fatalf("expected the github-actions and gomod ecosystems updated daily and weekly, got %+v", dependabot)
+	}
+
+	renovate := parseDependencyUpdateConfig("renovate", "renovate.json", []byte(`{
+		"extends": ["config:base", ":semanticCommits"],
+		"schedule": "before 5am on monday",
+		"automerge": true
+	}`))
+	if renovate.extends != ":semanticCommits,config:base" || renovate.schedule != "before 5am on monday" || renovate.automerge != true || renovate.ecosystems != "" {
+		t.Fatalf("expected the settings of the Renovate configuration, got %+v", renovate)
+	}
+
+	// a configuration which can't be parsed is still present
+	broken := parseDependencyUpdateConfig("renovate", "renovate.json5", []byte("{\n  // comment\n  extends: ['config:base'],\n"))
+	if broken.path != "renovate.json5" || broken.extends != "" {
+		t.Fatalf("expected a present configuration without settings, got %+v", broken)
+	}
+}
+
+func TestDependencyUpdates(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// there is a row per tool, with a path if it's present
+	var tools, inconsistent int
+	err = instance.DB.QueryRow("SELECT count(DISTINCT tool), count(*) FILTER (WHERE present = (path IS NULL)) FROM dependency_updates").Scan(&tools, &inconsistent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tools != len(dependencyUpdateTools) || inconsistent != 0 {
+		t.Fatalf("expected a row per tool, got %d tools and %d inconsistent rows", tools, inconsistent)
+	}
+}
@@ -0,0 +1,285 @@
+package gitqlite
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	git "github.com/libgit2/git2go/v30"
+	"github.com/mattn/go-sqlite3"
+)
+
+type gitDirStatsModule struct {
+	repos *repoPool
+}
+
+type gitDirStatsTable struct {
+	repoPath        string
+	repos           *repoPool
+	repo            *git.Repository
+	excludeVendored bool
+}
+
+func (m *gitDirStatsModule) Create(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	err := c.DeclareVTab(fmt.Sprintf(`
+		CREATE TABLE %q (
+			dir TEXT,
+			depth INT,
+			commit_count INT,
+			file_count INT,
+			additions INT,
+			deletions INT,
+			author_count INT,
+			first_modified DATETIME,
+			last_modified DATETIME,
+			max_depth INT HIDDEN
+		)`, args[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	// the repoPath will be enclosed in double quotes "..." since createTables uses %q when setting up the table
+	// we need to pop those off when referring to the actual directory in the fs
+	repoPath := args[3][1 : len(args[3])-1]
+	return &gitDirStatsTable{repoPath: repoPath, repos: m.repos, excludeVendored: moduleOption(args, "exclude_vendored")}, nil
+}
+
+func (m *gitDirStatsModule) Connect(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	return m.Create(c, args)
+}
+
+func (m *gitDirStatsModule) DestroyModule() {}
+
+func (v *gitDirStatsTable) Open() (sqlite3.VTabCursor, error) {
+	repo, err := v.repos.open(v.repoPath)
+	if err != nil {
+		return nil, err
+	}
+	v.repo = repo
+
+	return &dirStatsCursor{repo: v.repo, limit: v.repos.limit, excludeVendored: v.excludeVendored}, nil
+}
+
+func (v *gitDirStatsTable) BestIndex(cst []sqlite3.InfoConstraint, ob []sqlite3.InfoOrderBy) (*sqlite3.IndexResult, error) {
+	// the whole history needs to be walked whatever the constraints, max_depth only saves totaling deeper directories
+	used := make([]bool, len(cst))
+	for c, constraint := range cst {
+		if constraint.Usable && constraint.Op == sqlite3.OpEQ && constraint.Column == 9 {
+			used[c] = true
+			return &sqlite3.IndexResult{Used: used, IdxNum: 1}, nil
+		}
+	}
+	return &sqlite3.IndexResult{Used: used}, nil
+}
+
+func (v *gitDirStatsTable) Disconnect() error {
+	v.repo = nil
+	return nil
+}
+func (v *gitDirStatsTable) Destroy() error { return nil }
+
+// dirStats is the total of the changes made to the files of a directory (and of its subdirectories) over a history
+type dirStats struct {
+	dir           string
+	depth         int
+	commits       int
+	files         map[string]bool
+	additions     int
+	deletions     int
+	firstModified time.Time
+	lastModified  time.Time
+	authors       map[string]bool
+}
+
+type dirStatsCursor struct {
+	repo            *git.Repository
+	limit           *commitLimit
+	index           int
+	dirs            []*dirStats
+	maxDepth        interface{}
+	excludeVendored bool
+}
+
+func (vc *dirStatsCursor) Column(c *sqlite3.SQLiteContext, col int) error {
+	dir := vc.dirs[vc.index]
+
+	switch col {
+	case 0:
+		c.ResultText(dir.dir)
+	case 1:
+		c.ResultInt(dir.depth)
+	case 2:
+		c.ResultInt(dir.commits)
+	case 3:
+		c.ResultInt(len(dir.files))
+	case 4:
+		c.ResultInt(dir.additions)
+	case 5:
+		c.ResultInt(dir.deletions)
+	case 6:
+		c.ResultInt(len(dir.authors))
+	case 7:
+		c.ResultText(dir.firstModified.Format(time.RFC3339Nano))
+	case 8:
+		c.ResultText(dir.lastModified.Format(time.RFC3339Nano))
+	case 9:
+		// the constrained max_depth is returned as is, so that SQLite's own check of the constraint passes
+		if vc.maxDepth == nil {
+			c.ResultNull()
+		} else {
+			c.ResultInt64(vc.maxDepth.(int64))
+		}
+	}
+	return nil
+}
+
+func (vc *dirStatsCursor) Filter(idxNum int, idxStr string, vals []interface{}) error {
+	maxDepth := -1
+	vc.maxDepth = nil
+	if idxNum == 1 {
+		depth, ok := vals[0].(int64)
+		if !ok {
+			return fmt.Errorf("max_depth must be an integer, got %v", vals[0])
+		}
+		vc.maxDepth = depth
+		maxDepth = int(depth)
+	}
+
+	var exclude func(file string) bool
+	if vc.excludeVendored {
+		var err error
+		exclude, err = excludedFiles(vc.repo)
+		if err != nil {
+			return err
+		}
+	}
+
+	dirs, err := dirChurn(vc.repo, maxDepth, exclude, vc.limit)
+	if err != nil {
+		return err
+	}
+
+	vc.dirs = dirs
+	vc.index = 0
+
+	return nil
+}
+
+// parentDirs returns the directories containing file, from the root (".") down to its directory,
+// as long as they're at most maxDepth deep (all of them if maxDepth is negative)
+func parentDirs(file string, maxDepth int) []string {
+	dirs := []string{"."}
+	parts := strings.Split(path.Dir(file), "/")
+	if parts[0] == "." {
+		return dirs
+	}
+	for depth := 1; depth <= len(parts) && (maxDepth < 0 || depth <= maxDepth); depth++ {
+		dirs = append(dirs, strings.Join(parts[:depth], "/"))
+	}
+	return dirs
+}
+
+// dirChurn walks the history of HEAD once, totaling the changes made to the files of every directory up to maxDepth
+// (every directory if maxDepth is negative). The root directory is ".", at depth 0.
+// Like churn, changes are attributed to the path of a file at the time, and files for which exclude returns true are left out.
+func dirChurn(repo *git.Repository, maxDepth int, exclude func(file string) bool, limit *commitLimit) ([]*dirStats, error) {
+	dirs := make([]*dirStats, 0)
+
+	// if HEAD is unborn (no commit yet) no file was changed
+	unborn, err := repo.IsHeadUnborn()
+	if err != nil {
+		return nil, err
+	}
+	if unborn {
+		return dirs, nil
+	}
+
+	walk, err := walkHead(repo, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer walk.Free()
+
+	byDir := make(map[string]*dirStats)
+	id := new(git.Oid)
+	for {
+		err := walk.Next(id)
+		if err != nil {
+			if id.IsZero() {
+				break
+			}
+			return nil, err
+		}
+
+		commit, err := repo.LookupCommit(id)
+		if err != nil {
+			return nil, err
+		}
+		commitStats, err := stats(commit, false, "")
+		if err != nil {
+			commit.Free()
+			return nil, err
+		}
+		commitStats = filterStats(commitStats, exclude)
+		when := commit.Committer().When
+		author := strings.ToLower(commit.Author().Email)
+		commit.Free()
+		*id = git.Oid{}
+
+		// a commit changing several files of a directory only counts once for it
+		changed := make(map[string]bool)
+		for _, stat := range commitStats {
+			for _, name := range parentDirs(stat.file, maxDepth) {
+				dir, ok := byDir[name]
+				if !ok {
+					depth := 0
+					if name != "." {
+						depth = strings.Count(name, "/") + 1
+					}
+					dir = &dirStats{dir: name, depth: depth, files: make(map[string]bool), firstModified: when, lastModified: when, authors: make(map[string]bool)}
+					byDir[name] = dir
+					dirs = append(dirs, dir)
+				}
+				if !changed[name] {
+					changed[name] = true
+					dir.commits++
+				}
+				dir.files[stat.file] = true
+				dir.additions += stat.additions
+				dir.deletions += stat.deletions
+				dir.authors[author] = true
+				if when.Before(dir.firstModified) {
+					dir.firstModified = when
+				}
+				if when.After(dir.lastModified) {
+					dir.lastModified = when
+				}
+			}
+		}
+	}
+
+	sort.Slice(dirs, func(i, j int) bool {
+		return dirs[i].dir < dirs[j].dir
+	})
+
+	return dirs, nil
+}
+
+func (vc *dirStatsCursor) Next() error {
+	vc.index++
+	return nil
+}
+
+func (vc *dirStatsCursor) EOF() bool {
+	return vc.index >= len(vc.dirs)
+}
+
+func (vc *dirStatsCursor) Rowid() (int64, error) {
+	return int64(vc.index), nil
+}
+
+func (vc *dirStatsCursor) Close() error {
+	return nil
+}
@@ -0,0 +1,67 @@
+package gitqlite
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParentDirs(t *testing.T) {
+	tests := []struct {
+		file     string
+		maxDepth int
+		dirs     []string
+	}{
+		{"README.md", -1, []string{"."}},
+		{"pkg/comments/comments.go", -1, []string{".", "pkg", "pkg/comments"}},
+		{"pkg/comments/comments.go", 1, []string{".", "pkg"}},
+		{"pkg/comments/comments.go", 0, []string{"."}},
+	}
+	for _, test := range tests {
+		if dirs := parentDirs(test.file, test.maxDepth); !reflect.DeepEqual(dirs, test.dirs) {
+			t.Errorf("expected the directories of %s up to depth %d to be %v, got %v", test.file, test.maxDepth, test.dirs, dirs)
+		}
+	}
+}
+
+func TestDirStats(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the root directory totals every change
+	var commits, additions, deletions int
+	err = instance.DB.QueryRow("SELECT commit_count, additions, deletions FROM dir_stats WHERE dir = '.'").Scan(&commits, &additions, &deletions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expectedCommits, expectedAdditions, expectedDeletions int
+	err = instance.DB.QueryRow("SELECT count(DISTINCT commit_id), sum(additions), sum(deletions) FROM stats").Scan(&expectedCommits, &expectedAdditions, &expectedDeletions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commits != expectedCommits || additions != expectedAdditions || deletions != expectedDeletions {
+		t.Fatalf("expected the root to total %d commits, %d additions and %d deletions, got %d, %d and %d", expectedCommits, expectedAdditions, expectedDeletions, commits, additions, deletions)
+	}
+
+	// so does a directory for its files
+	rows, err := instance.DB.Query(`
+		SELECT dir_stats.dir, dir_stats.additions, sum(stats.additions) FROM dir_stats JOIN stats ON stats.file LIKE dir_stats.dir || '/%'
+		WHERE dir_stats.max_depth = 1 AND dir_stats.depth = 1 GROUP BY dir_stats.dir HAVING dir_stats.additions != sum(stats.additions)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	if count := GetRowsCount(rows); count != 0 {
+		t.Fatalf("expected the additions of every top level directory to total the additions of its files, got %d that don't", count)
+	}
+
+	var deeper int
+	err = instance.DB.QueryRow("SELECT count(*) FROM dir_stats WHERE max_depth = 1 AND depth > 1").Scan(&deeper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deeper != 0 {
+		t.Fatalf("expected no directory deeper than max_depth, got %d", deeper)
+	}
+}
@@ -0,0 +1,224 @@
+package gitqlite
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	git "github.com/libgit2/git2go/v30"
+	"github.com/mattn/go-sqlite3"
+)
+
+type gitFileChurnModule struct {
+	repos *repoPool
+}
+
+type gitFileChurnTable struct {
+	repoPath        string
+	repos           *repoPool
+	repo            *git.Repository
+	excludeVendored bool
+}
+
+func (m *gitFileChurnModule) Create(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	err := c.DeclareVTab(fmt.Sprintf(`
+		CREATE TABLE %q (
+			file TEXT,
+			commit_count INT,
+			additions INT,
+			deletions INT,
+			first_modified DATETIME,
+			last_modified DATETIME,
+			author_count INT
+		)`, args[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	// the repoPath will be enclosed in double quotes "..." since createTables uses %q when setting up the table
+	// we need to pop those off when referring to the actual directory in the fs
+	repoPath := args[3][1 : len(args[3])-1]
+	return &gitFileChurnTable{repoPath: repoPath, repos: m.repos, excludeVendored: moduleOption(args, "exclude_vendored")}, nil
+}
+
+func (m *gitFileChurnModule) Connect(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	return m.Create(c, args)
+}
+
+func (m *gitFileChurnModule) DestroyModule() {}
+
+func (v *gitFileChurnTable) Open() (sqlite3.VTabCursor, error) {
+	repo, err := v.repos.open(v.repoPath)
+	if err != nil {
+		return nil, err
+	}
+	v.repo = repo
+
+	return &fileChurnCursor{repo: v.repo, limit: v.repos.limit, excludeVendored: v.excludeVendored}, nil
+}
+
+func (v *gitFileChurnTable) BestIndex(cst []sqlite3.InfoConstraint, ob []sqlite3.InfoOrderBy) (*sqlite3.IndexResult, error) {
+	// the whole history needs to be walked whatever the constraints
+	dummy := make([]bool, len(cst))
+	return &sqlite3.IndexResult{Used: dummy}, nil
+}
+
+func (v *gitFileChurnTable) Disconnect() error {
+	v.repo = nil
+	return nil
+}
+func (v *gitFileChurnTable) Destroy() error { return nil }
+
+// fileChurn is the total of the changes made to a single file over a history
+type fileChurn struct {
+	file          string
+	commits       int
+	additions     int
+	deletions     int
+	firstModified time.Time
+	lastModified  time.Time
+	authors       map[string]bool
+}
+
+type fileChurnCursor struct {
+	repo            *git.Repository
+	limit           *commitLimit
+	index           int
+	files           []*fileChurn
+	excludeVendored bool
+}
+
+func (vc *fileChurnCursor) Column(c *sqlite3.SQLiteContext, col int) error {
+	file := vc.files[vc.index]
+
+	switch col {
+	case 0:
+		c.ResultText(file.file)
+	case 1:
+		c.ResultInt(file.commits)
+	case 2:
+		c.ResultInt(file.additions)
+	case 3:
+		c.ResultInt(file.deletions)
+	case 4:
+		c.ResultText(file.firstModified.Format(time.RFC3339Nano))
+	case 5:
+		c.ResultText(file.lastModified.Format(time.RFC3339Nano))
+	case 6:
+		c.ResultInt(len(file.authors))
+	}
+	return nil
+}
+
+func (vc *fileChurnCursor) Filter(idxNum int, idxStr string, vals []interface{}) error {
+	var exclude func(file string) bool
+	if vc.excludeVendored {
+		var err error
+		exclude, err = excludedFiles(vc.repo)
+		if err != nil {
+			return err
+		}
+	}
+
+	files, err := churn(vc.repo, exclude, vc.limit)
+	if err != nil {
+		return err
+	}
+
+	vc.files = files
+	vc.index = 0
+
+	return nil
+}
+
+// churn walks the history of HEAD once, totaling the changes made to every file.
+// Changes are attributed to the path of a file at the time (a renamed file starts over under its new path).
+// Files for which exclude returns true are left out, if it's set.
+func churn(repo *git.Repository, exclude func(file string) bool, limit *commitLimit) ([]*fileChurn, error) {
+	files := make([]*fileChurn, 0)
+
+	// if HEAD is unborn (no commit yet) no file was changed
+	unborn, err := repo.IsHeadUnborn()
+	if err != nil {
+		return nil, err
+	}
+	if unborn {
+		return files, nil
+	}
+
+	walk, err := walkHead(repo, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer walk.Free()
+
+	byFile := make(map[string]*fileChurn)
+	id := new(git.Oid)
+	for {
+		err := walk.Next(id)
+		if err != nil {
+			if id.IsZero() {
+				break
+			}
+			return nil, err
+		}
+
+		commit, err := repo.LookupCommit(id)
+		if err != nil {
+			return nil, err
+		}
+		commitStats, err := stats(commit, false, "")
+		if err != nil {
+			commit.Free()
+			return nil, err
+		}
+		commitStats = filterStats(commitStats, exclude)
+		when := commit.Committer().When
+		author := strings.ToLower(commit.Author().Email)
+		commit.Free()
+		*id = git.Oid{}
+
+		for _, stat := range commitStats {
+			file, ok := byFile[stat.file]
+			if !ok {
+				file = &fileChurn{file: stat.file, firstModified: when, lastModified: when, authors: make(map[string]bool)}
+				byFile[stat.file] = file
+				files = append(files, file)
+			}
+			file.commits++
+			file.additions += stat.additions
+			file.deletions += stat.deletions
+			file.authors[author] = true
+			if when.Before(file.firstModified) {
+				file.firstModified = when
+			}
+			if when.After(file.lastModified) {
+				file.lastModified = when
+			}
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].file < files[j].file
+	})
+
+	return files, nil
+}
+
+func (vc *fileChurnCursor) Next() error {
+	vc.index++
+	return nil
+}
+
+func (vc *fileChurnCursor) EOF() bool {
+	return vc.index >= len(vc.files)
+}
+
+func (vc *fileChurnCursor) Rowid() (int64, error) {
+	return int64(vc.index), nil
+}
+
+func (vc *fileChurnCursor) Close() error {
+	return nil
+}
@@ -0,0 +1,69 @@
+package gitqlite
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFileChurn(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := instance.DB.Query("SELECT file, commit_count, additions, deletions FROM file_churn ORDER BY file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, churn, err := GetContents(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+
+	// file_churn is a faster way of aggregating stats
+	rows, err = instance.DB.Query("SELECT file, count(*), sum(additions), sum(deletions) FROM stats GROUP BY file ORDER BY file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, expected, err := GetContents(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+
+	if len(churn) != len(expected) {
+		t.Fatalf("expected %d files, got %d", len(expected), len(churn))
+	}
+	for i := range expected {
+		if !reflect.DeepEqual(churn[i], expected[i]) {
+			t.Fatalf("expected %v at row %d, got %v", expected[i], i, churn[i])
+		}
+	}
+
+	rows, err = instance.DB.Query("SELECT file FROM file_churn WHERE first_modified > last_modified OR author_count < 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	if count := GetRowsCount(rows); count != 0 {
+		t.Fatalf("expected every file to be modified by an author between its first and last modification, got %d files that aren't", count)
+	}
+}
+
+func BenchmarkFileChurn(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		instance, err := New(fixtureRepoDir, &Options{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		rows, err := instance.DB.Query("SELECT * FROM file_churn")
+		if err != nil {
+			b.Fatal(err)
+		}
+		rowNum, _, err := GetContents(rows)
+		if err != nil {
+			b.Fatalf("err %d at row Number %d", err, rowNum)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package gitqlite
 
 import (
 	"io"
+	"path"
+	"strings"
 
 	git "github.com/libgit2/git2go/v30"
 )
@@ -12,55 +14,140 @@ type treeEntryWithPath struct {
 	treeID string
 }
 
+// commitFile is a file of the tree of a commit, Blob is nil for gitlinks (submodules), whose entries are commits
 type commitFile struct {
 	*git.Blob
 	*treeEntryWithPath
 	commitID string
 }
 
+// isFile tells whether a tree entry is listed by the files table: blobs (regular files and symlinks) and gitlinks
+func isFile(entry *git.TreeEntry) bool {
+	return entry.Type == git.ObjectBlob || entry.Type == git.ObjectCommit
+}
+
+// lookupFile returns the file of a tree entry of a commit, looking up its blob unless it's a gitlink
+func (iter *commitFileIter) lookupFile(f *treeEntryWithPath) (*commitFile, error) {
+	if f.Type == git.ObjectCommit {
+		return &commitFile{nil, f, iter.currentCommit.Id().String()}, nil
+	}
+	blob, err := iter.repo.LookupBlob(f.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &commitFile{blob, f, iter.currentCommit.Id().String()}, nil
+}
+
+// treeFiles lists the files of a tree, only walking the subtree of dir (a directory or a single file) if it's not empty
+func treeFiles(tree *git.Tree, dir string) ([]*treeEntryWithPath, error) {
+	files := make([]*treeEntryWithPath, 0)
+	treeID := tree.Id().String()
+	dir = strings.Trim(dir, "/")
+	if dir == "" {
+		err := tree.Walk(func(p string, treeEntry *git.TreeEntry) int {
+			if isFile(treeEntry) {
+				files = append(files, &treeEntryWithPath{treeEntry, p, treeID})
+			}
+			return 0
+		})
+		return files, err
+	}
+
+	entry, err := tree.EntryByPath(dir)
+	if err != nil {
+		if git.IsErrorCode(err, git.ErrNotFound) {
+			return files, nil
+		}
+		return nil, err
+	}
+	if isFile(entry) {
+		return append(files, &treeEntryWithPath{entry, path.Dir(dir), treeID}), nil
+	}
+	if entry.Type != git.ObjectTree {
+		return files, nil
+	}
+
+	subtree, err := tree.Owner().LookupTree(entry.Id)
+	if err != nil {
+		return nil, err
+	}
+	defer subtree.Free()
+	err = subtree.Walk(func(p string, treeEntry *git.TreeEntry) int {
+		if isFile(treeEntry) {
+			files = append(files, &treeEntryWithPath{treeEntry, path.Join(dir, p), treeID})
+		}
+		return 0
+	})
+	return files, err
+}
+
 type commitFileIter struct {
 	repo                  *git.Repository
-	commitIter            *git.RevWalk
+	commitIter            commitWalker
 	currentCommit         *git.Commit
 	treeEntries           []*treeEntryWithPath
 	currentTreeEntryIndex int
+	path                  string
 }
 
 type commitFileIterOptions struct {
 	commitID string
+	// path is a directory (or a single file) to list the files of, the whole tree if it's empty
+	path string
+	// ref is a revision to only list the tree of, rather than every tree of the history
+	ref string
+	// limit caps the walk of the history, if not nil
+	limit *commitLimit
 }
 
 func NewCommitFileIter(repo *git.Repository, opt *commitFileIterOptions) (*commitFileIter, error) {
-	if opt.commitID == "" {
-		revWalk, err := repo.Walk()
+	if opt.commitID == "" && opt.ref == "" {
+		// if HEAD is unborn (no commit yet) there is nothing to iterate over
+		unborn, err := repo.IsHeadUnborn()
 		if err != nil {
 			return nil, err
 		}
+		if unborn {
+			return &commitFileIter{repo: repo}, nil
+		}
 
-		err = revWalk.PushHead()
+		walk, err := walkHead(repo, opt.limit)
 		if err != nil {
 			return nil, err
 		}
 
-		revWalk.Sorting(git.SortNone)
-
 		return &commitFileIter{
 			repo:                  repo,
-			commitIter:            revWalk,
+			commitIter:            walk,
 			currentCommit:         nil,
 			treeEntries:           make([]*treeEntryWithPath, 10),
 			currentTreeEntryIndex: 100, // init with an index greater than above array, so that the first call to Next() sets up the first commit, rather than trying to return a current Blob
+			path:                  opt.path,
 		}, nil
 
 	} else {
-		commitID, err := git.NewOid(opt.commitID)
-		if err != nil {
-			return nil, err
-		}
+		var commit *git.Commit
+		if opt.ref != "" {
+			var err error
+			commit, err = lookupRef(repo, opt.ref)
+			if err != nil {
+				return nil, err
+			}
+			// a commit_id other than the one of ref has no files at ref
+			if opt.commitID != "" && commit.Id().String() != opt.commitID {
+				commit.Free()
+				return &commitFileIter{repo: repo}, nil
+			}
+		} else {
+			commitID, err := git.NewOid(opt.commitID)
+			if err != nil {
+				return nil, err
+			}
 
-		commit, err := repo.LookupCommit(commitID)
-		if err != nil {
-			return nil, err
+			commit, err = repo.LookupCommit(commitID)
+			if err != nil {
+				return nil, err
+			}
 		}
 
 		tree, err := commit.Tree()
@@ -69,14 +156,7 @@ func NewCommitFileIter(repo *git.Repository, opt *commitFileIterOptions) (*commi
 		}
 		defer tree.Free()
 
-		treeEntries := make([]*treeEntryWithPath, 0)
-		treeID := tree.Id().String()
-		err = tree.Walk(func(path string, treeEntry *git.TreeEntry) int {
-			if treeEntry.Type == git.ObjectBlob {
-				treeEntries = append(treeEntries, &treeEntryWithPath{treeEntry, path, treeID})
-			}
-			return 0
-		})
+		treeEntries, err := treeFiles(tree, opt.path)
 		if err != nil {
 			return nil, err
 		}
@@ -87,82 +167,64 @@ func NewCommitFileIter(repo *git.Repository, opt *commitFileIterOptions) (*commi
 			currentCommit:         commit,
 			treeEntries:           treeEntries,
 			currentTreeEntryIndex: 0,
+			path:                  opt.path,
 		}, nil
 	}
 }
 
 func (iter *commitFileIter) Next() (*commitFile, error) {
-	defer func() {
-		iter.currentTreeEntryIndex++
-	}()
-
-	if iter.currentTreeEntryIndex < len(iter.treeEntries) {
+	// commits with an empty tree (or nothing in path) have no files, move on to the next one until a commit has some
+	for iter.currentTreeEntryIndex >= len(iter.treeEntries) {
+		// if the commitIter is nil, there are no commits to iterate over, end
+		// this assumes that a currentCommit was set when this was first called, with treeEntries already populated
+		if iter.commitIter == nil {
+			return nil, io.EOF
+		}
 
-		f := iter.treeEntries[iter.currentTreeEntryIndex]
-		blob, err := iter.repo.LookupBlob(f.Id)
+		id := new(git.Oid)
+		err := iter.commitIter.Next(id)
 		if err != nil {
+			if id.IsZero() {
+				return nil, io.EOF
+			}
+
 			return nil, err
 		}
 
-		return &commitFile{blob, f, iter.currentCommit.Id().String()}, nil
-	}
-
-	// if the commitIter is nil, there are no commits to iterate over, end
-	// this assumes that a currentCommit was set when this was first called, with treeEntries already populated
-	if iter.commitIter == nil {
-		return nil, io.EOF
-	}
-
-	id := new(git.Oid)
-	err := iter.commitIter.Next(id)
-	if err != nil {
-		if id.IsZero() {
-			return nil, io.EOF
+		commit, err := iter.repo.LookupCommit(id)
+		if err != nil {
+			return nil, err
 		}
 
-		return nil, err
-	}
-
-	commit, err := iter.repo.LookupCommit(id)
-	if err != nil {
-		return nil, err
-	}
-
-	iter.currentCommit = commit
-
-	tree, err := commit.Tree()
-	if err != nil {
-		return nil, err
-	}
-	defer tree.Free()
-
-	iter.treeEntries = make([]*treeEntryWithPath, 0)
-	iter.currentTreeEntryIndex = 0
-	treeID := tree.Id().String()
-	err = tree.Walk(func(path string, treeEntry *git.TreeEntry) int {
-		if treeEntry.Type == git.ObjectBlob {
-			iter.treeEntries = append(iter.treeEntries, &treeEntryWithPath{treeEntry, path, treeID})
+		if iter.currentCommit != nil {
+			iter.currentCommit.Free()
 		}
-		return 0
-	})
-	if err != nil {
-		return nil, err
-	}
+		iter.currentCommit = commit
 
-	f := iter.treeEntries[iter.currentTreeEntryIndex]
-	blob, err := iter.repo.LookupBlob(f.Id)
-	if err != nil {
-		return nil, err
+		tree, err := commit.Tree()
+		if err != nil {
+			return nil, err
+		}
+		iter.currentTreeEntryIndex = 0
+		iter.treeEntries, err = treeFiles(tree, iter.path)
+		tree.Free()
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return &commitFile{blob, f, iter.currentCommit.Id().String()}, nil
+	file := iter.treeEntries[iter.currentTreeEntryIndex]
+	iter.currentTreeEntryIndex++
+	return iter.lookupFile(file)
 }
 
 func (iter *commitFileIter) Close() {
 	if iter == nil {
 		return
 	}
-	iter.currentCommit.Free()
+	if iter.currentCommit != nil {
+		iter.currentCommit.Free()
+	}
 	if iter.commitIter != nil {
 		iter.commitIter.Free()
 	}
@@ -0,0 +1,284 @@
+package gitqlite
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	git "github.com/libgit2/git2go/v30"
+	"github.com/mattn/go-sqlite3"
+)
+
+type gitFileLastModifiedModule struct {
+	repos *repoPool
+}
+
+type gitFileLastModifiedTable struct {
+	repoPath string
+	repos    *repoPool
+	repo     *git.Repository
+}
+
+func (m *gitFileLastModifiedModule) Create(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	err := c.DeclareVTab(fmt.Sprintf(`
+		CREATE TABLE %q (
+			file TEXT,
+			commit_id TEXT,
+			author_name TEXT,
+			author_email TEXT,
+			author_when DATETIME,
+			committer_when DATETIME,
+			age_days INT,
+			committer_name TEXT,
+			committer_email TEXT
+		)`, args[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	// the repoPath will be enclosed in double quotes "..." since createTables uses %q when setting up the table
+	// we need to pop those off when referring to the actual directory in the fs
+	repoPath := args[3][1 : len(args[3])-1]
+	return &gitFileLastModifiedTable{repoPath: repoPath, repos: m.repos}, nil
+}
+
+func (m *gitFileLastModifiedModule) Connect(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	return m.Create(c, args)
+}
+
+func (m *gitFileLastModifiedModule) DestroyModule() {}
+
+func (v *gitFileLastModifiedTable) Open() (sqlite3.VTabCursor, error) {
+	repo, err := v.repos.open(v.repoPath)
+	if err != nil {
+		return nil, err
+	}
+	v.repo = repo
+
+	return &fileLastModifiedCursor{repo: v.repo, limit: v.repos.limit}, nil
+}
+
+func (v *gitFileLastModifiedTable) BestIndex(cst []sqlite3.InfoConstraint, ob []sqlite3.InfoOrderBy) (*sqlite3.IndexResult, error) {
+	// the history is walked until every file is found, whatever the constraints
+	dummy := make([]bool, len(cst))
+	return &sqlite3.IndexResult{Used: dummy}, nil
+}
+
+func (v *gitFileLastModifiedTable) Disconnect() error {
+	v.repo = nil
+	return nil
+}
+func (v *gitFileLastModifiedTable) Destroy() error { return nil }
+
+// fileLastModified is the last commit changing a file
+type fileLastModified struct {
+	file           string
+	commitID       string
+	authorName     string
+	authorEmail    string
+	authorWhen     time.Time
+	committerName  string
+	committerEmail string
+	committerWhen  time.Time
+}
+
+type fileLastModifiedCursor struct {
+	repo  *git.Repository
+	limit *commitLimit
+	index int
+	files []*fileLastModified
+	now   time.Time
+}
+
+func (vc *fileLastModifiedCursor) Column(c *sqlite3.SQLiteContext, col int) error {
+	file := vc.files[vc.index]
+
+	switch col {
+	case 0:
+		c.ResultText(file.file)
+	case 1:
+		c.ResultText(file.commitID)
+	case 2:
+		c.ResultText(file.authorName)
+	case 3:
+		c.ResultText(file.authorEmail)
+	case 4:
+		c.ResultText(file.authorWhen.Format(time.RFC3339Nano))
+	case 5:
+		c.ResultText(file.committerWhen.Format(time.RFC3339Nano))
+	case 6:
+		c.ResultInt(int(vc.now.Sub(file.committerWhen) / (24 * time.Hour)))
+	case 7:
+		c.ResultText(file.committerName)
+	case 8:
+		c.ResultText(file.committerEmail)
+	}
+	return nil
+}
+
+func (vc *fileLastModifiedCursor) Filter(idxNum int, idxStr string, vals []interface{}) error {
+	files, err := lastModified(vc.repo, vc.limit)
+	if err != nil {
+		return err
+	}
+
+	vc.files = files
+	vc.index = 0
+	vc.now = time.Now()
+
+	return nil
+}
+
+// lastModified returns the last commit changing every file of the tree of HEAD.
+// It walks the history of HEAD from its most recent commits, and stops once every file was found, rather than walking all of it.
+// Like in the stats table, the changes of a commit are the ones relative to its first parent:
+// a file changed on a merged branch is last modified by the merge.
+func lastModified(repo *git.Repository, limit *commitLimit) ([]*fileLastModified, error) {
+	files := make([]*fileLastModified, 0)
+
+	// if HEAD is unborn (no commit yet) there is no file
+	unborn, err := repo.IsHeadUnborn()
+	if err != nil {
+		return nil, err
+	}
+	if unborn {
+		return files, nil
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return nil, err
+	}
+	defer head.Free()
+	headCommit, err := repo.LookupCommit(head.Target())
+	if err != nil {
+		return nil, err
+	}
+	defer headCommit.Free()
+	headTree, err := headCommit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	defer headTree.Free()
+
+	pending := make(map[string]bool)
+	err = headTree.Walk(func(dir string, entry *git.TreeEntry) int {
+		if entry.Type == git.ObjectBlob {
+			pending[dir+entry.Name] = true
+		}
+		return 0
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	walk, err := walkHead(repo, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer walk.Free()
+
+	id := new(git.Oid)
+	for len(pending) > 0 {
+		err := walk.Next(id)
+		if err != nil {
+			if id.IsZero() {
+				break
+			}
+			return nil, err
+		}
+
+		commit, err := repo.LookupCommit(id)
+		if err != nil {
+			return nil, err
+		}
+		changed, err := changedFiles(repo, commit)
+		if err != nil {
+			commit.Free()
+			return nil, err
+		}
+		for _, file := range changed {
+			if !pending[file] {
+				continue
+			}
+			delete(pending, file)
+			files = append(files, &fileLastModified{
+				file:           file,
+				commitID:       commit.Id().String(),
+				authorName:     commit.Author().Name,
+				authorEmail:    commit.Author().Email,
+				authorWhen:     commit.Author().When,
+				committerName:  commit.Committer().Name,
+				committerEmail: commit.Committer().Email,
+				committerWhen:  commit.Committer().When,
+			})
+		}
+		commit.Free()
+		*id = git.Oid{}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].file < files[j].file
+	})
+
+	return files, nil
+}
+
+// changedFiles returns the paths of the files a commit adds or modifies relative to its first parent.
+// Unlike stats, it doesn't look at the contents of the files.
+func changedFiles(repo *git.Repository, commit *git.Commit) ([]string, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	defer tree.Free()
+
+	var parentTree *git.Tree
+	if parent := commit.Parent(0); parent != nil {
+		defer parent.Free()
+		parentTree, err = parent.Tree()
+		if err != nil {
+			return nil, err
+		}
+		defer parentTree.Free()
+	}
+
+	diff, err := repo.DiffTreeToTree(parentTree, tree, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer diff.Free()
+
+	deltas, err := diff.NumDeltas()
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0, deltas)
+	for i := 0; i < deltas; i++ {
+		delta, err := diff.GetDelta(i)
+		if err != nil {
+			return nil, err
+		}
+		if delta.Status != git.DeltaDeleted {
+			files = append(files, delta.NewFile.Path)
+		}
+	}
+	return files, nil
+}
+
+func (vc *fileLastModifiedCursor) Next() error {
+	vc.index++
+	return nil
+}
+
+func (vc *fileLastModifiedCursor) EOF() bool {
+	return vc.index >= len(vc.files)
+}
+
+func (vc *fileLastModifiedCursor) Rowid() (int64, error) {
+	return int64(vc.index), nil
+}
+
+func (vc *fileLastModifiedCursor) Close() error {
+	return nil
+}
@@ -0,0 +1,75 @@
+package gitqlite
+
+import (
+	"testing"
+
+	git "github.com/libgit2/git2go/v30"
+)
+
+func TestFileLastModified(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every file of HEAD is listed
+	head, err := fixtureRepo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer head.Free()
+	commit, err := fixtureRepo.LookupCommit(head.Target())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer commit.Free()
+	tree, err := commit.Tree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Free()
+	expected := 0
+	err = tree.Walk(func(dir string, entry *git.TreeEntry) int {
+		if entry.Type == git.ObjectBlob {
+			expected++
+		}
+		return 0
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+	err = instance.DB.QueryRow("SELECT count(*) FROM file_last_modified").Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != expected {
+		t.Fatalf("expected %d files, got %d", expected, count)
+	}
+
+	// the last modification of a file is the most recent change of it in stats
+	rows, err := instance.DB.Query(`
+		WITH latest AS (SELECT stats.file, max(commits.committer_when) AS committer_when FROM stats JOIN commits ON commits.id = stats.commit_id GROUP BY stats.file)
+		SELECT file_last_modified.file FROM file_last_modified JOIN latest ON latest.file = file_last_modified.file
+		WHERE latest.committer_when != file_last_modified.committer_when OR file_last_modified.age_days < 0`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	if count := GetRowsCount(rows); count != 0 {
+		t.Fatalf("expected every file to be last modified by its most recent change, got %d that aren't", count)
+	}
+
+	// the committer of the last commit is the one of commits
+	var mismatched int
+	err = instance.DB.QueryRow(`
+		SELECT count(*) FROM file_last_modified JOIN commits ON commits.id = file_last_modified.commit_id
+		WHERE commits.committer_name != file_last_modified.committer_name OR commits.committer_email != file_last_modified.committer_email`).Scan(&mismatched)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mismatched != 0 {
+		t.Fatalf("expected the committers of the last commits of files to be the ones of commits, got %d that aren't", mismatched)
+	}
+}
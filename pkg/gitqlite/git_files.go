@@ -4,15 +4,19 @@ import (
 	"fmt"
 	"io"
 	"path"
+	"strings"
 
 	git "github.com/libgit2/git2go/v30"
 	"github.com/mattn/go-sqlite3"
 )
 
-type gitTreeModule struct{}
+type gitTreeModule struct {
+	repos *repoPool
+}
 
 type gitTreeTable struct {
 	repoPath string
+	repos    *repoPool
 	repo     *git.Repository
 }
 
@@ -24,13 +28,18 @@ func (m *gitTreeModule) Create(c *sqlite3.SQLiteConn, args []string) (sqlite3.VT
 				file_id TEXT,
 				name TEXT,
 				contents TEXT,
-				executable BOOL
+				executable BOOL,
+				is_binary BOOL,
+				type TEXT,
+				symlink_target TEXT,
+				path TEXT HIDDEN,
+				ref TEXT HIDDEN
 			)`, args[0]))
 	if err != nil {
 		return nil, err
 	}
 	repoPath := args[3][1 : len(args[3])-1]
-	return &gitTreeTable{repoPath: repoPath}, nil
+	return &gitTreeTable{repoPath: repoPath, repos: m.repos}, nil
 }
 
 func (m *gitTreeModule) Connect(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
@@ -50,20 +59,66 @@ func (vc *treeCursor) Column(c *sqlite3.SQLiteContext, col int) error {
 		//tree id
 		c.ResultText(file.treeID)
 	case 2:
-		//file id
-		c.ResultText(file.Blob.Id().String())
+		//file id, the id of the commit of a gitlink
+		c.ResultText(file.treeEntryWithPath.Id.String())
 	case 3:
 		//tree name
 		c.ResultText(path.Join(file.path, file.Name))
 	case 4:
-		c.ResultText(string(file.Contents()))
+		// gitlinks have no contents, and the contents of a symlink are its target
+		if file.Blob == nil {
+			c.ResultNull()
+		} else {
+			c.ResultText(string(file.Contents()))
+		}
 	case 5:
 		c.ResultBool(file.Filemode == git.FilemodeBlobExecutable)
+	case 6:
+		// like git, a file is binary if its first 8000 bytes have a NUL byte
+		if file.Blob == nil {
+			c.ResultNull()
+		} else {
+			c.ResultBool(file.IsBinary())
+		}
+	case 7:
+		c.ResultText(fileType(file.Filemode))
+	case 8:
+		if file.Filemode == git.FilemodeLink {
+			c.ResultText(string(file.Contents()))
+		} else {
+			c.ResultNull()
+		}
+	case 9:
+		// returned as is, so that SQLite's own check of the constraint passes
+		if vc.path == nil {
+			c.ResultNull()
+		} else {
+			c.ResultText(vc.path.(string))
+		}
+	case 10:
+		// returned as is, so that SQLite's own check of the constraint passes
+		if vc.ref == nil {
+			c.ResultNull()
+		} else {
+			c.ResultText(vc.ref.(string))
+		}
 	}
 
 	return nil
 }
 
+// fileType describes the kind of a file of a tree from its mode: blob for regular files, symlink or gitlink (a submodule)
+func fileType(mode git.Filemode) string {
+	switch mode {
+	case git.FilemodeLink:
+		return "symlink"
+	case git.FilemodeCommit:
+		return "gitlink"
+	default:
+		return "blob"
+	}
+}
+
 func (v *gitTreeTable) Disconnect() error {
 	v.repo = nil
 	return nil
@@ -73,43 +128,75 @@ func (v *gitTreeTable) Destroy() error { return nil }
 
 type treeCursor struct {
 	repo     *git.Repository
+	limit    *commitLimit
 	iterator *commitFileIter
 	current  *commitFile
+	path     interface{}
+	ref      interface{}
+	rowid    int64
 }
 
 func (v *gitTreeTable) Open() (sqlite3.VTabCursor, error) {
-	repo, err := git.OpenRepository(v.repoPath)
+	repo, err := v.repos.open(v.repoPath)
 	if err != nil {
 		return nil, err
 	}
 	v.repo = repo
 
-	return &treeCursor{repo: v.repo}, nil
+	return &treeCursor{repo: v.repo, limit: v.repos.limit}, nil
 }
 
 func (v *gitTreeTable) BestIndex(cst []sqlite3.InfoConstraint, ob []sqlite3.InfoOrderBy) (*sqlite3.IndexResult, error) {
 	used := make([]bool, len(cst))
-	// TODO implement an index for file name glob patterns?
-	// TODO this loop construct won't work well for multiple constraints...
+	// the values of used constraints are passed to Filter in the order they appear in cst,
+	// so the names of the constrained columns are passed along in that same order in the IdxStr
+	columns := make([]string, 0)
+	cost := 100.0
 	for c, constraint := range cst {
+		if !constraint.Usable || constraint.Op != sqlite3.OpEQ {
+			continue
+		}
 		switch {
-		case constraint.Usable && constraint.Column == 0 && constraint.Op == sqlite3.OpEQ:
+		case constraint.Column == 0 && !contains(columns, "commit_id"):
+			used[c] = true
+			columns = append(columns, "commit_id")
+			cost /= 100
+		case constraint.Column == 9 && !contains(columns, "path"):
+			used[c] = true
+			columns = append(columns, "path")
+			cost /= 10
+		case constraint.Column == 10 && !contains(columns, "ref"):
 			used[c] = true
-			return &sqlite3.IndexResult{Used: used, IdxNum: 1, IdxStr: "files-by-commit-id", EstimatedCost: 1.0, EstimatedRows: 1}, nil
+			columns = append(columns, "ref")
+			cost /= 100
 		}
 	}
 
-	return &sqlite3.IndexResult{Used: used, EstimatedCost: 100}, nil
+	return &sqlite3.IndexResult{Used: used, IdxNum: len(columns), IdxStr: strings.Join(columns, ","), EstimatedCost: cost}, nil
 }
 
 func (vc *treeCursor) Filter(idxNum int, idxStr string, vals []interface{}) error {
-	var opt *commitFileIterOptions
-
-	switch idxNum {
-	case 0:
-		opt = &commitFileIterOptions{}
-	case 1:
-		opt = &commitFileIterOptions{commitID: vals[0].(string)}
+	vc.rowid = 0
+	opt := &commitFileIterOptions{limit: vc.limit}
+	vc.path = nil
+	vc.ref = nil
+	if idxNum > 0 {
+		for i, column := range strings.Split(idxStr, ",") {
+			value, ok := vals[i].(string)
+			if !ok {
+				return fmt.Errorf("%s must be a string, got %v", column, vals[i])
+			}
+			switch column {
+			case "commit_id":
+				opt.commitID = value
+			case "path":
+				vc.path = vals[i]
+				opt.path = value
+			case "ref":
+				vc.ref = vals[i]
+				opt.ref = value
+			}
+		}
 	}
 
 	iter, err := NewCommitFileIter(vc.repo, opt)
@@ -133,6 +220,7 @@ func (vc *treeCursor) Filter(idxNum int, idxStr string, vals []interface{}) erro
 }
 
 func (vc *treeCursor) Next() error {
+	vc.rowid++
 	//Iterates to next file
 	file, err := vc.iterator.Next()
 	if err != nil {
@@ -151,7 +239,7 @@ func (vc *treeCursor) EOF() bool {
 }
 
 func (vc *treeCursor) Rowid() (int64, error) {
-	return int64(0), nil
+	return vc.rowid, nil
 }
 
 func (vc *treeCursor) Close() error {
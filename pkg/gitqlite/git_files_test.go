@@ -1,6 +1,7 @@
 package gitqlite
 
 import (
+	"database/sql"
 	"fmt"
 	"path"
 	"strconv"
@@ -65,8 +66,8 @@ func TestFileColumns(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if len(columns) != 6 {
-		t.Fatalf("expected %d columns got : %d", 6, len(columns))
+	if len(columns) != 9 {
+		t.Fatalf("expected %d columns got : %d", 9, len(columns))
 	}
 
 	_, contents, err := GetContents(columnQuery)
@@ -103,6 +104,16 @@ func TestFileColumns(t *testing.T) {
 	if entry.Name != path.Base(contents[0][3]) {
 		t.Fatalf("expected file_name to be %s got %s", entry.Name, path.Base(contents[0][3]))
 	}
+
+	// go source files are text
+	var binary int
+	err = instance.DB.QueryRow("SELECT count(*) FROM files WHERE commit_id = ? AND name LIKE '%.go' AND is_binary", commitID.String()).Scan(&binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if binary != 0 {
+		t.Fatalf("expected no go file to be binary, got %d", binary)
+	}
 }
 
 func TestFileByID(t *testing.T) {
@@ -160,3 +171,175 @@ func TestFileByID(t *testing.T) {
 		t.Fatalf("expected %d, got %d", count, gotCount)
 	}
 }
+
+func TestFileTypes(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	head, err := fixtureRepo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer head.Free()
+	commit, err := fixtureRepo.LookupCommit(head.Target())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer commit.Free()
+
+	// a commit with a symlink to the README and a submodule checked out at HEAD
+	linkID := commitContents(t, fixtureRepo, commit, "LINK.md", "README.md")
+	linkCommit, err := fixtureRepo.LookupCommit(linkID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer linkCommit.Free()
+	tree, err := linkCommit.Tree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Free()
+	builder, err := fixtureRepo.TreeBuilderFromTree(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer builder.Free()
+	err = builder.Insert("LINK.md", tree.EntryByName("LINK.md").Id, git.FilemodeLink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = builder.Insert("submodule", commit.Id(), git.FilemodeCommit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	treeID, err := builder.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+	newTree, err := fixtureRepo.LookupTree(treeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer newTree.Free()
+	id, err := fixtureRepo.CreateCommit("", commit.Author(), commit.Committer(), "add a symlink and a submodule", newTree, commit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fileType string
+	var target sql.NullString
+	err = instance.DB.QueryRow("SELECT type, symlink_target FROM files WHERE commit_id = ? AND name = 'LINK.md'", id.String()).Scan(&fileType, &target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileType != "symlink" || target.String != "README.md" {
+		t.Fatalf("expected a symlink to README.md, got %s to %s", fileType, target.String)
+	}
+
+	var fileID string
+	var contents interface{}
+	err = instance.DB.QueryRow("SELECT type, file_id, contents FROM files WHERE commit_id = ? AND name = 'submodule'", id.String()).Scan(&fileType, &fileID, &contents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileType != "gitlink" || fileID != commit.Id().String() || contents != nil {
+		t.Fatalf("expected a gitlink to %s, got %s to %s", commit.Id(), fileType, fileID)
+	}
+
+	err = instance.DB.QueryRow("SELECT type, symlink_target FROM files WHERE commit_id = ? AND name = 'README.md'", id.String()).Scan(&fileType, &target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileType != "blob" || target.Valid {
+		t.Fatalf("expected a regular file, got %s to %s", fileType, target.String)
+	}
+}
+
+func TestFilePath(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// listing the files of a directory gives the same files as filtering them afterwards
+	var count, expected int
+	err = instance.DB.QueryRow("SELECT count(*) FROM files WHERE path = 'pkg'").Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = instance.DB.QueryRow("SELECT count(*) FROM files WHERE name LIKE 'pkg/%'").Scan(&expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count == 0 || count != expected {
+		t.Fatalf("expected %d files under pkg, got %d", expected, count)
+	}
+
+	// as does a single file, or a path that doesn't exist
+	err = instance.DB.QueryRow("SELECT count(*) FROM files WHERE path = 'README.md'").Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = instance.DB.QueryRow("SELECT count(*) FROM files WHERE name = 'README.md'").Scan(&expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count == 0 || count != expected {
+		t.Fatalf("expected %d README.md files, got %d", expected, count)
+	}
+
+	err = instance.DB.QueryRow("SELECT count(*) FROM files WHERE path = 'missing/'").Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Fatalf("expected no files, got %d", count)
+	}
+}
+
+func TestFileRef(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o, err := fixtureRepo.RevparseSingle("HEAD~3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Free()
+
+	// the tree of a revision is the one of its commit
+	var count, expected int
+	err = instance.DB.QueryRow("SELECT count(*) FROM files WHERE ref = 'HEAD~3'").Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = instance.DB.QueryRow("SELECT count(*) FROM files WHERE commit_id = ?", o.Id().String()).Scan(&expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count == 0 || count != expected {
+		t.Fatalf("expected %d files at HEAD~3, got %d", expected, count)
+	}
+
+	var commitID string
+	err = instance.DB.QueryRow("SELECT DISTINCT commit_id FROM files WHERE ref = 'HEAD~3'").Scan(&commitID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commitID != o.Id().String() {
+		t.Fatalf("expected the files of %s, got the ones of %s", o.Id(), commitID)
+	}
+
+	// along with another commit_id, there are none
+	err = instance.DB.QueryRow("SELECT count(*) FROM files WHERE ref = 'HEAD~3' AND commit_id IN (SELECT id FROM commits LIMIT 1)").Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Fatalf("expected no files, got %d", count)
+	}
+}
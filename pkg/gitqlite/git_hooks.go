@@ -0,0 +1,384 @@
+package gitqlite
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	git "github.com/libgit2/git2go/v30"
+	"github.com/mattn/go-sqlite3"
+)
+
+type gitHookModule struct {
+	repos *repoPool
+}
+
+type gitHookTable struct {
+	repoPath string
+	repos    *repoPool
+	repo     *git.Repository
+}
+
+func (m *gitHookModule) Create(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	err := c.DeclareVTab(fmt.Sprintf(`
+		CREATE TABLE %q (
+			name TEXT,
+			source TEXT,
+			path TEXT,
+			present BOOL,
+			executable BOOL,
+			size INT,
+			shebang TEXT
+		)`, args[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	// the repoPath will be enclosed in double quotes "..." since createTables uses %q when setting up the table
+	// we need to pop those off when referring to the actual directory in the fs
+	repoPath := args[3][1 : len(args[3])-1]
+	return &gitHookTable{repoPath: repoPath, repos: m.repos}, nil
+}
+
+func (m *gitHookModule) Connect(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	return m.Create(c, args)
+}
+
+func (m *gitHookModule) DestroyModule() {}
+
+func (v *gitHookTable) Open() (sqlite3.VTabCursor, error) {
+	repo, err := v.repos.open(v.repoPath)
+	if err != nil {
+		return nil, err
+	}
+	v.repo = repo
+
+	return &hookCursor{repo: v.repo}, nil
+}
+
+func (v *gitHookTable) BestIndex(cst []sqlite3.InfoConstraint, ob []sqlite3.InfoOrderBy) (*sqlite3.IndexResult, error) {
+	// there are only a handful of hooks, SQLite can filter them
+	dummy := make([]bool, len(cst))
+	return &sqlite3.IndexResult{Used: dummy}, nil
+}
+
+func (v *gitHookTable) Disconnect() error {
+	v.repo = nil
+	return nil
+}
+func (v *gitHookTable) Destroy() error { return nil }
+
+// gitHookNames are the hooks git runs, see https://git-scm.com/docs/githooks
+var gitHookNames = []string{
+	"applypatch-msg", "pre-applypatch", "post-applypatch",
+	"pre-commit", "pre-merge-commit", "prepare-commit-msg", "commit-msg", "post-commit",
+	"pre-rebase", "post-checkout", "post-merge", "pre-push",
+	"pre-receive", "update", "proc-receive", "post-receive", "post-update", "reference-transaction", "push-to-checkout",
+	"pre-auto-gc", "post-rewrite", "sendemail-validate", "fsmonitor-watchman", "p4-changelist", "p4-prepare-changelist", "p4-post-changelist", "p4-pre-submit", "post-index-change",
+}
+
+// hookConfigFiles maps the configuration files of hook managers, committed to the repository, to the hook manager they configure
+var hookConfigFiles = map[string]string{
+	"lefthook.yml":            "lefthook",
+	".lefthook.yml":           "lefthook",
+	"lefthook.yaml":           "lefthook",
+	".lefthook.yaml":          "lefthook",
+	".pre-commit-config.yaml": "pre-commit",
+	".huskyrc":                "husky",
+	".huskyrc.json":           "husky",
+	".overcommit.yml":         "overcommit",
+}
+
+// hookDirs maps the directories committed to the repository holding a script per hook, to the tool installing them
+var hookDirs = map[string]string{
+	".husky":    "husky",
+	".githooks": "githooks",
+}
+
+// hook is a hook script or the configuration of a hook manager
+type hook struct {
+	// name is the name of the hook (i.e. pre-commit), it's empty for configuration files
+	name       string
+	source     string
+	path       string
+	present    bool
+	executable bool
+	size       int64
+	shebang    string
+}
+
+type hookCursor struct {
+	repo  *git.Repository
+	index int
+	hooks []*hook
+}
+
+func (vc *hookCursor) Column(c *sqlite3.SQLiteContext, col int) error {
+	hook := vc.hooks[vc.index]
+
+	switch col {
+	case 0:
+		if hook.name == "" {
+			c.ResultNull()
+		} else {
+			c.ResultText(hook.name)
+		}
+	case 1:
+		c.ResultText(hook.source)
+	case 2:
+		c.ResultText(hook.path)
+	case 3:
+		c.ResultBool(hook.present)
+	case 4:
+		c.ResultBool(hook.executable)
+	case 5:
+		if hook.present {
+			c.ResultInt64(hook.size)
+		} else {
+			c.ResultNull()
+		}
+	case 6:
+		if hook.shebang == "" {
+			c.ResultNull()
+		} else {
+			c.ResultText(hook.shebang)
+		}
+	}
+	return nil
+}
+
+func (vc *hookCursor) Filter(idxNum int, idxStr string, vals []interface{}) error {
+	hooks, err := installedHooks(vc.repo)
+	if err != nil {
+		return err
+	}
+
+	committed, err := committedHooks(vc.repo)
+	if err != nil {
+		return err
+	}
+
+	vc.hooks = append(hooks, committed...)
+	vc.index = 0
+
+	return nil
+}
+
+// HooksDir returns the directory git looks for the hooks of the repository at repoPath in, see hooksDir
+func HooksDir(repoPath string) (string, error) {
+	repo, err := git.OpenRepository(repoPath)
+	if err != nil {
+		return "", err
+	}
+	defer repo.Free()
+	return hooksDir(repo)
+}
+
+// hooksDir returns the directory git looks for hooks in, which is set by core.hooksPath or defaults to the hooks directory of the repository.
+// A relative core.hooksPath is relative to the top level directory of the working tree, like git does.
+func hooksDir(repo *git.Repository) (string, error) {
+	config, err := repo.Config()
+	if err != nil {
+		return "", err
+	}
+	defer config.Free()
+
+	dir, err := config.LookupString("core.hooksPath")
+	if err != nil || dir == "" {
+		return filepath.Join(CommonDir(repo), "hooks"), nil
+	}
+
+	if strings.HasPrefix(dir, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, dir[2:])
+		}
+	}
+	if !filepath.IsAbs(dir) {
+		base := repo.Workdir()
+		if base == "" {
+			base = repo.Path()
+		}
+		dir = filepath.Join(base, dir)
+	}
+	return dir, nil
+}
+
+// installedHooks lists the hooks git runs for the repository: one row per known hook, whether it's present or not,
+// followed by any other file of the hooks directory. Samples (*.sample) aren't run by git, so they're skipped.
+func installedHooks(repo *git.Repository) ([]*hook, error) {
+	dir, err := hooksDir(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	names := append([]string{}, gitHookNames...)
+	others := make([]string, 0)
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasSuffix(entry.Name(), ".sample") || contains(names, entry.Name()) {
+			continue
+		}
+		others = append(others, entry.Name())
+	}
+	sort.Strings(others)
+
+	hooks := make([]*hook, 0, len(names)+len(others))
+	for _, name := range append(names, others...) {
+		h := &hook{name: name, source: "git", path: filepath.Join(dir, name)}
+
+		info, err := os.Stat(h.path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				hooks = append(hooks, h)
+				continue
+			}
+			return nil, err
+		}
+		if info.IsDir() {
+			hooks = append(hooks, h)
+			continue
+		}
+
+		h.present = true
+		h.executable = info.Mode()&0111 != 0
+		h.size = info.Size()
+
+		f, err := os.Open(h.path)
+		if err != nil {
+			return nil, err
+		}
+		h.shebang = shebang(bufio.NewReader(f))
+		f.Close()
+
+		hooks = append(hooks, h)
+	}
+
+	return hooks, nil
+}
+
+// committedHooks lists the hook scripts and hook manager configurations committed to the repository, as of HEAD.
+// These are only run once installed, which is up to every clone (or to the hook manager).
+func committedHooks(repo *git.Repository) ([]*hook, error) {
+	hooks := make([]*hook, 0)
+
+	// if HEAD is unborn (no commit yet) nothing is committed
+	unborn, err := repo.IsHeadUnborn()
+	if err != nil {
+		return nil, err
+	}
+	if unborn {
+		return hooks, nil
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return nil, err
+	}
+	defer head.Free()
+
+	commit, err := repo.LookupCommit(head.Target())
+	if err != nil {
+		return nil, err
+	}
+	defer commit.Free()
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	defer tree.Free()
+
+	for i := uint64(0); i < tree.EntryCount(); i++ {
+		entry := tree.EntryByIndex(i)
+
+		if source, ok := hookConfigFiles[entry.Name]; ok && entry.Type == git.ObjectBlob {
+			h, err := committedHook(repo, entry, "", source, entry.Name)
+			if err != nil {
+				return nil, err
+			}
+			hooks = append(hooks, h)
+			continue
+		}
+
+		source, ok := hookDirs[entry.Name]
+		if !ok || entry.Type != git.ObjectTree {
+			continue
+		}
+		dir, err := repo.LookupTree(entry.Id)
+		if err != nil {
+			return nil, err
+		}
+		for j := uint64(0); j < dir.EntryCount(); j++ {
+			script := dir.EntryByIndex(j)
+			// skip subdirectories, i.e. where husky keeps its own helper scripts (.husky/_)
+			if script.Type != git.ObjectBlob {
+				continue
+			}
+			h, err := committedHook(repo, script, script.Name, source, path.Join(entry.Name, script.Name))
+			if err != nil {
+				dir.Free()
+				return nil, err
+			}
+			hooks = append(hooks, h)
+		}
+		dir.Free()
+	}
+
+	return hooks, nil
+}
+
+func committedHook(repo *git.Repository, entry *git.TreeEntry, name, source, filePath string) (*hook, error) {
+	blob, err := repo.LookupBlob(entry.Id)
+	if err != nil {
+		return nil, err
+	}
+	defer blob.Free()
+
+	return &hook{
+		name:       name,
+		source:     source,
+		path:       filePath,
+		present:    true,
+		executable: entry.Filemode == git.FilemodeBlobExecutable,
+		size:       blob.Size(),
+		shebang:    shebang(bufio.NewReader(bytes.NewReader(blob.Contents()))),
+	}, nil
+}
+
+// shebang returns the interpreter line of a script (without the leading #!), or "" if it has none
+func shebang(r *bufio.Reader) string {
+	line, err := r.ReadString('\n')
+	if err != nil && line == "" {
+		return ""
+	}
+	if !strings.HasPrefix(line, "#!") {
+		return ""
+	}
+	return strings.TrimSpace(line[2:])
+}
+
+func (vc *hookCursor) Next() error {
+	vc.index++
+	return nil
+}
+
+func (vc *hookCursor) EOF() bool {
+	return vc.index >= len(vc.hooks)
+}
+
+func (vc *hookCursor) Rowid() (int64, error) {
+	return int64(vc.index), nil
+}
+
+func (vc *hookCursor) Close() error {
+	return nil
+}
@@ -0,0 +1,53 @@
+package gitqlite
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHooks(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a fresh clone only has samples, which aren't run
+	var present int
+	err = instance.DB.QueryRow("SELECT count(*) FROM hooks WHERE source = 'git' AND present").Scan(&present)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if present != 0 {
+		t.Fatalf("expected no hook to be present, got %d", present)
+	}
+
+	var missing int
+	err = instance.DB.QueryRow("SELECT count(*) FROM hooks WHERE source = 'git' AND NOT present").Scan(&missing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if missing != len(gitHookNames) {
+		t.Fatalf("expected a row for each of the %d hooks, got %d", len(gitHookNames), missing)
+	}
+
+	script := "#!/bin/sh -e\nexit 0\n"
+	hookPath := filepath.Join(fixtureRepoDir, ".git", "hooks", "pre-push")
+	err = ioutil.WriteFile(hookPath, []byte(script), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(hookPath)
+
+	var executable bool
+	var size int
+	var interpreter string
+	err = instance.DB.QueryRow("SELECT executable, size, shebang FROM hooks WHERE source = 'git' AND name = 'pre-push' AND present").Scan(&executable, &size, &interpreter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !executable || size != len(script) || interpreter != "/bin/sh -e" {
+		t.Fatalf("unexpected pre-push hook: executable %v, size %d, shebang %q", executable, size, interpreter)
+	}
+}
@@ -4,16 +4,21 @@ import (
 	"bytes"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
+	"github.com/augmentable-dev/askgit/pkg/gitlog"
 	git "github.com/libgit2/git2go/v30"
 	"github.com/mattn/go-sqlite3"
 )
 
-type gitLogModule struct{}
+type gitLogModule struct {
+	repos *repoPool
+}
 
 type gitLogTable struct {
 	repoPath string
+	repos    *repoPool
 	repo     *git.Repository
 }
 
@@ -31,16 +36,31 @@ func (m *gitLogModule) Create(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTa
 			committer_when DATETIME, 
 			parent_id TEXT,
 			parent_count INT,
-			tree_id TEXT
+			tree_id TEXT,
+			patch_id TEXT,
+			is_shallow_boundary BOOL,
+			author_when_utc DATETIME,
+			author_tz_offset INT,
+			committer_when_utc DATETIME,
+			committer_tz_offset INT,
+			is_merge BOOL,
+			parent_1 TEXT,
+			parent_2 TEXT,
+			body TEXT,
+			reverts_hash TEXT,
+			reverted_by TEXT,
+			author_domain TEXT,
+			pr_number INT,
+			ref TEXT HIDDEN
 		)`, args[0]))
 	if err != nil {
 		return nil, err
 	}
 
-	// the repoPath will be enclosed in double quotes "..." since ensureTables uses %q when setting up the table
+	// the repoPath will be enclosed in double quotes "..." since createTables uses %q when setting up the table
 	// we need to pop those off when referring to the actual directory in the fs
 	repoPath := args[3][1 : len(args[3])-1]
-	return &gitLogTable{repoPath: repoPath}, nil
+	return &gitLogTable{repoPath: repoPath, repos: m.repos}, nil
 }
 
 func (m *gitLogModule) Connect(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
@@ -50,13 +70,13 @@ func (m *gitLogModule) Connect(c *sqlite3.SQLiteConn, args []string) (sqlite3.VT
 func (m *gitLogModule) DestroyModule() {}
 
 func (v *gitLogTable) Open() (sqlite3.VTabCursor, error) {
-	repo, err := git.OpenRepository(v.repoPath)
+	repo, err := v.repos.open(v.repoPath)
 	if err != nil {
 		return nil, err
 	}
 	v.repo = repo
 
-	return &commitCursor{repo: v.repo}, nil
+	return &commitCursor{repo: v.repo, limit: v.repos.limit}, nil
 }
 
 func (v *gitLogTable) Disconnect() error {
@@ -67,10 +87,27 @@ func (v *gitLogTable) Destroy() error { return nil }
 
 type commitCursor struct {
 	repo       *git.Repository
+	limit      *commitLimit
 	current    *git.Commit
-	commitIter *git.RevWalk
+	commitIter commitWalker
+	// boundary holds the commits whose parents are missing from a shallow repository
+	boundary map[git.Oid]bool
+	// ref is the value of the ref constraint the history is walked from, nil for HEAD
+	ref interface{}
+	// revertIndex pairs the reverts of the history with the commits they revert, it's only built once they're asked for
+	revertIndex *reverts
+	rowid       int64
 }
 
+// the orders of the commits listed by the commits table, passed from BestIndex to Filter as the IdxNum
+const (
+	// commitsUnordered is the order of a walk of the history, about the most recent commits first
+	commitsUnordered = iota
+	// commitsNewestFirst and commitsOldestFirst are orders by commit time, which SQLite then doesn't have to sort by
+	commitsNewestFirst
+	commitsOldestFirst
+)
+
 func (vc *commitCursor) Column(c *sqlite3.SQLiteContext, col int) error {
 	commit := vc.current
 	author := commit.Author()
@@ -106,82 +143,219 @@ func (vc *commitCursor) Column(c *sqlite3.SQLiteContext, col int) error {
 		c.ResultText(committer.When.Format(time.RFC3339Nano))
 	case 9:
 		//parent_id
-		if int(commit.ParentCount()) > 0 {
-			p := commit.Parent(0)
-			c.ResultText(p.Id().String())
-			p.Free()
-		} else {
-			c.ResultNull()
-		}
+		resultParent(c, vc.parentIDs(commit), 0)
 	case 10:
 		//parent_count
-		c.ResultInt(int(commit.ParentCount()))
+		c.ResultInt(len(vc.parentIDs(commit)))
 	case 11:
 		//tree_id
 		c.ResultText(commit.TreeId().String())
-
 	case 12:
-		additions, _, err := statCalc(vc.repo, commit)
+		//patch_id
+		id, err := patchID(vc.repo, commit)
 		if err != nil {
 			return err
 		}
-		c.ResultInt(additions)
+		if id == "" {
+			c.ResultNull()
+		} else {
+			c.ResultText(id)
+		}
 	case 13:
-		_, deletions, err := statCalc(vc.repo, commit)
+		//is_shallow_boundary
+		c.ResultBool(vc.boundary[*commit.Id()])
+	case 14:
+		//author when, in UTC
+		c.ResultText(author.When.UTC().Format(time.RFC3339Nano))
+	case 15:
+		//author timezone offset
+		c.ResultInt(tzOffset(author.When))
+	case 16:
+		//committer when, in UTC
+		c.ResultText(committer.When.UTC().Format(time.RFC3339Nano))
+	case 17:
+		//committer timezone offset
+		c.ResultInt(tzOffset(committer.When))
+	case 18:
+		//is_merge
+		c.ResultBool(len(vc.parentIDs(commit)) > 1)
+	case 19:
+		//parent_1
+		resultParent(c, vc.parentIDs(commit), 0)
+	case 20:
+		//parent_2
+		resultParent(c, vc.parentIDs(commit), 1)
+	case 21:
+		//body
+		resultBody(c, commit.Message())
+	case 22:
+		//reverts_hash
+		reverts, err := vc.reverts()
 		if err != nil {
 			return err
 		}
-		c.ResultInt(deletions)
+		resultRevert(c, reverts.reverted[commit.Id().String()])
+	case 23:
+		//reverted_by
+		reverts, err := vc.reverts()
+		if err != nil {
+			return err
+		}
+		resultRevert(c, reverts.revertedBy[commit.Id().String()])
+	case 24:
+		//author_domain
+		resultDomain(c, commit.Author().Email)
+	case 25:
+		//pr_number
+		resultPullRequest(c, commit.Message())
+	case 26:
+		//ref
+		if vc.ref == nil {
+			c.ResultNull()
+		} else {
+			c.ResultText(vc.ref.(string))
+		}
 	}
 	return nil
 }
 
 func (v *gitLogTable) BestIndex(cst []sqlite3.InfoConstraint, ob []sqlite3.InfoOrderBy) (*sqlite3.IndexResult, error) {
 	used := make([]bool, len(cst))
-	// TODO this loop construct won't work well for multiple constraints...
+	// the values of used constraints are passed to Filter in the order they appear in cst,
+	// so the names of the constrained columns are passed along in that same order in the IdxStr
+	columns := make([]string, 0)
+	cost := 100.0
 	for c, constraint := range cst {
+		if !constraint.Usable {
+			continue
+		}
+		// lower bounds of the commit time end the walk of the history early
+		if constraint.Op == sqlite3.OpGT || constraint.Op == sqlite3.OpGE {
+			switch {
+			case constraint.Column == 8 && !contains(columns, "committer_when"):
+				used[c] = true
+				columns = append(columns, "committer_when")
+				cost /= 10
+			case constraint.Column == 16 && !contains(columns, "committer_when_utc"):
+				used[c] = true
+				columns = append(columns, "committer_when_utc")
+				cost /= 10
+			}
+			continue
+		}
+		if constraint.Op != sqlite3.OpEQ {
+			continue
+		}
 		switch {
-		case constraint.Usable && constraint.Column == 0 && constraint.Op == sqlite3.OpEQ:
+		case constraint.Column == 0 && !contains(columns, "id"):
+			used[c] = true
+			columns = append(columns, "id")
+			cost = 1.0
+		case constraint.Column == 26 && !contains(columns, "ref"):
 			used[c] = true
-			return &sqlite3.IndexResult{Used: used, IdxNum: 1, IdxStr: "commit-by-id", EstimatedCost: 1.0, EstimatedRows: 1}, nil
+			columns = append(columns, "ref")
 		}
 	}
 
-	return &sqlite3.IndexResult{Used: used, EstimatedCost: 100}, nil
+	// rows are listed by commit time if that's the order of the query, by committer_when_utc (col 16) or committer_when (col 8),
+	// which is then sorted chronologically rather than by its text (which isn't across timezone offsets)
+	order := commitsUnordered
+	if len(ob) == 1 && (ob[0].Column == 16 || ob[0].Column == 8) {
+		order = commitsOldestFirst
+		if ob[0].Desc {
+			order = commitsNewestFirst
+		}
+	}
+
+	return &sqlite3.IndexResult{Used: used, IdxNum: order, IdxStr: strings.Join(columns, ","), AlreadyOrdered: order != commitsUnordered, EstimatedCost: cost}, nil
 }
 
 func (vc *commitCursor) Filter(idxNum int, idxStr string, vals []interface{}) error {
-	switch idxNum {
-	case 0:
-		// no index is used, walk over all commits
-		revWalk, err := vc.repo.Walk()
-		if err != nil {
-			return err
-		}
-
-		err = revWalk.PushHead()
-		if err != nil {
-			return err
+	vc.rowid = 0
+	boundary, err := shallowBoundary(vc.repo)
+	if err != nil {
+		return err
+	}
+	vc.boundary = boundary
+
+	var commitID string
+	var since time.Time
+	vc.ref = nil
+	vc.revertIndex = nil
+	if idxStr != "" {
+		for i, column := range strings.Split(idxStr, ",") {
+			switch column {
+			case "id":
+				id, ok := vals[i].(string)
+				if !ok {
+					return fmt.Errorf("id must be a commit id, got %v", vals[i])
+				}
+				commitID = id
+			case "ref":
+				if _, ok := vals[i].(string); !ok {
+					return fmt.Errorf("ref must be a ref, got %v", vals[i])
+				}
+				vc.ref = vals[i]
+			case "committer_when", "committer_when_utc":
+				if t, ok := parseTime(vals[i]); ok && t.After(since) {
+					since = t
+				}
+			}
 		}
+	}
 
-		revWalk.Sorting(git.SortNone)
-
-		vc.commitIter = revWalk
+	if commitID == "" {
+		// walk over all the commits of ref, or HEAD if there is none
+		// unless HEAD is unborn (no commit yet), in which case there are none
+		var start *git.Oid
+		if vc.ref == nil {
+			unborn, err := vc.repo.IsHeadUnborn()
+			if err != nil {
+				return err
+			}
+			if unborn {
+				vc.current = nil
+				return nil
+			}
 
-		id := new(git.Oid)
-		err = revWalk.Next(id)
-		if err != nil {
-			return err
+			head, err := vc.repo.Head()
+			if err != nil {
+				return err
+			}
+			start = head.Target()
+			head.Free()
+		} else {
+			commit, err := lookupRef(vc.repo, vc.ref.(string))
+			if err != nil {
+				return err
+			}
+			start = commit.Id()
+			commit.Free()
 		}
 
-		commit, err := vc.repo.LookupCommit(id)
+		var walk commitWalker
+		switch {
+		case idxNum == commitsNewestFirst:
+			walk, err = walkNewestFirst(vc.repo, start, since, vc.limit)
+		case since.IsZero():
+			walk, err = walkFrom(vc.repo, start, vc.limit)
+		default:
+			walk, err = walkSince(vc.repo, start, since, vc.limit)
+		}
 		if err != nil {
 			return err
 		}
+		if idxNum == commitsOldestFirst {
+			walk, err = sortWalk(vc.repo, walk, true)
+			if err != nil {
+				return err
+			}
+		}
 
-		vc.current = commit
-	case 1:
-		// commit-by-id - lookup a commit by the ID used in the query
+		vc.commitIter = walk
+		return vc.advance()
+	} else {
+		// lookup a commit by the ID used in the query
 		revWalk, err := vc.repo.Walk()
 		if err != nil {
 			return err
@@ -189,7 +363,7 @@ func (vc *commitCursor) Filter(idxNum int, idxStr string, vals []interface{}) er
 		// nothing is pushed to this revWalk
 		vc.commitIter = revWalk
 
-		id, err := git.NewOid(vals[0].(string))
+		id, err := git.NewOid(commitID)
 		if err != nil {
 			return err
 		}
@@ -204,11 +378,18 @@ func (vc *commitCursor) Filter(idxNum int, idxStr string, vals []interface{}) er
 }
 
 func (vc *commitCursor) Next() error {
+	vc.rowid++
+	vc.current.Free()
+	vc.current = nil
+	return vc.advance()
+}
+
+// advance moves the cursor to the next commit of the walk, or past the end of the walk if there is none
+func (vc *commitCursor) advance() error {
 	id := new(git.Oid)
 	err := vc.commitIter.Next(id)
 	if err != nil {
 		if id.IsZero() {
-			vc.current.Free()
 			vc.current = nil
 			return nil
 		}
@@ -219,7 +400,6 @@ func (vc *commitCursor) Next() error {
 	if err != nil {
 		return err
 	}
-	vc.current.Free()
 	vc.current = commit
 	return nil
 }
@@ -229,61 +409,140 @@ func (vc *commitCursor) EOF() bool {
 }
 
 func (vc *commitCursor) Rowid() (int64, error) {
-	return int64(0), nil
+	return vc.rowid, nil
 }
 
 func (vc *commitCursor) Close() error {
-	vc.commitIter.Free()
-	vc.repo.Free()
+	if vc.commitIter != nil {
+		vc.commitIter.Free()
+	}
 	return nil
 }
 
-// statCalc calculates the number of additions/deletions and returns in format additions, deletions
-func statCalc(r *git.Repository, c *git.Commit) (int, int, error) {
+// reverts returns the reverts in the history the cursor lists (the history of HEAD when it's constrained to a commit id),
+// which takes computing the patch ids of every commit of it
+func (vc *commitCursor) reverts() (*reverts, error) {
+	if vc.revertIndex != nil {
+		return vc.revertIndex, nil
+	}
+
+	var walk commitWalker
+	var err error
+	if vc.ref == nil {
+		walk, err = walkHead(vc.repo, vc.limit)
+	} else {
+		walk, err = walkRef(vc.repo, vc.ref.(string), vc.limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer walk.Free()
+
+	vc.revertIndex, err = indexReverts(vc.repo, walk)
+	return vc.revertIndex, err
+}
+
+// parseTime parses the value of a constraint on a commit time, a date or time as SQLite's date and time functions return them
+// or an RFC 3339 timestamp like the values of the committer_when column, ok is false for other values
+func parseTime(val interface{}) (t time.Time, ok bool) {
+	s, isString := val.(string)
+	if !isString {
+		return time.Time{}, false
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// resultRevert sets the result of the reverts_hash and reverted_by columns, NULL if there is no such commit
+func resultRevert(c *sqlite3.SQLiteContext, id string) {
+	if id == "" {
+		c.ResultNull()
+	} else {
+		c.ResultText(id)
+	}
+}
+
+// parentIDs returns the ids of the parents of a commit, like git a shallow boundary commit is reported without parents
+func (vc *commitCursor) parentIDs(commit *git.Commit) []string {
+	if vc.boundary[*commit.Id()] {
+		return nil
+	}
+	ids := make([]string, commit.ParentCount())
+	for i := range ids {
+		ids[i] = commit.ParentId(uint(i)).String()
+	}
+	return ids
+}
+
+// resultParent sets the nth parent id of a commit, or NULL if it doesn't have that many parents
+func resultParent(c *sqlite3.SQLiteContext, parentIDs []string, n int) {
+	if n < len(parentIDs) {
+		c.ResultText(parentIDs[n])
+	} else {
+		c.ResultNull()
+	}
+}
+
+// patchID computes the stable patch ID (see gitlog.PatchID) of the changes a commit introduces relative to its first parent
+func patchID(r *git.Repository, c *git.Commit) (string, error) {
 	tree, err := c.Tree()
 	if err != nil {
-		return 0, 0, err
+		return "", err
 	}
 	defer tree.Free()
 
-	if c.ParentCount() == 0 {
-		var additions int
-		err = tree.Walk(func(path string, treeEntry *git.TreeEntry) int {
-			if treeEntry.Type == git.ObjectBlob {
-				blob, err := r.LookupBlob(treeEntry.Id)
-				if err != nil {
-					return 1
-				}
-				defer blob.Free()
-				contents := blob.Contents()
-				lineSep := []byte{'\n'}
-				additions += bytes.Count(contents, lineSep)
-			}
-			return 0
-		})
+	// the parent is missing at the boundary of a shallow repository, the commit is then diffed like a root commit
+	var parentTree *git.Tree
+	if parent := c.Parent(0); parent != nil {
+		defer parent.Free()
+		parentTree, err = parent.Tree()
 		if err != nil {
-			return 0, 0, err
+			return "", err
 		}
-		return additions, 0, nil
-
+		defer parentTree.Free()
 	}
 
+	return treesPatchID(r, parentTree, tree)
+}
+
+// reversePatchID computes the stable patch ID of the changes undoing the ones a commit introduces relative to its first parent,
+// which is the patch ID of a revert of the commit. It's empty for a root commit.
+func reversePatchID(r *git.Repository, c *git.Commit) (string, error) {
 	parent := c.Parent(0)
+	if parent == nil {
+		return "", nil
+	}
+	defer parent.Free()
 	parentTree, err := parent.Tree()
 	if err != nil {
-		return 0, 0, err
+		return "", err
 	}
-	defer parent.Free()
 	defer parentTree.Free()
 
+	tree, err := c.Tree()
+	if err != nil {
+		return "", err
+	}
+	defer tree.Free()
+
+	return treesPatchID(r, tree, parentTree)
+}
+
+// treesPatchID computes the stable patch ID of the changes between two trees, oldTree being nil for the empty tree
+func treesPatchID(r *git.Repository, oldTree, newTree *git.Tree) (string, error) {
 	diffOpt, err := git.DefaultDiffOptions()
 	if err != nil {
-		return 0, 0, err
+		return "", err
 	}
 
-	diff, err := r.DiffTreeToTree(parentTree, tree, &diffOpt)
+	diff, err := r.DiffTreeToTree(oldTree, newTree, &diffOpt)
 	if err != nil {
-		return 0, 0, err
+		return "", err
 	}
 	defer func() {
 		err := diff.Free()
@@ -294,24 +553,18 @@ func statCalc(r *git.Repository, c *git.Commit) (int, int, error) {
 
 	diffFindOpt, err := git.DefaultDiffFindOptions()
 	if err != nil {
-		return 0, 0, err
+		return "", err
 	}
 
 	err = diff.FindSimilar(&diffFindOpt)
 	if err != nil {
-		return 0, 0, err
+		return "", err
 	}
 
-	stats, err := diff.Stats()
+	patch, err := diff.ToBuf(git.DiffFormatPatch)
 	if err != nil {
-		return 0, 0, err
+		return "", err
 	}
-	defer func() {
-		err := stats.Free()
-		if err != nil {
-			log.Fatal(err)
-		}
-	}()
 
-	return stats.Insertions(), stats.Deletions(), nil
+	return gitlog.PatchID(bytes.NewReader(patch))
 }
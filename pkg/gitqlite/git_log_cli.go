@@ -1,6 +1,7 @@
 package gitqlite
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"strings"
@@ -30,13 +31,28 @@ func (m *gitLogCLIModule) Create(c *sqlite3.SQLiteConn, args []string) (sqlite3.
 			committer_when DATETIME, 
 			parent_id TEXT,
 			parent_count INT,
-			tree_id TEXT
+			tree_id TEXT,
+			patch_id TEXT,
+			is_shallow_boundary BOOL,
+			author_when_utc DATETIME,
+			author_tz_offset INT,
+			committer_when_utc DATETIME,
+			committer_tz_offset INT,
+			is_merge BOOL,
+			parent_1 TEXT,
+			parent_2 TEXT,
+			body TEXT,
+			reverts_hash TEXT,
+			reverted_by TEXT,
+			author_domain TEXT,
+			pr_number INT,
+			ref TEXT HIDDEN
 		)`, args[0]))
 	if err != nil {
 		return nil, err
 	}
 
-	// the repoPath will be enclosed in double quotes "..." since ensureTables uses %q when setting up the table
+	// the repoPath will be enclosed in double quotes "..." since createTables uses %q when setting up the table
 	// we need to pop those off when referring to the actual directory in the fs
 	repoPath := args[3][1 : len(args[3])-1]
 	return &gitLogCLITable{repoPath: repoPath}, nil
@@ -53,9 +69,16 @@ func (v *gitLogCLITable) Open() (sqlite3.VTabCursor, error) {
 }
 
 func (v *gitLogCLITable) BestIndex(cst []sqlite3.InfoConstraint, ob []sqlite3.InfoOrderBy) (*sqlite3.IndexResult, error) {
-	// TODO this should actually be implemented!
-	dummy := make([]bool, len(cst))
-	return &sqlite3.IndexResult{Used: dummy}, nil
+	// TODO implement an index on id
+	used := make([]bool, len(cst))
+	for c, constraint := range cst {
+		if constraint.Usable && constraint.Column == 26 && constraint.Op == sqlite3.OpEQ {
+			used[c] = true
+			return &sqlite3.IndexResult{Used: used, IdxNum: 1, IdxStr: "commits-by-ref"}, nil
+		}
+	}
+
+	return &sqlite3.IndexResult{Used: used}, nil
 }
 
 func (v *gitLogCLITable) Disconnect() error {
@@ -67,10 +90,36 @@ type commitCLICursor struct {
 	repoPath string
 	iter     *gitlog.CommitIter
 	current  *gitlog.Commit
+	// boundary holds the commits whose parents are missing from a shallow repository
+	boundary map[string]bool
+	// ref is the value of the ref constraint the history is listed from, nil for HEAD
+	ref interface{}
+	// revertIndex pairs the reverts of the history with the commits they revert, it's only built once they're asked for
+	revertIndex *reverts
+	rowid       int64
 }
 
 func (vc *commitCLICursor) Filter(idxNum int, idxStr string, vals []interface{}) error {
-	iter, err := gitlog.Execute(vc.repoPath)
+	vc.rowid = 0
+	boundary, err := gitlog.ShallowCommits(vc.repoPath)
+	if err != nil {
+		return err
+	}
+	vc.boundary = boundary
+
+	vc.ref = nil
+	vc.revertIndex = nil
+	ref := ""
+	if idxNum == 1 {
+		value, ok := vals[0].(string)
+		if !ok {
+			return fmt.Errorf("ref must be a ref, got %v", vals[0])
+		}
+		vc.ref = vals[0]
+		ref = value
+	}
+
+	iter, err := gitlog.Execute(vc.repoPath, ref)
 	if err != nil {
 		return err
 	}
@@ -78,6 +127,11 @@ func (vc *commitCLICursor) Filter(idxNum int, idxStr string, vals []interface{})
 
 	commit, err := iter.Next()
 	if err != nil {
+		// no commits at all, i.e. in a freshly initialized repository
+		if err == io.EOF {
+			vc.current = nil
+			return nil
+		}
 		return err
 	}
 
@@ -86,6 +140,7 @@ func (vc *commitCLICursor) Filter(idxNum int, idxStr string, vals []interface{})
 }
 
 func (vc *commitCLICursor) Next() error {
+	vc.rowid++
 	commit, err := vc.iter.Next()
 	if err != nil {
 		if err == io.EOF {
@@ -114,7 +169,7 @@ func (vc *commitCLICursor) Column(c *sqlite3.SQLiteContext, col int) error {
 		c.ResultText(current.Message)
 	case 2:
 		//commit summary
-		c.ResultText(strings.Split(current.Message, "\n")[0])
+		c.ResultText(summary(current.Message))
 	case 3:
 		//commit author name
 		c.ResultText(current.AuthorName)
@@ -135,29 +190,103 @@ func (vc *commitCLICursor) Column(c *sqlite3.SQLiteContext, col int) error {
 		c.ResultText(current.CommitterWhen.Format(time.RFC3339Nano))
 	case 9:
 		//parent_id
-		parentID := strings.Split(current.ParentID, " ")[0]
-		if strings.Trim(parentID, " ") == "" {
-			c.ResultNull()
-		} else {
-			c.ResultText(parentID)
-		}
+		resultParent(c, strings.Fields(current.ParentID), 0)
 	case 10:
 		//parent_count
-		parentIDs := strings.Split(current.ParentID, " ")
-		if len(parentIDs) > 0 && parentIDs[0] == "" {
-			c.ResultInt(0)
-		} else {
-			c.ResultInt(len(parentIDs))
-		}
+		c.ResultInt(len(strings.Fields(current.ParentID)))
 	case 11:
 		//tree_id
 		c.ResultText(current.TreeID)
+	case 12:
+		//patch_id
+		patch, err := gitlog.Patch(vc.repoPath, current.SHA, strings.Split(current.ParentID, " ")[0])
+		if err != nil {
+			return err
+		}
+		patchID, err := gitlog.PatchID(bytes.NewReader(patch))
+		if err != nil {
+			return err
+		}
+		if patchID == "" {
+			c.ResultNull()
+		} else {
+			c.ResultText(patchID)
+		}
+	case 13:
+		//is_shallow_boundary
+		c.ResultBool(vc.boundary[current.SHA])
+	case 14:
+		//author when, in UTC
+		c.ResultText(current.AuthorWhen.UTC().Format(time.RFC3339Nano))
+	case 15:
+		//author timezone offset
+		c.ResultInt(tzOffset(current.AuthorWhen))
+	case 16:
+		//committer when, in UTC
+		c.ResultText(current.CommitterWhen.UTC().Format(time.RFC3339Nano))
+	case 17:
+		//committer timezone offset
+		c.ResultInt(tzOffset(current.CommitterWhen))
+	case 18:
+		//is_merge
+		c.ResultBool(len(strings.Fields(current.ParentID)) > 1)
+	case 19:
+		//parent_1
+		resultParent(c, strings.Fields(current.ParentID), 0)
+	case 20:
+		//parent_2
+		resultParent(c, strings.Fields(current.ParentID), 1)
+	case 21:
+		//body
+		resultBody(c, current.Message)
+	case 22:
+		//reverts_hash
+		reverts, err := vc.reverts()
+		if err != nil {
+			return err
+		}
+		resultRevert(c, reverts.reverted[current.SHA])
+	case 23:
+		//reverted_by
+		reverts, err := vc.reverts()
+		if err != nil {
+			return err
+		}
+		resultRevert(c, reverts.revertedBy[current.SHA])
+	case 24:
+		//author_domain
+		resultDomain(c, current.AuthorEmail)
+	case 25:
+		//pr_number
+		resultPullRequest(c, current.Message)
+	case 26:
+		//ref
+		if vc.ref == nil {
+			c.ResultNull()
+		} else {
+			c.ResultText(vc.ref.(string))
+		}
 	}
 	return nil
 }
 
+// reverts returns the reverts in the history the cursor lists, which takes computing the patch ids of every commit of it
+func (vc *commitCLICursor) reverts() (*reverts, error) {
+	if vc.revertIndex != nil {
+		return vc.revertIndex, nil
+	}
+
+	ref := ""
+	if vc.ref != nil {
+		ref = vc.ref.(string)
+	}
+	var err error
+	vc.revertIndex, err = indexRevertsCLI(vc.repoPath, ref)
+	return vc.revertIndex, err
+}
+
 func (vc *commitCLICursor) Rowid() (int64, error) {
-	return int64(0), nil
+	return vc.rowid, nil
 }
 
 func (vc *commitCLICursor) Close() error {
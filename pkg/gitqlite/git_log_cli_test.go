@@ -42,7 +42,7 @@ func TestCommitCounts(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	expected := 12
+	expected := 26
 	if len(columns) != expected {
 		t.Fatalf("expected %d columns, got: %d", expected, len(columns))
 	}
@@ -97,3 +97,36 @@ func BenchmarkCLICommitCounts(b *testing.B) {
 		}
 	}
 }
+
+func TestPatchIDMatchesCLI(t *testing.T) {
+	patchIDs := func(options *Options) map[string]string {
+		instance, err := New(fixtureRepoDir, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rows, err := instance.DB.Query("SELECT id, patch_id FROM commits WHERE parent_count < 2 LIMIT 10")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+
+		_, contents, err := GetContents(rows)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids := make(map[string]string)
+		for _, c := range contents {
+			ids[c[0]] = c[1]
+		}
+		return ids
+	}
+
+	cli := patchIDs(&Options{UseGitCLI: true})
+	for id, patchID := range patchIDs(&Options{}) {
+		if cliPatchID, ok := cli[id]; ok && cliPatchID != patchID {
+			t.Fatalf("expected patch id %s for commit %s, got %s from the git CLI", patchID, id, cliPatchID)
+		}
+	}
+}
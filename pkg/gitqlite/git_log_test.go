@@ -2,7 +2,10 @@ package gitqlite
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	git "github.com/libgit2/git2go/v30"
 )
@@ -45,7 +48,7 @@ func TestCommits(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	expected := 12
+	expected := 26
 	if len(columns) != expected {
 		t.Fatalf("expected %d columns, got: %d", expected, len(columns))
 	}
@@ -140,3 +143,317 @@ func BenchmarkCommitCounts(b *testing.B) {
 		}
 	}
 }
+
+func TestCommitTimezones(t *testing.T) {
+	for _, options := range []*Options{{}, {UseGitCLI: true}} {
+		instance, err := New(fixtureRepoDir, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rows, err := instance.DB.Query("SELECT id, author_when_utc, author_tz_offset, strftime('%H', author_when_utc, author_tz_offset || ' minutes'), committer_when_utc, committer_tz_offset FROM commits LIMIT 20")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, contents, err := GetContents(rows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows.Close()
+
+		for _, c := range contents {
+			id, err := git.NewOid(c[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			commit, err := fixtureRepo.LookupCommit(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			author, committer := commit.Author().When, commit.Committer().When
+			commit.Free()
+
+			if expected := author.UTC().Format(time.RFC3339); c[1] != expected {
+				t.Fatalf("expected author_when_utc %s for commit %s, got %s", expected, c[0], c[1])
+			}
+			if expected := strconv.Itoa(tzOffset(author)); c[2] != expected {
+				t.Fatalf("expected author_tz_offset %s for commit %s, got %s", expected, c[0], c[2])
+			}
+			if expected := fmt.Sprintf("%02d", author.Hour()); c[3] != expected {
+				t.Fatalf("expected local author hour %s for commit %s, got %s", expected, c[0], c[3])
+			}
+			if expected := committer.UTC().Format(time.RFC3339); c[4] != expected {
+				t.Fatalf("expected committer_when_utc %s for commit %s, got %s", expected, c[0], c[4])
+			}
+			if expected := strconv.Itoa(tzOffset(committer)); c[5] != expected {
+				t.Fatalf("expected committer_tz_offset %s for commit %s, got %s", expected, c[0], c[5])
+			}
+		}
+	}
+}
+
+func TestCommitsByRef(t *testing.T) {
+	for _, options := range []*Options{{}, {UseGitCLI: true}} {
+		instance, err := New(fixtureRepoDir, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var all, head int
+		err = instance.DB.QueryRow("SELECT count(*) FROM commits").Scan(&all)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = instance.DB.QueryRow("SELECT count(*) FROM commits WHERE ref = 'HEAD'").Scan(&head)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if head != all {
+			t.Fatalf("expected %d commits in the history of HEAD, got %d", all, head)
+		}
+
+		// the history of a root commit is only that commit
+		var root string
+		err = instance.DB.QueryRow("SELECT id FROM commits WHERE parent_count = 0 LIMIT 1").Scan(&root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows, err := instance.DB.Query("SELECT id, ref FROM commits WHERE ref = ? UNION ALL SELECT id, ref FROM commits WHERE ref = ?", root, root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, contents, err := GetContents(rows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows.Close()
+
+		if len(contents) != 2 {
+			t.Fatalf("expected a single commit in the history of root commit %s, twice, got %d rows", root, len(contents))
+		}
+		for _, c := range contents {
+			if c[0] != root || c[1] != root {
+				t.Fatalf("expected root commit %s, got %v", root, c)
+			}
+		}
+
+		// a ref which isn't a string fails the query rather than the process
+		err = instance.DB.QueryRow("SELECT count(*) FROM commits WHERE ref = 1").Scan(&head)
+		if err == nil {
+			t.Fatal("expected a ref which isn't a string to fail")
+		}
+	}
+}
+
+func TestCommitsBySignature(t *testing.T) {
+	for _, options := range []*Options{{}, {UseGitCLI: true}} {
+		instance, err := New(fixtureRepoDir, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var email, name string
+		err = instance.DB.QueryRow("SELECT author_email, committer_name FROM commits LIMIT 1").Scan(&email, &name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the unary + keeps the constraints from being passed to the table, for SQLite to filter the commits itself
+		for query, args := range map[string][]interface{}{
+			"SELECT count(*) FROM commits WHERE author_email = ?":                        {email},
+			"SELECT count(*) FROM commits WHERE author_email = ? AND committer_name = ?": {email, name},
+			"SELECT count(*) FROM commits WHERE author_email = upper(?) COLLATE NOCASE":  {email},
+		} {
+			var count, expected int
+			err = instance.DB.QueryRow(query, args...).Scan(&count)
+			if err != nil {
+				t.Fatal(err)
+			}
+			unindexed := strings.NewReplacer("author_email", "+author_email", "committer_name", "+committer_name").Replace(query)
+			err = instance.DB.QueryRow(unindexed, args...).Scan(&expected)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count == 0 || count != expected {
+				t.Fatalf("expected %d commits for %q, got %d", expected, query, count)
+			}
+		}
+
+		var count int
+		err = instance.DB.QueryRow("SELECT count(*) FROM commits WHERE committer_email = 'nobody@example.com'").Scan(&count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Fatalf("expected no commits, got %d", count)
+		}
+	}
+}
+
+func TestCommitsOrderedByTime(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the unary + keeps the order from being pushed down, for SQLite to sort the commits itself
+	for _, order := range []string{"committer_when_utc DESC", "committer_when_utc ASC", "committer_when DESC"} {
+		rows, err := instance.DB.Query(fmt.Sprintf("SELECT committer_when_utc FROM commits ORDER BY %s LIMIT 20", order))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, got, err := GetContents(rows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows.Close()
+
+		rows, err = instance.DB.Query(fmt.Sprintf("SELECT committer_when_utc FROM commits ORDER BY +%s LIMIT 20", strings.Replace(order, "committer_when ", "committer_when_utc ", 1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, expected, err := GetContents(rows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows.Close()
+
+		if len(got) != len(expected) {
+			t.Fatalf("expected %d commits, got %d", len(expected), len(got))
+		}
+		for i := range expected {
+			if got[i][0] != expected[i][0] {
+				t.Fatalf("expected commit %d to be committed at %s, got %s", i, expected[i][0], got[i][0])
+			}
+		}
+	}
+}
+
+func TestCommitsSince(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var since string
+	err = instance.DB.QueryRow("SELECT committer_when FROM commits ORDER BY committer_when_utc DESC LIMIT 1 OFFSET 10").Scan(&since)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the unary + keeps the constraints from being pushed down, for SQLite to filter the commits itself
+	for _, query := range []string{
+		"SELECT count(*) FROM commits WHERE committer_when > ?",
+		"SELECT count(*) FROM commits WHERE committer_when_utc >= date(?)",
+		"SELECT count(*) FROM commits WHERE committer_when >= date(?, '-1 month')",
+	} {
+		var count, expected int
+		err = instance.DB.QueryRow(query, since).Scan(&count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		unindexed := strings.NewReplacer("committer_when", "+committer_when").Replace(query)
+		err = instance.DB.QueryRow(unindexed, since).Scan(&expected)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count == 0 || count != expected {
+			t.Fatalf("expected %d commits for %q, got %d", expected, query, count)
+		}
+	}
+}
+
+func TestCommitParents(t *testing.T) {
+	for _, options := range []*Options{{}, {UseGitCLI: true}} {
+		instance, err := New(fixtureRepoDir, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rows, err := instance.DB.Query("SELECT id, parent_id, parent_count, is_merge, parent_1, parent_2 FROM commits")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, contents, err := GetContents(rows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows.Close()
+
+		for _, c := range contents {
+			id, err := git.NewOid(c[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			commit, err := fixtureRepo.LookupCommit(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			parentCount := commit.ParentCount()
+			var secondParent string
+			if parentCount > 1 {
+				secondParent = commit.ParentId(1).String()
+			}
+			commit.Free()
+
+			if c[4] != c[1] {
+				t.Fatalf("expected parent_1 to be parent_id %s for commit %s, got %s", c[1], c[0], c[4])
+			}
+			if isMerge := c[3] == "1"; isMerge != (parentCount > 1) {
+				t.Fatalf("expected is_merge %t for commit %s with %s parents, got %s", parentCount > 1, c[0], c[2], c[3])
+			}
+			if parentCount > 1 {
+				if c[5] != secondParent {
+					t.Fatalf("expected parent_2 %s for merge commit %s, got %s", secondParent, c[0], c[5])
+				}
+			} else if c[5] != "NULL" {
+				t.Fatalf("expected no parent_2 for commit %s, got %s", c[0], c[5])
+			}
+		}
+	}
+}
+
+func TestSummaryAndBody(t *testing.T) {
+	tests := []struct {
+		message, summary, body string
+	}{
+		{"fix typo\n", "fix typo", ""},
+		{"fix typo", "fix typo", ""},
+		{"\nfix typo\nin README\n\nthe word was misspelled\n\nfixes #1\n", "fix typo in README", "the word was misspelled\n\nfixes #1"},
+		{"fix typo\n  \n  indented body  \n", "fix typo", "indented body"},
+	}
+
+	for _, test := range tests {
+		if s := summary(test.message); s != test.summary {
+			t.Fatalf("expected summary %q for message %q, got %q", test.summary, test.message, s)
+		}
+		if b := body(test.message); b != test.body {
+			t.Fatalf("expected body %q for message %q, got %q", test.body, test.message, b)
+		}
+	}
+}
+
+func TestPullRequestNumber(t *testing.T) {
+	tests := []struct {
+		message string
+		number  int
+	}{
+		{"Merge pull request #123 from user/branch\n\nAdd a feature", 123},
+		{"Add a feature (#456)\n\n* first change\n* second change", 456},
+		{"Add a feature (#456)", 456},
+		{"Merged in feature/x (pull request #78)\n\nAdd a feature", 78},
+		{"Merge branch 'feature' into 'main'\n\nAdd a feature\n\nSee merge request group/project!90", 90},
+		{"Add a feature\n\nPR-URL: https://github.com/nodejs/node/pull/34567\nReviewed-By: Jane Doe <jane@example.com>", 34567},
+		{"Add a feature\n\nPull-Request: #12", 12},
+		// issues referenced elsewhere than at the end of the summary aren't pull requests
+		{"Fix #12 in the parser", 0},
+		{"Fix the parser\n\nSee (#34) for details", 0},
+		{"Merge branch 'main' into feature", 0},
+	}
+
+	for _, test := range tests {
+		if n := pullRequestNumber(test.message); n != test.number {
+			t.Fatalf("expected pull request %d for message %q, got %d", test.number, test.message, n)
+		}
+	}
+}
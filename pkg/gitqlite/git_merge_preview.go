@@ -0,0 +1,261 @@
+package gitqlite
+
+import (
+	"fmt"
+	"strings"
+
+	git "github.com/libgit2/git2go/v30"
+	"github.com/mattn/go-sqlite3"
+)
+
+type gitMergePreviewModule struct {
+	repos *repoPool
+}
+
+type gitMergePreviewTable struct {
+	repoPath string
+	repos    *repoPool
+	repo     *git.Repository
+}
+
+func (m *gitMergePreviewModule) Create(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	err := c.DeclareVTab(fmt.Sprintf(`
+		CREATE TABLE %q (
+			path TEXT,
+			conflict TEXT,
+			ancestor_id TEXT,
+			our_id TEXT,
+			their_id TEXT,
+			ref1 TEXT HIDDEN,
+			ref2 TEXT HIDDEN
+		)`, args[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	// the repoPath will be enclosed in double quotes "..." since createTables uses %q when setting up the table
+	// we need to pop those off when referring to the actual directory in the fs
+	repoPath := args[3][1 : len(args[3])-1]
+	return &gitMergePreviewTable{repoPath: repoPath, repos: m.repos}, nil
+}
+
+func (m *gitMergePreviewModule) Connect(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	return m.Create(c, args)
+}
+
+func (m *gitMergePreviewModule) DestroyModule() {}
+
+func (v *gitMergePreviewTable) Open() (sqlite3.VTabCursor, error) {
+	repo, err := v.repos.open(v.repoPath)
+	if err != nil {
+		return nil, err
+	}
+	v.repo = repo
+
+	return &mergePreviewCursor{repo: v.repo}, nil
+}
+
+func (v *gitMergePreviewTable) BestIndex(cst []sqlite3.InfoConstraint, ob []sqlite3.InfoOrderBy) (*sqlite3.IndexResult, error) {
+	used := make([]bool, len(cst))
+	// the values of used constraints are passed to Filter in the order they appear in cst,
+	// so the names of the constrained columns are passed along in that same order in the IdxStr
+	columns := make([]string, 0)
+	for c, constraint := range cst {
+		if !constraint.Usable || constraint.Op != sqlite3.OpEQ {
+			continue
+		}
+		switch {
+		case constraint.Column == 5 && !contains(columns, "ref1"):
+			used[c] = true
+			columns = append(columns, "ref1")
+		case constraint.Column == 6 && !contains(columns, "ref2"):
+			used[c] = true
+			columns = append(columns, "ref2")
+		}
+	}
+
+	// both refs are needed to merge anything, plans constraining them both are always preferred
+	cost := 1e12
+	if len(columns) == 2 {
+		cost = 1
+	}
+	return &sqlite3.IndexResult{Used: used, IdxNum: len(columns), IdxStr: strings.Join(columns, ","), EstimatedCost: cost}, nil
+}
+
+func (v *gitMergePreviewTable) Disconnect() error {
+	v.repo = nil
+	return nil
+}
+func (v *gitMergePreviewTable) Destroy() error { return nil }
+
+// mergeConflict is a path which conflicts when merging two commits, the ids of its sides are nil when they're missing
+type mergeConflict struct {
+	path       string
+	ancestorID *git.Oid
+	ourID      *git.Oid
+	theirID    *git.Oid
+}
+
+// kind describes a conflict like git status does: content when both sides modify the path, add/add when both add it,
+// modify/delete or delete/modify when one of them deletes it
+func (c *mergeConflict) kind() string {
+	switch {
+	case c.ancestorID == nil:
+		return "add/add"
+	case c.ourID == nil:
+		return "delete/modify"
+	case c.theirID == nil:
+		return "modify/delete"
+	default:
+		return "content"
+	}
+}
+
+type mergePreviewCursor struct {
+	repo      *git.Repository
+	index     int
+	conflicts []*mergeConflict
+	ref1      string
+	ref2      string
+}
+
+func (vc *mergePreviewCursor) Column(c *sqlite3.SQLiteContext, col int) error {
+	conflict := vc.conflicts[vc.index]
+
+	switch col {
+	case 0:
+		c.ResultText(conflict.path)
+	case 1:
+		c.ResultText(conflict.kind())
+	case 2:
+		resultOid(c, conflict.ancestorID)
+	case 3:
+		resultOid(c, conflict.ourID)
+	case 4:
+		resultOid(c, conflict.theirID)
+	case 5:
+		// the constrained refs are returned as is, so that SQLite's own check of the constraints passes
+		c.ResultText(vc.ref1)
+	case 6:
+		c.ResultText(vc.ref2)
+	}
+	return nil
+}
+
+// resultOid sets the result of a column to an id, NULL for a nil id
+func resultOid(c *sqlite3.SQLiteContext, id *git.Oid) {
+	if id == nil {
+		c.ResultNull()
+	} else {
+		c.ResultText(id.String())
+	}
+}
+
+func (vc *mergePreviewCursor) Filter(idxNum int, idxStr string, vals []interface{}) error {
+	vc.index = 0
+	vc.conflicts = nil
+	// there is nothing to merge unless both refs are given, i.e. SELECT * FROM merge_preview('main', 'feature')
+	if idxNum != 2 {
+		return nil
+	}
+
+	for i, column := range strings.Split(idxStr, ",") {
+		ref, ok := vals[i].(string)
+		if !ok {
+			return fmt.Errorf("%s must be a ref, got %v", column, vals[i])
+		}
+		switch column {
+		case "ref1":
+			vc.ref1 = ref
+		case "ref2":
+			vc.ref2 = ref
+		}
+	}
+
+	conflicts, err := mergeConflicts(vc.repo, vc.ref1, vc.ref2)
+	if err != nil {
+		return err
+	}
+	vc.conflicts = conflicts
+
+	return nil
+}
+
+// mergeConflicts merges the commits ours and theirs point to in memory, without touching the working tree or the index,
+// and returns the paths which conflict, like git merge-tree would. Renames are followed like git merge does by default.
+func mergeConflicts(repo *git.Repository, ours, theirs string) ([]*mergeConflict, error) {
+	ourCommit, err := lookupRef(repo, ours)
+	if err != nil {
+		return nil, err
+	}
+	defer ourCommit.Free()
+	theirCommit, err := lookupRef(repo, theirs)
+	if err != nil {
+		return nil, err
+	}
+	defer theirCommit.Free()
+
+	index, err := repo.MergeCommits(ourCommit, theirCommit, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer index.Free()
+
+	conflicts := make([]*mergeConflict, 0)
+	if !index.HasConflicts() {
+		return conflicts, nil
+	}
+
+	iter, err := index.ConflictIterator()
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Free()
+
+	for {
+		entry, err := iter.Next()
+		if err != nil {
+			if git.IsErrorCode(err, git.ErrIterOver) {
+				break
+			}
+			return nil, err
+		}
+
+		conflict := &mergeConflict{}
+		// the path reported is the one of ours, or the one of theirs if ours deleted it
+		for _, side := range []*git.IndexEntry{entry.Ancestor, entry.Their, entry.Our} {
+			if side != nil {
+				conflict.path = side.Path
+			}
+		}
+		if entry.Ancestor != nil {
+			conflict.ancestorID = entry.Ancestor.Id
+		}
+		if entry.Our != nil {
+			conflict.ourID = entry.Our.Id
+		}
+		if entry.Their != nil {
+			conflict.theirID = entry.Their.Id
+		}
+		conflicts = append(conflicts, conflict)
+	}
+
+	return conflicts, nil
+}
+
+func (vc *mergePreviewCursor) Next() error {
+	vc.index++
+	return nil
+}
+
+func (vc *mergePreviewCursor) EOF() bool {
+	return vc.index >= len(vc.conflicts)
+}
+
+func (vc *mergePreviewCursor) Rowid() (int64, error) {
+	return int64(vc.index), nil
+}
+
+func (vc *mergePreviewCursor) Close() error {
+	return nil
+}
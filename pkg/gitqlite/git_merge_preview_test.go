@@ -0,0 +1,97 @@
+package gitqlite
+
+import (
+	"testing"
+	"time"
+
+	git "github.com/libgit2/git2go/v30"
+)
+
+// commitContents commits contents to the file at name on top of parent, without updating any ref
+func commitContents(t *testing.T, repo *git.Repository, parent *git.Commit, name, contents string) *git.Oid {
+	tree, err := parent.Tree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Free()
+
+	builder, err := repo.TreeBuilderFromTree(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer builder.Free()
+	blobID, err := repo.CreateBlobFromBuffer([]byte(contents))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = builder.Insert(name, blobID, git.FilemodeBlob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	treeID, err := builder.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+	newTree, err := repo.LookupTree(treeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer newTree.Free()
+
+	signature := &git.Signature{Name: "askgit", Email: "askgit@example.com", When: time.Now()}
+	id, err := repo.CreateCommit("", signature, signature, "change "+name, newTree, parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestMergePreview(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// merging a commit with its own history doesn't conflict
+	var count int
+	err = instance.DB.QueryRow("SELECT count(*) FROM merge_preview('HEAD', 'HEAD~3')").Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Fatalf("expected no conflict, got %d", count)
+	}
+
+	// and neither does anything if the refs to merge aren't given
+	err = instance.DB.QueryRow("SELECT count(*) FROM merge_preview").Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Fatalf("expected no conflict, got %d", count)
+	}
+
+	// two commits changing the same file differently conflict
+	head, err := fixtureRepo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer head.Free()
+	commit, err := fixtureRepo.LookupCommit(head.Target())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer commit.Free()
+	ours := commitContents(t, fixtureRepo, commit, "CONFLICT.md", "ours\n")
+	theirs := commitContents(t, fixtureRepo, commit, "CONFLICT.md", "theirs\n")
+
+	var path, conflict string
+	var ancestorID interface{}
+	err = instance.DB.QueryRow("SELECT path, conflict, ancestor_id FROM merge_preview(?, ?)", ours.String(), theirs.String()).Scan(&path, &conflict, &ancestorID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "CONFLICT.md" || conflict != "add/add" || ancestorID != nil {
+		t.Fatalf("expected an add/add conflict on CONFLICT.md, got %s on %s", conflict, path)
+	}
+}
@@ -0,0 +1,259 @@
+package gitqlite
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	git "github.com/libgit2/git2go/v30"
+	"github.com/mattn/go-sqlite3"
+)
+
+// gitRepoModule is a summary of the repository, in a single row
+type gitRepoModule struct {
+	repos *repoPool
+}
+
+type gitRepoTable struct {
+	repoPath string
+	repos    *repoPool
+	repo     *git.Repository
+}
+
+func (m *gitRepoModule) Create(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	err := c.DeclareVTab(fmt.Sprintf(`
+		CREATE TABLE %q (
+			path TEXT,
+			is_bare BOOL,
+			head TEXT,
+			default_branch TEXT,
+			remote_url TEXT,
+			commit_count INT,
+			first_commit_at DATETIME,
+			last_commit_at DATETIME
+		)`, args[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	// the repoPath will be enclosed in double quotes "..." since createTables uses %q when setting up the table
+	// we need to pop those off when referring to the actual directory in the fs
+	repoPath := args[3][1 : len(args[3])-1]
+	return &gitRepoTable{repoPath: repoPath, repos: m.repos}, nil
+}
+
+func (m *gitRepoModule) Connect(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	return m.Create(c, args)
+}
+
+func (m *gitRepoModule) DestroyModule() {}
+
+func (v *gitRepoTable) Open() (sqlite3.VTabCursor, error) {
+	repo, err := v.repos.open(v.repoPath)
+	if err != nil {
+		return nil, err
+	}
+	v.repo = repo
+
+	return &repoCursor{repoPath: v.repoPath, repo: v.repo, limit: v.repos.limit}, nil
+}
+
+func (v *gitRepoTable) BestIndex(cst []sqlite3.InfoConstraint, ob []sqlite3.InfoOrderBy) (*sqlite3.IndexResult, error) {
+	// there is a single row, SQLite can filter it
+	dummy := make([]bool, len(cst))
+	return &sqlite3.IndexResult{Used: dummy}, nil
+}
+
+func (v *gitRepoTable) Disconnect() error {
+	v.repo = nil
+	return nil
+}
+func (v *gitRepoTable) Destroy() error { return nil }
+
+// historySummary is the number of commits in the history of HEAD, and when the oldest and most recent were committed
+type historySummary struct {
+	commits int
+	first   time.Time
+	last    time.Time
+}
+
+type repoCursor struct {
+	repoPath string
+	repo     *git.Repository
+	limit    *commitLimit
+	done     bool
+	// history is only summarized once its columns are asked for, which takes walking all of it
+	history *historySummary
+}
+
+func (vc *repoCursor) Column(c *sqlite3.SQLiteContext, col int) error {
+	switch col {
+	case 0:
+		c.ResultText(vc.repoPath)
+	case 1:
+		c.ResultBool(vc.repo.IsBare())
+	case 2:
+		head, err := headRef(vc.repo)
+		if err != nil {
+			return err
+		}
+		resultString(c, head)
+	case 3:
+		branch, err := defaultBranch(vc.repo)
+		if err != nil {
+			return err
+		}
+		resultString(c, branch)
+	case 4:
+		url, err := remoteURL(vc.repo, "origin")
+		if err != nil {
+			return err
+		}
+		resultString(c, url)
+	case 5, 6, 7:
+		history, err := vc.summary()
+		if err != nil {
+			return err
+		}
+		switch col {
+		case 5:
+			c.ResultInt(history.commits)
+		case 6:
+			resultTime(c, history.first)
+		case 7:
+			resultTime(c, history.last)
+		}
+	}
+	return nil
+}
+
+// resultString sets a text result, or NULL if it's empty
+func resultString(c *sqlite3.SQLiteContext, s string) {
+	if s == "" {
+		c.ResultNull()
+	} else {
+		c.ResultText(s)
+	}
+}
+
+func (vc *repoCursor) Filter(idxNum int, idxStr string, vals []interface{}) error {
+	vc.done = false
+	vc.history = nil
+	return nil
+}
+
+// summary walks the history of HEAD to summarize it, it's empty if HEAD is unborn (no commit yet)
+func (vc *repoCursor) summary() (*historySummary, error) {
+	if vc.history != nil {
+		return vc.history, nil
+	}
+
+	history := &historySummary{}
+	unborn, err := vc.repo.IsHeadUnborn()
+	if err != nil {
+		return nil, err
+	}
+	if unborn {
+		vc.history = history
+		return history, nil
+	}
+
+	walk, err := walkHead(vc.repo, vc.limit)
+	if err != nil {
+		return nil, err
+	}
+	defer walk.Free()
+
+	for {
+		id := new(git.Oid)
+		err := walk.Next(id)
+		if err != nil {
+			if id.IsZero() {
+				break
+			}
+			return nil, err
+		}
+
+		commit, err := vc.repo.LookupCommit(id)
+		if err != nil {
+			return nil, err
+		}
+		when := commit.Committer().When
+		commit.Free()
+
+		history.commits++
+		if history.first.IsZero() || when.Before(history.first) {
+			history.first = when
+		}
+		if when.After(history.last) {
+			history.last = when
+		}
+	}
+
+	vc.history = history
+	return history, nil
+}
+
+// headRef returns the name of the branch HEAD points to (i.e. refs/heads/main), even if it's unborn,
+// or "" if HEAD is detached
+func headRef(repo *git.Repository) (string, error) {
+	ref, err := repo.References.Lookup("HEAD")
+	if err != nil {
+		return "", err
+	}
+	defer ref.Free()
+
+	if ref.Type() != git.ReferenceSymbolic {
+		return "", nil
+	}
+	return ref.SymbolicTarget(), nil
+}
+
+// defaultBranch returns the default branch of the origin remote (the branch its HEAD points to, i.e. main),
+// or "" if there is none, i.e. if the repository wasn't cloned
+func defaultBranch(repo *git.Repository) (string, error) {
+	ref, err := repo.References.Lookup("refs/remotes/origin/HEAD")
+	if err != nil {
+		if git.IsErrorCode(err, git.ErrNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	defer ref.Free()
+
+	if ref.Type() != git.ReferenceSymbolic {
+		return "", nil
+	}
+	return strings.TrimPrefix(ref.SymbolicTarget(), "refs/remotes/origin/"), nil
+}
+
+// remoteURL returns the URL of a remote, or "" if there is no such remote
+func remoteURL(repo *git.Repository, name string) (string, error) {
+	remote, err := repo.Remotes.Lookup(name)
+	if err != nil {
+		if git.IsErrorCode(err, git.ErrNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	defer remote.Free()
+
+	return remote.Url(), nil
+}
+
+func (vc *repoCursor) Next() error {
+	vc.done = true
+	return nil
+}
+
+func (vc *repoCursor) EOF() bool {
+	return vc.done
+}
+
+func (vc *repoCursor) Rowid() (int64, error) {
+	return 0, nil
+}
+
+func (vc *repoCursor) Close() error {
+	return nil
+}
@@ -0,0 +1,45 @@
+package gitqlite
+
+import "testing"
+
+func TestRepo(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var path, head, defaultBranch, url, first, last string
+	var bare bool
+	var count int
+	err = instance.DB.QueryRow("SELECT path, is_bare, head, default_branch, remote_url, commit_count, first_commit_at, last_commit_at FROM repo").Scan(&path, &bare, &head, &defaultBranch, &url, &count, &first, &last)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if path != fixtureRepoDir || bare {
+		t.Fatalf("expected the working tree %s, got %s (bare: %t)", fixtureRepoDir, path, bare)
+	}
+	// a fresh clone checks the default branch of its origin out
+	if url != fixtureRepoCloneURL || head != "refs/heads/"+defaultBranch {
+		t.Fatalf("expected %s checked out from %s, got %s from %s", defaultBranch, fixtureRepoCloneURL, head, url)
+	}
+
+	var commits int
+	var oldest, newest string
+	err = instance.DB.QueryRow("SELECT count(*), min(committer_when_utc), max(committer_when_utc) FROM commits").Scan(&commits, &oldest, &newest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != commits {
+		t.Fatalf("expected %d commits, got %d", commits, count)
+	}
+
+	var matches bool
+	err = instance.DB.QueryRow("SELECT datetime(?) = datetime(?) AND datetime(?) = datetime(?)", first, oldest, last, newest).Scan(&matches)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !matches {
+		t.Fatalf("expected commits from %s to %s, got %s to %s", oldest, newest, first, last)
+	}
+}
@@ -3,16 +3,22 @@ package gitqlite
 import (
 	"fmt"
 	"io"
+	"strings"
 
 	git "github.com/libgit2/git2go/v30"
 	"github.com/mattn/go-sqlite3"
 )
 
-type gitStatsModule struct{}
+// gitStatsModule is a summary of the files changed by each commit, see gitCommitFilesModule for the full details of each change
+type gitStatsModule struct {
+	repos *repoPool
+}
 
 type gitStatsTable struct {
-	repoPath string
-	repo     *git.Repository
+	repoPath        string
+	repos           *repoPool
+	repo            *git.Repository
+	excludeVendored bool
 }
 
 func (m *gitStatsModule) Create(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
@@ -21,16 +27,23 @@ func (m *gitStatsModule) Create(c *sqlite3.SQLiteConn, args []string) (sqlite3.V
 			commit_id TEXT,
 			file TEXT,
 			additions INT,
-			deletions INT
+			deletions INT,
+			is_binary BOOL,
+			old_mode TEXT,
+			new_mode TEXT,
+			status TEXT,
+			ignore_whitespace BOOL HIDDEN,
+			ref TEXT HIDDEN,
+			path TEXT HIDDEN
 			)`, args[0]))
 	if err != nil {
 		return nil, err
 	}
 
-	// the repoPath will be enclosed in double quotes "..." since ensureTables uses %q when setting up the table
+	// the repoPath will be enclosed in double quotes "..." since createTables uses %q when setting up the table
 	// we need to pop those off when referring to the actual directory in the fs
 	repoPath := args[3][1 : len(args[3])-1]
-	return &gitStatsTable{repoPath: repoPath}, nil
+	return &gitStatsTable{repoPath: repoPath, repos: m.repos, excludeVendored: moduleOption(args, "exclude_vendored")}, nil
 }
 
 func (m *gitStatsModule) Connect(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
@@ -40,28 +53,44 @@ func (m *gitStatsModule) Connect(c *sqlite3.SQLiteConn, args []string) (sqlite3.
 func (m *gitStatsModule) DestroyModule() {}
 
 func (v *gitStatsTable) Open() (sqlite3.VTabCursor, error) {
-	repo, err := git.OpenRepository(v.repoPath)
+	repo, err := v.repos.open(v.repoPath)
 	if err != nil {
 		return nil, err
 	}
 	v.repo = repo
 
-	return &StatsCursor{repo: v.repo}, nil
+	return &StatsCursor{repo: v.repo, limit: v.repos.limit, excludeVendored: v.excludeVendored}, nil
 }
 
 func (v *gitStatsTable) BestIndex(cst []sqlite3.InfoConstraint, ob []sqlite3.InfoOrderBy) (*sqlite3.IndexResult, error) {
 	used := make([]bool, len(cst))
-	// TODO implement an index for file name glob patterns?
-	// TODO this loop construct won't work well for multiple constraints...
+	// the values of used constraints are passed to Filter in the order they appear in cst,
+	// so the names of the constrained columns are passed along in that same order in the IdxStr
+	columns := make([]string, 0)
+	cost := 100.0
 	for c, constraint := range cst {
+		if !constraint.Usable || constraint.Op != sqlite3.OpEQ {
+			continue
+		}
 		switch {
-		case constraint.Usable && constraint.Column == 0 && constraint.Op == sqlite3.OpEQ:
+		case constraint.Column == 0 && !contains(columns, "commit_id"):
+			used[c] = true
+			columns = append(columns, "commit_id")
+			cost /= 100
+		case constraint.Column == 8 && !contains(columns, "ignore_whitespace"):
+			used[c] = true
+			columns = append(columns, "ignore_whitespace")
+		case constraint.Column == 9 && !contains(columns, "ref"):
+			used[c] = true
+			columns = append(columns, "ref")
+		case constraint.Column == 10 && !contains(columns, "path"):
 			used[c] = true
-			return &sqlite3.IndexResult{Used: used, IdxNum: 1, IdxStr: "stats-by-commit-id", EstimatedCost: 1.0, EstimatedRows: 1}, nil
+			columns = append(columns, "path")
+			cost /= 10
 		}
 	}
 
-	return &sqlite3.IndexResult{Used: used, EstimatedCost: 100}, nil
+	return &sqlite3.IndexResult{Used: used, IdxNum: len(columns), IdxStr: strings.Join(columns, ","), EstimatedCost: cost}, nil
 }
 
 func (v *gitStatsTable) Disconnect() error {
@@ -71,9 +100,15 @@ func (v *gitStatsTable) Disconnect() error {
 func (v *gitStatsTable) Destroy() error { return nil }
 
 type StatsCursor struct {
-	repo     *git.Repository
-	iterator *commitStatsIter
-	current  *commitStat
+	repo             *git.Repository
+	limit            *commitLimit
+	iterator         *commitStatsIter
+	current          *commitStat
+	ignoreWhitespace bool
+	ref              interface{}
+	path             interface{}
+	rowid            int64
+	excludeVendored  bool
 }
 
 func (vc *StatsCursor) Column(c *sqlite3.SQLiteContext, col int) error {
@@ -88,19 +123,76 @@ func (vc *StatsCursor) Column(c *sqlite3.SQLiteContext, col int) error {
 		c.ResultInt(stat.additions)
 	case 3:
 		c.ResultInt(stat.deletions)
-
+	case 4:
+		c.ResultBool(stat.isBinary)
+	case 5:
+		resultFileMode(c, stat.oldMode)
+	case 6:
+		resultFileMode(c, stat.newMode)
+	case 7:
+		c.ResultText(deltaStatus(stat.status))
+	case 8:
+		c.ResultBool(vc.ignoreWhitespace)
+	case 9:
+		if vc.ref == nil {
+			c.ResultNull()
+		} else {
+			c.ResultText(vc.ref.(string))
+		}
+	case 10:
+		// returned as is, so that SQLite's own check of the constraint passes
+		if vc.path == nil {
+			c.ResultNull()
+		} else {
+			c.ResultText(vc.path.(string))
+		}
 	}
 
 	return nil
 }
+
+// resultFileMode sets a file mode as its octal representation (i.e. 100644), or NULL if the file doesn't exist on that side of the diff
+func resultFileMode(c *sqlite3.SQLiteContext, mode uint16) {
+	if mode == 0 {
+		c.ResultNull()
+	} else {
+		c.ResultText(fmt.Sprintf("%06o", mode))
+	}
+}
+
 func (vc *StatsCursor) Filter(idxNum int, idxStr string, vals []interface{}) error {
-	var opt *commitStatsIterOptions
+	vc.rowid = 0
+	opt := &commitStatsIterOptions{limit: vc.limit}
+	vc.ref = nil
+	vc.path = nil
+	if idxNum > 0 {
+		for i, column := range strings.Split(idxStr, ",") {
+			value, ok := vals[i].(string)
+			if !ok && column != "ignore_whitespace" {
+				return fmt.Errorf("%s must be a string, got %v", column, vals[i])
+			}
+			switch column {
+			case "commit_id":
+				opt.commitID = value
+			case "ignore_whitespace":
+				opt.ignoreWhitespace = truthy(vals[i])
+			case "ref":
+				vc.ref = vals[i]
+				opt.ref = value
+			case "path":
+				vc.path = vals[i]
+				opt.path = value
+			}
+		}
+	}
+	vc.ignoreWhitespace = opt.ignoreWhitespace
 
-	switch idxNum {
-	case 0:
-		opt = &commitStatsIterOptions{}
-	case 1:
-		opt = &commitStatsIterOptions{commitID: vals[0].(string)}
+	if vc.excludeVendored {
+		exclude, err := excludedFiles(vc.repo)
+		if err != nil {
+			return err
+		}
+		opt.exclude = exclude
 	}
 
 	iter, err := NewCommitStatsIter(vc.repo, opt)
@@ -124,6 +216,7 @@ func (vc *StatsCursor) Filter(idxNum int, idxStr string, vals []interface{}) err
 }
 
 func (vc *StatsCursor) Next() error {
+	vc.rowid++
 	file, err := vc.iterator.Next()
 	if err != nil {
 		if err == io.EOF {
@@ -141,7 +234,7 @@ func (vc *StatsCursor) EOF() bool {
 }
 
 func (vc *StatsCursor) Rowid() (int64, error) {
-	return int64(0), nil
+	return vc.rowid, nil
 }
 
 func (vc *StatsCursor) Close() error {
@@ -2,30 +2,51 @@ package gitqlite
 
 import (
 	"io"
+	"strings"
 
 	git "github.com/libgit2/git2go/v30"
 )
 
+// commitStat is a single file changed by a commit, the stats and commit_files tables are both built on top of it
 type commitStat struct {
-	commitID  string
-	file      string
-	additions int
-	deletions int
+	commitID   string
+	file       string
+	oldFile    string
+	additions  int
+	deletions  int
+	isBinary   bool
+	oldMode    uint16
+	newMode    uint16
+	status     git.Delta
+	similarity uint16
 }
 
 type commitStatsIter struct {
 	repo                   *git.Repository
-	commitIter             *git.RevWalk
+	commitIter             commitWalker
 	currentCommit          *git.Commit
 	commitStats            []*commitStat
 	currentCommitStatIndex int
+	ignoreWhitespace       bool
+	path                   string
+	exclude                func(file string) bool
 }
 
 type commitStatsIterOptions struct {
-	commitID string
+	commitID         string
+	ignoreWhitespace bool
+	// ref is the revision to iterate over the history of, HEAD if it's empty
+	ref string
+	// path is a directory (or a single file) to limit the diffs to, the whole tree if it's empty
+	path string
+	// exclude tells whether to leave the stats of a file out, i.e. because it's vendored
+	exclude func(file string) bool
+	// limit caps the walk of the history, if not nil
+	limit *commitLimit
 }
 
-func stats(commit *git.Commit) ([]*commitStat, error) {
+// stats diffs a commit against its first parent, only looking at the changes under path if it's not empty
+func stats(commit *git.Commit, ignoreWhitespace bool, path string) ([]*commitStat, error) {
 
 	stats := make([]*commitStat, 0)
 
@@ -52,6 +73,14 @@ func stats(commit *git.Commit) ([]*commitStat, error) {
 	if err != nil {
 		return nil, err
 	}
+	if ignoreWhitespace {
+		diffOpts.Flags |= git.DiffIgnoreWhitespace
+	}
+	if path = strings.Trim(path, "/"); path != "" {
+		// a literal path rather than a pattern, matching the files under it if it's a directory
+		diffOpts.Pathspec = []string{path}
+		diffOpts.Flags |= git.DiffDisablePathspecMatch
+	}
 	diff, err := repo.DiffTreeToTree(parentTree, tree, &diffOpts)
 	if err != nil {
 		return nil, err
@@ -67,8 +96,14 @@ func stats(commit *git.Commit) ([]*commitStat, error) {
 
 	err = diff.ForEach(func(delta git.DiffDelta, progress float64) (git.DiffForEachHunkCallback, error) {
 		stat := &commitStat{
-			commitID: commit.Id().String(),
-			file:     delta.NewFile.Path,
+			commitID:   commit.Id().String(),
+			file:       delta.NewFile.Path,
+			oldFile:    delta.OldFile.Path,
+			isBinary:   delta.Flags&git.DiffFlagBinary != 0,
+			oldMode:    delta.OldFile.Mode,
+			newMode:    delta.NewFile.Mode,
+			status:     delta.Status,
+			similarity: delta.Similarity,
 		}
 		stats = append(stats, stat)
 		return func(hunk git.DiffHunk) (git.DiffForEachLineCallback, error) {
@@ -90,26 +125,60 @@ func stats(commit *git.Commit) ([]*commitStat, error) {
 	return stats, nil
 }
 
+// deltaStatus returns the single letter status of a file change, as shown by `git diff --name-status`
+func deltaStatus(d git.Delta) string {
+	switch d {
+	case git.DeltaAdded:
+		return "A"
+	case git.DeltaDeleted:
+		return "D"
+	case git.DeltaModified:
+		return "M"
+	case git.DeltaRenamed:
+		return "R"
+	case git.DeltaCopied:
+		return "C"
+	case git.DeltaTypeChange:
+		return "T"
+	default:
+		return "X"
+	}
+}
+
 func NewCommitStatsIter(repo *git.Repository, opt *commitStatsIterOptions) (*commitStatsIter, error) {
 	if opt.commitID == "" {
-		revWalk, err := repo.Walk()
-		if err != nil {
-			return nil, err
+		var walk commitWalker
+		if opt.ref == "" {
+			// if HEAD is unborn (no commit yet) there is nothing to iterate over
+			unborn, err := repo.IsHeadUnborn()
+			if err != nil {
+				return nil, err
+			}
+			if unborn {
+				return &commitStatsIter{repo: repo}, nil
+			}
+
+			walk, err = walkHead(repo, opt.limit)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			var err error
+			walk, err = walkRef(repo, opt.ref, opt.limit)
+			if err != nil {
+				return nil, err
+			}
 		}
 
-		err = revWalk.PushHead()
-		if err != nil {
-			return nil, err
-		}
-
-		revWalk.Sorting(git.SortNone)
-
 		return &commitStatsIter{
 			repo:                   repo,
-			commitIter:             revWalk,
+			commitIter:             walk,
 			currentCommit:          nil,
 			commitStats:            make([]*commitStat, 0),
 			currentCommitStatIndex: 100, // init with an index greater than above array, so that the first call to Next() sets up the first commit, rather than trying to return a current Blob
+			ignoreWhitespace:       opt.ignoreWhitespace,
+			path:                   opt.path,
+			exclude:                opt.exclude,
 		}, nil
 
 	} else {
@@ -123,10 +192,11 @@ func NewCommitStatsIter(repo *git.Repository, opt *commitStatsIterOptions) (*com
 			return nil, err
 		}
 
-		commitStats, err := stats(commit)
+		commitStats, err := stats(commit, opt.ignoreWhitespace, opt.path)
 		if err != nil {
 			return nil, err
 		}
+		commitStats = filterStats(commitStats, opt.exclude)
 
 		return &commitStatsIter{
 			repo:                   repo,
@@ -134,6 +204,9 @@ func NewCommitStatsIter(repo *git.Repository, opt *commitStatsIterOptions) (*com
 			currentCommit:          commit,
 			commitStats:            commitStats,
 			currentCommitStatIndex: 0,
+			ignoreWhitespace:       opt.ignoreWhitespace,
+			path:                   opt.path,
+			exclude:                opt.exclude,
 		}, nil
 	}
 }
@@ -170,16 +243,21 @@ func (iter *commitStatsIter) Next() (*commitStat, error) {
 
 	iter.currentCommit = commit
 
-	commitStats, err := stats(commit)
+	commitStats, err := stats(commit, iter.ignoreWhitespace, iter.path)
 	if err != nil {
 		return nil, err
 	}
+	commitStats = filterStats(commitStats, iter.exclude)
 
 	iter.commitStats = commitStats
 	iter.currentCommitStatIndex = 0
 
+	// commits without any changes (i.e. empty commits, or only changing excluded files) have no stats, move on to the next one
 	if len(commitStats) == 0 {
-		return iter.Next()
+		stat, err := iter.Next()
+		// the deferred increment of this call would otherwise skip a stat of the next commit
+		iter.currentCommitStatIndex--
+		return stat, err
 	}
 
 	return commitStats[iter.currentCommitStatIndex], nil
@@ -189,7 +267,9 @@ func (iter *commitStatsIter) Close() {
 	if iter == nil {
 		return
 	}
-	iter.currentCommit.Free()
+	if iter.currentCommit != nil {
+		iter.currentCommit.Free()
+	}
 	if iter.commitIter != nil {
 		iter.commitIter.Free()
 	}
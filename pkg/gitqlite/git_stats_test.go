@@ -42,8 +42,8 @@ func TestStatsTable(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if len(contents[0]) != 4 {
-		t.Fatalf("expected 4 columns, got %d", len(contents[0]))
+	if len(contents[0]) != 8 {
+		t.Fatalf("expected 8 columns, got %d", len(contents[0]))
 	}
 
 }
@@ -65,8 +65,8 @@ func TestStatsTableCommitIDIndex(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if len(contents[0]) != 4 {
-		t.Fatalf("expected 4 columns, got %d", len(contents[0]))
+	if len(contents[0]) != 8 {
+		t.Fatalf("expected 8 columns, got %d", len(contents[0]))
 	}
 
 	// TODO actually test the results here?
@@ -74,13 +74,88 @@ func TestStatsTableCommitIDIndex(t *testing.T) {
 	// (avoiding a full table scan)
 }
 
+func TestStatsStatus(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := instance.DB.Query("SELECT status, old_mode, new_mode FROM stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	_, contents, err := GetContents(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, c := range contents {
+		switch c[0] {
+		case "A":
+			if c[1] != "NULL" || c[2] == "NULL" {
+				t.Fatalf("expected only a new mode for added file at row %d, got %s and %s", i, c[1], c[2])
+			}
+		case "D":
+			if c[1] == "NULL" || c[2] != "NULL" {
+				t.Fatalf("expected only an old mode for deleted file at row %d, got %s and %s", i, c[1], c[2])
+			}
+		case "M", "R", "C", "T":
+		default:
+			t.Fatalf("unexpected status %s at row %d", c[0], i)
+		}
+	}
+}
+
+func TestStatsIgnoreWhitespace(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := instance.DB.Query(`
+		SELECT
+			(SELECT sum(additions + deletions) FROM stats),
+			(SELECT sum(additions + deletions) FROM stats WHERE ignore_whitespace = 1),
+			(SELECT count(*) FROM stats WHERE commit_id = (SELECT id FROM commits LIMIT 1) AND ignore_whitespace = 1 AND NOT ignore_whitespace)
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	_, contents, err := GetContents(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := strconv.Atoi(contents[0][0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ignoringWhitespace, err := strconv.Atoi(contents[0][1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ignoringWhitespace > all {
+		t.Fatalf("expected at most %d changed lines when ignoring whitespace, got %d", all, ignoringWhitespace)
+	}
+
+	if contents[0][2] != "0" {
+		t.Fatalf("expected ignore_whitespace to be set on returned rows, got %s rows where it isn't", contents[0][2])
+	}
+}
+
 func TestStatsTotals(t *testing.T) {
 	instance, err := New(fixtureRepoDir, &Options{})
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	iter, err := gitlog.Execute(fixtureRepoDir)
+	iter, err := gitlog.Execute(fixtureRepoDir, "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -149,3 +224,59 @@ func BenchmarkStats(b *testing.B) {
 		}
 	}
 }
+
+func TestStatsByRef(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var root string
+	err = instance.DB.QueryRow("SELECT id FROM commits WHERE parent_count = 0 LIMIT 1").Scan(&root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var byRef, byID int
+	err = instance.DB.QueryRow("SELECT count(*) FROM stats WHERE ref = ?", root).Scan(&byRef)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = instance.DB.QueryRow("SELECT count(*) FROM stats WHERE commit_id = ?", root).Scan(&byID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if byRef != byID {
+		t.Fatalf("expected %d stats in the history of root commit %s, got %d", byID, root, byRef)
+	}
+}
+
+func TestStatsPath(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// limiting the diffs to a directory gives the same stats as filtering them afterwards
+	var count, expected int
+	err = instance.DB.QueryRow("SELECT count(*) FROM stats WHERE path = 'pkg/'").Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = instance.DB.QueryRow("SELECT count(*) FROM stats WHERE file LIKE 'pkg/%' AND status NOT IN ('R', 'C')").Scan(&expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count == 0 || count < expected {
+		t.Fatalf("expected at least %d stats under pkg, got %d", expected, count)
+	}
+
+	err = instance.DB.QueryRow("SELECT count(*) FROM stats WHERE path = 'pkg' AND file NOT LIKE 'pkg/%'").Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Fatalf("expected no stats outside of pkg, got %d", count)
+	}
+}
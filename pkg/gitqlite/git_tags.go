@@ -2,15 +2,19 @@ package gitqlite
 
 import (
 	"fmt"
+	"time"
 
 	git "github.com/libgit2/git2go/v30"
 	"github.com/mattn/go-sqlite3"
 )
 
-type gitTagModule struct{}
+type gitTagModule struct {
+	repos *repoPool
+}
 
 type gitTagTable struct {
 	repoPath string
+	repos    *repoPool
 	repo     *git.Repository
 }
 
@@ -24,16 +28,17 @@ func (m *gitTagModule) Create(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTa
 			tagger_name TEXT,
 			tagger_email TEXT,
 			message TEXT,
-			target_type TEXT
+			target_type TEXT,
+			tagger_when DATETIME
 		)`, args[0]))
 	if err != nil {
 		return nil, err
 	}
 
-	// the repoPath will be enclosed in double quotes "..." since ensureTables uses %q when setting up the table
+	// the repoPath will be enclosed in double quotes "..." since createTables uses %q when setting up the table
 	// we need to pop those off when referring to the actual directory in the fs
 	repoPath := args[3][1 : len(args[3])-1]
-	return &gitTagTable{repoPath: repoPath}, nil
+	return &gitTagTable{repoPath: repoPath, repos: m.repos}, nil
 }
 
 func (m *gitTagModule) Connect(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
@@ -43,7 +48,7 @@ func (m *gitTagModule) Connect(c *sqlite3.SQLiteConn, args []string) (sqlite3.VT
 func (m *gitTagModule) DestroyModule() {}
 
 func (v *gitTagTable) Open() (sqlite3.VTabCursor, error) {
-	repo, err := git.OpenRepository(v.repoPath)
+	repo, err := v.repos.open(v.repoPath)
 	if err != nil {
 		return nil, err
 	}
@@ -130,6 +135,12 @@ func (vc *tagCursor) Column(c *sqlite3.SQLiteContext, col int) error {
 		} else {
 			c.ResultNull()
 		}
+	case 8:
+		if tag != nil {
+			c.ResultText(tag.Tagger().When.Format(time.RFC3339Nano))
+		} else {
+			c.ResultNull()
+		}
 	}
 	return nil
 
@@ -160,7 +171,7 @@ func (vc *tagCursor) EOF() bool {
 }
 
 func (vc *tagCursor) Rowid() (int64, error) {
-	return int64(0), nil
+	return int64(vc.index), nil
 }
 
 func (vc *tagCursor) Close() error {
@@ -29,6 +29,16 @@ func TestTags(t *testing.T) {
 		t.Fatalf("mismatched count of tags, expected %d got %d", len(tags), len(contents))
 	}
 
+	for _, c := range contents {
+		if len(c) != 9 {
+			t.Fatalf("expected 9 columns, got %d", len(c))
+		}
+		// only annotated tags have a tagger
+		if lightweight := c[2] == "1"; lightweight != (c[8] == "NULL") {
+			t.Fatalf("expected a tagger_when for annotated tags only, got %s for %s", c[8], c[0])
+		}
+	}
+
 	// for i, c := range contents {
 	// 	tag, err := tagIterator.Next()
 	// 	if err != nil {
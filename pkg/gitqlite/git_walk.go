@@ -0,0 +1,393 @@
+package gitqlite
+
+import (
+	"container/heap"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	git "github.com/libgit2/git2go/v30"
+)
+
+// commitWalker iterates over the ids of commits in a history, it's implemented by *git.RevWalk
+type commitWalker interface {
+	Next(id *git.Oid) error
+	Free()
+}
+
+// walkHead returns a commitWalker over the history of HEAD, which stops at limit if it isn't nil
+func walkHead(repo *git.Repository, limit *commitLimit) (commitWalker, error) {
+	head, err := repo.Head()
+	if err != nil {
+		return nil, err
+	}
+	defer head.Free()
+
+	return walkFrom(repo, head.Target(), limit)
+}
+
+// walkRef returns a commitWalker over the history of ref, which is anything `git rev-parse` understands
+// (i.e. a branch or tag name, origin/main, HEAD~2 or a commit id)
+func walkRef(repo *git.Repository, ref string, limit *commitLimit) (commitWalker, error) {
+	commit, err := lookupRef(repo, ref)
+	if err != nil {
+		return nil, err
+	}
+	defer commit.Free()
+
+	return walkFrom(repo, commit.Id(), limit)
+}
+
+// lookupRef returns the commit ref points to, ref is anything `git rev-parse` understands
+func lookupRef(repo *git.Repository, ref string) (*git.Commit, error) {
+	obj, err := repo.RevparseSingle(ref)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Free()
+
+	commit, err := obj.Peel(git.ObjectCommit)
+	if err != nil {
+		return nil, err
+	}
+	return commit.AsCommit()
+}
+
+// walkFrom returns a commitWalker over the history of a commit.
+// In a shallow repository, the walk stops at the shallow boundary (commits whose parents weren't fetched)
+// rather than failing on the missing parents, like git does.
+func walkFrom(repo *git.Repository, id *git.Oid, limit *commitLimit) (commitWalker, error) {
+	shallow, err := repo.IsShallow()
+	if err != nil {
+		return nil, err
+	}
+
+	if shallow {
+		boundary, err := shallowBoundary(repo)
+		if err != nil {
+			return nil, err
+		}
+
+		walk := &shallowWalk{repo: repo, boundary: boundary, seen: make(map[git.Oid]bool)}
+		err = walk.push(*id)
+		if err != nil {
+			return nil, err
+		}
+		return limitWalk(walk, limit), nil
+	}
+
+	revWalk, err := repo.Walk()
+	if err != nil {
+		return nil, err
+	}
+
+	err = revWalk.Push(id)
+	if err != nil {
+		revWalk.Free()
+		return nil, err
+	}
+
+	revWalk.Sorting(git.SortNone)
+
+	return limitWalk(revWalk, limit), nil
+}
+
+// DefaultMaxCommits is the number of commits walks of a history stop at, unless Options.MaxCommits says otherwise
+const DefaultMaxCommits = 1000000
+
+// commitLimit caps the number of commits of each walk of a history of the repositories of a GitQLite instance,
+// so that queries accidentally going through the whole history of an enormous repository can't run for ever
+type commitLimit struct {
+	max int
+	// reached is set (to 1) once a walk stopped at max
+	reached int32
+}
+
+// limitWalk caps walk to limit, if it isn't nil
+func limitWalk(walk commitWalker, limit *commitLimit) commitWalker {
+	if limit == nil {
+		return walk
+	}
+	return &limitedWalk{commitWalker: walk, limit: limit}
+}
+
+// limitedWalk ends a walk once it has listed as many commits as its limit, as if it was over
+type limitedWalk struct {
+	commitWalker
+	limit *commitLimit
+	count int
+}
+
+func (w *limitedWalk) Next(id *git.Oid) error {
+	if w.count >= w.limit.max {
+		atomic.StoreInt32(&w.limit.reached, 1)
+		return io.EOF
+	}
+	w.count++
+	return w.commitWalker.Next(id)
+}
+
+// shallowBoundary returns the ids of the commits at the boundary of a shallow repository, as listed in its shallow file.
+// It's empty if the repository isn't shallow.
+func shallowBoundary(repo *git.Repository) (map[git.Oid]bool, error) {
+	boundary := make(map[git.Oid]bool)
+
+	contents, err := ioutil.ReadFile(filepath.Join(CommonDir(repo), "shallow"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return boundary, nil
+		}
+		return nil, err
+	}
+
+	for _, line := range strings.Fields(string(contents)) {
+		id, err := git.NewOid(line)
+		if err != nil {
+			return nil, err
+		}
+		boundary[*id] = true
+	}
+
+	return boundary, nil
+}
+
+// CommonDir returns the git directory shared by all the worktrees of a repository,
+// which is the git directory of the repository itself unless it's a linked worktree
+func CommonDir(repo *git.Repository) string {
+	gitDir := repo.Path()
+	contents, err := ioutil.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return gitDir
+	}
+
+	dir := strings.TrimSpace(string(contents))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(gitDir, dir)
+	}
+	return dir
+}
+
+type queuedCommit struct {
+	id      git.Oid
+	when    time.Time
+	parents []git.Oid
+}
+
+// commitQueue is a heap of commits, the most recently committed first
+type commitQueue []*queuedCommit
+
+func (q commitQueue) Len() int            { return len(q) }
+func (q commitQueue) Less(i, j int) bool  { return q[i].when.After(q[j].when) }
+func (q commitQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *commitQueue) Push(x interface{}) { *q = append(*q, x.(*queuedCommit)) }
+func (q *commitQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	c := old[n-1]
+	*q = old[:n-1]
+	return c
+}
+
+// shallowWalk walks a history in reverse chronological order, without going past the shallow boundary
+type shallowWalk struct {
+	repo     *git.Repository
+	boundary map[git.Oid]bool
+	seen     map[git.Oid]bool
+	queue    commitQueue
+	// until ends the walk at the first commit committed before it, unless it's zero
+	until time.Time
+}
+
+// commitTimeSlop is how much older than the bound of a walkSince the commits it lists can be.
+// The commits of a history aren't always committed in order, as the clocks of committers can be off,
+// and the times they're compared to in queries are often local times (i.e. dates) rather than UTC ones.
+const commitTimeSlop = 48 * time.Hour
+
+// walkSince returns a commitWalker over the history of a commit in reverse chronological order,
+// which ends once it reaches the commits committed well before since (see commitTimeSlop) rather than going through the whole history
+func walkSince(repo *git.Repository, id *git.Oid, since time.Time, limit *commitLimit) (commitWalker, error) {
+	boundary, err := shallowBoundary(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	walk := &shallowWalk{repo: repo, boundary: boundary, seen: make(map[git.Oid]bool), until: since.Add(-commitTimeSlop)}
+	err = walk.push(*id)
+	if err != nil {
+		return nil, err
+	}
+	return limitWalk(walk, limit), nil
+}
+
+func (w *shallowWalk) push(id git.Oid) error {
+	if w.seen[id] {
+		return nil
+	}
+	w.seen[id] = true
+
+	commit, err := w.repo.LookupCommit(&id)
+	if err != nil {
+		return err
+	}
+	defer commit.Free()
+
+	parents := make([]git.Oid, 0, commit.ParentCount())
+	if !w.boundary[id] {
+		for i := uint(0); i < commit.ParentCount(); i++ {
+			parents = append(parents, *commit.ParentId(i))
+		}
+	}
+
+	heap.Push(&w.queue, &queuedCommit{id: id, when: commit.Committer().When, parents: parents})
+	return nil
+}
+
+// Next sets id to the next commit of the walk, or returns io.EOF (leaving id zeroed) once the walk is over
+func (w *shallowWalk) Next(id *git.Oid) error {
+	next, err := w.pop()
+	if next != nil {
+		*id = next.id
+	}
+	return err
+}
+
+// pop returns the next commit of the walk, queuing its parents, or io.EOF once the walk is over.
+// The commit is returned along with the error of queuing its parents, if any.
+func (w *shallowWalk) pop() (*queuedCommit, error) {
+	if w.queue.Len() == 0 || w.queue[0].when.Before(w.until) {
+		return nil, io.EOF
+	}
+
+	next := heap.Pop(&w.queue).(*queuedCommit)
+	for _, parent := range next.parents {
+		err := w.push(parent)
+		if err != nil {
+			return next, err
+		}
+	}
+	return next, nil
+}
+
+func (w *shallowWalk) Free() {}
+
+// newestFirstWalk lists the commits of a shallowWalk by commit time, the most recent first, as it walks the history.
+// The commits of the walk aren't always in order, as a parent can be committed after its child when the clocks of committers
+// were off, so they're held until the walk gets to commits older than them by more than commitTimeSlop,
+// past which no commit can be more recent than them (unless the clocks were off by more than that).
+type newestFirstWalk struct {
+	walk    *shallowWalk
+	pending commitQueue
+	done    bool
+}
+
+// walkNewestFirst returns a commitWalker over the history of a commit by commit time, the most recent first,
+// which ends once it reaches the commits committed well before since (see walkSince) unless it's zero.
+// Unlike sortWalk it doesn't read the whole history first, so that the latest commits come right away.
+func walkNewestFirst(repo *git.Repository, id *git.Oid, since time.Time, limit *commitLimit) (commitWalker, error) {
+	boundary, err := shallowBoundary(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	walk := &shallowWalk{repo: repo, boundary: boundary, seen: make(map[git.Oid]bool)}
+	if !since.IsZero() {
+		walk.until = since.Add(-commitTimeSlop)
+	}
+	err = walk.push(*id)
+	if err != nil {
+		return nil, err
+	}
+	return limitWalk(&newestFirstWalk{walk: walk}, limit), nil
+}
+
+// Next sets id to the next commit of the walk, or returns io.EOF (leaving id zeroed) once the walk is over
+func (w *newestFirstWalk) Next(id *git.Oid) error {
+	for {
+		if w.pending.Len() > 0 && (w.done || w.walk.queue.Len() == 0 || w.pending[0].when.After(w.walk.queue[0].when.Add(commitTimeSlop))) {
+			*id = heap.Pop(&w.pending).(*queuedCommit).id
+			return nil
+		}
+		if w.done {
+			return io.EOF
+		}
+
+		next, err := w.walk.pop()
+		if err == io.EOF {
+			w.done = true
+			continue
+		}
+		if err != nil {
+			*id = next.id
+			return err
+		}
+		heap.Push(&w.pending, next)
+	}
+}
+
+func (w *newestFirstWalk) Free() {}
+
+// sortedWalk lists the commits of a walk by commit time, the most recent first.
+// Unlike a walk sorted by libgit2 it's strictly ordered, even when the clocks of committers were off.
+type sortedWalk struct {
+	ids   []git.Oid
+	index int
+}
+
+type timedCommit struct {
+	id   git.Oid
+	when int64
+}
+
+// sortWalk reads all the commits of walk (and frees it) to list them by commit time, the oldest first if oldestFirst is set
+func sortWalk(repo *git.Repository, walk commitWalker, oldestFirst bool) (commitWalker, error) {
+	defer walk.Free()
+
+	commits := make([]timedCommit, 0)
+	for {
+		id := new(git.Oid)
+		err := walk.Next(id)
+		if err != nil {
+			if id.IsZero() {
+				break
+			}
+			return nil, err
+		}
+
+		commit, err := repo.LookupCommit(id)
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, timedCommit{*id, commit.Committer().When.Unix()})
+		commit.Free()
+	}
+
+	sort.SliceStable(commits, func(i, j int) bool {
+		if oldestFirst {
+			return commits[i].when < commits[j].when
+		}
+		return commits[i].when > commits[j].when
+	})
+
+	ids := make([]git.Oid, len(commits))
+	for i, c := range commits {
+		ids[i] = c.id
+	}
+	return &sortedWalk{ids: ids}, nil
+}
+
+// Next sets id to the next commit of the walk, or returns io.EOF (leaving id zeroed) once the walk is over
+func (w *sortedWalk) Next(id *git.Oid) error {
+	if w.index >= len(w.ids) {
+		return io.EOF
+	}
+	*id = w.ids[w.index]
+	w.index++
+	return nil
+}
+
+func (w *sortedWalk) Free() {}
@@ -0,0 +1,376 @@
+package gitqlite
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	git "github.com/libgit2/git2go/v30"
+	"github.com/mattn/go-sqlite3"
+)
+
+// githubEndpoint is the URL of the GraphQL API of GitHub
+var githubEndpoint = "https://api.github.com/graphql"
+
+// errGitHubToken is the error of the github_* tables queried without a token
+var errGitHubToken = errors.New("the github tables need a GitHub token, i.e. in the GITHUB_TOKEN environment variable")
+
+// githubClient queries the GraphQL API of GitHub
+type githubClient struct {
+	token string
+	http  *http.Client
+}
+
+// pageInfo is where a page of a GraphQL connection ends, and whether there is another one
+type pageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
+// query runs a GraphQL query with variables, and decodes its data into data
+func (c *githubClient) query(query string, variables map[string]interface{}, data interface{}) error {
+	if c.token == "" {
+		return errGitHubToken
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, githubEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GitHub API: %s", resp.Status)
+	}
+
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return err
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("GitHub API: %s", result.Errors[0].Message)
+	}
+	return json.Unmarshal(result.Data, data)
+}
+
+// orgRepos returns the repositories of an organization, as owner/name
+func (c *githubClient) orgRepos(org string) ([]string, error) {
+	repos := make([]string, 0)
+	variables := map[string]interface{}{"org": org}
+	for {
+		var data struct {
+			Organization *struct {
+				Repositories struct {
+					Nodes []struct {
+						NameWithOwner string `json:"nameWithOwner"`
+					} `json:"nodes"`
+					PageInfo pageInfo `json:"pageInfo"`
+				} `json:"repositories"`
+			} `json:"organization"`
+		}
+		err := c.query(`
+			query($org: String!, $after: String) {
+				organization(login: $org) {
+					repositories(first: 100, after: $after, orderBy: {field: NAME, direction: ASC}) {
+						nodes { nameWithOwner }
+						pageInfo { hasNextPage endCursor }
+					}
+				}
+			}`, variables, &data)
+		if err != nil {
+			return nil, err
+		}
+		if data.Organization == nil {
+			return nil, fmt.Errorf("no GitHub organization %s", org)
+		}
+
+		for _, node := range data.Organization.Repositories.Nodes {
+			repos = append(repos, node.NameWithOwner)
+		}
+		if !data.Organization.Repositories.PageInfo.HasNextPage {
+			return repos, nil
+		}
+		variables["after"] = data.Organization.Repositories.PageInfo.EndCursor
+	}
+}
+
+// splitRepo splits the owner/name of a GitHub repository
+func splitRepo(repo string) (string, string, error) {
+	parts := strings.Split(repo, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid GitHub repository %s, expected owner/name", repo)
+	}
+	return parts[0], parts[1], nil
+}
+
+// githubRemote matches the URLs of GitHub repositories, over HTTPS or SSH
+var githubRemote = regexp.MustCompile(`github\.com[:/]+([^/]+)/([^/]+?)(?:\.git)?/*$`)
+
+// githubRepo returns the GitHub repository (as owner/name) the origin remote of the repository at repoPath is,
+// "" if it isn't one
+func githubRepo(repoPath string) (string, error) {
+	repo, err := git.OpenRepository(repoPath)
+	if err != nil {
+		return "", err
+	}
+	defer repo.Free()
+
+	url, err := remoteURL(repo, "origin")
+	if err != nil {
+		return "", err
+	}
+	match := githubRemote.FindStringSubmatch(url)
+	if match == nil {
+		return "", nil
+	}
+	return match[1] + "/" + match[2], nil
+}
+
+// githubTable is a table of data of GitHub repositories read with the GraphQL API, one repository at a time.
+// Its first column is the repository (owner/name), and it has a hidden org column listing the repositories of an organization.
+type githubTable struct {
+	name string
+	// columns are the definitions of the columns past the repository, i.e. "pattern TEXT"
+	columns []string
+	// rows returns the rows of a repository, without the repository
+	rows func(c *githubClient, owner, name string) ([][]interface{}, error)
+}
+
+// githubTables lists the github tables created by New, in the order they're created
+var githubTables = []*githubTable{
+	{name: "github_branch_protection", columns: branchProtectionColumns, rows: branchProtectionRows},
+	{name: "github_stargazers", columns: stargazerColumns, rows: stargazerRows},
+	{name: "github_forks", columns: forkColumns, rows: forkRows},
+}
+
+// findGitHubTable returns the github table named name, nil if there is none
+func findGitHubTable(name string) *githubTable {
+	for _, t := range githubTables {
+		if t.name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// gitHubModule implements a github table, reading the repository its origin remote is unless constrained
+type gitHubModule struct {
+	table  *githubTable
+	client *githubClient
+}
+
+type gitHubVTab struct {
+	repoPath string
+	table    *githubTable
+	client   *githubClient
+}
+
+func (m *gitHubModule) Create(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	err := c.DeclareVTab(fmt.Sprintf(`
+		CREATE TABLE %q (
+			repo TEXT,
+			%s,
+			org TEXT HIDDEN
+		)`, args[0], strings.Join(m.table.columns, ",\n\t\t\t")))
+	if err != nil {
+		return nil, err
+	}
+
+	// the repoPath will be enclosed in double quotes "..." since createTables uses %q when setting up the table
+	// we need to pop those off when referring to the actual directory in the fs
+	repoPath := args[3][1 : len(args[3])-1]
+	return &gitHubVTab{repoPath: repoPath, table: m.table, client: m.client}, nil
+}
+
+func (m *gitHubModule) Connect(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	return m.Create(c, args)
+}
+
+func (m *gitHubModule) DestroyModule() {}
+
+func (v *gitHubVTab) Open() (sqlite3.VTabCursor, error) {
+	return &gitHubCursor{repoPath: v.repoPath, table: v.table, client: v.client}, nil
+}
+
+func (v *gitHubVTab) BestIndex(cst []sqlite3.InfoConstraint, ob []sqlite3.InfoOrderBy) (*sqlite3.IndexResult, error) {
+	used := make([]bool, len(cst))
+	// the values of used constraints are passed to Filter in the order they appear in cst,
+	// so the names of the constrained columns are passed along in that same order in the IdxStr
+	columns := make([]string, 0)
+	// every repository read is a round trip to the API, by far the cheapest plan is to read a single one
+	cost := 1000.0
+	org := len(v.table.columns) + 1
+	for c, constraint := range cst {
+		if !constraint.Usable || constraint.Op != sqlite3.OpEQ {
+			continue
+		}
+		switch {
+		case constraint.Column == 0 && !contains(columns, "repo"):
+			used[c] = true
+			columns = append(columns, "repo")
+			cost /= 100
+		case constraint.Column == org && !contains(columns, "org"):
+			used[c] = true
+			columns = append(columns, "org")
+		}
+	}
+
+	return &sqlite3.IndexResult{Used: used, IdxNum: len(columns), IdxStr: strings.Join(columns, ","), EstimatedCost: cost}, nil
+}
+
+func (v *gitHubVTab) Disconnect() error { return nil }
+func (v *gitHubVTab) Destroy() error    { return nil }
+
+type gitHubCursor struct {
+	repoPath string
+	table    *githubTable
+	client   *githubClient
+	// the values of the constraints on the repo and org columns, org is returned as is so that SQLite's own check of the constraint passes
+	repo, org interface{}
+	repos     []string
+	rows      [][]interface{}
+	index     int
+	rowid     int64
+}
+
+func (vc *gitHubCursor) Column(c *sqlite3.SQLiteContext, col int) error {
+	row := vc.rows[vc.index]
+
+	switch {
+	case col < len(row):
+		switch v := row[col].(type) {
+		case string:
+			c.ResultText(v)
+		case int:
+			c.ResultInt(v)
+		case bool:
+			c.ResultBool(v)
+		default:
+			c.ResultNull()
+		}
+	default:
+		resultConstraint(c, vc.org)
+	}
+	return nil
+}
+
+func (vc *gitHubCursor) Filter(idxNum int, idxStr string, vals []interface{}) error {
+	vc.rowid = 0
+	vc.repo, vc.org = nil, nil
+	vc.rows = nil
+	vc.index = 0
+
+	if idxNum > 0 {
+		for i, column := range strings.Split(idxStr, ",") {
+			switch column {
+			case "repo":
+				vc.repo = vals[i]
+			case "org":
+				vc.org = vals[i]
+			}
+		}
+	}
+
+	switch {
+	case vc.repo != nil:
+		repo, ok := vc.repo.(string)
+		if !ok {
+			return fmt.Errorf("repo must be a GitHub repository, got %v", vc.repo)
+		}
+		vc.repos = []string{repo}
+	case vc.org != nil:
+		org, ok := vc.org.(string)
+		if !ok {
+			return fmt.Errorf("org must be a GitHub organization, got %v", vc.org)
+		}
+		repos, err := vc.client.orgRepos(org)
+		if err != nil {
+			return err
+		}
+		vc.repos = repos
+	default:
+		// there is nothing to read if the repository isn't on GitHub
+		repo, err := githubRepo(vc.repoPath)
+		if err != nil {
+			return err
+		}
+		vc.repos = nil
+		if repo != "" {
+			vc.repos = []string{repo}
+		}
+	}
+
+	return vc.nextRepo()
+}
+
+// nextRepo moves the cursor to the rows of the next repository having any
+func (vc *gitHubCursor) nextRepo() error {
+	for len(vc.repos) > 0 {
+		repo := vc.repos[0]
+		vc.repos = vc.repos[1:]
+
+		owner, name, err := splitRepo(repo)
+		if err != nil {
+			return err
+		}
+		rows, err := vc.table.rows(vc.client, owner, name)
+		if err != nil {
+			return fmt.Errorf("%s: %v", repo, err)
+		}
+		if len(rows) == 0 {
+			continue
+		}
+
+		vc.rows = make([][]interface{}, len(rows))
+		for i, row := range rows {
+			vc.rows[i] = append([]interface{}{repo}, row...)
+		}
+		vc.index = 0
+		return nil
+	}
+
+	vc.rows = nil
+	vc.index = 0
+	return nil
+}
+
+func (vc *gitHubCursor) Next() error {
+	vc.rowid++
+	vc.index++
+	if vc.index < len(vc.rows) {
+		return nil
+	}
+	return vc.nextRepo()
+}
+
+func (vc *gitHubCursor) EOF() bool {
+	return vc.index >= len(vc.rows)
+}
+
+func (vc *gitHubCursor) Rowid() (int64, error) {
+	return vc.rowid, nil
+}
+
+func (vc *gitHubCursor) Close() error {
+	return nil
+}
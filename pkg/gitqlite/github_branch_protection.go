@@ -0,0 +1,99 @@
+package gitqlite
+
+import "strings"
+
+// branchProtectionColumns are the columns of github_branch_protection, one row per branch protection rule
+var branchProtectionColumns = []string{
+	"pattern TEXT",
+	"required_reviews INT",
+	"dismiss_stale_reviews BOOL",
+	"require_code_owner_reviews BOOL",
+	"required_checks TEXT",
+	"strict_checks BOOL",
+	"enforce_admins BOOL",
+	"allow_force_pushes BOOL",
+	"allow_deletions BOOL",
+}
+
+// branchProtectionRows returns the branch protection rules of a repository, which takes admin access to it
+func branchProtectionRows(c *githubClient, owner, name string) ([][]interface{}, error) {
+	rows := make([][]interface{}, 0)
+	variables := map[string]interface{}{"owner": owner, "name": name}
+	for {
+		var data struct {
+			Repository *struct {
+				BranchProtectionRules struct {
+					Nodes []struct {
+						Pattern                      string   `json:"pattern"`
+						RequiresApprovingReviews     bool     `json:"requiresApprovingReviews"`
+						RequiredApprovingReviewCount int      `json:"requiredApprovingReviewCount"`
+						DismissesStaleReviews        bool     `json:"dismissesStaleReviews"`
+						RequiresCodeOwnerReviews     bool     `json:"requiresCodeOwnerReviews"`
+						RequiresStatusChecks         bool     `json:"requiresStatusChecks"`
+						RequiresStrictStatusChecks   bool     `json:"requiresStrictStatusChecks"`
+						RequiredStatusCheckContexts  []string `json:"requiredStatusCheckContexts"`
+						IsAdminEnforced              bool     `json:"isAdminEnforced"`
+						AllowsForcePushes            bool     `json:"allowsForcePushes"`
+						AllowsDeletions              bool     `json:"allowsDeletions"`
+					} `json:"nodes"`
+					PageInfo pageInfo `json:"pageInfo"`
+				} `json:"branchProtectionRules"`
+			} `json:"repository"`
+		}
+		err := c.query(`
+			query($owner: String!, $name: String!, $after: String) {
+				repository(owner: $owner, name: $name) {
+					branchProtectionRules(first: 100, after: $after) {
+						nodes {
+							pattern
+							requiresApprovingReviews
+							requiredApprovingReviewCount
+							dismissesStaleReviews
+							requiresCodeOwnerReviews
+							requiresStatusChecks
+							requiresStrictStatusChecks
+							requiredStatusCheckContexts
+							isAdminEnforced
+							allowsForcePushes
+							allowsDeletions
+						}
+						pageInfo { hasNextPage endCursor }
+					}
+				}
+			}`, variables, &data)
+		if err != nil {
+			return nil, err
+		}
+		if data.Repository == nil {
+			return rows, nil
+		}
+
+		rules := data.Repository.BranchProtectionRules
+		for _, rule := range rules.Nodes {
+			// the reviews and checks required, 0 and NULL if none are
+			reviews := 0
+			if rule.RequiresApprovingReviews {
+				reviews = rule.RequiredApprovingReviewCount
+			}
+			var checks interface{}
+			if rule.RequiresStatusChecks {
+				checks = strings.Join(rule.RequiredStatusCheckContexts, ",")
+			}
+			rows = append(rows, []interface{}{
+				rule.Pattern,
+				reviews,
+				rule.DismissesStaleReviews,
+				rule.RequiresCodeOwnerReviews,
+				checks,
+				rule.RequiresStrictStatusChecks,
+				rule.IsAdminEnforced,
+				rule.AllowsForcePushes,
+				rule.AllowsDeletions,
+			})
+		}
+		if !rules.PageInfo.HasNextPage {
+			return rows, nil
+		}
+		variables["after"] = rules.PageInfo.EndCursor
+	}
+}
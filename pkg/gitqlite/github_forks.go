@@ -0,0 +1,64 @@
+package gitqlite
+
+// forkColumns are the columns of github_forks, one row per fork of the repository
+var forkColumns = []string{
+	"fork TEXT",
+	"owner TEXT",
+	"created_at DATETIME",
+	"pushed_at DATETIME",
+	"stars INT",
+}
+
+// forkRows returns the forks of a repository (not the forks of its forks), from the first one
+func forkRows(c *githubClient, owner, name string) ([][]interface{}, error) {
+	rows := make([][]interface{}, 0)
+	variables := map[string]interface{}{"owner": owner, "name": name}
+	for {
+		var data struct {
+			Repository *struct {
+				Forks struct {
+					Nodes []struct {
+						NameWithOwner string `json:"nameWithOwner"`
+						Owner         struct {
+							Login string `json:"login"`
+						} `json:"owner"`
+						CreatedAt  string  `json:"createdAt"`
+						PushedAt   *string `json:"pushedAt"`
+						Stargazers struct {
+							TotalCount int `json:"totalCount"`
+						} `json:"stargazers"`
+					} `json:"nodes"`
+					PageInfo pageInfo `json:"pageInfo"`
+				} `json:"forks"`
+			} `json:"repository"`
+		}
+		err := c.query(`
+			query($owner: String!, $name: String!, $after: String) {
+				repository(owner: $owner, name: $name) {
+					forks(first: 100, after: $after, orderBy: {field: CREATED_AT, direction: ASC}) {
+						nodes { nameWithOwner owner { login } createdAt pushedAt stargazers { totalCount } }
+						pageInfo { hasNextPage endCursor }
+					}
+				}
+			}`, variables, &data)
+		if err != nil {
+			return nil, err
+		}
+		if data.Repository == nil {
+			return rows, nil
+		}
+
+		forks := data.Repository.Forks
+		for _, fork := range forks.Nodes {
+			var pushedAt interface{}
+			if fork.PushedAt != nil {
+				pushedAt = *fork.PushedAt
+			}
+			rows = append(rows, []interface{}{fork.NameWithOwner, fork.Owner.Login, fork.CreatedAt, pushedAt, fork.Stargazers.TotalCount})
+		}
+		if !forks.PageInfo.HasNextPage {
+			return rows, nil
+		}
+		variables["after"] = forks.PageInfo.EndCursor
+	}
+}
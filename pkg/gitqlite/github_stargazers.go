@@ -0,0 +1,52 @@
+package gitqlite
+
+// stargazerColumns are the columns of github_stargazers, one row per user who starred the repository
+var stargazerColumns = []string{
+	"login TEXT",
+	"starred_at DATETIME",
+}
+
+// stargazerRows returns the users who starred a repository, and when, from the first one
+func stargazerRows(c *githubClient, owner, name string) ([][]interface{}, error) {
+	rows := make([][]interface{}, 0)
+	variables := map[string]interface{}{"owner": owner, "name": name}
+	for {
+		var data struct {
+			Repository *struct {
+				Stargazers struct {
+					Edges []struct {
+						StarredAt string `json:"starredAt"`
+						Node      struct {
+							Login string `json:"login"`
+						} `json:"node"`
+					} `json:"edges"`
+					PageInfo pageInfo `json:"pageInfo"`
+				} `json:"stargazers"`
+			} `json:"repository"`
+		}
+		err := c.query(`
+			query($owner: String!, $name: String!, $after: String) {
+				repository(owner: $owner, name: $name) {
+					stargazers(first: 100, after: $after, orderBy: {field: STARRED_AT, direction: ASC}) {
+						edges { starredAt node { login } }
+						pageInfo { hasNextPage endCursor }
+					}
+				}
+			}`, variables, &data)
+		if err != nil {
+			return nil, err
+		}
+		if data.Repository == nil {
+			return rows, nil
+		}
+
+		stargazers := data.Repository.Stargazers
+		for _, edge := range stargazers.Edges {
+			rows = append(rows, []interface{}{edge.Node.Login, edge.StarredAt})
+		}
+		if !stargazers.PageInfo.HasNextPage {
+			return rows, nil
+		}
+		variables["after"] = stargazers.PageInfo.EndCursor
+	}
+}
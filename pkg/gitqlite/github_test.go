@@ -0,0 +1,168 @@
+package gitqlite
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeGitHub serves the GraphQL API of GitHub, answering every query with the response of the first key of responses it contains
+func fakeGitHub(t *testing.T, responses map[string]string) func() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var body struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		err := json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for key, response := range responses {
+			if strings.Contains(body.Query, key) {
+				_, _ = w.Write([]byte(response))
+				return
+			}
+		}
+		_, _ = w.Write([]byte(`{"errors": [{"message": "unexpected query"}]}`))
+	}))
+
+	endpoint := githubEndpoint
+	githubEndpoint = server.URL
+	return func() {
+		githubEndpoint = endpoint
+		server.Close()
+	}
+}
+
+func TestGitHubRemote(t *testing.T) {
+	tests := map[string]string{
+		"https://github.com/augmentable-dev/askgit.git": "augmentable-dev/askgit",
+		"https://github.com/augmentable-dev/askgit":     "augmentable-dev/askgit",
+		"git@github.com:augmentable-dev/askgit.git":     "augmentable-dev/askgit",
+		"ssh://git@github.com/augmentable-dev/askgit/":  "augmentable-dev/askgit",
+		"https://gitlab.com/augmentable-dev/askgit.git": "",
+	}
+	for url, expected := range tests {
+		repo := ""
+		if match := githubRemote.FindStringSubmatch(url); match != nil {
+			repo = match[1] + "/" + match[2]
+		}
+		if repo != expected {
+			t.Fatalf("expected %q for %s, got %q", expected, url, repo)
+		}
+	}
+}
+
+func TestGitHubBranchProtection(t *testing.T) {
+	defer fakeGitHub(t, map[string]string{
+		"organization": `{"data": {"organization": {"repositories": {
+			"nodes": [{"nameWithOwner": "octo/one"}, {"nameWithOwner": "octo/two"}],
+			"pageInfo": {"hasNextPage": false}
+		}}}}`,
+		"branchProtectionRules": `{"data": {"repository": {"branchProtectionRules": {
+			"nodes": [{
+				"pattern": "main", "requiresApprovingReviews": true, "requiredApprovingReviewCount": 2,
+				"requiresStatusChecks": true, "requiredStatusCheckContexts": ["build", "test"], "isAdminEnforced": true
+			}, {
+				"pattern": "release/*", "requiresApprovingReviews": false, "requiredApprovingReviewCount": 1
+			}],
+			"pageInfo": {"hasNextPage": false}
+		}}}}`,
+	})()
+
+	instance, err := New(fixtureRepoDir, &Options{GitHubToken: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := instance.DB.Query("SELECT pattern, required_reviews, required_checks, CAST(enforce_admins AS INT) FROM github_branch_protection WHERE repo = 'octo/one' ORDER BY pattern")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, contents, err := GetContents(rows)
+	rows.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]string{{"main", "2", "build,test", "1"}, {"release/*", "0", "NULL", "0"}}
+	if len(contents) != len(expected) {
+		t.Fatalf("expected %d rules, got %v", len(expected), contents)
+	}
+	for i, row := range expected {
+		if strings.Join(contents[i], " ") != strings.Join(row, " ") {
+			t.Fatalf("expected rule %v, got %v", row, contents[i])
+		}
+	}
+
+	// the rules of every repository of an organization
+	var repos int
+	err = instance.DB.QueryRow("SELECT count(DISTINCT repo) FROM github_branch_protection WHERE org = 'octo'").Scan(&repos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repos != 2 {
+		t.Fatalf("expected the rules of 2 repositories, got %d", repos)
+	}
+
+	// the API can't be queried without a token
+	instance, err = New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = instance.DB.QueryRow("SELECT count(*) FROM github_branch_protection WHERE repo = 'octo/one'").Scan(&repos)
+	if err == nil || !strings.Contains(err.Error(), "token") {
+		t.Fatalf("expected querying without a token to fail, got %v", err)
+	}
+}
+
+func TestGitHubStargazersAndForks(t *testing.T) {
+	defer fakeGitHub(t, map[string]string{
+		"stargazers(first": `{"data": {"repository": {"stargazers": {
+			"edges": [
+				{"starredAt": "2020-01-02T10:00:00Z", "node": {"login": "jane"}},
+				{"starredAt": "2020-03-04T10:00:00Z", "node": {"login": "john"}}
+			],
+			"pageInfo": {"hasNextPage": false}
+		}}}}`,
+		"forks(first": `{"data": {"repository": {"forks": {
+			"nodes": [
+				{"nameWithOwner": "jane/repo", "owner": {"login": "jane"}, "createdAt": "2020-02-03T10:00:00Z", "pushedAt": null, "stargazers": {"totalCount": 3}}
+			],
+			"pageInfo": {"hasNextPage": false}
+		}}}}`,
+	})()
+
+	instance, err := New(fixtureRepoDir, &Options{GitHubToken: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stars int
+	var first string
+	err = instance.DB.QueryRow("SELECT count(*), min(starred_at) FROM github_stargazers WHERE repo = 'octo/one'").Scan(&stars, &first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stars != 2 || !strings.HasPrefix(first, "2020-01-02") {
+		t.Fatalf("expected 2 stargazers from 2020-01-02, got %d from %s", stars, first)
+	}
+
+	rows, err := instance.DB.Query("SELECT fork, owner, pushed_at, stars FROM github_forks WHERE repo = 'octo/one'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, contents, err := GetContents(rows)
+	rows.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contents) != 1 || strings.Join(contents[0], " ") != "jane/repo jane NULL 3" {
+		t.Fatalf("expected the fork of jane, got %v", contents)
+	}
+}
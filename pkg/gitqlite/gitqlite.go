@@ -1,130 +1,696 @@
 package gitqlite
 
 import (
+	"context"
 	"crypto/md5"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
-	"os/exec"
+	"io/ioutil"
+	"net/http"
+	"os"
 	"os/user"
 	"path"
+	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gitsight/go-vcsurl"
 	git "github.com/libgit2/git2go/v30"
 	"github.com/mattn/go-sqlite3"
 )
 
-// GitQLite loads git repositories into sqlite
+// GitQLite loads git repositories into sqlite.
+// It's safe for concurrent use: every connection of DB has its own tables, and its own handles on the repository,
+// so queries may run concurrently (each on a connection of the pool).
+// A connection opens the repository once, for all of its tables, and keeps it open while it's pooled:
+// queries run one after the other reuse the same connection and repository handle.
 type GitQLite struct {
 	DB       *sql.DB
 	RepoPath string
+	// modules maps every table to the name of the virtual table module implementing it
+	modules map[string]string
+	options *Options
+	// unavailable maps the tables which failed to be created to why, their errors are reported by queries using them
+	mu          sync.Mutex
+	unavailable map[string]error
+	// limit caps the walks of histories, nil if there is no limit
+	limit *commitLimit
 }
 type Options struct {
 	UseGitCLI bool
+	// MaxMemory is a soft limit, in bytes, on the memory SQLite uses for a query (no limit if 0).
+	// Past it, SQLite frees cached pages and spills temporary results (i.e. of a large GROUP BY or ORDER BY) to disk.
+	MaxMemory int64
+	// ExcludeVendored leaves vendored and generated files out of code statistics (the stats, file_churn, dir_stats and contributors tables).
+	// Files are classified like GitHub linguist does, honoring the linguist-vendored and linguist-generated attributes.
+	ExcludeVendored bool
+	// ReadOnly only lets queries read (SELECT and WITH statements, and PRAGMA statements which don't set anything),
+	// so that untrusted queries can be run. Other statements, such as ATTACH or CREATE, fail with "not authorized".
+	ReadOnly bool
+	// Hooks instrument queries, if not nil
+	Hooks *Hooks
+	// MaxCommits is the number of commits walks of a history stop at, leaving the results of queries incomplete (see Truncated),
+	// so that an accidental scan of an enormous repository can't run for ever. It's DefaultMaxCommits if 0, and there is no limit if it's negative.
+	// The commits table read with the git CLI isn't limited.
+	MaxCommits int
+	// Views maps the names of custom views to the SELECT statements defining them, i.e. curated queries for end users.
+	// They're created as temporary views on every connection, and queried like the tables. Each of them must be a single SELECT statement.
+	Views map[string]string
+	// Tables lists the tables to create (see Tables), all of them if empty. Creating only the tables a query uses saves
+	// reading the repository for the others. Views (see Views) may be listed too, they select the tables they read from. The attr, is_vendored and is_generated functions need the gitattributes table.
+	Tables []string
+	// Organizations maps email domains (i.e. example.com) to the organizations they belong to, for the organization function
+	// to report on the contributions of every organization. Subdomains belong to the organization of their parent domain.
+	Organizations map[string]string
+	// GitHubToken authenticates the github_* tables with the API of GitHub, they can't be queried without it
+	GitHubToken string
 }
 
 func init() {
-	sql.Register("gitqlite", &sqlite3.SQLiteDriver{
-		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
-			err := conn.CreateModule("git_log", &gitLogModule{})
-			if err != nil {
-				return err
-			}
+	sql.Register("gitqlite", &sqlite3.SQLiteDriver{ConnectHook: connectHook(nil, nil)})
+}
 
-			err = conn.CreateModule("git_log_cli", &gitLogCLIModule{})
-			if err != nil {
-				return err
+// connectHook returns the function registering the virtual table modules and functions of a new connection,
+// the modules are instrumented with hooks if it isn't nil and their walks of histories capped to limit if it isn't nil
+func connectHook(hooks *Hooks, limit *commitLimit) func(*sqlite3.SQLiteConn) error {
+	return func(conn *sqlite3.SQLiteConn) error {
+		createModule := func(name string, module sqlite3.Module) error {
+			if hooks != nil {
+				module = &instrumentedModule{module, hooks}
 			}
+			return conn.CreateModule(name, module)
+		}
 
-			err = conn.CreateModule("git_tree", &gitTreeModule{})
-			if err != nil {
-				return err
-			}
+		// the tables of a connection share their repositories, along with the objects libgit2 caches for them
+		repos := newRepoPool(limit)
 
-			err = conn.CreateModule("git_tag", &gitTagModule{})
-			if err != nil {
-				return err
-			}
+		err := createModule("git_log", &gitLogModule{repos})
+		if err != nil {
+			return err
+		}
 
-			err = conn.CreateModule("git_branch", &gitBranchModule{})
-			if err != nil {
-				return err
-			}
-			err = conn.CreateModule("git_stats", &gitStatsModule{})
-			if err != nil {
-				return err
-			}
+		err = createModule("git_log_cli", &gitLogCLIModule{})
+		if err != nil {
+			return err
+		}
 
-			err = loadHelperFuncs(conn)
-			if err != nil {
-				return err
-			}
+		err = createModule("git_tree", &gitTreeModule{repos})
+		if err != nil {
+			return err
+		}
 
-			return nil
-		},
-	})
+		err = createModule("git_tag", &gitTagModule{repos})
+		if err != nil {
+			return err
+		}
+
+		err = createModule("git_branch", &gitBranchModule{repos})
+		if err != nil {
+			return err
+		}
+		err = createModule("git_stats", &gitStatsModule{repos})
+		if err != nil {
+			return err
+		}
+
+		err = createModule("git_commit_files", &gitCommitFilesModule{repos})
+		if err != nil {
+			return err
+		}
+
+		err = createModule("git_author", &gitAuthorModule{repos})
+		if err != nil {
+			return err
+		}
+
+		err = createModule("git_file_churn", &gitFileChurnModule{repos})
+		if err != nil {
+			return err
+		}
+
+		err = createModule("git_contributor", &gitContributorModule{repos})
+		if err != nil {
+			return err
+		}
+
+		err = createModule("git_dir_stats", &gitDirStatsModule{repos})
+		if err != nil {
+			return err
+		}
+
+		err = createModule("git_file_last_modified", &gitFileLastModifiedModule{repos})
+		if err != nil {
+			return err
+		}
+
+		err = createModule("git_commit_branch", &gitCommitBranchModule{repos})
+		if err != nil {
+			return err
+		}
+
+		err = createModule("git_branch_lifecycle", &gitBranchLifecycleModule{repos})
+		if err != nil {
+			return err
+		}
+
+		err = createModule("git_merge_preview", &gitMergePreviewModule{repos})
+		if err != nil {
+			return err
+		}
+
+		err = createModule("git_cherry", &gitCherryModule{repos})
+		if err != nil {
+			return err
+		}
+
+		err = createModule("git_commit_lint", &gitCommitLintModule{repos})
+		if err != nil {
+			return err
+		}
+
+		err = createModule("git_commit_issue_ref", &gitCommitIssueRefModule{repos})
+		if err != nil {
+			return err
+		}
+
+		err = createModule("git_hook", &gitHookModule{repos})
+		if err != nil {
+			return err
+		}
+
+		err = createModule("git_ci_workflow", &gitCIWorkflowModule{repos})
+		if err != nil {
+			return err
+		}
+
+		err = createModule("git_dependency_update", &gitDependencyUpdateModule{repos})
+		if err != nil {
+			return err
+		}
+
+		err = createModule("git_repo", &gitRepoModule{repos})
+		if err != nil {
+			return err
+		}
+
+		// attr(path, name) looks attributes up in the repository of the gitattributes table
+		attrs := &attributes{repos: repos}
+		err = createModule("git_attribute", &gitAttributeModule{repos, attrs})
+		if err != nil {
+			return err
+		}
+
+		err = conn.RegisterFunc("attr", attrs.attr, false)
+		if err != nil {
+			return err
+		}
+
+		err = conn.RegisterFunc("is_vendored", attrs.isVendored, false)
+		if err != nil {
+			return err
+		}
+
+		err = conn.RegisterFunc("is_generated", attrs.isGenerated, false)
+		if err != nil {
+			return err
+		}
+
+		err = loadHelperFuncs(conn)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}
 }
 
 // New creates an instance of GitQLite
 func New(repoPath string, options *Options) (*GitQLite, error) {
-	// see https://github.com/mattn/go-sqlite3/issues/204
-	// also mentioned in the FAQ of the README: https://github.com/mattn/go-sqlite3#faq
-	db, err := sql.Open("gitqlite", fmt.Sprintf("file:%x?mode=memory", md5.Sum([]byte(repoPath))))
+	repo, err := git.OpenRepository(repoPath)
 	if err != nil {
 		return nil, err
 	}
-	_, err = git.OpenRepository(repoPath)
+	repo.Free()
+
+	g := &GitQLite{RepoPath: repoPath, modules: make(map[string]string), options: options, unavailable: make(map[string]error)}
+	maxCommits := options.MaxCommits
+	if maxCommits == 0 {
+		maxCommits = DefaultMaxCommits
+	}
+	if maxCommits > 0 {
+		g.limit = &commitLimit{max: maxCommits}
+	}
+
+	err = g.selectModules()
 	if err != nil {
 		return nil, err
 	}
 
-	g := &GitQLite{DB: db, RepoPath: repoPath}
+	// every connection has its own in-memory database (see https://github.com/mattn/go-sqlite3/issues/204,
+	// also mentioned in the FAQ of the README: https://github.com/mattn/go-sqlite3#faq), which setupConn creates the tables in
+	g.DB = sql.OpenDB(newConnector(fmt.Sprintf("file:%x?mode=memory", md5.Sum([]byte(repoPath))), options.Hooks, g.limit, g.setupConn))
 
-	err = g.ensureTables(options)
+	// connect right away, so that a connection failing to be set up fails New rather than the first query
+	err = g.DB.Ping()
 	if err != nil {
+		g.DB.Close()
 		return nil, err
 	}
 	return g, nil
 }
 
-// creates the virtual tables inside of the *sql.DB
-func (g *GitQLite) ensureTables(options *Options) error {
+// connector opens the connections of a GitQLite instance, with the virtual table modules instrumented with hooks (if not nil)
+// and their walks capped to limit (if not nil), and sets each of them up with setup
+type connector struct {
+	driver *sqlite3.SQLiteDriver
+	dsn    string
+}
+
+func newConnector(dsn string, hooks *Hooks, limit *commitLimit, setup func(*sqlite3.SQLiteConn) error) *connector {
+	register := connectHook(hooks, limit)
+	return &connector{
+		driver: &sqlite3.SQLiteDriver{
+			ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+				err := register(conn)
+				if err != nil {
+					return err
+				}
+				return setup(conn)
+			},
+		},
+		dsn: dsn,
+	}
+}
+
+func (c *connector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
 
-	_, err := exec.LookPath("git")
-	localGitExists := err == nil
-	g.RepoPath = strings.ReplaceAll(g.RepoPath, "'", "''")
-	if !options.UseGitCLI || !localGitExists {
-		_, err := g.DB.Exec(fmt.Sprintf("CREATE VIRTUAL TABLE IF NOT EXISTS commits USING git_log('%s');", g.RepoPath))
+func (c *connector) Driver() driver.Driver {
+	return c.driver
+}
+
+// setupConn sets a new connection up: it limits its memory, creates the virtual tables and only authorizes reads if need be
+func (g *GitQLite) setupConn(conn *sqlite3.SQLiteConn) error {
+	if g.options.MaxMemory > 0 {
+		err := limitMemory(conn, g.options.MaxMemory)
 		if err != nil {
 			return err
 		}
+	}
 
-	} else {
-		_, err := g.DB.Exec(fmt.Sprintf("CREATE VIRTUAL TABLE IF NOT EXISTS commits USING git_log_cli('%s');", g.RepoPath))
+	orgs := make(organizations, len(g.options.Organizations))
+	for domain, org := range g.options.Organizations {
+		orgs[strings.ToLower(domain)] = org
+	}
+	err := conn.RegisterFunc("organization", orgs.organization, true)
+	if err != nil {
+		return err
+	}
+
+	// the github tables share a client authenticated with the token of the options
+	client := &githubClient{token: g.options.GitHubToken, http: &http.Client{Timeout: time.Minute}}
+	for _, t := range githubTables {
+		var module sqlite3.Module = &gitHubModule{table: t, client: client}
+		if g.options.Hooks != nil {
+			module = &instrumentedModule{module, g.options.Hooks}
+		}
+		err := conn.CreateModule(t.name, module)
 		if err != nil {
 			return err
 		}
+	}
 
+	err = g.createTables(conn)
+	if err != nil {
+		return err
 	}
-	_, err = g.DB.Exec(fmt.Sprintf("CREATE VIRTUAL TABLE IF NOT EXISTS stats USING git_stats('%s');", g.RepoPath))
+	err = g.createViews(conn)
 	if err != nil {
 		return err
 	}
 
-	_, err = g.DB.Exec(fmt.Sprintf("CREATE VIRTUAL TABLE IF NOT EXISTS files USING git_tree('%s');", g.RepoPath))
+	// only once the tables are created, which isn't a read
+	if g.options.ReadOnly {
+		readOnly(conn)
+	}
+	return nil
+}
+
+// Tables returns the names of the tables available for querying
+func Tables() []string {
+	names := append([]string{}, tables...)
+	for _, t := range githubTables {
+		names = append(names, t.name)
+	}
+	return names
+}
+
+// Module returns the name of the virtual table module implementing table, i.e. git_log_cli when commits are read with the git CLI.
+// It's empty if there is no such table.
+func (g *GitQLite) Module(table string) string {
+	return g.modules[table]
+}
+
+// FindRepoPath looks for the git repository containing path, searching parent directories like git does,
+// so that a subdirectory of a working tree can be used to refer to its repository.
+// If fromEnv is set, path may be empty and GIT_DIR, GIT_WORK_TREE and GIT_CEILING_DIRECTORIES are honored.
+func FindRepoPath(path string, fromEnv bool) (string, error) {
+	var flags git.RepositoryOpenFlag
+	if fromEnv {
+		flags |= git.RepositoryOpenFromEnv
+
+		// libgit2 only honors GIT_DIR when it opens a repository without a path, so it's read here
+		if gitDir := os.Getenv("GIT_DIR"); gitDir != "" {
+			return envRepoPath(gitDir, os.Getenv("GIT_WORK_TREE"))
+		}
+	}
+
+	repo, err := git.OpenRepositoryExtended(path, flags, "")
+	if err != nil {
+		return "", err
+	}
+	defer repo.Free()
+
+	// prefer the top level directory of the working tree, unless it's been moved away from the git directory
+	// (i.e. with GIT_WORK_TREE) in which case it can't be used to open the repository again.
+	// linked worktrees (and submodules) have a .git file pointing at their git directory rather than a .git directory
+	gitDir := filepath.Clean(repo.Path())
+	if workdir := repo.Workdir(); workdir != "" {
+		dotGit := filepath.Join(workdir, ".git")
+		if dotGit == gitDir || isGitFile(dotGit) {
+			return filepath.Clean(workdir), nil
+		}
+	}
+	return gitDir, nil
+}
+
+// envRepoPath returns the path of the repository of the git directory set with GIT_DIR, and the working tree set with GIT_WORK_TREE if any:
+// the top level directory of the working tree if its .git is the git directory, or else the git directory itself
+func envRepoPath(gitDir, workTree string) (string, error) {
+	repo, err := git.OpenRepository(gitDir)
+	if err != nil {
+		return "", err
+	}
+	defer repo.Free()
+
+	gitDir = filepath.Clean(repo.Path())
+	workdir := repo.Workdir()
+	if workTree != "" {
+		workdir = workTree
+	}
+	if workdir != "" {
+		workdir, err = filepath.Abs(workdir)
+		if err != nil {
+			return "", err
+		}
+		if filepath.Join(workdir, ".git") == gitDir {
+			return workdir, nil
+		}
+	}
+	return gitDir, nil
+}
+
+// isGitFile returns whether path is a "gitfile", a regular file containing the location of a git directory
+func isGitFile(path string) bool {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(string(contents), "gitdir: ")
+}
+
+// selectModules selects the module implementing each table to create,
+// each table is implemented by the first available backend supporting it, falling back to libgit2
+func (g *GitQLite) selectModules() error {
+	selected, err := selectTables(g.options.Tables)
 	if err != nil {
 		return err
 	}
-	_, err = g.DB.Exec(fmt.Sprintf("CREATE VIRTUAL TABLE IF NOT EXISTS tags USING git_tag('%s');", g.RepoPath))
+
+	backends := backends(g.options)
+	for _, table := range selected {
+		// the github tables read the API of GitHub whatever the backend, with the module of their own name
+		if findGitHubTable(table) != nil {
+			g.modules[table] = table
+			continue
+		}
+		module := selectModule(table, backends)
+		if module == "" {
+			return fmt.Errorf("no backend available for table %s", table)
+		}
+		g.modules[table] = module
+	}
+	return nil
+}
+
+// createTables creates the virtual tables inside of a connection, with the modules selected for them, and the views over them.
+// A table failing to be created doesn't fail the connection, it's left out and queries using it fail with its error (see Query).
+func (g *GitQLite) createTables(conn *sqlite3.SQLiteConn) error {
+	args := fmt.Sprintf("'%s'", strings.ReplaceAll(g.RepoPath, "'", "''"))
+	if g.options.ExcludeVendored {
+		args += ", 'exclude_vendored'"
+	}
+	created := make(map[string]bool)
+	for _, table := range Tables() {
+		module, ok := g.modules[table]
+		if !ok {
+			continue
+		}
+
+		_, err := conn.Exec(fmt.Sprintf("CREATE VIRTUAL TABLE IF NOT EXISTS %s USING %s(%s);", table, module, args), nil)
+		if err != nil {
+			g.mu.Lock()
+			g.unavailable[table] = err
+			g.mu.Unlock()
+			continue
+		}
+		created[table] = true
+	}
+
+views:
+	for _, v := range views {
+		for _, table := range v.tables {
+			if !created[table] {
+				continue views
+			}
+		}
+
+		_, err := conn.Exec(fmt.Sprintf("CREATE VIEW IF NOT EXISTS %s AS %s;", v.name, v.query), nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createViews creates the custom views of the options inside of a connection. Each of them must be a single SELECT statement,
+// which is checked by an authorizer only allowing to read and to create temporary views while they're created.
+func (g *GitQLite) createViews(conn *sqlite3.SQLiteConn) error {
+	if len(g.options.Views) == 0 {
+		return nil
+	}
+
+	conn.RegisterAuthorizer(func(action int, arg1, arg2, dbName string) int {
+		switch action {
+		case sqlite3.SQLITE_CREATE_TEMP_VIEW:
+			return sqlite3.SQLITE_OK
+		case sqlite3.SQLITE_INSERT:
+			// creating a temporary view inserts it in the temporary schema
+			if arg1 == "sqlite_temp_master" || arg1 == "sqlite_temp_schema" {
+				return sqlite3.SQLITE_OK
+			}
+			return sqlite3.SQLITE_DENY
+		}
+		return readOnlyAction(action, arg2)
+	})
+	defer conn.RegisterAuthorizer(nil)
+
+	for name, query := range g.options.Views {
+		statements := splitStatements(query)
+		if len(statements) != 1 {
+			return fmt.Errorf("invalid view %s: expected a single SELECT statement, got %d statements", name, len(statements))
+		}
+		_, err := conn.Exec(fmt.Sprintf("CREATE TEMP VIEW IF NOT EXISTS %q AS %s", name, statements[0]), nil)
+		if err != nil {
+			return fmt.Errorf("invalid view %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// Truncated returns whether a walk of a history stopped at the MaxCommits limit since the instance was created,
+// in which case the results of the queries run since may be incomplete
+func (g *GitQLite) Truncated() bool {
+	return g.limit != nil && atomic.LoadInt32(&g.limit.reached) == 1
+}
+
+// Unavailable returns the tables which failed to be created, mapped to why
+func (g *GitQLite) Unavailable() map[string]error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	unavailable := make(map[string]error, len(g.unavailable))
+	for table, err := range g.unavailable {
+		unavailable[table] = err
+	}
+	return unavailable
+}
+
+// Validate checks that every statement of query is valid, and only refers to existing tables, columns and functions, without running it.
+func (g *GitQLite) Validate(query string) error {
+	for _, statement := range splitStatements(query) {
+		stmt, err := g.DB.Prepare(statement)
+		if err != nil {
+			return g.explain(err)
+		}
+		err = stmt.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitStatements returns the statements of query, separated by semicolons outside of quotes and comments.
+// Statements made of nothing but comments and whitespace are left out, like SQLite does.
+func splitStatements(query string) []string {
+	var statements []string
+	start, code := 0, false
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			end := c
+			if c == '[' {
+				end = ']'
+			}
+			// a doubled quote is an escaped one, which reads as closing then opening the quotes again
+			next := strings.IndexByte(query[i+1:], end)
+			if next < 0 {
+				i = len(query)
+			} else {
+				i += 1 + next
+			}
+			code = true
+		case strings.HasPrefix(query[i:], "--"):
+			next := strings.IndexByte(query[i:], '\n')
+			if next < 0 {
+				i = len(query)
+			} else {
+				i += next
+			}
+		case strings.HasPrefix(query[i:], "/*"):
+			next := strings.Index(query[i+2:], "*/")
+			if next < 0 {
+				i = len(query)
+			} else {
+				i += 2 + next + 1
+			}
+		case c == ';':
+			if code {
+				statements = append(statements, query[start:i])
+			}
+			start, code = i+1, false
+		case c != ' ' && c != '\t' && c != '\n' && c != '\r':
+			code = true
+		}
+	}
+	if code {
+		statements = append(statements, query[start:])
+	}
+	return statements
+}
+
+// explain replaces the error of a query using a table which failed to be created ("no such table") with why it did
+func (g *GitQLite) explain(err error) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for table, tableErr := range g.unavailable {
+		if strings.Contains(err.Error(), "no such table: "+table) {
+			return fmt.Errorf("table %s is unavailable: %v", table, tableErr)
+		}
+	}
+	return err
+}
+
+// selectTables returns the tables to create among the given names, in the order of tables, or every table if names is empty.
+// Naming a view selects the tables it reads from.
+func selectTables(names []string) ([]string, error) {
+	if len(names) == 0 {
+		return Tables(), nil
+	}
+
+	known := make(map[string]bool)
+	for _, table := range Tables() {
+		known[table] = true
+	}
+	wanted := make(map[string]bool)
+	for _, name := range names {
+		if v := findView(name); v != nil {
+			for _, table := range v.tables {
+				wanted[table] = true
+			}
+			continue
+		}
+		if !known[name] {
+			return nil, fmt.Errorf("unknown table %s", name)
+		}
+		wanted[name] = true
+	}
+
+	selected := make([]string, 0, len(wanted))
+	for _, table := range Tables() {
+		if wanted[table] {
+			selected = append(selected, table)
+		}
+	}
+	return selected, nil
+}
+
+// limitMemory bounds the memory SQLite uses to roughly maxMemory bytes, and has a connection store temporary results in files
+func limitMemory(conn *sqlite3.SQLiteConn, maxMemory int64) error {
+	_, err := conn.Exec(fmt.Sprintf("PRAGMA soft_heap_limit = %d;", maxMemory), nil)
 	if err != nil {
 		return err
 	}
-	_, err = g.DB.Exec(fmt.Sprintf("CREATE VIRTUAL TABLE IF NOT EXISTS branches USING git_branch('%s');", g.RepoPath))
+
+	// a negative cache size is a number of KiB rather than pages, keep a quarter of the memory for the page cache
+	_, err = conn.Exec(fmt.Sprintf("PRAGMA cache_size = %d;", -maxMemory/4/1024), nil)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	_, err = conn.Exec("PRAGMA temp_store = FILE;", nil)
+	return err
+}
+
+// readOnly has SQLite deny any statement which doesn't only read on a connection, by authorizing only reads when statements are prepared
+func readOnly(conn *sqlite3.SQLiteConn) {
+	conn.RegisterAuthorizer(func(action int, arg1, arg2, dbName string) int {
+		return readOnlyAction(action, arg2)
+	})
+}
+
+// readOnlyAction authorizes the actions of statements which only read, arg2 being the second argument of the action
+func readOnlyAction(action int, arg2 string) int {
+	// the action code of recursive common table expressions, which go-sqlite3 doesn't define
+	const sqliteRecursive = 33
+
+	switch action {
+	case sqlite3.SQLITE_SELECT, sqlite3.SQLITE_READ, sqlite3.SQLITE_FUNCTION, sqliteRecursive:
+		return sqlite3.SQLITE_OK
+	case sqlite3.SQLITE_PRAGMA:
+		// arg2 is the value a pragma is set to, if any
+		if arg2 == "" {
+			return sqlite3.SQLITE_OK
+		}
+	}
+	return sqlite3.SQLITE_DENY
 }
 
 func loadHelperFuncs(conn *sqlite3.SQLiteConn) error {
@@ -141,8 +707,46 @@ func loadHelperFuncs(conn *sqlite3.SQLiteConn) error {
 		return err
 	}
 
+	if err := conn.RegisterFunc("is_weekend", isWeekend, true); err != nil {
+		return err
+	}
+
+	if err := conn.RegisterFunc("is_after_hours", isAfterHours, true); err != nil {
+		return err
+	}
+
 	return nil
 }
+
+// isWeekend is is_weekend(when), whether a date (i.e. author_when) is on a Saturday or a Sunday,
+// in the timezone of its offset rather than in UTC. It's NULL if when is NULL or isn't a date.
+func isWeekend(when interface{}) interface{} {
+	t, ok := parseTime(when)
+	if !ok {
+		return nil
+	}
+	return t.Weekday() == time.Saturday || t.Weekday() == time.Sunday
+}
+
+// isAfterHours is is_after_hours(when[, start, end]), whether a date (i.e. author_when) is outside of working hours,
+// from start (9 by default) to end (18 by default) o'clock, in the timezone of its offset rather than in UTC.
+// It's NULL if when is NULL or isn't a date.
+func isAfterHours(when interface{}, hours ...int) (interface{}, error) {
+	start, end := 9, 18
+	switch len(hours) {
+	case 0:
+	case 2:
+		start, end = hours[0], hours[1]
+	default:
+		return nil, fmt.Errorf("expected both the start and the end of working hours, got %d hours", len(hours))
+	}
+	t, ok := parseTime(when)
+	if !ok {
+		return nil, nil
+	}
+	return t.Hour() < start || t.Hour() >= end, nil
+}
+
 func CreateAuthenticationCallback(remote *vcsurl.VCS) *git.CloneOptions {
 	cloneOptions := &git.CloneOptions{}
 
@@ -5,6 +5,11 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/gitsight/go-vcsurl"
@@ -65,6 +70,481 @@ func TestModuleInitialization(t *testing.T) {
 	}
 }
 
+func TestFindRepoPath(t *testing.T) {
+	subdir := filepath.Join(fixtureRepoDir, "askgit-subdir", "nested")
+	err := os.MkdirAll(subdir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(filepath.Join(fixtureRepoDir, "askgit-subdir"))
+
+	found, err := FindRepoPath(subdir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found != filepath.Clean(fixtureRepoDir) {
+		t.Fatalf("expected repo path %s, got %s", fixtureRepoDir, found)
+	}
+
+	os.Setenv("GIT_DIR", filepath.Join(fixtureRepoDir, ".git"))
+	defer os.Unsetenv("GIT_DIR")
+
+	found, err = FindRepoPath(os.TempDir(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found != filepath.Clean(fixtureRepoDir) {
+		t.Fatalf("expected repo path %s from GIT_DIR, got %s", fixtureRepoDir, found)
+	}
+}
+
+func TestBareRepository(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bare")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	bare, err := git.Clone(fixtureRepoDir, dir, &git.CloneOptions{Bare: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bare.Free()
+
+	if !bare.IsBare() {
+		t.Fatal("expected a bare repository")
+	}
+
+	queryAllTables(t, dir)
+}
+
+func TestLinkedWorktree(t *testing.T) {
+	dir, err := ioutil.TempDir("", "worktree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	worktree := filepath.Join(dir, "wt")
+	cmd := exec.Command("git", "worktree", "add", "--detach", worktree)
+	cmd.Dir = fixtureRepoDir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("adding worktree: %v: %s", err, out)
+	}
+	defer func() {
+		cmd := exec.Command("git", "worktree", "remove", "--force", worktree)
+		cmd.Dir = fixtureRepoDir
+		_ = cmd.Run()
+	}()
+
+	found, err := FindRepoPath(worktree, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found != worktree {
+		t.Fatalf("expected repo path %s for linked worktree, got %s", worktree, found)
+	}
+
+	queryAllTables(t, worktree)
+}
+
+func TestShallowRepository(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shallow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cmd := exec.Command("git", "clone", "--quiet", "--depth", "3", "file://"+fixtureRepoDir, dir)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("cloning shallow repository: %v: %s", err, out)
+	}
+
+	cmd = exec.Command("git", "rev-list", "--count", "HEAD")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, options := range []*Options{{}, {UseGitCLI: true}} {
+		instance, err := New(dir, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rows, err := instance.DB.Query("SELECT id, parent_id, parent_count, is_shallow_boundary FROM commits")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, contents, err := GetContents(rows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows.Close()
+
+		if len(contents) != expected {
+			t.Fatalf("expected %d commits in shallow repository, got %d", expected, len(contents))
+		}
+
+		boundaries := 0
+		for _, commit := range contents {
+			if commit[3] != "1" {
+				continue
+			}
+			boundaries++
+			if commit[1] != "NULL" || commit[2] != "0" {
+				t.Fatalf("expected shallow boundary commit %s to have no parent, got %s (%s parents)", commit[0], commit[1], commit[2])
+			}
+		}
+		if boundaries == 0 {
+			t.Fatal("expected at least one shallow boundary commit")
+		}
+	}
+
+	queryAllTables(t, dir)
+}
+
+func TestEmptyRepository(t *testing.T) {
+	dir, err := ioutil.TempDir("", "empty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	empty, err := git.InitRepository(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer empty.Free()
+
+	for _, options := range []*Options{{}, {UseGitCLI: true}} {
+		instance, err := New(dir, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, table := range tables {
+			rows, err := instance.DB.Query(fmt.Sprintf("SELECT * FROM %s", table))
+			if err != nil {
+				t.Fatalf("querying %s of an empty repository: %v", table, err)
+			}
+			_, contents, err := GetContents(rows)
+			if err != nil {
+				t.Fatalf("querying %s of an empty repository: %v", table, err)
+			}
+			rows.Close()
+
+			// repo describes the repository itself, which has no commit yet
+			if table == "repo" {
+				if len(contents) != 1 || contents[0][5] != "0" {
+					t.Fatalf("expected a single row without commits in repo of an empty repository, got %v", contents)
+				}
+				continue
+			}
+			if len(contents) != 0 {
+				t.Fatalf("expected no rows in %s of an empty repository, got %d", table, len(contents))
+			}
+		}
+	}
+}
+
+func TestMaxMemory(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{MaxMemory: 16 * 1024 * 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := instance.DB.Query("PRAGMA temp_store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, contents, err := GetContents(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+
+	// 1 is FILE
+	if len(contents) != 1 || contents[0][0] != "1" {
+		t.Fatalf("expected temporary results to be stored in files, got temp_store %v", contents)
+	}
+
+	rows, err = instance.DB.Query("SELECT file, sum(additions) FROM stats GROUP BY file ORDER BY 2 DESC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = GetContents(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+}
+
+func TestMaxCommits(t *testing.T) {
+	for _, maxCommits := range []int{0, 5, -1} {
+		instance, err := New(fixtureRepoDir, &Options{MaxCommits: maxCommits})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var count, stats int
+		err = instance.DB.QueryRow("SELECT count(*) FROM commits").Scan(&count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = instance.DB.QueryRow("SELECT count(DISTINCT commit_id) FROM stats").Scan(&stats)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if maxCommits == 5 {
+			if count != 5 || stats > 5 || !instance.Truncated() {
+				t.Fatalf("expected walks to stop at 5 commits, got %d commits and the stats of %d", count, stats)
+			}
+		} else if count <= 5 || instance.Truncated() {
+			t.Fatalf("expected walks of the whole history, got %d commits", count)
+		}
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	allowed := []string{
+		"SELECT count(*) FROM commits",
+		"WITH recent AS (SELECT id FROM commits LIMIT 10) SELECT count(*) FROM recent JOIN stats ON stats.commit_id = recent.id",
+		"SELECT str_split(summary, ' ', 0) FROM commits LIMIT 1",
+		"WITH RECURSIVE counter(n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM counter WHERE n < 10) SELECT sum(n) FROM counter",
+		"PRAGMA temp_store",
+	}
+	for _, query := range allowed {
+		rows, err := instance.DB.Query(query)
+		if err != nil {
+			t.Fatalf("expected %q to be allowed, got %v", query, err)
+		}
+		_, _, err = GetContents(rows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows.Close()
+	}
+
+	denied := []string{
+		"CREATE TABLE t (x TEXT)",
+		"ATTACH DATABASE ':memory:' AS other",
+		"PRAGMA temp_store = MEMORY",
+		"CREATE VIRTUAL TABLE other_commits USING git_log('/')",
+		"DROP TABLE commits",
+	}
+	for _, query := range denied {
+		_, err := instance.DB.Exec(query)
+		if err == nil {
+			t.Fatalf("expected %q to be denied", query)
+		}
+	}
+}
+
+func TestSelectedTables(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{Tables: []string{"tags", "commits"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, table := range []string{"commits", "tags"} {
+		if instance.Module(table) == "" {
+			t.Fatalf("expected table %s to be created", table)
+		}
+		rows, err := instance.DB.Query(fmt.Sprintf("SELECT count(*) FROM %s", table))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows.Close()
+	}
+
+	if instance.Module("stats") != "" {
+		t.Fatal("expected table stats not to be created")
+	}
+	_, err = instance.DB.Exec("SELECT count(*) FROM stats")
+	if err == nil {
+		t.Fatal("expected querying a table which isn't created to fail")
+	}
+
+	_, err = New(fixtureRepoDir, &Options{Tables: []string{"commits", "not_a_table"}})
+	if err == nil {
+		t.Fatal("expected an unknown table to fail")
+	}
+}
+
+func TestUnavailableTable(t *testing.T) {
+	// stats fails to be created, with a module which doesn't exist
+	g := &GitQLite{
+		RepoPath:    fixtureRepoDir,
+		modules:     map[string]string{"commits": "git_log", "stats": "not_a_module"},
+		options:     &Options{},
+		unavailable: make(map[string]error),
+	}
+	g.DB = sql.OpenDB(newConnector("file:unavailable?mode=memory", nil, nil, g.setupConn))
+	defer g.DB.Close()
+
+	rows, err := g.Query("SELECT count(*) FROM commits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+
+	_, err = g.Query("SELECT count(*) FROM stats")
+	if err == nil || !strings.Contains(err.Error(), "table stats is unavailable") || !strings.Contains(err.Error(), "not_a_module") {
+		t.Fatalf("expected stats to be reported unavailable, got %v", err)
+	}
+	if _, ok := g.Unavailable()["stats"]; !ok {
+		t.Fatal("expected stats to be listed as unavailable")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := []string{
+		"SELECT id, summary FROM commits WHERE ref = 'HEAD~1'",
+		"SELECT file, sum(additions) FROM stats GROUP BY file",
+		"SELECT attr(name, 'text') FROM files",
+		"SELECT 1; SELECT 'a;b' FROM commits; -- done",
+	}
+	for _, query := range valid {
+		err := instance.Validate(query)
+		if err != nil {
+			t.Fatalf("expected %q to be valid, got %v", query, err)
+		}
+	}
+
+	invalid := []string{
+		"SELEC id FROM commits",
+		"SELECT id FROM not_a_table",
+		"SELECT not_a_column FROM commits",
+		"SELECT not_a_function(id) FROM commits",
+		"SELECT id FROM commits; SELECT id FROM not_a_table",
+	}
+	for _, query := range invalid {
+		err := instance.Validate(query)
+		if err == nil {
+			t.Fatalf("expected %q to be invalid", query)
+		}
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	statements := splitStatements("SELECT 'a;b', \"c;d\" FROM commits; /* ; */ SELECT [e;f] -- ;\n FROM files;\n-- the end")
+	if len(statements) != 2 || !strings.HasPrefix(statements[0], "SELECT 'a;b'") || !strings.HasSuffix(statements[1], "FROM files") {
+		t.Fatalf("unexpected statements: %q", statements)
+	}
+}
+
+func TestConcurrentQueries(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queries := []string{
+		"SELECT count(*) FROM commits",
+		"SELECT count(*) FROM commits JOIN stats ON stats.commit_id = commits.id",
+		"SELECT count(*) FROM files",
+		"SELECT count(*) FROM branches",
+	}
+	expected := make([]int, len(queries))
+	for i, query := range queries {
+		err := instance.DB.QueryRow(query).Scan(&expected[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8*len(queries))
+	for n := 0; n < 8; n++ {
+		for i, query := range queries {
+			wg.Add(1)
+			go func(query string, expected int) {
+				defer wg.Done()
+				var count int
+				err := instance.DB.QueryRow(query).Scan(&count)
+				if err == nil && count != expected {
+					err = fmt.Errorf("expected %d from %q, got %d", expected, query, count)
+				}
+				errs <- err
+			}(query, expected[i])
+		}
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRowid(t *testing.T) {
+	for _, options := range []*Options{{}, {UseGitCLI: true}} {
+		instance, err := New(fixtureRepoDir, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, table := range tables {
+			rows, err := instance.DB.Query(fmt.Sprintf("SELECT rowid FROM %s", table))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, contents, err := GetContents(rows)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rows.Close()
+
+			for i, row := range contents {
+				if row[0] != strconv.Itoa(i) {
+					t.Fatalf("expected rowid %d for row %d of %s, got %s", i, i, table, row[0])
+				}
+			}
+		}
+	}
+}
+
+// queryAllTables checks that every table of the repository at dir can be queried, with both backends
+func queryAllTables(t *testing.T, dir string) {
+	for _, options := range []*Options{{}, {UseGitCLI: true}} {
+		instance, err := New(dir, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, table := range tables {
+			rows, err := instance.DB.Query(fmt.Sprintf("SELECT * FROM %s LIMIT 1", table))
+			if err != nil {
+				t.Fatalf("querying %s of %s: %v", table, dir, err)
+			}
+			_, _, err = GetContents(rows)
+			if err != nil {
+				t.Fatalf("querying %s of %s: %v", table, dir, err)
+			}
+			rows.Close()
+		}
+	}
+}
+
 func GetRowsCount(rows *sql.Rows) int {
 	count := 0
 	for rows.Next() {
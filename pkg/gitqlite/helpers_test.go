@@ -1,6 +1,7 @@
 package gitqlite
 
 import (
+	"database/sql"
 	"testing"
 )
 
@@ -36,3 +37,86 @@ func TestStrSplit(t *testing.T) {
 		t.Fatalf("expected string: %s, got %s", "", contents[0][0])
 	}
 }
+
+func TestWorkingHours(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Saturday 2020-11-07 at 10:00 in Paris is Saturday at 09:00 UTC, but Friday 2020-11-06 at 23:00 is Saturday in UTC
+	for query, expected := range map[string]bool{
+		"SELECT is_weekend('2020-11-07T10:00:00+01:00')":                true,
+		"SELECT is_weekend('2020-11-06T23:00:00+01:00')":                false,
+		"SELECT is_weekend('2020-11-09 12:00:00')":                      false,
+		"SELECT is_after_hours('2020-11-06T23:00:00+01:00')":            true,
+		"SELECT is_after_hours('2020-11-06T10:00:00-08:00')":            false,
+		"SELECT is_after_hours('2020-11-06T08:30:00+01:00')":            true,
+		"SELECT is_after_hours('2020-11-06T08:30:00+01:00', 8, 16)":     false,
+		"SELECT is_after_hours('2020-11-06T16:00:00+01:00', 8, 16)":     true,
+		"SELECT is_after_hours('2020-11-06T17:59:59.5+01:00')":          false,
+		"SELECT is_weekend(author_when) IN (0, 1) FROM commits LIMIT 1": true,
+	} {
+		var got bool
+		err := instance.DB.QueryRow(query).Scan(&got)
+		if err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		if got != expected {
+			t.Fatalf("%s: expected %t, got %t", query, expected, got)
+		}
+	}
+
+	for _, query := range []string{"SELECT is_weekend('not a date')", "SELECT is_weekend(NULL)", "SELECT is_after_hours(NULL, 8, 16)"} {
+		var got sql.NullBool
+		err := instance.DB.QueryRow(query).Scan(&got)
+		if err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		if got.Valid {
+			t.Fatalf("%s: expected NULL, got %t", query, got.Bool)
+		}
+	}
+
+	var got bool
+	if err := instance.DB.QueryRow("SELECT is_after_hours('2020-11-06T16:00:00Z', 8)").Scan(&got); err == nil {
+		t.Fatal("expected is_after_hours with a single hour to fail")
+	}
+}
+
+func TestOrganizations(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{Organizations: map[string]string{"Example.com": "Example Inc", "example.org": "Example Foundation"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for query, expected := range map[string]interface{}{
+		"SELECT organization('jane@example.com')":     "Example Inc",
+		"SELECT organization('jane@eng.EXAMPLE.com')": "Example Inc",
+		"SELECT organization('example.org')":          "Example Foundation",
+		"SELECT organization('jane@example.net')":     nil,
+		"SELECT organization('jane@notexample.com')":  nil,
+		"SELECT organization('not an email')":         nil,
+	} {
+		var got sql.NullString
+		err := instance.DB.QueryRow(query).Scan(&got)
+		if err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		if (expected == nil && got.Valid) || (expected != nil && got.String != expected) {
+			t.Fatalf("%s: expected %v, got %v", query, expected, got)
+		}
+	}
+
+	// the domain of authors is the end of their email
+	var count int
+	err = instance.DB.QueryRow(`
+		SELECT count(*) FROM commits
+		WHERE author_domain IS NOT lower(substr(author_email, instr(author_email, '@') + 1)) AND instr(author_email, '@') > 0`).Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Fatalf("expected the domain of every author, got %d unexpected domains", count)
+	}
+}
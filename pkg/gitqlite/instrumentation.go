@@ -0,0 +1,148 @@
+package gitqlite
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Hooks are callbacks instrumenting the queries of a GitQLite instance, i.e. to log slow queries or to trace them
+// (starting an OpenTelemetry span in OnTableOpen, and ending it in OnRowsScanned).
+// Any of them may be nil, and they may be called concurrently by queries running at the same time.
+type Hooks struct {
+	// OnTableOpen is called whenever a query starts reading a table
+	OnTableOpen func(table string)
+	// OnRowsScanned is called once a query is done reading a table, with the number of rows it read
+	// and the time spent since the table was opened
+	OnRowsScanned func(table string, rows int64, duration time.Duration)
+	// OnQueryDone is called once the rows of a query run with GitQLite.Query are all read or closed,
+	// with the number of rows read, the time spent since the query was run and the error it failed with, if any
+	OnQueryDone func(query string, rows int64, duration time.Duration, err error)
+}
+
+// instrumentedModule reports the tables it implements being read to hooks
+type instrumentedModule struct {
+	sqlite3.Module
+	hooks *Hooks
+}
+
+func (m *instrumentedModule) Create(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	vtab, err := m.Module.Create(c, args)
+	if err != nil {
+		return nil, err
+	}
+	// args[2] is the name of the table
+	return &instrumentedTable{VTab: vtab, table: args[2], hooks: m.hooks}, nil
+}
+
+func (m *instrumentedModule) Connect(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	vtab, err := m.Module.Connect(c, args)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedTable{VTab: vtab, table: args[2], hooks: m.hooks}, nil
+}
+
+type instrumentedTable struct {
+	sqlite3.VTab
+	table string
+	hooks *Hooks
+}
+
+func (v *instrumentedTable) Open() (sqlite3.VTabCursor, error) {
+	cursor, err := v.VTab.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	if v.hooks.OnTableOpen != nil {
+		v.hooks.OnTableOpen(v.table)
+	}
+	return &instrumentedCursor{VTabCursor: cursor, table: v.table, hooks: v.hooks, start: time.Now()}, nil
+}
+
+// instrumentedCursor counts the rows read from a cursor, a cursor is filtered again for each row of the outer table
+// of a join, so it's the rows of every pass which are counted
+type instrumentedCursor struct {
+	sqlite3.VTabCursor
+	table string
+	hooks *Hooks
+	start time.Time
+	rows  int64
+}
+
+func (vc *instrumentedCursor) Filter(idxNum int, idxStr string, vals []interface{}) error {
+	err := vc.VTabCursor.Filter(idxNum, idxStr, vals)
+	if err == nil && !vc.VTabCursor.EOF() {
+		vc.rows++
+	}
+	return err
+}
+
+func (vc *instrumentedCursor) Next() error {
+	err := vc.VTabCursor.Next()
+	if err == nil && !vc.VTabCursor.EOF() {
+		vc.rows++
+	}
+	return err
+}
+
+func (vc *instrumentedCursor) Close() error {
+	err := vc.VTabCursor.Close()
+	if vc.hooks.OnRowsScanned != nil {
+		vc.hooks.OnRowsScanned(vc.table, vc.rows, time.Since(vc.start))
+	}
+	return err
+}
+
+// QueryRows are the rows of a query run with GitQLite.Query, which report the query to the OnQueryDone hook
+// once they're all read or closed
+type QueryRows struct {
+	*sql.Rows
+	query string
+	hooks *Hooks
+	start time.Time
+	count int64
+	done  bool
+}
+
+func (r *QueryRows) Next() bool {
+	if r.Rows.Next() {
+		r.count++
+		return true
+	}
+	r.finish(r.Rows.Err())
+	return false
+}
+
+func (r *QueryRows) Close() error {
+	err := r.Rows.Close()
+	r.finish(r.Rows.Err())
+	return err
+}
+
+func (r *QueryRows) finish(err error) {
+	if r.done {
+		return
+	}
+	r.done = true
+	if r.hooks != nil && r.hooks.OnQueryDone != nil {
+		r.hooks.OnQueryDone(r.query, r.count, time.Since(r.start), err)
+	}
+}
+
+// Query runs a query like DB.Query, its rows report it to the OnQueryDone hook once they're read or closed.
+// Queries using a table which failed to be created fail with the reason it did.
+func (g *GitQLite) Query(query string, args ...interface{}) (*QueryRows, error) {
+	start := time.Now()
+	rows, err := g.DB.Query(query, args...)
+	if err != nil {
+		err = g.explain(err)
+		if hooks := g.options.Hooks; hooks != nil && hooks.OnQueryDone != nil {
+			hooks.OnQueryDone(query, 0, time.Since(start), err)
+		}
+		return nil, err
+	}
+	return &QueryRows{Rows: rows, query: query, hooks: g.options.Hooks, start: start}, nil
+}
@@ -0,0 +1,68 @@
+package gitqlite
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestInstrumentationHooks(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		opened  []string
+		scanned = make(map[string]int64)
+		queries []string
+		count   int64
+	)
+	hooks := &Hooks{
+		OnTableOpen: func(table string) {
+			mu.Lock()
+			defer mu.Unlock()
+			opened = append(opened, table)
+		},
+		OnRowsScanned: func(table string, rows int64, duration time.Duration) {
+			mu.Lock()
+			defer mu.Unlock()
+			scanned[table] += rows
+		},
+		OnQueryDone: func(query string, rows int64, duration time.Duration, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				t.Errorf("unexpected error reported for %q: %v", query, err)
+			}
+			queries = append(queries, query)
+			count = rows
+		},
+	}
+
+	instance, err := New(fixtureRepoDir, &Options{Hooks: hooks})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var commits int64
+	err = instance.DB.QueryRow("SELECT count(*) FROM commits").Scan(&commits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opened) != 1 || opened[0] != "commits" {
+		t.Fatalf("expected the commits table to be opened once, got %v", opened)
+	}
+	if scanned["commits"] != commits {
+		t.Fatalf("expected %d rows to be scanned from commits, got %d", commits, scanned["commits"])
+	}
+
+	query := "SELECT id FROM commits LIMIT 5"
+	rows, err := instance.Query(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+	}
+	// the query is done once all its rows are read, closing them doesn't report it again
+	rows.Close()
+	if len(queries) != 1 || queries[0] != query || count != 5 {
+		t.Fatalf("expected %q to be reported done once with 5 rows, got %v with %d rows", query, queries, count)
+	}
+}
@@ -0,0 +1,133 @@
+package gitqlite
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// junitDisplay outputs the rows as a JUnit XML test suite, so that violations show as failed tests in CI.
+// There is a test case per rule (or rule_id, a single askgit one if there is no such column), failing with every row of the rule
+// unless a passed column tells the row passed. Failures list the message (or detail) of the rows, located by their path (or file)
+// and line, or their commit_id.
+func junitDisplay(rows ResultRows, write io.Writer) error {
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	types, err := declaredTypes(rows)
+	if err != nil {
+		return err
+	}
+	ruleCol, messageCol, passedCol := findColumn(columns, "rule", "rule_id"), findColumn(columns, "message", "detail"), findColumn(columns, "passed")
+	pathCol, lineCol, commitCol := findColumn(columns, "path", "file"), findColumn(columns, "line"), findColumn(columns, "commit_id")
+
+	values := make([]interface{}, len(columns))
+	for i := range values {
+		values[i] = new(interface{})
+	}
+	text := func(i int) string {
+		if i < 0 {
+			return ""
+		}
+		v := jsonValue(types[i], *(values[i].(*interface{})))
+		if v == nil {
+			return ""
+		}
+		return fmt.Sprint(v)
+	}
+
+	// the rules in the order they first appear, and the violations of each of them
+	rules := make([]string, 0)
+	violations := make(map[string][]string)
+	for rows.Next() {
+		err = rows.Scan(values...)
+		if err != nil {
+			return err
+		}
+
+		rule := text(ruleCol)
+		if rule == "" {
+			rule = "askgit"
+		}
+		if _, ok := violations[rule]; !ok {
+			rules = append(rules, rule)
+			violations[rule] = make([]string, 0)
+		}
+		if passedCol >= 0 {
+			if passed := text(passedCol); passed == "true" || passed == "1" {
+				continue
+			}
+		}
+
+		message := text(messageCol)
+		if message == "" {
+			fields := make([]string, 0, len(columns))
+			for i, c := range columns {
+				fields = append(fields, fmt.Sprintf("%s: %s", c, text(i)))
+			}
+			message = strings.Join(fields, ", ")
+		}
+		location := text(pathCol)
+		if line := text(lineCol); location != "" && line != "" {
+			location += ":" + line
+		}
+		if location == "" {
+			location = text(commitCol)
+		}
+		if location != "" {
+			message = location + ": " + message
+		}
+		violations[rule] = append(violations[rule], message)
+	}
+
+	suite := junitTestSuite{Name: "askgit", Tests: len(rules), TestCases: make([]junitTestCase, 0, len(rules))}
+	for _, rule := range rules {
+		testCase := junitTestCase{ClassName: "askgit", Name: rule}
+		if n := len(violations[rule]); n > 0 {
+			suite.Failures++
+			testCase.Failure = &junitFailure{
+				Message: fmt.Sprintf("%d violation(s) of %s", n, rule),
+				Text:    strings.Join(violations[rule], "\n"),
+			}
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+
+	_, err = io.WriteString(write, xml.Header)
+	if err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(write)
+	enc.Indent("", "  ")
+	err = enc.Encode(junitTestSuites{Suites: []junitTestSuite{suite}})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(write, "\n")
+	return err
+}
@@ -0,0 +1,116 @@
+package gitqlite
+
+import (
+	"regexp"
+
+	git "github.com/libgit2/git2go/v30"
+)
+
+// vendoredPaths are the paths of vendored (third-party) code, from GitHub linguist's vendor.yml:
+// https://github.com/github/linguist/blob/master/lib/linguist/vendor.yml
+var vendoredPaths = []*regexp.Regexp{
+	regexp.MustCompile(`(^|/)[Vv]endors?/`),
+	regexp.MustCompile(`(^|/)(3rd|[Tt]hird)[-_]?[Pp]arty/`),
+	regexp.MustCompile(`(^|/)extern(al)?/`),
+	regexp.MustCompile(`(^|/)node_modules/`),
+	regexp.MustCompile(`(^|/)bower_components/`),
+	regexp.MustCompile(`(^|/)Godeps/_workspace/`),
+	regexp.MustCompile(`(^|/)Pods/`),
+	regexp.MustCompile(`(^|/)Carthage/`),
+	regexp.MustCompile(`(^|/)\.yarn/(releases|plugins|sdks|versions)/`),
+	regexp.MustCompile(`^[Dd]ependencies/`),
+	regexp.MustCompile(`^deps/`),
+	regexp.MustCompile(`(^|/)cache/`),
+	regexp.MustCompile(`(^|/)dist/`),
+	regexp.MustCompile(`(^|/)configure$`),
+	regexp.MustCompile(`(^|/)config\.(guess|sub)$`),
+	regexp.MustCompile(`(\.|-)min\.(js|css)$`),
+	regexp.MustCompile(`(^|/)jquery([^.]*)\.js$`),
+	regexp.MustCompile(`(^|/)bootstrap([^/.]*)(\.min)?\.(js|css)$`),
+}
+
+// generatedPaths are the paths of generated code, after the path based checks of GitHub linguist's generated.rb:
+// https://github.com/github/linguist/blob/master/lib/linguist/generated.rb
+// Its checks of file contents (i.e. "Code generated ... DO NOT EDIT.") aren't done, they'd require reading every changed file.
+var generatedPaths = []*regexp.Regexp{
+	regexp.MustCompile(`\.pb\.(go|cc|h)$`),
+	regexp.MustCompile(`_pb2(_grpc)?\.py$`),
+	regexp.MustCompile(`(^|/)zz_generated\.[^/]*\.go$`),
+	regexp.MustCompile(`\.designer\.(cs|vb)$`),
+	regexp.MustCompile(`\.(js|css)\.map$`),
+	regexp.MustCompile(`(^|/)Godeps/`),
+	regexp.MustCompile(`(^|/)(package-lock\.json|npm-shrinkwrap\.json|yarn\.lock|pnpm-lock\.yaml)$`),
+	regexp.MustCompile(`(^|/)(Gemfile\.lock|Cargo\.lock|composer\.lock|poetry\.lock|Pipfile\.lock|go\.sum)$`),
+	regexp.MustCompile(`(^|/)__generated__/`),
+}
+
+// isVendored returns whether file is vendored (third-party) code, as marked with the linguist-vendored attribute
+// or, if the attribute is unspecified, as guessed from its path like GitHub linguist does
+func isVendored(rules []*attributeRule, file string) bool {
+	return classify(rules, file, "linguist-vendored", vendoredPaths)
+}
+
+// isGenerated returns whether file is generated code, as marked with the linguist-generated attribute
+// or, if the attribute is unspecified, as guessed from its path like GitHub linguist does
+func isGenerated(rules []*attributeRule, file string) bool {
+	return classify(rules, file, "linguist-generated", generatedPaths)
+}
+
+func classify(rules []*attributeRule, file, name string, paths []*regexp.Regexp) bool {
+	switch attribute(rules, file, name) {
+	case "set", "true":
+		return true
+	case "unset", "false":
+		return false
+	}
+	for _, re := range paths {
+		if re.MatchString(file) {
+			return true
+		}
+	}
+	return false
+}
+
+// excludedFiles returns a func telling whether a file is vendored or generated in repo, as of its HEAD,
+// for the tables computing code statistics to leave it out
+func excludedFiles(repo *git.Repository) (func(file string) bool, error) {
+	rules, err := attributeRules(repo)
+	if err != nil {
+		return nil, err
+	}
+	return func(file string) bool {
+		return isVendored(rules, file) || isGenerated(rules, file)
+	}, nil
+}
+
+// filterStats returns the stats of the files which aren't excluded, all of them if exclude is nil
+func filterStats(stats []*commitStat, exclude func(file string) bool) []*commitStat {
+	if exclude == nil {
+		return stats
+	}
+	filtered := stats[:0]
+	for _, stat := range stats {
+		if !exclude(stat.file) {
+			filtered = append(filtered, stat)
+		}
+	}
+	return filtered
+}
+
+// isVendored implements is_vendored(path)
+func (a *attributes) isVendored(file string) (bool, error) {
+	rules, err := a.load()
+	if err != nil {
+		return false, err
+	}
+	return isVendored(rules, file), nil
+}
+
+// isGenerated implements is_generated(path)
+func (a *attributes) isGenerated(file string) (bool, error) {
+	rules, err := a.load()
+	if err != nil {
+		return false, err
+	}
+	return isGenerated(rules, file), nil
+}
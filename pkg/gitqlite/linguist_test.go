@@ -0,0 +1,73 @@
+package gitqlite
+
+import (
+	"testing"
+)
+
+func TestClassification(t *testing.T) {
+	rules := parseAttributes(".gitattributes", "", `
+third_party/ours/** -linguist-vendored
+api/*.json linguist-generated
+`)
+
+	tests := []struct {
+		file                string
+		vendored, generated bool
+	}{
+		{"main.go", false, false},
+		{"vendor/github.com/pkg/errors/errors.go", true, false},
+		{"web/node_modules/left-pad/index.js", true, false},
+		{"third_party/theirs/lib.c", true, false},
+		{"third_party/ours/lib.c", false, false},
+		{"static/app.min.js", true, false},
+		{"api/service.pb.go", false, true},
+		{"api/openapi.json", false, true},
+		{"go.sum", false, true},
+		{"web/package-lock.json", false, true},
+		{"vendored.go", false, false},
+	}
+	for _, test := range tests {
+		if vendored := isVendored(rules, test.file); vendored != test.vendored {
+			t.Errorf("expected %s to be vendored: %v, got %v", test.file, test.vendored, vendored)
+		}
+		if generated := isGenerated(rules, test.file); generated != test.generated {
+			t.Errorf("expected %s to be generated: %v, got %v", test.file, test.generated, generated)
+		}
+	}
+}
+
+func TestExcludeVendored(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	excluding, err := New(fixtureRepoDir, &Options{ExcludeVendored: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var want, got int
+	err = instance.DB.QueryRow("SELECT count(*) FROM stats WHERE NOT is_vendored(file) AND NOT is_generated(file)").Scan(&want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = excluding.DB.QueryRow("SELECT count(*) FROM stats").Scan(&got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Fatalf("expected %d stats once vendored and generated files are excluded, got %d", want, got)
+	}
+
+	err = instance.DB.QueryRow("SELECT count(DISTINCT file) FROM stats WHERE NOT is_vendored(file) AND NOT is_generated(file)").Scan(&want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = excluding.DB.QueryRow("SELECT count(*) FROM file_churn").Scan(&got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Fatalf("expected %d files in file_churn once vendored and generated files are excluded, got %d", want, got)
+	}
+}
@@ -0,0 +1,151 @@
+package gitqlite
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	git "github.com/libgit2/git2go/v30"
+)
+
+// mailmapEntry maps the identity of commits (the email, and the name if it's set) to a proper name and email,
+// either of which is "" if it isn't replaced
+type mailmapEntry struct {
+	properName, properEmail string
+	commitName, commitEmail string
+}
+
+// mailmap maps the identities of commits to the identities they're canonically known as, see gitmailmap(5)
+type mailmap struct {
+	entries []*mailmapEntry
+}
+
+// readMailmap reads the mailmap of a repository like git does: the .mailmap of the working tree (of HEAD in a bare repository),
+// then the files of the mailmap.file and mailmap.blob settings, later entries taking precedence.
+// A missing or unreadable mailmap simply means no mapping is applied.
+func readMailmap(repo *git.Repository) *mailmap {
+	m := &mailmap{}
+
+	if workdir := repo.Workdir(); workdir != "" {
+		if contents, err := ioutil.ReadFile(filepath.Join(workdir, ".mailmap")); err == nil {
+			m.parse(string(contents))
+		}
+	} else if contents, err := revisionContents(repo, "HEAD:.mailmap"); err == nil {
+		m.parse(contents)
+	}
+
+	config, err := repo.Config()
+	if err != nil {
+		return m
+	}
+	defer config.Free()
+	if file, err := config.LookupString("mailmap.file"); err == nil && file != "" {
+		if strings.HasPrefix(file, "~/") {
+			if home, err := os.UserHomeDir(); err == nil {
+				file = filepath.Join(home, file[2:])
+			}
+		}
+		if contents, err := ioutil.ReadFile(file); err == nil {
+			m.parse(string(contents))
+		}
+	}
+	if blob, err := config.LookupString("mailmap.blob"); err == nil && blob != "" {
+		if contents, err := revisionContents(repo, blob); err == nil {
+			m.parse(contents)
+		}
+	}
+	return m
+}
+
+// revisionContents returns the contents of the blob a revision (i.e. HEAD:.mailmap) is
+func revisionContents(repo *git.Repository, revision string) (string, error) {
+	object, err := repo.RevparseSingle(revision)
+	if err != nil {
+		return "", err
+	}
+	defer object.Free()
+	blob, err := object.AsBlob()
+	if err != nil {
+		return "", err
+	}
+	defer blob.Free()
+	return string(blob.Contents()), nil
+}
+
+// parse adds the entries of the lines of a mailmap file: "Proper Name <commit@email>", "<proper@email> <commit@email>",
+// "Proper Name <proper@email> <commit@email>" or "Proper Name <proper@email> Commit Name <commit@email>", optionally followed by a # comment
+func (m *mailmap) parse(contents string) {
+	scanner := bufio.NewScanner(strings.NewReader(contents))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		// the names and emails of the line, in order
+		names := make([]string, 0, 2)
+		emails := make([]string, 0, 2)
+		for len(emails) < 2 {
+			open := strings.Index(line, "<")
+			if open < 0 {
+				break
+			}
+			end := strings.Index(line[open:], ">")
+			if end < 0 {
+				break
+			}
+			names = append(names, strings.TrimSpace(line[:open]))
+			emails = append(emails, strings.TrimSpace(line[open+1:open+end]))
+			line = line[open+end+1:]
+		}
+
+		switch len(emails) {
+		case 1:
+			if names[0] != "" {
+				m.entries = append(m.entries, &mailmapEntry{properName: names[0], commitEmail: emails[0]})
+			}
+		case 2:
+			m.entries = append(m.entries, &mailmapEntry{
+				properName:  names[0],
+				properEmail: emails[0],
+				commitName:  names[1],
+				commitEmail: emails[1],
+			})
+		}
+	}
+}
+
+// resolve returns the proper name and email of an identity: the ones of the last entry matching both its name and email,
+// or else its email only, each of them unchanged if the entry doesn't replace it. Names and emails match regardless of case.
+func (m *mailmap) resolve(name, email string) (string, string) {
+	if m == nil {
+		return name, email
+	}
+
+	var match *mailmapEntry
+	for _, entry := range m.entries {
+		if !strings.EqualFold(entry.commitEmail, email) {
+			continue
+		}
+		if entry.commitName != "" {
+			if strings.EqualFold(entry.commitName, name) {
+				match = entry
+			}
+		} else if match == nil || match.commitName == "" {
+			match = entry
+		}
+	}
+
+	if match == nil {
+		return name, email
+	}
+	if match.properName != "" {
+		name = match.properName
+	}
+	if match.properEmail != "" {
+		email = match.properEmail
+	}
+	return name, email
+}
@@ -0,0 +1,41 @@
+package gitqlite
+
+import "testing"
+
+func TestMailmap(t *testing.T) {
+	m := &mailmap{}
+	m.parse(`# the mailmap of the project
+Jane Doe <jane@example.com>
+<john@example.com> <john@laptop.local>
+Joe Developer <joe@example.com> <joe@old.example.com> # moved
+Other Author <other@example.com> Nick1 <bugs@example.com>
+Santa Claus <santa.claus@northpole.xx> <me@company.xx>
+not an entry
+`)
+
+	tests := []struct {
+		name, email   string
+		expectedName  string
+		expectedEmail string
+	}{
+		{"jane", "JANE@example.com", "Jane Doe", "JANE@example.com"},
+		{"John", "john@laptop.local", "John", "john@example.com"},
+		{"Joe", "joe@old.example.com", "Joe Developer", "joe@example.com"},
+		{"nick1", "bugs@example.com", "Other Author", "other@example.com"},
+		{"Nick2", "bugs@example.com", "Nick2", "bugs@example.com"},
+		{"Santa", "me@company.xx", "Santa Claus", "santa.claus@northpole.xx"},
+		{"Nobody", "nobody@example.com", "Nobody", "nobody@example.com"},
+	}
+	for _, test := range tests {
+		name, email := m.resolve(test.name, test.email)
+		if name != test.expectedName || email != test.expectedEmail {
+			t.Fatalf("expected %s <%s> to be %s <%s>, got %s <%s>", test.name, test.email, test.expectedName, test.expectedEmail, name, email)
+		}
+	}
+
+	// no mailmap maps nothing
+	var none *mailmap
+	if name, email := none.resolve("Jane", "jane@example.com"); name != "Jane" || email != "jane@example.com" {
+		t.Fatalf("expected no mapping without a mailmap, got %s <%s>", name, email)
+	}
+}
@@ -0,0 +1,38 @@
+package gitqlite
+
+import (
+	"sync"
+
+	git "github.com/libgit2/git2go/v30"
+)
+
+// repoPool opens each repository only once, rather than once per cursor.
+// libgit2 caches the objects read from a repository, so cursors sharing it don't read the same commits and trees again
+// (i.e. when joining commits and stats).
+type repoPool struct {
+	mu    sync.Mutex
+	repos map[string]*git.Repository
+	// limit caps the walks of the histories of the repositories, if not nil: the tables of the pool pass it on to their cursors
+	limit *commitLimit
+}
+
+func newRepoPool(limit *commitLimit) *repoPool {
+	return &repoPool{repos: make(map[string]*git.Repository), limit: limit}
+}
+
+// open returns the repository at path, opening it if it isn't in the pool yet
+func (p *repoPool) open(path string) (*git.Repository, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if repo, ok := p.repos[path]; ok {
+		return repo, nil
+	}
+
+	repo, err := git.OpenRepository(path)
+	if err != nil {
+		return nil, err
+	}
+	p.repos[path] = repo
+	return repo, nil
+}
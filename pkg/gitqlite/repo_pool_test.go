@@ -0,0 +1,62 @@
+package gitqlite
+
+import "testing"
+
+func TestRepoPool(t *testing.T) {
+	pool := newRepoPool(nil)
+
+	repo, err := pool.open(fixtureRepoDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := pool.open(fixtureRepoDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if repo != again {
+		t.Fatal("expected the repository to be opened once")
+	}
+}
+
+func TestSharedRepoJoin(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// both sides of the join, and the subquery, read from the same repository
+	rows, err := instance.DB.Query("SELECT count(*) FROM commits JOIN stats ON commits.id = stats.commit_id WHERE commits.id IN (SELECT id FROM commits LIMIT 5)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, contents, err := GetContents(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+
+	if len(contents) != 1 {
+		t.Fatalf("expected a single row, got %d", len(contents))
+	}
+}
+
+func TestConnectionReuse(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// queries run one after the other reuse the connection set up by New, along with its repository
+	for i := 0; i < 3; i++ {
+		var count int
+		err := instance.DB.QueryRow("SELECT count(*) FROM commits").Scan(&count)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if open := instance.DB.Stats().OpenConnections; open != 1 {
+		t.Fatalf("expected a single connection, got %d", open)
+	}
+}
@@ -0,0 +1,169 @@
+package gitqlite
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/augmentable-dev/askgit/pkg/gitlog"
+	git "github.com/libgit2/git2go/v30"
+)
+
+// revertMessage matches the line git revert adds to the message of a revert
+var revertMessage = regexp.MustCompile(`This reverts commit ([0-9a-f]{40})`)
+
+// revertedID returns the id of the commit the message of a revert names, empty if it doesn't name any
+func revertedID(message string) string {
+	match := revertMessage.FindStringSubmatch(message)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+// revertCandidate is a commit of a history, as needed to find the reverts in it.
+// patchID and reversePatchID are the patch ids of its changes and of the changes undoing them, empty for merges.
+type revertCandidate struct {
+	id             string
+	message        string
+	when           time.Time
+	patchID        string
+	reversePatchID string
+}
+
+// reverts maps the commits of a history which revert others to the commits they revert, and back
+type reverts struct {
+	reverted   map[string]string
+	revertedBy map[string]string
+}
+
+// findReverts pairs reverts with the commits they revert among commits.
+// A commit reverts the one its message names ("This reverts commit ..."), or if it names none, the most recent commit before it
+// whose changes it undoes (its reverse patch id being the other's patch id). A commit reverted several times is reverted by the first revert.
+func findReverts(commits []*revertCandidate) *reverts {
+	r := &reverts{reverted: make(map[string]string), revertedBy: make(map[string]string)}
+
+	byPatch := make(map[string][]*revertCandidate)
+	when := make(map[string]time.Time)
+	for _, commit := range commits {
+		when[commit.id] = commit.when
+		if commit.patchID != "" {
+			byPatch[commit.patchID] = append(byPatch[commit.patchID], commit)
+		}
+	}
+
+	for _, commit := range commits {
+		reverted := revertedID(commit.message)
+		if reverted == "" && commit.reversePatchID != "" {
+			var match *revertCandidate
+			for _, candidate := range byPatch[commit.reversePatchID] {
+				if candidate.id == commit.id || candidate.when.After(commit.when) {
+					continue
+				}
+				if match == nil || candidate.when.After(match.when) {
+					match = candidate
+				}
+			}
+			if match != nil {
+				reverted = match.id
+			}
+		}
+		if reverted == "" {
+			continue
+		}
+
+		r.reverted[commit.id] = reverted
+		if by, ok := r.revertedBy[reverted]; !ok || commit.when.Before(when[by]) {
+			r.revertedBy[reverted] = commit.id
+		}
+	}
+
+	return r
+}
+
+// indexReverts finds the reverts in the history walk goes over, computing the patch ids of every commit
+func indexReverts(repo *git.Repository, walk commitWalker) (*reverts, error) {
+	commits := make([]*revertCandidate, 0)
+	id := new(git.Oid)
+	for {
+		err := walk.Next(id)
+		if err != nil {
+			if id.IsZero() {
+				break
+			}
+			return nil, err
+		}
+
+		commit, err := repo.LookupCommit(id)
+		if err != nil {
+			return nil, err
+		}
+		candidate := &revertCandidate{id: id.String(), message: commit.Message(), when: commit.Committer().When}
+		if commit.ParentCount() <= 1 {
+			candidate.patchID, err = patchID(repo, commit)
+			if err == nil {
+				candidate.reversePatchID, err = reversePatchID(repo, commit)
+			}
+		}
+		commit.Free()
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, candidate)
+		*id = git.Oid{}
+	}
+
+	return findReverts(commits), nil
+}
+
+// indexRevertsCLI finds the reverts in the history of ref (HEAD if empty) with the git CLI, computing the patch ids of every commit
+func indexRevertsCLI(repoPath, ref string) (*reverts, error) {
+	iter, err := gitlog.Execute(repoPath, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	commits := make([]*revertCandidate, 0)
+	for {
+		commit, err := iter.Next()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+
+		candidate := &revertCandidate{id: commit.SHA, message: commit.Message, when: commit.CommitterWhen}
+		parents := strings.Fields(commit.ParentID)
+		if len(parents) <= 1 {
+			parent := ""
+			if len(parents) == 1 {
+				parent = parents[0]
+			}
+			patch, err := gitlog.Patch(repoPath, commit.SHA, parent)
+			if err != nil {
+				return nil, err
+			}
+			candidate.patchID, err = gitlog.PatchID(bytes.NewReader(patch))
+			if err != nil {
+				return nil, err
+			}
+			// a root commit can't be undone by a commit, there would be nothing left to diff it with
+			if parent != "" {
+				patch, err := gitlog.Patch(repoPath, parent, commit.SHA)
+				if err != nil {
+					return nil, err
+				}
+				candidate.reversePatchID, err = gitlog.PatchID(bytes.NewReader(patch))
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		commits = append(commits, candidate)
+	}
+
+	return findReverts(commits), nil
+}
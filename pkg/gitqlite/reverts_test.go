@@ -0,0 +1,82 @@
+package gitqlite
+
+import (
+	"testing"
+	"time"
+
+	git "github.com/libgit2/git2go/v30"
+)
+
+func TestFindReverts(t *testing.T) {
+	now := time.Now()
+	named := "0123456789abcdef0123456789abcdef01234567"
+	commits := []*revertCandidate{
+		{id: "c", message: "Revert \"b\"\n\nThis reverts commit " + named + ".\n", when: now, patchID: "3", reversePatchID: "4"},
+		{id: "b", message: "b", when: now.Add(-time.Hour), patchID: "2", reversePatchID: "1"},
+		{id: "a", message: "a", when: now.Add(-2 * time.Hour), patchID: "1", reversePatchID: "2"},
+	}
+	reverts := findReverts(commits)
+
+	// c names the commit it reverts, and b undoes the changes of a
+	if reverts.reverted["c"] != named || reverts.revertedBy[named] != "c" {
+		t.Fatalf("expected c to revert %s, got %q", named, reverts.reverted["c"])
+	}
+	if reverts.reverted["b"] != "a" || reverts.revertedBy["a"] != "b" {
+		t.Fatalf("expected b to revert a, got %q", reverts.reverted["b"])
+	}
+	// a undoes the changes of b too, but b is more recent so a can't revert it
+	if reverts.reverted["a"] != "" || reverts.revertedBy["b"] != "" {
+		t.Fatalf("expected a not to revert anything, got %q", reverts.reverted["a"])
+	}
+}
+
+func TestRevertColumns(t *testing.T) {
+	head, err := fixtureRepo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer head.Free()
+	commit, err := fixtureRepo.LookupCommit(head.Target())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer commit.Free()
+	tree, err := commit.Tree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Free()
+
+	// a change, then a commit undoing it without naming it
+	changed := commitContents(t, fixtureRepo, commit, "REVERTED.md", "reverted\n")
+	changedCommit, err := fixtureRepo.LookupCommit(changed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer changedCommit.Free()
+	signature := &git.Signature{Name: "askgit", Email: "askgit@example.com", When: time.Now().Add(time.Second)}
+	revert, err := fixtureRepo.CreateCommit("", signature, signature, "undo the change", tree, changedCommit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, options := range []*Options{{}, {UseGitCLI: true}} {
+		instance, err := New(fixtureRepoDir, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var revertsHash, revertedBy string
+		err = instance.DB.QueryRow("SELECT reverts_hash FROM commits WHERE ref = ? AND id = ?", revert.String(), revert.String()).Scan(&revertsHash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = instance.DB.QueryRow("SELECT reverted_by FROM commits WHERE ref = ? AND id = ?", revert.String(), changed.String()).Scan(&revertedBy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if revertsHash != changed.String() || revertedBy != revert.String() {
+			t.Fatalf("expected %s to revert %s, got %s reverting %s", revert, changed, revertedBy, revertsHash)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package gitqlite
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// sarifSchema is the JSON schema of SARIF 2.1.0, the version GitHub code scanning accepts
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// sarifLevels are the levels of SARIF results, a level column with any other value reports warnings
+var sarifLevels = []string{"error", "warning", "note", "none"}
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool struct {
+		Driver struct {
+			Name           string      `json:"name"`
+			InformationURI string      `json:"informationUri"`
+			Rules          []sarifRule `json:"rules"`
+		} `json:"driver"`
+	} `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID  string `json:"ruleId"`
+	Level   string `json:"level"`
+	Message struct {
+		Text string `json:"text"`
+	} `json:"message"`
+	Locations  []sarifLocation        `json:"locations,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+		Region *struct {
+			StartLine int64 `json:"startLine"`
+		} `json:"region,omitempty"`
+	} `json:"physicalLocation"`
+}
+
+// findColumn returns the index of the first of names which is one of columns, -1 if none is
+func findColumn(columns []string, names ...string) int {
+	for _, name := range names {
+		for i, c := range columns {
+			if strings.EqualFold(c, name) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// sarifDisplay outputs the rows as the results of a single SARIF run, to upload violations to GitHub code scanning.
+// Results are read from the columns by name: the rule (or rule_id), the message (or detail), the level,
+// and the path (or file) and line they're located at. Every column is kept in the properties of the results.
+func sarifDisplay(rows ResultRows, write io.Writer) error {
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	types, err := declaredTypes(rows)
+	if err != nil {
+		return err
+	}
+
+	ruleCol, messageCol, levelCol := findColumn(columns, "rule", "rule_id"), findColumn(columns, "message", "detail"), findColumn(columns, "level")
+	pathCol, lineCol := findColumn(columns, "path", "file"), findColumn(columns, "line")
+
+	values := make([]interface{}, len(columns))
+	for i := range values {
+		values[i] = new(interface{})
+	}
+	value := func(i int) interface{} {
+		if i < 0 {
+			return nil
+		}
+		return jsonValue(types[i], *(values[i].(*interface{})))
+	}
+	text := func(i int) string {
+		v := value(i)
+		if v == nil {
+			return ""
+		}
+		return fmt.Sprint(v)
+	}
+
+	run := sarifRun{Results: make([]sarifResult, 0)}
+	run.Tool.Driver.Name = "askgit"
+	run.Tool.Driver.InformationURI = "https://github.com/augmentable-dev/askgit"
+	run.Tool.Driver.Rules = make([]sarifRule, 0)
+	rules := make(map[string]bool)
+
+	for rows.Next() {
+		err = rows.Scan(values...)
+		if err != nil {
+			return err
+		}
+
+		result := sarifResult{RuleID: text(ruleCol), Level: "warning", Properties: make(map[string]interface{})}
+		if result.RuleID == "" {
+			result.RuleID = "askgit"
+		}
+		if !rules[result.RuleID] {
+			rules[result.RuleID] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: result.RuleID})
+		}
+		if level := strings.ToLower(text(levelCol)); contains(sarifLevels, level) {
+			result.Level = level
+		}
+
+		fields := make([]string, 0, len(columns))
+		for i, c := range columns {
+			result.Properties[c] = value(i)
+			fields = append(fields, fmt.Sprintf("%s: %s", c, text(i)))
+		}
+		// a message is required, rows without one are described by all of their values
+		result.Message.Text = text(messageCol)
+		if result.Message.Text == "" {
+			result.Message.Text = strings.Join(fields, ", ")
+		}
+
+		if path := text(pathCol); path != "" {
+			var location sarifLocation
+			location.PhysicalLocation.ArtifactLocation.URI = path
+			if line, ok := value(lineCol).(int64); ok && line > 0 {
+				location.PhysicalLocation.Region = &struct {
+					StartLine int64 `json:"startLine"`
+				}{line}
+			}
+			result.Locations = []sarifLocation{location}
+		}
+
+		run.Results = append(run.Results, result)
+	}
+
+	enc := json.NewEncoder(write)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{Version: "2.1.0", Schema: sarifSchema, Runs: []sarifRun{run}})
+}
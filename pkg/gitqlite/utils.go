@@ -0,0 +1,166 @@
+package gitqlite
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// contains returns whether s is one of the elements of list
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+// moduleOption returns whether option is passed to a virtual table module, past the repository path,
+// i.e. CREATE VIRTUAL TABLE stats USING git_stats('/path/to/repo', 'exclude_vendored')
+func moduleOption(args []string, option string) bool {
+	if len(args) < 4 {
+		return false
+	}
+	for _, arg := range args[4:] {
+		if strings.Trim(arg, `'"`) == option {
+			return true
+		}
+	}
+	return false
+}
+
+// truthy interprets a constraint value passed in from SQLite as a boolean
+func truthy(val interface{}) bool {
+	switch v := val.(type) {
+	case int64:
+		return v != 0
+	case float64:
+		return v != 0
+	case string:
+		return v != "" && v != "0" && v != "false"
+	case []byte:
+		return len(v) > 0
+	default:
+		return false
+	}
+}
+
+// tzOffset returns the offset of the timezone t was recorded in, in minutes east of UTC
+func tzOffset(t time.Time) int {
+	_, offset := t.Zone()
+	return offset / 60
+}
+
+// summary returns the first paragraph of a commit message, on a single line, like libgit2's git_commit_summary
+func summary(message string) string {
+	lines := make([]string, 0)
+	for _, line := range strings.Split(strings.TrimLeft(message, " \t\r\n"), "\n") {
+		if strings.TrimSpace(line) == "" {
+			break
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimRight(strings.Join(lines, " "), " \t\r\n")
+}
+
+// body returns a commit message without its summary (first paragraph), trimmed of surrounding whitespace
+func body(message string) string {
+	lines := strings.Split(strings.TrimLeft(message, " \t\r\n"), "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			return strings.TrimSpace(strings.Join(lines[i:], "\n"))
+		}
+	}
+	return ""
+}
+
+// resultBody sets the body of a commit message, or NULL if it only has a summary
+func resultBody(c *sqlite3.SQLiteContext, message string) {
+	if b := body(message); b == "" {
+		c.ResultNull()
+	} else {
+		c.ResultText(b)
+	}
+}
+
+// emailDomain returns the domain of an email address, lowercased, or "" if it has none
+func emailDomain(email string) string {
+	i := strings.LastIndex(email, "@")
+	if i < 0 || i == len(email)-1 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(email[i+1:]))
+}
+
+// resultDomain sets the domain of an email address, or NULL if it has none
+func resultDomain(c *sqlite3.SQLiteContext, email string) {
+	if d := emailDomain(email); d == "" {
+		c.ResultNull()
+	} else {
+		c.ResultText(d)
+	}
+}
+
+// organizations maps email domains to the organizations they belong to (see Options.Organizations)
+type organizations map[string]string
+
+// organization is organization(email), the organization an email address (or a domain) belongs to,
+// from its domain or else from the closest parent domain mapped (i.e. example.com for eng.example.com), NULL if none is
+func (o organizations) organization(email string) interface{} {
+	domain := emailDomain(email)
+	if !strings.Contains(email, "@") {
+		domain = strings.ToLower(strings.TrimSpace(email))
+	}
+	for domain != "" {
+		if org, ok := o[domain]; ok {
+			return org
+		}
+		i := strings.Index(domain, ".")
+		if i < 0 {
+			break
+		}
+		domain = domain[i+1:]
+	}
+	return nil
+}
+
+// pullRequestPatterns match the number of the pull request (or merge request) a commit landed with, in its message,
+// as written by the merge and squash buttons of code hosts, or by tools in a trailer
+var pullRequestPatterns = []*regexp.Regexp{
+	// GitHub merge commits
+	regexp.MustCompile(`\AMerge pull request #(\d+) `),
+	// Bitbucket merge commits
+	regexp.MustCompile(`\AMerged in .*\(pull request #(\d+)\)`),
+	// GitHub squashed commits, whose summary ends with the number
+	regexp.MustCompile(`\A[^\n]*\(#(\d+)\)[ \t]*(?:\n|\z)`),
+	// GitLab merge commits
+	regexp.MustCompile(`(?m)^See merge request \S+!(\d+)\s*$`),
+	// trailers, i.e. the PR-URL of commits landed by the tools of Node.js
+	regexp.MustCompile(`(?mi)^(?:PR-URL|Pull-Request|PR):\s*\S*?(?:/pull/|/pulls/|/merge_requests/|#)?(\d+)/?\s*$`),
+}
+
+// pullRequestNumber returns the number of the pull request a commit landed with, from its message, 0 if it names none
+func pullRequestNumber(message string) int {
+	for _, pattern := range pullRequestPatterns {
+		if match := pattern.FindStringSubmatch(message); match != nil {
+			n, err := strconv.Atoi(match[1])
+			if err == nil {
+				return n
+			}
+		}
+	}
+	return 0
+}
+
+// resultPullRequest sets the number of the pull request a commit landed with, or NULL if its message names none
+func resultPullRequest(c *sqlite3.SQLiteContext, message string) {
+	if n := pullRequestNumber(message); n == 0 {
+		c.ResultNull()
+	} else {
+		c.ResultInt(n)
+	}
+}
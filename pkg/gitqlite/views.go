@@ -0,0 +1,118 @@
+package gitqlite
+
+import "fmt"
+
+// view is an SQL view created along with the tables it reads from
+type view struct {
+	name   string
+	tables []string
+	query  string
+}
+
+// views lists the views created by New, in the order they're created.
+// A view is only created if all of its tables are (see Options.Tables).
+var views = []*view{
+	{
+		// the size of every commit, in lines changed, bucketed like the size labels of Kubernetes pull requests
+		name:   "commit_sizes",
+		tables: []string{"commits", "stats"},
+		query: `
+			SELECT
+				commits.id AS commit_id,
+				count(stats.file) AS files_changed,
+				coalesce(sum(stats.additions), 0) AS additions,
+				coalesce(sum(stats.deletions), 0) AS deletions,
+				coalesce(sum(stats.additions + stats.deletions), 0) AS lines_changed,
+				CASE
+					WHEN coalesce(sum(stats.additions + stats.deletions), 0) < 10 THEN 'XS'
+					WHEN sum(stats.additions + stats.deletions) < 30 THEN 'S'
+					WHEN sum(stats.additions + stats.deletions) < 100 THEN 'M'
+					WHEN sum(stats.additions + stats.deletions) < 500 THEN 'L'
+					WHEN sum(stats.additions + stats.deletions) < 1000 THEN 'XL'
+					ELSE 'XXL'
+				END AS size
+			FROM commits LEFT JOIN stats ON stats.commit_id = commits.id
+			GROUP BY commits.id`,
+	},
+	{
+		// the activity of every week, from the Monday starting it, with trends over the last 4 weeks
+		name:   "weekly_activity",
+		tables: []string{"commits", "stats"},
+		query:  activityQuery("date(commits.committer_when_utc, '-6 days', 'weekday 1')", "+7 days", 4),
+	},
+	{
+		// the activity of every month, from its first day, with trends over the last 3 months
+		name:   "monthly_activity",
+		tables: []string{"commits", "stats"},
+		query:  activityQuery("date(commits.committer_when_utc, 'start of month')", "+1 month", 3),
+	},
+}
+
+// activityQuery is the query of a view of the activity of every period of the history, from the first one to the last one
+// a commit landed in (periods without commits included), and of its rolling totals over window periods.
+// period is the expression of the start of the period of a commit, and step the modifier of SQLite date functions
+// moving to the next period.
+func activityQuery(period, step string, window int) string {
+	return fmt.Sprintf(`
+		WITH RECURSIVE
+			changes AS (
+				SELECT
+					%[1]s AS period,
+					commits.author_email,
+					coalesce(sum(stats.additions), 0) AS additions,
+					coalesce(sum(stats.deletions), 0) AS deletions
+				FROM commits LEFT JOIN stats ON stats.commit_id = commits.id
+				GROUP BY commits.id
+			),
+			activity AS (
+				SELECT period, count(*) AS commits, count(DISTINCT author_email) AS authors, sum(additions) AS additions, sum(deletions) AS deletions
+				FROM changes GROUP BY period
+			),
+			periods(period) AS (
+				SELECT * FROM (SELECT min(period) AS period FROM activity) WHERE period IS NOT NULL
+				UNION ALL
+				SELECT date(period, '%[2]s') FROM periods WHERE period < (SELECT max(period) FROM activity)
+			),
+			filled AS (
+				SELECT
+					periods.period,
+					coalesce(activity.commits, 0) AS commits,
+					coalesce(activity.authors, 0) AS authors,
+					coalesce(activity.additions, 0) AS additions,
+					coalesce(activity.deletions, 0) AS deletions
+				FROM periods LEFT JOIN activity ON activity.period = periods.period
+			)
+		SELECT
+			period,
+			commits,
+			authors,
+			additions,
+			deletions,
+			additions + deletions AS churn,
+			sum(commits) OVER trend AS rolling_commits,
+			sum(additions + deletions) OVER trend AS rolling_churn,
+			commits - lag(commits) OVER (ORDER BY period) AS commits_change,
+			additions + deletions - lag(additions + deletions) OVER (ORDER BY period) AS churn_change
+		FROM filled
+		WINDOW trend AS (ORDER BY period ROWS %[3]d PRECEDING)
+		ORDER BY period`, period, step, window-1)
+}
+
+// Views returns the names of the views available for querying
+func Views() []string {
+	names := make([]string, len(views))
+	for i, v := range views {
+		names[i] = v.name
+	}
+	return names
+}
+
+// findView returns the view named name, nil if there is none
+func findView(name string) *view {
+	for _, v := range views {
+		if v.name == name {
+			return v
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package gitqlite
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCommitSizes(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every commit has a size
+	var commits, sized int
+	err = instance.DB.QueryRow("SELECT count(*) FROM commits").Scan(&commits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = instance.DB.QueryRow("SELECT count(*) FROM commit_sizes WHERE size IN ('XS', 'S', 'M', 'L', 'XL', 'XXL')").Scan(&sized)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sized != commits {
+		t.Fatalf("expected %d commits to have a size, got %d", commits, sized)
+	}
+
+	// the totals are the ones of stats
+	var statsLines, sizesLines int
+	err = instance.DB.QueryRow("SELECT sum(additions + deletions) FROM stats").Scan(&statsLines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = instance.DB.QueryRow("SELECT sum(lines_changed) FROM commit_sizes").Scan(&sizesLines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sizesLines != statsLines {
+		t.Fatalf("expected %d lines changed, got %d", statsLines, sizesLines)
+	}
+}
+
+func TestViewTables(t *testing.T) {
+	// selecting a view creates the tables it reads from
+	instance, err := New(fixtureRepoDir, &Options{Tables: []string{"commit_sizes"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if instance.Module("commits") == "" || instance.Module("stats") == "" || instance.Module("files") != "" {
+		t.Fatal("expected only the commits and stats tables to be created")
+	}
+	rows, err := instance.DB.Query("SELECT * FROM commit_sizes LIMIT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	// a view isn't created without its tables
+	instance, err = New(fixtureRepoDir, &Options{Tables: []string{"commits"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := instance.DB.Query("SELECT * FROM commit_sizes"); err == nil {
+		t.Fatal("expected commit_sizes not to exist without the stats table")
+	}
+}
+
+func TestCustomViews(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{Views: map[string]string{
+		"authors_by_month": "SELECT strftime('%Y-%m', author_when) AS month, count(DISTINCT author_email) AS authors FROM commits GROUP BY month;",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var months, expected int
+	err = instance.DB.QueryRow("SELECT count(*) FROM authors_by_month").Scan(&months)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = instance.DB.QueryRow("SELECT count(DISTINCT strftime('%Y-%m', author_when)) FROM commits").Scan(&expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if months == 0 || months != expected {
+		t.Fatalf("expected %d months, got %d", expected, months)
+	}
+
+	// an invalid view fails the instance to be created
+	for _, query := range []string{
+		"SELECT FROM",
+		"SELECT 1; DROP VIEW IF EXISTS activity",
+		"SELECT 1 AS n; ATTACH DATABASE ':memory:' AS other",
+	} {
+		_, err = New(fixtureRepoDir, &Options{Views: map[string]string{"broken": query}})
+		if err == nil {
+			t.Fatalf("expected the invalid view %q to fail", query)
+		}
+	}
+}
+
+func TestActivity(t *testing.T) {
+	instance, err := New(fixtureRepoDir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var commits int
+	err = instance.DB.QueryRow("SELECT count(*) FROM commits").Scan(&commits)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, view := range []string{"weekly_activity", "monthly_activity"} {
+		// every commit is counted once, and periods without commits are listed too
+		var total, periods, gaps int
+		err = instance.DB.QueryRow(fmt.Sprintf("SELECT sum(commits), count(*) FROM %s", view)).Scan(&total, &periods)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != commits {
+			t.Fatalf("%s: expected %d commits, got %d", view, commits, total)
+		}
+		if periods == 0 {
+			t.Fatalf("%s: expected periods", view)
+		}
+
+		// rolling totals sum the last periods, and changes are relative to the previous one
+		err = instance.DB.QueryRow(fmt.Sprintf(`
+			SELECT count(*) FROM %[1]s AS a
+			WHERE a.rolling_commits < a.commits
+				OR a.commits_change IS NOT a.commits - (SELECT commits FROM %[1]s AS b WHERE b.period < a.period ORDER BY b.period DESC LIMIT 1)
+				OR a.churn != a.additions + a.deletions`, view)).Scan(&gaps)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gaps != 0 {
+			t.Fatalf("%s: expected consistent trends, got %d inconsistent periods", view, gaps)
+		}
+	}
+
+	// periods are consecutive weeks
+	var weeks, span int
+	err = instance.DB.QueryRow("SELECT count(*), CAST((julianday(max(period)) - julianday(min(period))) / 7 AS INT) + 1 FROM weekly_activity").Scan(&weeks, &span)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if weeks != span {
+		t.Fatalf("expected %d consecutive weeks, got %d", span, weeks)
+	}
+}
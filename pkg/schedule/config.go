@@ -0,0 +1,136 @@
+package schedule
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config is a set of queries to run on schedules, as read from a YAML file with a list of schedules
+type Config struct {
+	Schedules []*Schedule `yaml:"schedules"`
+}
+
+// Schedule is a query run on a cron expression, whose results are written to an output
+type Schedule struct {
+	Name string `yaml:"name"`
+	Cron string `yaml:"cron"`
+	// Repo is the repository to query, the one given to the schedule command if empty
+	Repo  string `yaml:"repo"`
+	Query string `yaml:"query"`
+	// Format is any format of the --format flag, json if empty
+	Format string `yaml:"format"`
+	// Output is a file path, or an http(s) URL the results are sent to
+	Output string `yaml:"output"`
+	// Method is the HTTP method results are sent to an http(s) output with, POST if empty (i.e. PUT for a presigned S3 URL)
+	Method string `yaml:"method"`
+
+	cron *Cron
+}
+
+// Load reads and validates a config file
+func Load(path string) (*Config, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{}
+	err = yaml.UnmarshalStrict(contents, config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+
+	names := make(map[string]bool)
+	for i, s := range config.Schedules {
+		if s.Name == "" {
+			return nil, fmt.Errorf("schedule %d has no name", i+1)
+		}
+		if names[s.Name] {
+			return nil, fmt.Errorf("schedule %s is defined more than once", s.Name)
+		}
+		names[s.Name] = true
+
+		if s.Query == "" {
+			return nil, fmt.Errorf("schedule %s has no query", s.Name)
+		}
+		if s.Output == "" {
+			return nil, fmt.Errorf("schedule %s has no output", s.Name)
+		}
+		if u, err := url.Parse(s.Output); err == nil && u.Scheme != "" && u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "file" {
+			return nil, fmt.Errorf("schedule %s has an unsupported output %s, only file paths and http(s) URLs are (use a presigned URL and the PUT method for S3)", s.Name, s.Output)
+		}
+		if s.Format == "" {
+			s.Format = "json"
+		}
+
+		s.cron, err = ParseCron(s.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("schedule %s: %v", s.Name, err)
+		}
+	}
+
+	return config, nil
+}
+
+// Next returns the next time the query of the schedule runs, after t
+func (s *Schedule) Next(t time.Time) time.Time {
+	return s.cron.Next(t)
+}
+
+// Write writes the results of a run of the query to the output of the schedule
+func (s *Schedule) Write(results []byte) error {
+	u, err := url.Parse(s.Output)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		path := strings.TrimPrefix(s.Output, "file://")
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(path, results, 0644)
+	}
+
+	method := s.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequest(strings.ToUpper(method), s.Output, bytes.NewReader(results))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType(s.Format))
+
+	// a server which doesn't respond mustn't hold up the runs of the other schedules
+	client := &http.Client{Timeout: time.Minute}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("sending the results of %s to %s failed: %s", s.Name, s.Output, res.Status)
+	}
+	return nil
+}
+
+func contentType(format string) string {
+	switch format {
+	case "json":
+		return "application/json"
+	case "csv":
+		return "text/csv"
+	case "tsv":
+		return "text/tab-separated-values"
+	default:
+		return "text/plain"
+	}
+}
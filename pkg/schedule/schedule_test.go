@@ -0,0 +1,136 @@
+package schedule
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	// a wednesday
+	from := time.Date(2020, time.November, 18, 10, 30, 15, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2020, time.November, 18, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2020, time.November, 18, 10, 45, 0, 0, time.UTC)},
+		{"0 9 * * *", time.Date(2020, time.November, 19, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * mon", time.Date(2020, time.November, 23, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2020, time.November, 19, 9, 0, 0, 0, time.UTC)},
+		{"30 10 1,18 * *", time.Date(2020, time.December, 1, 10, 30, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * 7", time.Date(2020, time.November, 22, 0, 0, 0, 0, time.UTC)},
+		{"5/20 11 * * *", time.Date(2020, time.November, 18, 11, 5, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2020, time.December, 1, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2020, time.November, 18, 11, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		cron, err := ParseCron(test.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next := cron.Next(from); !next.Equal(test.next) {
+			t.Errorf("expected %q to run next at %s, got %s", test.expr, test.next, next)
+		}
+	}
+}
+
+func TestCronInvalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "*/0 * * * *", "5-1 * * * *", "* * * * funday"} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("expected %q to be invalid", expr)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schedule")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "schedules.yaml")
+	err = ioutil.WriteFile(path, []byte(`
+schedules:
+  - name: authors
+    cron: "0 9 * * mon"
+    query: SELECT author_email FROM commits
+    output: reports/authors.json
+  - name: churn
+    cron: "@daily"
+    query: SELECT * FROM file_churn
+    format: csv
+    output: https://example.com/upload
+    method: put
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Schedules) != 2 || config.Schedules[0].Format != "json" || config.Schedules[1].Format != "csv" {
+		t.Fatalf("unexpected schedules: %+v", config.Schedules)
+	}
+
+	for _, invalid := range []string{
+		"schedules:\n  - name: a\n    cron: '* * * * *'\n    query: SELECT 1\n",
+		"schedules:\n  - name: a\n    cron: '* * *'\n    query: SELECT 1\n    output: out.json\n",
+		"schedules:\n  - name: a\n    cron: '* * * * *'\n    query: SELECT 1\n    output: s3://bucket/key\n",
+		"schedules:\n  - name: a\n    cron: '* * * * *'\n    query: SELECT 1\n    output: out.json\n    unknown: true\n",
+	} {
+		err = ioutil.WriteFile(path, []byte(invalid), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(path); err == nil {
+			t.Errorf("expected config to be invalid:\n%s", invalid)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schedule")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := &Schedule{Name: "file", Format: "csv", Output: filepath.Join(dir, "reports", "out.csv")}
+	err = file.Write([]byte("a,b\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents, err := ioutil.ReadFile(file.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(contents) != "a,b\n" {
+		t.Fatalf("unexpected output file contents: %q", contents)
+	}
+
+	var method, contentType, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		method, contentType, body = r.Method, r.Header.Get("Content-Type"), string(b)
+	}))
+	defer server.Close()
+
+	endpoint := &Schedule{Name: "http", Format: "json", Output: server.URL, Method: "put"}
+	err = endpoint.Write([]byte("{}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if method != http.MethodPut || contentType != "application/json" || body != "{}\n" {
+		t.Fatalf("unexpected request: %s %s %q", method, contentType, body)
+	}
+}
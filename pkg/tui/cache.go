@@ -0,0 +1,84 @@
+package tui
+
+import (
+	"strings"
+
+	git "github.com/libgit2/git2go/v30"
+)
+
+// cachedResults is how many query results are kept, so that running one of the last queries again is instantaneous
+const cachedResults = 20
+
+// results is the cache of the output of the last queries run in the TUI
+var results = newResultCache(cachedResults)
+
+// resultCache keeps the output of the last queries, keyed by the query and the commit checked out (see resultKey)
+type resultCache struct {
+	size int
+	// keys are the keys of the outputs, the least recently used first
+	keys    []string
+	outputs map[string]string
+}
+
+func newResultCache(size int) *resultCache {
+	return &resultCache{size: size, outputs: make(map[string]string)}
+}
+
+// get returns the output cached for key, if any
+func (c *resultCache) get(key string) (string, bool) {
+	output, ok := c.outputs[key]
+	if ok {
+		c.touch(key)
+	}
+	return output, ok
+}
+
+// put caches the output for key, evicting the least recently used output if the cache is full
+func (c *resultCache) put(key, output string) {
+	if _, ok := c.outputs[key]; ok {
+		c.touch(key)
+	} else {
+		c.keys = append(c.keys, key)
+	}
+	c.outputs[key] = output
+
+	for len(c.keys) > c.size {
+		delete(c.outputs, c.keys[0])
+		c.keys = c.keys[1:]
+	}
+}
+
+// touch marks key as the most recently used
+func (c *resultCache) touch(key string) {
+	for i, k := range c.keys {
+		if k == key {
+			c.keys = append(append(c.keys[:i:i], c.keys[i+1:]...), key)
+			return
+		}
+	}
+}
+
+// resultKey returns the key of the results of query in the cache: the query along with the commit checked out,
+// so that results aren't reused once the repository moved on. ok is false for statements which aren't only reads,
+// whose results shouldn't be reused.
+func resultKey(query string) (key string, ok bool, err error) {
+	statement := strings.ToUpper(strings.TrimSpace(query))
+	if !strings.HasPrefix(statement, "SELECT") && !strings.HasPrefix(statement, "WITH") {
+		return "", false, nil
+	}
+
+	repo, err := git.OpenRepository(repoPath)
+	if err != nil {
+		return "", false, err
+	}
+	defer repo.Free()
+
+	// an unborn HEAD has no commit yet, which is a state of its own
+	head := ""
+	ref, err := repo.Head()
+	if err == nil {
+		head = ref.Target().String()
+		ref.Free()
+	}
+	return head + "\n" + query, true, nil
+}
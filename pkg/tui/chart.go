@@ -0,0 +1,168 @@
+package tui
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/augmentable-dev/askgit/pkg/gitqlite"
+)
+
+// charting is whether results are rendered as a bar chart rather than as a table, when they can be (see chartResults)
+var charting = false
+
+// maxLabelWidth is the width past which the labels of a chart are truncated
+const maxLabelWidth = 30
+
+// eighths are the blocks drawing the fraction of a cell at the end of a bar
+var eighths = []string{"", "▏", "▎", "▍", "▌", "▋", "▊", "▉"}
+
+// bufferedRows are rows read in memory, so that they can still be displayed once read
+type bufferedRows struct {
+	columns []string
+	types   []*sql.ColumnType
+	values  [][]sql.NullString
+	row     int
+}
+
+func (r *bufferedRows) Columns() ([]string, error) { return r.columns, nil }
+
+func (r *bufferedRows) ColumnTypes() ([]*sql.ColumnType, error) { return r.types, nil }
+
+func (r *bufferedRows) Next() bool {
+	if r.row >= len(r.values) {
+		return false
+	}
+	r.row++
+	return true
+}
+
+func (r *bufferedRows) Scan(dest ...interface{}) error {
+	for i, d := range dest {
+		s, ok := d.(*sql.NullString)
+		if !ok {
+			return fmt.Errorf("unsupported scan of column %s into %T", r.columns[i], d)
+		}
+		*s = r.values[r.row-1][i]
+	}
+	return nil
+}
+
+// chartResults renders rows as a horizontal bar chart fitting in width, if they are made of a label column and a numeric one,
+// as aggregate queries usually are (i.e. SELECT author_email, count(*) FROM commits GROUP BY author_email).
+// Other results are displayed as a table, after the reason why they can't be charted.
+func chartResults(rows *sql.Rows, w io.Writer, width int) error {
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
+	buffered := &bufferedRows{columns: columns, types: types}
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range pointers {
+			pointers[i] = &values[i]
+		}
+		err := rows.Scan(pointers...)
+		if err != nil {
+			return err
+		}
+		buffered.values = append(buffered.values, values)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	numbers, reason := chartable(buffered)
+	if reason != "" {
+		fmt.Fprintf(w, "results can't be charted: %s\n\n", reason)
+		return gitqlite.DisplayDB(buffered, w, "")
+	}
+
+	labelWidth := 0
+	valueWidth := 0
+	max := 0.0
+	for i, values := range buffered.values {
+		labelWidth = maxInt(labelWidth, utf8.RuneCountInString(label(values[0])))
+		valueWidth = maxInt(valueWidth, len(values[1].String))
+		max = math.Max(max, numbers[i])
+	}
+	if labelWidth > maxLabelWidth {
+		labelWidth = maxLabelWidth
+	}
+	// room for the label, the axis and the value following the bar
+	barWidth := width - labelWidth - valueWidth - 3
+	if barWidth < 1 {
+		barWidth = 1
+	}
+
+	for i, values := range buffered.values {
+		fmt.Fprintf(w, "%s │%s %s\n", pad(label(values[0]), labelWidth), bar(numbers[i], max, barWidth), values[1].String)
+	}
+	return nil
+}
+
+// chartable returns the numeric values of the second column of rows,
+// or the reason why rows can't be charted if they aren't made of a label column and a numeric one
+func chartable(rows *bufferedRows) ([]float64, string) {
+	if len(rows.columns) != 2 {
+		return nil, fmt.Sprintf("expected a label column and a numeric column, got %d columns", len(rows.columns))
+	}
+	if len(rows.values) == 0 {
+		return nil, "no rows"
+	}
+	numbers := make([]float64, len(rows.values))
+	for i, values := range rows.values {
+		// NULL values (i.e. sums of nothing) are drawn as empty bars
+		if !values[1].Valid {
+			continue
+		}
+		n, err := strconv.ParseFloat(values[1].String, 64)
+		if err != nil {
+			return nil, fmt.Sprintf("column %s isn't numeric (%q)", rows.columns[1], values[1].String)
+		}
+		numbers[i] = n
+	}
+	return numbers, ""
+}
+
+// bar draws a bar of value, relatively to the max value drawn with width cells (negative values are drawn as empty bars)
+func bar(value, max float64, width int) string {
+	if value <= 0 || max <= 0 {
+		return ""
+	}
+	cells := int(math.Round(value / max * float64(width) * 8))
+	return strings.Repeat("█", cells/8) + eighths[cells%8]
+}
+
+// label returns the label of a row, NULL labels being grouped under "NULL" as in tables
+func label(value sql.NullString) string {
+	if !value.Valid {
+		return "NULL"
+	}
+	return value.String
+}
+
+// pad truncates or right pads s to width runes
+func pad(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) > width {
+		return string(runes[:width-1]) + "…"
+	}
+	return s + strings.Repeat(" ", width-len(runes))
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -13,8 +13,8 @@ var (
 		ORDER BY count(*) DESC`,
 
 		"author-stats": `SELECT 
-		count(*) AS commits, SUM(additions) AS additions, SUM(deletions) AS  deletions, author_email 
-		FROM commits 
+		count(DISTINCT commits.id) AS commits, SUM(additions) AS additions, SUM(deletions) AS  deletions, author_email 
+		FROM commits LEFT JOIN stats ON commits.id = stats.commit_id
 		GROUP BY author_email
 		ORDER BY commits`,
 
@@ -30,6 +30,14 @@ var (
 			author_email
 		FROM commits GROUP BY author_email ORDER BY commits`,
 
+		"off-hours": `SELECT
+			author_email,
+			count(*) AS commits,
+			sum(is_weekend(author_when)) AS weekend,
+			sum(is_after_hours(author_when) AND NOT is_weekend(author_when)) AS weekday_after_hours,
+			round(100.0 * sum(is_weekend(author_when) OR is_after_hours(author_when)) / count(*), 1) AS off_hours_pct
+		FROM commits GROUP BY author_email ORDER BY off_hours_pct DESC`,
+
 		"tables": `
 		SELECT name FROM sqlite_master
 		WHERE
@@ -37,4 +45,12 @@ var (
 			name NOT LIKE 'sqlite_%'
 		`,
 	}
+
+	// RowQueries are the queries loaded for the row selected in the Output view, given its commit hash or its path
+	RowQueries = map[string]string{
+
+		"commit": "SELECT file, additions, deletions, status FROM stats WHERE commit_id = '%s'",
+
+		"path": "SELECT commit_id, file, additions, deletions FROM stats WHERE path = '%s'",
+	}
 )
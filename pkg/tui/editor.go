@@ -0,0 +1,112 @@
+package tui
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/jroimartin/gocui"
+)
+
+// pairs are the brackets and quotes closed as they're opened in the Query view
+var pairs = map[rune]rune{'(': ')', '[': ']', '\'': '\'', '"': '"'}
+
+// queryEditor edits multi-line statements in the Query view: Enter starts a new line indented like the current one
+// (statements are run with Ctrl+E), brackets and quotes are closed as they're opened, and typing or deleting
+// the closing one of a pair doesn't leave it unbalanced
+var queryEditor = gocui.EditorFunc(func(v *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier) {
+	switch {
+	case key == gocui.KeyEnter:
+		runes, _ := cursorLine(v)
+		line := string(runes)
+		indent := line[:len(line)-len(strings.TrimLeftFunc(line, unicode.IsSpace))]
+		v.EditNewLine()
+		for _, r := range indent {
+			v.EditWrite(r)
+		}
+	case key == gocui.KeyBackspace || key == gocui.KeyBackspace2:
+		line, x := cursorLine(v)
+		// deleting the opening one of an empty pair deletes both
+		if x > 0 && x < len(line) {
+			if closing, ok := pairs[line[x-1]]; ok && line[x] == closing {
+				v.EditDelete(false)
+			}
+		}
+		v.EditDelete(true)
+	case ch != 0 && mod == gocui.ModNone:
+		line, x := cursorLine(v)
+		var next rune
+		if x < len(line) {
+			next = line[x]
+		}
+		inString := strings.Count(string(line[:x]), string(ch))%2 == 1
+		switch {
+		// typing the closing one of a pair which is already there moves over it
+		case ch == next && (ch == ')' || ch == ']' || (isQuote(ch) && inString)):
+			v.MoveCursor(1, 0, false)
+		case opensPair(ch, line, x, inString):
+			v.EditWrite(ch)
+			v.EditWrite(pairs[ch])
+			v.MoveCursor(-1, 0, false)
+		default:
+			v.EditWrite(ch)
+		}
+	default:
+		gocui.DefaultEditor.Edit(v, key, ch, mod)
+	}
+})
+
+func isQuote(ch rune) bool {
+	return ch == '\'' || ch == '"'
+}
+
+// opensPair returns whether typing ch at x in line opens a pair to close: brackets are closed unless they're typed
+// right before a word, and quotes unless they close a string or are typed within a word (as in don't)
+func opensPair(ch rune, line []rune, x int, inString bool) bool {
+	if _, ok := pairs[ch]; !ok {
+		return false
+	}
+	if x < len(line) && (unicode.IsLetter(line[x]) || unicode.IsDigit(line[x])) {
+		return false
+	}
+	if isQuote(ch) {
+		if inString {
+			return false
+		}
+		if x > 0 && (unicode.IsLetter(line[x-1]) || unicode.IsDigit(line[x-1])) {
+			return false
+		}
+	}
+	return true
+}
+
+// cursorLine returns the line of the buffer of v the cursor is on, and the position of the cursor in it.
+// When v wraps its lines, the line the cursor is on on screen is only part of a line of the buffer: lines are
+// wrapped as gocui draws them, every width of the view, so that the buffer itself is never wrapped and the statement
+// is drawn again as it was when the view is resized.
+func cursorLine(v *gocui.View) ([]rune, int) {
+	cx, cy := v.Cursor()
+	_, oy := v.Origin()
+	y := oy + cy
+	width, _ := v.Size()
+	if !v.Wrap || width <= 0 {
+		line, err := v.Line(cy)
+		if err != nil {
+			return nil, 0
+		}
+		return []rune(line), cx
+	}
+
+	for _, line := range v.BufferLines() {
+		runes := []rune(line)
+		wrapped := len(runes)/width + 1
+		if y < wrapped {
+			x := y*width + cx
+			if x > len(runes) {
+				x = len(runes)
+			}
+			return runes, x
+		}
+		y -= wrapped
+	}
+	return nil, 0
+}
@@ -1,8 +1,11 @@
 package tui
 
 import (
+	"database/sql"
 	"fmt"
 	"path/filepath"
+	"strings"
+	"sync"
 	"text/tabwriter"
 	"time"
 
@@ -10,12 +13,36 @@ import (
 	"github.com/jroimartin/gocui"
 )
 
-//Displays a selection of information into the Info view
+var (
+	// info is the summary of the repository displayed in the Info view, nil while it's loading
+	info   *repoInfo
+	infoMu sync.Mutex
+	// queryTime is how long the last query took, displayed in the Info view
+	queryTime time.Duration
+)
+
+// repoInfo is the row of the repo table, or the error it failed to be read with
+type repoInfo struct {
+	head          sql.NullString
+	defaultBranch sql.NullString
+	remoteURL     sql.NullString
+	commits       int
+	first         sql.NullString
+	last          sql.NullString
+	err           error
+}
+
+//Displays the summary of the repository (once it's loaded) and how long the last query took into the Info view
 func DisplayInformation(g *gocui.Gui, git *gitqlite.GitQLite, length time.Duration) error {
 	out, err := g.View("Info")
 	if err != nil {
 		return err
 	}
+	queryTime = length
+	infoMu.Lock()
+	current := info
+	infoMu.Unlock()
+
 	w := tabwriter.NewWriter(out, 0, 0, 1, ' ', 0)
 	out.Clear()
 	path, err := filepath.Abs(usrInpt)
@@ -24,40 +51,57 @@ func DisplayInformation(g *gocui.Gui, git *gitqlite.GitQLite, length time.Durati
 	}
 	fmt.Fprintln(w, "Repo \t "+path+"\t")
 
-	row := git.DB.QueryRow("select count(*) from commits")
-	var commitCount int
-	err = row.Scan(&commitCount)
-	if err != nil {
-		return err
+	switch {
+	case current == nil:
+		fmt.Fprintln(w, "Loading... \t\t")
+	case current.err != nil:
+		fmt.Fprintln(w, "Error \t", current.err, "\t")
+	default:
+		head := "(detached)"
+		if current.head.Valid {
+			head = strings.TrimPrefix(current.head.String, "refs/heads/")
+		}
+		fmt.Fprintln(w, "Branch \t", head, "\t")
+		fmt.Fprintln(w, "Default branch \t", orNone(current.defaultBranch), "\t")
+		fmt.Fprintln(w, "Origin \t", orNone(current.remoteURL), "\t")
+		fmt.Fprintln(w, "# Commits \t", current.commits, "\t")
+		fmt.Fprintln(w, "First commit \t", orNone(current.first), "\t")
+		fmt.Fprintln(w, "Last commit \t", orNone(current.last), "\t")
 	}
-	fmt.Fprintln(w, "# Commits \t", commitCount, "\t")
 
-	row = git.DB.QueryRow("select count(distinct author_name) from commits")
-	var distinctAuthorCount int
-	err = row.Scan(&distinctAuthorCount)
-	if err != nil {
-		return err
-	}
-	fmt.Fprintln(w, "# Authors \t", distinctAuthorCount, "\t")
+	fmt.Fprintln(w, "Query time (ms)\t", length.String(), "\t")
+	w.Flush()
+	return nil
 
-	row = git.DB.QueryRow("select count(distinct name) from branches where remote = 1")
-	var distinctRemotes int
-	err = row.Scan(&distinctRemotes)
-	if err != nil {
-		return err
-	}
-	fmt.Fprintln(w, "# Remote branches \t", distinctRemotes, "\t")
+}
 
-	row = git.DB.QueryRow("select count(distinct name) from branches where remote = 0")
-	var distinctLocals int
-	err = row.Scan(&distinctLocals)
-	if err != nil {
-		return err
+// orNone returns a string to display, or - if it's NULL
+func orNone(s sql.NullString) string {
+	if !s.Valid {
+		return "-"
 	}
-	fmt.Fprintln(w, "# Local branches \t", distinctLocals, "\t")
+	return s.String
+}
 
-	fmt.Fprintln(w, "Query time (ms)\t", length.String(), "\t")
-	w.Flush()
-	return nil
+//Loads the summary of the repository from the repo table in the background, so that large repositories don't block the layout,
+//and displays it once it's loaded
+func RefreshInformation(g *gocui.Gui, git *gitqlite.GitQLite) error {
+	infoMu.Lock()
+	info = nil
+	infoMu.Unlock()
+
+	go func() {
+		loaded := &repoInfo{}
+		loaded.err = git.DB.QueryRow("SELECT head, default_branch, remote_url, commit_count, first_commit_at, last_commit_at FROM repo").
+			Scan(&loaded.head, &loaded.defaultBranch, &loaded.remoteURL, &loaded.commits, &loaded.first, &loaded.last)
+
+		infoMu.Lock()
+		info = loaded
+		infoMu.Unlock()
+		g.Update(func(g *gocui.Gui) error {
+			return DisplayInformation(g, git, queryTime)
+		})
+	}()
 
+	return DisplayInformation(g, git, queryTime)
 }
@@ -1,6 +1,7 @@
 package tui
 
 import (
+	"bytes"
 	"fmt"
 	"time"
 
@@ -75,6 +76,9 @@ func HandleClick(g *gocui.Gui, v *gocui.View) error {
 		if err != nil {
 			return nil
 		}
+		if v.Name() == "Output" {
+			selectRow(v)
+		}
 	}
 	return nil
 }
@@ -116,6 +120,11 @@ func HandleCursor(g *gocui.Gui, v *gocui.View) error {
 	return nil
 }
 
+//Reloads the summary of the repository in the Info view
+func RefreshInfo(g *gocui.Gui, v *gocui.View) error {
+	return RefreshInformation(g, instance)
+}
+
 //Run's the query
 func RunQuery(g *gocui.Gui, v *gocui.View) error {
 	input, err := g.View("Query")
@@ -133,23 +142,43 @@ func RunQuery(g *gocui.Gui, v *gocui.View) error {
 			return err
 		}
 		query = input.Buffer()
-		git, err := gitqlite.New(repoPath, &gitqlite.Options{})
-		if err != nil {
-			return err
-		}
 		start := time.Now()
-		rows, err := git.DB.Query(query)
+		key, cacheable, err := resultKey(query)
 		if err != nil {
-			fmt.Fprint(out, err)
+			showOutput(out, err.Error())
 			return nil
 		}
+		width, _ := out.Size()
+		if charting {
+			// charts are drawn to fit the Output view, so they depend on its width too
+			key = fmt.Sprintf("chart %d\n%s", width, key)
+		}
+		if output, ok := results.get(key); cacheable && ok {
+			showOutput(out, output)
+		} else {
+			rows, err := instance.DB.Query(query)
+			if err != nil {
+				showOutput(out, err.Error())
+				return nil
+			}
+			defer rows.Close()
 
-		err = gitqlite.DisplayDB(rows, out, "")
-		if err != nil {
-			return err
+			var output bytes.Buffer
+			if charting {
+				err = chartResults(rows, &output, width)
+			} else {
+				err = gitqlite.DisplayDB(rows, &output, "")
+			}
+			if err != nil {
+				return err
+			}
+			showOutput(out, output.String())
+			if cacheable {
+				results.put(key, output.String())
+			}
 		}
 		total := time.Since(start)
-		err = DisplayInformation(g, git, total)
+		err = DisplayInformation(g, instance, total)
 		if err != nil {
 			return err
 		}
@@ -157,6 +186,20 @@ func RunQuery(g *gocui.Gui, v *gocui.View) error {
 	return nil
 }
 
+//Displays s in the Output view, scrolled back to its start
+func showOutput(out *gocui.View, s string) {
+	selected = nil
+	out.Title = outputTitle
+	scrolled.set(s)
+	scrolled.render(out)
+}
+
+//Switches between rendering results as a table and as a bar chart, and runs the query again
+func ToggleChart(g *gocui.Gui, v *gocui.View) error {
+	charting = !charting
+	return RunQuery(g, v)
+}
+
 //Goes to the previous line
 func PreviousLine(g *gocui.Gui, v *gocui.View) error {
 
@@ -182,25 +225,18 @@ func NextLine(g *gocui.Gui, v *gocui.View) error {
 
 	return nil
 }
+//Scrolls the output a column to the left, its first column staying pinned
 func GoLeft(g *gocui.Gui, v *gocui.View) error {
-
-	x, y := v.Origin()
-	err := v.SetOrigin(x-1, y)
-	if err != nil {
-		//do nothing print for lint
-		fmt.Print()
-	}
-
+	width, _ := v.Size()
+	scrolled.scroll(true, width)
+	scrolled.render(v)
 	return nil
 }
-func GoRight(g *gocui.Gui, v *gocui.View) error {
-
-	x, y := v.Origin()
-	err := v.SetOrigin(x+1, y)
-	if err != nil {
-		//do nothing print for lint
-		fmt.Print()
-	}
 
+//Scrolls the output a column to the right, its first column staying pinned
+func GoRight(g *gocui.Gui, v *gocui.View) error {
+	width, _ := v.Size()
+	scrolled.scroll(false, width)
+	scrolled.render(v)
 	return nil
 }
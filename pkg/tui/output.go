@@ -0,0 +1,116 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jroimartin/gocui"
+)
+
+// scrolled is what's displayed in the Output view, scrolled horizontally with its first column pinned
+var scrolled = &scrolledOutput{}
+
+// scrolledOutput is some output scrolled horizontally. When it's a table, its first column (usually a hash or a path)
+// stays pinned to the left, and scrolling moves column by column, using the widths measured from the borders of the table.
+type scrolledOutput struct {
+	lines []string
+	// frozen is the width of the pinned first column, borders included (0 if the output isn't a table)
+	frozen int
+	// columns are where the columns following the first one start, relatively to the end of the frozen one
+	columns []int
+	// offset is how far the output is scrolled to the right, past the frozen column
+	offset int
+}
+
+// set replaces the output, scrolled back to its start
+func (o *scrolledOutput) set(s string) {
+	o.lines = strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	o.frozen = 0
+	o.columns = nil
+	o.offset = 0
+
+	// tables are drawn by tablewriter, where the first line of the table is its top border: +-----+------+
+	for _, line := range o.lines {
+		if !strings.HasPrefix(line, "+") {
+			continue
+		}
+		var borders []int
+		for i, r := range []rune(line) {
+			if r == '+' {
+				borders = append(borders, i)
+			}
+		}
+		// a table of a single column has nothing to scroll to past it
+		if len(borders) > 2 {
+			o.frozen = borders[1]
+			for _, b := range borders[1 : len(borders)-1] {
+				o.columns = append(o.columns, b-o.frozen)
+			}
+		}
+		break
+	}
+}
+
+// width is the width of the widest line
+func (o *scrolledOutput) width() int {
+	width := 0
+	for _, line := range o.lines {
+		width = maxInt(width, len([]rune(line)))
+	}
+	return width
+}
+
+// scroll moves the output by a column to the right (or to the left if left is true), within a view that's width wide.
+// Columns wider than the view are scrolled through by half the width of the view, and outputs which aren't tables
+// are scrolled by a character.
+func (o *scrolledOutput) scroll(left bool, width int) {
+	step := 1
+	if o.frozen > 0 {
+		step = maxInt((width-o.frozen)/2, 1)
+	}
+
+	if left {
+		target := maxInt(o.offset-step, 0)
+		for _, c := range o.columns {
+			if c < o.offset && c > target {
+				target = c
+			}
+		}
+		o.offset = target
+		return
+	}
+
+	// the last column is scrolled to its end, at most
+	last := maxInt(o.width()-width, 0)
+	target := o.offset + step
+	for _, c := range o.columns {
+		if c > o.offset && c < target {
+			target = c
+		}
+	}
+	if target > last {
+		target = maxInt(last, o.offset)
+	}
+	o.offset = target
+}
+
+// render draws the output in v, as scrolled. The lines around a table (i.e. notices) aren't scrolled.
+func (o *scrolledOutput) render(v *gocui.View) {
+	v.Clear()
+	for _, line := range o.lines {
+		if o.frozen > 0 && !strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "|") {
+			fmt.Fprintln(v, line)
+			continue
+		}
+		runes := []rune(line)
+		frozen := o.frozen
+		if frozen > len(runes) {
+			frozen = len(runes)
+		}
+		start := frozen + o.offset
+		if start > len(runes) {
+			start = len(runes)
+		}
+		fmt.Fprintf(v, "%s%s\n", string(runes[:frozen]), string(runes[start:]))
+	}
+}
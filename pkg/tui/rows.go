@@ -0,0 +1,186 @@
+package tui
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/augmentable-dev/askgit/pkg/gitqlite"
+	"github.com/jroimartin/gocui"
+)
+
+// outputTitle is the title of the Output view, when no row is selected
+const outputTitle = "Output"
+
+// selected is the row of the results selected in the Output view, nil if none is
+var selected *selectedRow
+
+// hashPattern matches full commit hashes
+var hashPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// selectedRow is a row of results, with its values by column
+type selectedRow struct {
+	columns []string
+	values  []string
+}
+
+// hash returns the commit hash of the row, from its id or commit_id column, or from any column holding a hash,
+// or "" if there is none
+func (r *selectedRow) hash() string {
+	for _, name := range []string{"id", "commit_id", "hash"} {
+		if value := r.value(name); hashPattern.MatchString(value) {
+			return value
+		}
+	}
+	for _, value := range r.values {
+		if hashPattern.MatchString(value) {
+			return value
+		}
+	}
+	return ""
+}
+
+// path returns the path of the file or directory of the row, or "" if there is none
+func (r *selectedRow) path() string {
+	for _, name := range []string{"path", "file", "dir"} {
+		if value := r.value(name); value != "" && value != "NULL" {
+			return value
+		}
+	}
+	return ""
+}
+
+func (r *selectedRow) value(column string) string {
+	for i, c := range r.columns {
+		if strings.EqualFold(c, column) && i < len(r.values) {
+			return r.values[i]
+		}
+	}
+	return ""
+}
+
+// cells splits a line of a table drawn by tablewriter (| a | b |) into its trimmed cells
+func cells(line string) []string {
+	parts := strings.Split(strings.TrimSpace(line), "|")
+	if len(parts) < 3 {
+		return nil
+	}
+	parts = parts[1 : len(parts)-1]
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+	}
+	return parts
+}
+
+// selectRow selects the row of the results under the cursor of the Output view, and lists what can be done with it in its title
+func selectRow(v *gocui.View) {
+	selected = nil
+	v.Title = outputTitle
+
+	_, oy := v.Origin()
+	_, cy := v.Cursor()
+	y := oy + cy
+	if y >= len(scrolled.lines) || !strings.HasPrefix(scrolled.lines[y], "|") {
+		return
+	}
+
+	// the header is the first row of the table, which isn't a row of results
+	header := -1
+	for i, line := range scrolled.lines {
+		if strings.HasPrefix(line, "|") {
+			header = i
+			break
+		}
+	}
+	if header == y {
+		return
+	}
+
+	row := &selectedRow{columns: cells(scrolled.lines[header]), values: cells(scrolled.lines[y])}
+	var actions []string
+	if row.hash() != "" {
+		actions = append(actions, "c: show commit", "y: copy hash")
+	}
+	if row.hash() != "" || row.path() != "" {
+		actions = append(actions, "p: load query")
+	}
+	if len(actions) == 0 {
+		return
+	}
+	selected = row
+	v.Title = fmt.Sprintf("%s (%s)", outputTitle, strings.Join(actions, ", "))
+}
+
+// Shows the commit of the selected row, with its message and the stats of its changes
+func ShowCommit(g *gocui.Gui, v *gocui.View) error {
+	if selected == nil || selected.hash() == "" {
+		return nil
+	}
+	hash := selected.hash()
+
+	var output bytes.Buffer
+	var name, email, when, message string
+	err := instance.DB.QueryRow("SELECT author_name, author_email, author_when, message FROM commits WHERE id = ?", hash).Scan(&name, &email, &when, &message)
+	if err != nil {
+		showOutput(v, err.Error())
+		return nil
+	}
+	fmt.Fprintf(&output, "commit %s\nAuthor: %s <%s>\nDate:   %s\n\n", hash, name, email, when)
+	for _, line := range strings.Split(strings.TrimRight(message, "\n"), "\n") {
+		fmt.Fprintf(&output, "    %s\n", line)
+	}
+	fmt.Fprintln(&output)
+
+	rows, err := instance.DB.Query("SELECT file, additions, deletions, status FROM stats WHERE commit_id = ?", hash)
+	if err != nil {
+		showOutput(v, err.Error())
+		return nil
+	}
+	defer rows.Close()
+	err = gitqlite.DisplayDB(rows, &output, "")
+	if err != nil {
+		return err
+	}
+
+	showOutput(v, output.String())
+	return nil
+}
+
+// Copies the commit hash of the selected row to the clipboard
+func CopyHash(g *gocui.Gui, v *gocui.View) error {
+	if selected == nil || selected.hash() == "" {
+		return nil
+	}
+	hash := selected.hash()
+
+	// the OSC 52 escape sequence sets the clipboard of the terminal, which works over SSH too
+	fmt.Fprintf(os.Stdout, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(hash)))
+	v.Title = fmt.Sprintf("%s (copied %s)", outputTitle, hash[:7])
+	return nil
+}
+
+// Loads the query of RowQueries for the selected row in the Query view, for its commit hash or else its path
+func LoadRowQuery(g *gocui.Gui, v *gocui.View) error {
+	if selected == nil {
+		return nil
+	}
+	var q string
+	if hash := selected.hash(); hash != "" {
+		q = fmt.Sprintf(RowQueries["commit"], hash)
+	} else if path := selected.path(); path != "" {
+		q = fmt.Sprintf(RowQueries["path"], strings.ReplaceAll(path, "'", "''"))
+	} else {
+		return nil
+	}
+
+	input, err := g.View("Query")
+	if err != nil {
+		return err
+	}
+	input.Clear()
+	fmt.Fprint(input, q)
+	return nil
+}
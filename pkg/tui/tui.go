@@ -14,10 +14,22 @@ var (
 	query    = ""
 	repoPath = ""
 	usrInpt  = ""
+	// instance is the GitQLite instance every query of the TUI runs on
+	instance *gitqlite.GitQLite
+)
+
+// minWidth and minHeight are the smallest size of the terminal the views are laid out in
+const (
+	minWidth  = 40
+	minHeight = 20
 )
 
 func layout(g *gocui.Gui) error {
 	maxX, maxY := g.Size()
+	// views can't be laid out in a terminal that small, leave them as they are until it's resized again
+	if maxX < minWidth || maxY < minHeight {
+		return nil
+	}
 	if v, err := g.SetView("Query", 0, 0, maxX/2-1, maxY*2/10); err != nil {
 		if err != gocui.ErrUnknownView {
 			return err
@@ -25,6 +37,7 @@ func layout(g *gocui.Gui) error {
 		v.Title = "Query"
 		v.Editable = true
 		v.Wrap = true
+		v.Editor = queryEditor
 		fmt.Fprint(v, query)
 		if _, err = SetCurrentViewOnTop(g, "Query"); err != nil {
 			return err
@@ -38,7 +51,7 @@ func layout(g *gocui.Gui) error {
 		v.Title = "Keybinds"
 		w := tabwriter.NewWriter(v, 0, 0, 1, ' ', 0)
 
-		fmt.Fprint(w, "Ctrl+C\t exit \nCtrl+E\t execute query \nCtrl+Q\t clear query box\nDefault L-click \t select a default to be displayed in the query view\n\n")
+		fmt.Fprint(w, "Ctrl+C\t exit \nCtrl+E\t execute query \nEnter\t new line in query \nCtrl+Q\t clear query box\nCtrl+R\t refresh info\nCtrl+G\t toggle chart of results\nOutput L-click \t select a row (c: show commit, y: copy hash, p: load query)\nDefault L-click \t select a default to be displayed in the query view\n\n")
 
 	}
 	if v, err := g.SetView("Info", maxX/2, maxY*2/10+1, maxX-1, maxY*4/10); err != nil {
@@ -46,11 +59,7 @@ func layout(g *gocui.Gui) error {
 			return err
 		}
 		v.Title = "Info"
-		git, err := gitqlite.New(repoPath, &gitqlite.Options{})
-		if err != nil {
-			return err
-		}
-		err = DisplayInformation(g, git, 0)
+		err = RefreshInformation(g, instance)
 		if err != nil {
 			return err
 		}
@@ -60,8 +69,11 @@ func layout(g *gocui.Gui) error {
 		if err != gocui.ErrUnknownView {
 			return err
 		}
-		v.Title = "Output"
+		v.Title = outputTitle
 		v.Wrap = false
+		v.Highlight = true
+		v.SelFgColor = gocui.ColorBlack
+		v.SelBgColor = gocui.ColorGreen
 
 	}
 	if v, err := g.SetView("Default", maxX/2, 0, maxX-1, maxY*2/10); err != nil {
@@ -83,7 +95,7 @@ func test(g *gocui.Gui, v *gocui.View) error {
 func quit(g *gocui.Gui, v *gocui.View) error {
 	return gocui.ErrQuit
 }
-func RunGUI(repo string, directory string, q string) {
+func RunGUI(repo string, directory string, q string, options *gitqlite.Options) {
 	g, err := gocui.NewGui(gocui.OutputNormal)
 	if err != nil {
 		log.Panicln(err)
@@ -92,6 +104,17 @@ func RunGUI(repo string, directory string, q string) {
 	query = q
 	repoPath = directory
 	usrInpt = repo
+	instance, err = gitqlite.New(repoPath, options)
+	if err != nil {
+		log.Panicln(err)
+	}
+	// custom views are presets too, unless there is a preset of the same name
+	for name := range options.Views {
+		if _, ok := Queries[name]; !ok {
+			Queries[name] = fmt.Sprintf("SELECT * FROM %q", name)
+		}
+	}
+	defer instance.DB.Close()
 	g.Highlight = true
 	g.Cursor = true
 	g.SelFgColor = gocui.ColorGreen
@@ -135,6 +158,21 @@ func RunGUI(repo string, directory string, q string) {
 	if err := g.SetKeybinding("Output", gocui.KeyArrowLeft, gocui.ModNone, GoLeft); err != nil {
 		log.Panicln(err)
 	}
+	if err := g.SetKeybinding("", gocui.KeyCtrlR, gocui.ModNone, RefreshInfo); err != nil {
+		log.Panicln(err)
+	}
+	if err := g.SetKeybinding("", gocui.KeyCtrlG, gocui.ModNone, ToggleChart); err != nil {
+		log.Panicln(err)
+	}
+	if err := g.SetKeybinding("Output", 'c', gocui.ModNone, ShowCommit); err != nil {
+		log.Panicln(err)
+	}
+	if err := g.SetKeybinding("Output", 'y', gocui.ModNone, CopyHash); err != nil {
+		log.Panicln(err)
+	}
+	if err := g.SetKeybinding("Output", 'p', gocui.ModNone, LoadRowQuery); err != nil {
+		log.Panicln(err)
+	}
 	if err := g.SetKeybinding("", gocui.KeyCtrlT, gocui.ModNone, test); err != nil {
 		log.Panicln(err)
 	}